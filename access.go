@@ -0,0 +1,71 @@
+package sample1
+
+import "sync"
+
+const (
+	accessBufferSize = 64                    // hits buffered before they are replayed into the policy
+	maxBufferedHits  = 16 * accessBufferSize // hits buffered at most while the cache is too busy to replay them
+)
+
+// bufferedPolicy is the EvictionPolicy of a bounded cache: its hits are buffered instead of taking the lock of the
+// cache one by one, and replayed into the policy in the order they happened, before any other change to it or once
+// the buffer is full, as W-TinyLFU implementations do. The hits that come while the buffer is full and the cache
+// busy are dropped, the policy is only an approximation of the use of the keys anyway
+// It is guarded by the mutex of the cache, OnAccess aside
+type bufferedPolicy[K comparable] struct {
+	EvictionPolicy[K]
+	lock    *sync.RWMutex  // the mutex of the cache
+	members map[K]struct{} // the keys the policy was told about, the hits of the others are dropped on replay
+
+	mu   sync.Mutex // guards hits
+	hits []K
+}
+
+func newBufferedPolicy[K comparable](policy EvictionPolicy[K], lock *sync.RWMutex) *bufferedPolicy[K] {
+	return &bufferedPolicy[K]{EvictionPolicy: policy, lock: lock, members: map[K]struct{}{}}
+}
+
+// OnAccess buffers a hit of key, it must be called without holding the mutex of the cache
+func (p *bufferedPolicy[K]) OnAccess(key K) {
+	p.mu.Lock()
+	if len(p.hits) < maxBufferedHits {
+		p.hits = append(p.hits, key)
+	}
+	full := len(p.hits) >= accessBufferSize
+	p.mu.Unlock()
+	if full && p.lock.TryLock() {
+		p.replay()
+		p.lock.Unlock()
+	}
+}
+
+func (p *bufferedPolicy[K]) OnInsert(key K) {
+	p.replay()
+	p.members[key] = struct{}{}
+	p.EvictionPolicy.OnInsert(key)
+}
+
+func (p *bufferedPolicy[K]) OnRemove(key K) {
+	p.replay()
+	delete(p.members, key)
+	p.EvictionPolicy.OnRemove(key)
+}
+
+func (p *bufferedPolicy[K]) Victim() (K, bool) {
+	p.replay()
+	return p.EvictionPolicy.Victim()
+}
+
+// replay gives the buffered hits of the keys still in the policy to it, the mutex of the cache must be held for
+// writing
+func (p *bufferedPolicy[K]) replay() {
+	p.mu.Lock()
+	hits := p.hits
+	p.hits = nil
+	p.mu.Unlock()
+	for _, key := range hits {
+		if _, ok := p.members[key]; ok {
+			p.EvictionPolicy.OnAccess(key)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package sample1
+
+import (
+	"testing"
+	"time"
+)
+
+// Check that a hit reads the store once, its access only reaching the policy later
+func TestBufferedPolicy_HitsAreBuffered(t *testing.T) {
+	store := &countingStore{MapStore: NewMapStore[string, float64]()}
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 1}, "p2": {price: 2}, "p3": {price: 3}}}
+	cache := NewTransparentCache(mockService, time.Minute, WithStore[string, float64](store), WithMaxEntries(2))
+	getPricesWithNoErr(t, cache, "p1", "p2")
+	store.mu.Lock()
+	store.gets = 0
+	store.mu.Unlock()
+
+	getPriceWithNoErr(t, cache, "p1")
+	cache.policy.mu.Lock()
+	assertInt(t, 1, len(cache.policy.hits), "the hit should have been buffered")
+	cache.policy.mu.Unlock()
+	assertInt(t, 1, store.gets, "a hit should read the store once")
+
+	getPriceWithNoErr(t, cache, "p3") // the hit of p1 is replayed first, p2 is the least recently used
+	if !cache.Contains("p1") || cache.Contains("p2") {
+		t.Error("the buffered hit should have kept p1 over p2")
+	}
+}
+
+// Check that the buffered hits of the keys that left the policy are dropped, instead of coming back into it
+func TestBufferedPolicy_DropsHitsOfRemovedKeys(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 1}, "p2": {price: 2}}}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxEntries(2))
+	getPricesWithNoErr(t, cache, "p1", "p2")
+	getPriceWithNoErr(t, cache, "p1")
+	cache.policy.mu.Lock()
+	cache.policy.hits = append(cache.policy.hits, "p1") // a hit racing with the invalidation below
+	cache.policy.mu.Unlock()
+	cache.Invalidate("p1")
+	cache.mu.Lock()
+	cache.policy.replay()
+	cache.mu.Unlock()
+	assertInt(t, 1, cache.policy.EvictionPolicy.(*LRUPolicy[string]).order.len(), "the invalidated key shouldn't be back in the policy")
+}
+
+// Check that a full buffer is replayed by the hit that filled it, and that the hits are dropped rather than
+// waiting while the cache is busy
+func TestBufferedPolicy_ReplaysWhenFull(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 1}}}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxEntries(2))
+	getPriceWithNoErr(t, cache, "p1")
+	for range accessBufferSize {
+		getPriceWithNoErr(t, cache, "p1")
+	}
+	cache.policy.mu.Lock()
+	assertInt(t, 0, len(cache.policy.hits), "the full buffer should have been replayed")
+	cache.policy.mu.Unlock()
+
+	cache.mu.RLock()
+	for range 2 * maxBufferedHits {
+		getPriceWithNoErr(t, cache, "p1")
+	}
+	cache.mu.RUnlock()
+	cache.policy.mu.Lock()
+	assertInt(t, maxBufferedHits, len(cache.policy.hits), "the hits past the buffer should have been dropped")
+	cache.policy.mu.Unlock()
+}
@@ -0,0 +1,67 @@
+package sample1
+
+import (
+	"reflect"
+	"time"
+)
+
+// WithAdaptiveMaxAge makes the maxAge of every key follow how often its value changes: each load that replaces
+// a cached value halves the maxAge of the key if the value changed and doubles it if it didn't, within
+// [minMaxAge, maxMaxAge]. Keys start from the maxAge of the cache, so volatile values are soon refreshed more
+// often and stable ones less often
+// A maxAge set for a key with SetMaxAgeFor, or given by WithFreshness, still wins
+func WithAdaptiveMaxAge(minMaxAge, maxMaxAge time.Duration) Option {
+	return func(c *config) {
+		c.adaptiveMin, c.adaptiveMax = minMaxAge, maxMaxAge
+	}
+}
+
+// adaptiveMaxAges keeps the maxAge every key adapted to, it is guarded by the mutex of the cache
+type adaptiveMaxAges[K comparable] struct {
+	min, max time.Duration // nothing is adapted if max is zero or less
+	byKey    map[K]time.Duration
+}
+
+func (a *adaptiveMaxAges[K]) enabled() bool {
+	return a.max > 0
+}
+
+// get returns the maxAge key adapted to, ok is false if it didn't yet
+func (a *adaptiveMaxAges[K]) get(key K) (time.Duration, bool) {
+	maxAge, ok := a.byKey[key]
+	return maxAge, ok
+}
+
+// adapt halves or doubles the maxAge of key, from current, depending on whether its value changed
+func (a *adaptiveMaxAges[K]) adapt(key K, current time.Duration, changed bool) {
+	if maxAge, ok := a.byKey[key]; ok {
+		current = maxAge
+	}
+	if changed {
+		current /= 2
+	} else {
+		current *= 2
+	}
+	if a.byKey == nil {
+		a.byKey = map[K]time.Duration{}
+	}
+	a.byKey[key] = min(max(current, a.min), a.max)
+}
+
+// remove forgets the maxAge key adapted to
+func (a *adaptiveMaxAges[K]) remove(key K) {
+	delete(a.byKey, key)
+}
+
+// clear forgets every adapted maxAge
+func (a *adaptiveMaxAges[K]) clear() {
+	a.byKey = nil
+}
+
+// adapt adapts the maxAge of key to a load of value replacing previous, c.mu must be held for writing
+func (c *Cache[K, V]) adapt(key K, value V, previous Entry[V]) {
+	if !c.adaptive.enabled() || previous.Err != nil {
+		return
+	}
+	c.adaptive.adapt(key, c.maxAge, !reflect.DeepEqual(previous.Value, value))
+}
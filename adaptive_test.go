@@ -0,0 +1,66 @@
+package sample1
+
+import (
+	"testing"
+	"time"
+)
+
+// loadAndTTL loads price for p1 once its cached price expired and returns the TTL it is cached with
+func loadAndTTL(t *testing.T, clock *fakeClock, cache *TransparentCache, mockService *mockPriceService, price float64) time.Duration {
+	t.Helper()
+	if ttl, ok := cache.TTL("p1"); ok {
+		clock.Advance(ttl + time.Second)
+	}
+	mockService.setPrice("p1", price)
+	getPriceWithNoErr(t, cache, "p1")
+	ttl, ok := cache.TTL("p1")
+	if !ok {
+		t.Fatalf("p1 should be cached")
+	}
+	return ttl
+}
+
+// Check that the maxAge of an item halves at every change of its price and doubles when it doesn't change,
+// within the bounds
+func TestAdaptiveMaxAge_FollowsChanges(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 1, err: nil},
+	}}
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock), WithAdaptiveMaxAge(10*time.Second, 4*time.Minute))
+	if ttl := loadAndTTL(t, clock, cache, mockService, 1); ttl != time.Minute {
+		t.Errorf("the first load should use the maxAge of the cache, got %v", ttl)
+	}
+	for i, want := range []time.Duration{30 * time.Second, 15 * time.Second, 10 * time.Second, 10 * time.Second} {
+		if ttl := loadAndTTL(t, clock, cache, mockService, float64(i+2)); ttl != want {
+			t.Errorf("change %d: expected a maxAge of %v but got %v", i, want, ttl)
+		}
+	}
+	for i, want := range []time.Duration{20 * time.Second, 40 * time.Second, 80 * time.Second, 160 * time.Second, 4 * time.Minute} {
+		if ttl := loadAndTTL(t, clock, cache, mockService, 5); ttl != want {
+			t.Errorf("no change %d: expected a maxAge of %v but got %v", i, want, ttl)
+		}
+	}
+	assertInt(t, 10, mockService.getNumCalls(), "every expired price should have been loaded")
+}
+
+// Check that the adapted maxAges are forgotten with a clear, and that per key overrides win
+func TestAdaptiveMaxAge_OverridesAndClear(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 1, err: nil},
+	}}
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock), WithAdaptiveMaxAge(time.Second, time.Hour))
+	loadAndTTL(t, clock, cache, mockService, 1)
+	if ttl := loadAndTTL(t, clock, cache, mockService, 2); ttl != 30*time.Second {
+		t.Errorf("expected a maxAge of 30s after a change but got %v", ttl)
+	}
+	cache.Clear()
+	if ttl := loadAndTTL(t, clock, cache, mockService, 3); ttl != time.Minute {
+		t.Errorf("the clear should have forgotten the adapted maxAge, got %v", ttl)
+	}
+	cache.SetMaxAgeFor("p1", 5*time.Minute)
+	if ttl := loadAndTTL(t, clock, cache, mockService, 4); ttl != 5*time.Minute {
+		t.Errorf("the maxAge set for the item should win, got %v", ttl)
+	}
+}
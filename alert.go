@@ -0,0 +1,81 @@
+package sample1
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HitRatioAlert tells when the cache stopped being effective, see WithHitRatioAlert
+type HitRatioAlert struct {
+	Threshold  float64       // the hit ratio below which the cache is alerting
+	Window     time.Duration // the window the hit ratio is computed over, see StatsOver, 5m if zero
+	For        time.Duration // how long the hit ratio must stay below Threshold before alerting, right away if zero
+	MinLookups uint64        // lookups needed in the window for its hit ratio to count, 100 if zero
+	// OnAlert is called once the hit ratio stayed below Threshold for For, with the stats of the window, and
+	// again only after it recovered
+	OnAlert func(WindowStats)
+	// OnRecover is called, if not nil, once the hit ratio of an alerting cache is back to Threshold or more
+	OnRecover func(WindowStats)
+}
+
+// WithHitRatioAlert makes the cache call alert.OnAlert when its hit ratio stays below alert.Threshold, so that the
+// bugs busting the cache are caught before the price service melts
+// The hit ratio is checked every 10 seconds at most, in the goroutine of a lookup, so the callbacks should be quick
+func WithHitRatioAlert(alert HitRatioAlert) Option {
+	return func(c *config) {
+		if alert.Window <= 0 {
+			alert.Window = 5 * time.Minute
+		}
+		if alert.MinLookups == 0 {
+			alert.MinLookups = 100
+		}
+		c.hitRatioAlert = &alert
+	}
+}
+
+// hitRatioAlerter checks the hit ratio of the cache against its alert
+type hitRatioAlerter struct {
+	alert   HitRatioAlert
+	checked atomic.Int64 // the period of recentBucketWidth the hit ratio was last checked in
+
+	mu       sync.Mutex
+	lowSince time.Time // when the hit ratio went below the threshold, zero if it is above
+	alerting bool
+}
+
+// check calls the callbacks of the alert if the hit ratio of recent went below or back above the threshold,
+// once per period of recentBucketWidth at most
+func (a *hitRatioAlerter) check(recent *recentCounters, now time.Time) {
+	epoch, checked := epochOf(now), a.checked.Load()
+	if epoch == checked || !a.checked.CompareAndSwap(checked, epoch) {
+		return
+	}
+	stats := recent.over(a.alert.Window, now)
+	a.mu.Lock()
+	var notify func(WindowStats)
+	if stats.HitRatio() >= a.alert.Threshold {
+		if a.alerting {
+			notify = a.alert.OnRecover
+		}
+		a.lowSince, a.alerting = time.Time{}, false
+	} else if stats.Hits+stats.Misses >= a.alert.MinLookups && !a.alerting {
+		if a.lowSince.IsZero() {
+			a.lowSince = now
+		}
+		if now.Sub(a.lowSince) >= a.alert.For {
+			notify, a.alerting = a.alert.OnAlert, true
+		}
+	}
+	a.mu.Unlock()
+	if notify != nil {
+		notify(stats)
+	}
+}
+
+// checkHitRatio checks the hit ratio at now against the alert of WithHitRatioAlert, if there is one
+func (c *Cache[K, V]) checkHitRatio(now time.Time) {
+	if c.hitRatioAlert != nil {
+		c.hitRatioAlert.check(&c.counters.recent, now)
+	}
+}
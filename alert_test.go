@@ -0,0 +1,60 @@
+package sample1
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Check that the alert fires once the hit ratio stayed low long enough, and that its recovery is told
+func TestWithHitRatioAlert(t *testing.T) {
+	clock := newFakeClock()
+	var alerts, recoveries []WindowStats
+	cache := NewCache(func(_ context.Context, key int) (int, error) { return key, nil },
+		WithMaxAge(time.Hour), WithClock(clock), WithHitRatioAlert(HitRatioAlert{
+			Threshold: 0.5, Window: time.Minute, For: 30 * time.Second, MinLookups: 4,
+			OnAlert:   func(stats WindowStats) { alerts = append(alerts, stats) },
+			OnRecover: func(stats WindowStats) { recoveries = append(recoveries, stats) },
+		}))
+	next := 0
+	miss := func() {
+		next++
+		if _, err := cache.Get(context.Background(), next); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for range 4 {
+		miss()
+	}
+	clock.Advance(10 * time.Second)
+	miss()
+	assertInt(t, 0, len(alerts), "the hit ratio should have to stay low before alerting")
+	clock.Advance(30 * time.Second)
+	miss()
+	assertInt(t, 1, len(alerts), "the alert should have fired")
+	assertInt(t, 6, int(alerts[0].Misses), "the alert should get the stats of the window")
+	clock.Advance(10 * time.Second)
+	miss()
+	assertInt(t, 1, len(alerts), "the alert should fire once")
+	assertInt(t, 0, len(recoveries), "the cache should not have recovered yet")
+
+	clock.Advance(70 * time.Second)
+	cache.Get(context.Background(), 1)
+	assertInt(t, 1, len(recoveries), "the recovery should have been told")
+	assertInt(t, 1, int(recoveries[0].Hits), "the recovery should get the stats of the window")
+}
+
+// Check that a low hit ratio over too few lookups doesn't alert
+func TestWithHitRatioAlert_MinLookups(t *testing.T) {
+	clock := newFakeClock()
+	alerted := false
+	cache := NewCache(func(_ context.Context, key int) (int, error) { return key, nil }, WithClock(clock),
+		WithHitRatioAlert(HitRatioAlert{Threshold: 0.5, OnAlert: func(WindowStats) { alerted = true }}))
+	for key := range 50 {
+		cache.Get(context.Background(), key)
+		clock.Advance(10 * time.Second)
+	}
+	if alerted {
+		t.Error("50 lookups should not be enough to alert by default")
+	}
+}
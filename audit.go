@@ -0,0 +1,173 @@
+package sample1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"sync"
+	"time"
+)
+
+// AuditRecord is a call to the price service, as told to an AuditSink
+type AuditRecord struct {
+	ItemCode string
+	Price    float64 // meaningless if Err is set
+	Err      error
+	Start    time.Time
+	Latency  time.Duration
+	Caller   map[string]string // the metadata of the lookup that made the call, see WithCallerMetadata
+}
+
+// AuditSink records the calls to the price service, see NewAuditedService
+// Record is called in the goroutine of the call once it returned, so it must be safe for concurrent use
+type AuditSink interface {
+	Record(ctx context.Context, record AuditRecord)
+}
+
+// AuditSinkFunc is a function used as an AuditSink
+type AuditSinkFunc func(ctx context.Context, record AuditRecord)
+
+func (f AuditSinkFunc) Record(ctx context.Context, record AuditRecord) {
+	f(ctx, record)
+}
+
+type callerMetadataKey struct{}
+
+// WithCallerMetadata returns a context for lookups whose calls to the price service are recorded with metadata
+// (the caller, a request id...), see AuditRecord.Caller. The metadata of ctx, if any, is kept
+// Only the caller that starts a load gives it its metadata, and background refreshes have none
+func WithCallerMetadata(ctx context.Context, metadata map[string]string) context.Context {
+	merged := maps.Clone(CallerMetadata(ctx))
+	if merged == nil {
+		merged = map[string]string{}
+	}
+	maps.Copy(merged, metadata)
+	return context.WithValue(ctx, callerMetadataKey{}, merged)
+}
+
+// CallerMetadata returns the metadata WithCallerMetadata gave ctx, nil if there is none
+func CallerMetadata(ctx context.Context) map[string]string {
+	metadata, _ := ctx.Value(callerMetadataKey{}).(map[string]string)
+	return metadata
+}
+
+// NewAuditedService returns a PriceService recording every call to service in sink, with the metadata of the
+// caller (see WithCallerMetadata). Give it to the cache instead of the actual service so that no call goes unrecorded
+// If service is a BulkPriceService (or a ContextBulkPriceService), so is the returned service, recording a
+// record per item of every batch
+func NewAuditedService(service PriceService, sink AuditSink) PriceService {
+	audited := &auditedService{service: AsContextPriceService(service), sink: sink}
+	if bulkLoader := bulkLoaderForService(service); bulkLoader != nil {
+		return &auditedBulkService{auditedService: audited, bulkLoader: bulkLoader}
+	}
+	return audited
+}
+
+// Auditing is a Decorator recording every call to the service in sink, see NewAuditedService
+func Auditing(sink AuditSink) Decorator {
+	return func(service PriceService) PriceService {
+		return NewAuditedService(service, sink)
+	}
+}
+
+// auditedService is the PriceService returned by NewAuditedService
+type auditedService struct {
+	service ContextPriceService
+	sink    AuditSink
+}
+
+func (s *auditedService) GetPriceFor(itemCode string) (float64, error) {
+	return s.GetPriceForCtx(context.Background(), itemCode)
+}
+
+func (s *auditedService) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	start := time.Now()
+	price, err := s.service.GetPriceForCtx(ctx, itemCode)
+	s.sink.Record(ctx, AuditRecord{
+		ItemCode: itemCode, Price: price, Err: err, Start: start, Latency: time.Since(start), Caller: CallerMetadata(ctx),
+	})
+	return price, err
+}
+
+// auditedBulkService is the PriceService returned by NewAuditedService for a BulkPriceService
+type auditedBulkService struct {
+	*auditedService
+	bulkLoader BulkLoaderFunc[string, float64]
+}
+
+func (s *auditedBulkService) GetPricesFor(itemCodes ...string) ([]float64, error) {
+	return s.GetPricesForCtx(context.Background(), itemCodes...)
+}
+
+func (s *auditedBulkService) GetPricesForCtx(ctx context.Context, itemCodes ...string) ([]float64, error) {
+	start := time.Now()
+	prices, err := s.bulkLoader(ctx, itemCodes)
+	latency := time.Since(start)
+	var batchErr *BatchError[string]
+	failed := map[string]error{}
+	if errors.As(err, &batchErr) {
+		for _, keyErr := range batchErr.Errors {
+			failed[keyErr.Key] = keyErr.Err
+		}
+	}
+	callErr := err
+	if batchErr != nil {
+		callErr = nil // the items it names get their own error, the others their price
+	}
+	if callErr == nil && len(prices) != len(itemCodes) {
+		callErr = fmt.Errorf("asked for %d prices but got %d", len(itemCodes), len(prices))
+	}
+	for i, itemCode := range itemCodes {
+		record := AuditRecord{ItemCode: itemCode, Start: start, Latency: latency, Caller: CallerMetadata(ctx)}
+		switch {
+		case failed[itemCode] != nil:
+			record.Err = failed[itemCode]
+		case callErr != nil:
+			record.Err = callErr
+		default:
+			record.Price = prices[i]
+		}
+		s.sink.Record(ctx, record)
+	}
+	return prices, err
+}
+
+// jsonAuditSink is the AuditSink returned by NewJSONAuditSink
+type jsonAuditSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// jsonAuditRecord is an AuditRecord as written by NewJSONAuditSink
+type jsonAuditRecord struct {
+	ItemCode  string            `json:"itemCode"`
+	Price     float64           `json:"price"`
+	Err       string            `json:"err,omitempty"`
+	NotFound  bool              `json:"notFound,omitempty"` // Err is, or wraps, ErrNotFound
+	Start     time.Time         `json:"start"`
+	LatencyMs float64           `json:"latencyMs"`
+	Caller    map[string]string `json:"caller,omitempty"`
+}
+
+// NewJSONAuditSink returns an AuditSink writing every record to w as a line of JSON, the writes failing are lost
+func NewJSONAuditSink(w io.Writer) AuditSink {
+	return &jsonAuditSink{enc: json.NewEncoder(w)}
+}
+
+func (s *jsonAuditSink) Record(_ context.Context, record AuditRecord) {
+	line := jsonAuditRecord{
+		ItemCode: record.ItemCode, Start: record.Start, Caller: record.Caller,
+		LatencyMs: float64(record.Latency.Microseconds()) / 1000,
+	}
+	if record.Err != nil {
+		line.Err, line.NotFound = record.Err.Error(), isNotFound(record.Err)
+	} else {
+		line.Price = record.Price
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enc.Encode(line)
+}
@@ -0,0 +1,101 @@
+package sample1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// auditLog is an AuditSink keeping the records
+type auditLog struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (l *auditLog) Record(_ context.Context, record AuditRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, record)
+}
+
+// Check that every call to the service is recorded, with the metadata of the caller
+func TestNewAuditedService(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 5, err: nil},
+		"p2": {price: 0, err: errors.New("unknown item")},
+	}}
+	audit := &auditLog{}
+	cache := NewTransparentCache(NewAuditedService(mockService, audit), time.Minute)
+	ctx := WithCallerMetadata(WithCallerMetadata(context.Background(), map[string]string{"caller": "checkout"}),
+		map[string]string{"request": "r1"})
+	cache.GetPriceForCtx(ctx, "p1")
+	cache.GetPriceForCtx(ctx, "p1")
+	cache.GetPriceFor("p2")
+	assertInt(t, 2, len(audit.records), "every call to the service, and only those, should be recorded")
+	p1, p2 := audit.records[0], audit.records[1]
+	if p1.ItemCode != "p1" || p1.Price != 5 || p1.Err != nil || p1.Start.IsZero() {
+		t.Errorf("wrong record %+v", p1)
+	}
+	if p1.Caller["caller"] != "checkout" || p1.Caller["request"] != "r1" {
+		t.Errorf("the metadata of the caller should be recorded, got %v", p1.Caller)
+	}
+	if p2.ItemCode != "p2" || p2.Err == nil || p2.Caller != nil {
+		t.Errorf("wrong record %+v", p2)
+	}
+}
+
+// Check that the batches are recorded item by item, the failed items with their own error
+func TestNewAuditedService_Bulk(t *testing.T) {
+	mockService := &bulkMockPriceService{mockPriceService: mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 5, err: nil},
+		"p2": {price: 7, err: nil},
+	}}}
+	audit := &auditLog{}
+	service := NewAuditedService(mockService, audit)
+	if _, ok := service.(BulkPriceService); !ok {
+		t.Fatal("the audited service of a bulk service should be a bulk service")
+	}
+	cache := NewTransparentCache(service, time.Minute)
+	getPricesWithNoErr(t, cache, "p1", "p2")
+	assertInt(t, 1, len(mockService.getBulkCalls()), "the batch should have been loaded in one call")
+	assertInt(t, 2, len(audit.records), "every item of the batch should be recorded")
+	assertFloat(t, 7, audit.records[1].Price, "wrong recorded price")
+
+	mockService.mockResults["p3"] = mockResult{price: 0, err: errors.New("unknown item")}
+	mockService.mockResults["p4"] = mockResult{price: 9, err: nil}
+	cache.GetPricesFor("p3", "p4")
+	var failed []string
+	for _, record := range audit.records[2:] {
+		if record.Err != nil {
+			failed = append(failed, record.ItemCode+" : "+record.Err.Error())
+		}
+	}
+	if len(failed) == 0 || !strings.HasPrefix(failed[0], "p3 : unknown item") {
+		t.Errorf("the failed item should be recorded with its error, got %v", failed)
+	}
+}
+
+// Check that NewJSONAuditSink writes a line of JSON per record
+func TestNewJSONAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONAuditSink(&buf)
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	sink.Record(context.Background(), AuditRecord{ItemCode: "p1", Price: 5, Start: start, Latency: 1500 * time.Microsecond,
+		Caller: map[string]string{"caller": "checkout"}})
+	sink.Record(context.Background(), AuditRecord{ItemCode: "p2", Err: errors.New("unknown item"), Start: start})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assertInt(t, 2, len(lines), "wrong number of lines")
+	expected := `{"itemCode":"p1","price":5,"start":"2021-01-01T00:00:00Z","latencyMs":1.5,"caller":{"caller":"checkout"}}`
+	if lines[0] != expected {
+		t.Errorf("expected %s but got %s", expected, lines[0])
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil || record["err"] != "unknown item" {
+		t.Errorf("the error should be written, got %s", lines[1])
+	}
+}
@@ -0,0 +1,233 @@
+// Package boltstore keeps the entries of a cache in a bbolt file, so that they survive restarts
+// without running a separate server, it is meant for single node deployments
+// It lives in its own package so that the cache itself doesn't depend on bbolt
+package boltstore
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+	"github.com/MadHive/deviget_challenge/internal/entrycodec"
+)
+
+// Options configures where a Store keeps its entries
+type Options struct {
+	Bucket   string        // where the entries are kept, "cache" if empty, so that several caches can share a file
+	ExtraTTL time.Duration // kept on top of the entry maxAge, so that stale values can still be served
+	Clock    sample1.Clock // tells when entries are too old to be read, the real clock if nil
+	OnError  func(error)   // called with every bbolt failure, which the store treats as a miss
+}
+
+// Store is a sample1.Store keeping its entries in a bbolt database, encoded as JSON
+// Entries older than their maxAge plus ExtraTTL are not read anymore, Purge deletes them
+type Store[V any] struct {
+	db     *bolt.DB
+	bucket []byte
+	opts   Options
+	owned  bool // whether Close closes the database
+}
+
+// Open opens (or creates) the bbolt file at path and creates a store in it, Close closes the file
+func Open[V any](path string, opts Options) (*Store[V], error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening %s : %w", path, err)
+	}
+	s, err := New[V](db, opts)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.owned = true
+	return s, nil
+}
+
+// New creates a store in an already open database, creating its bucket if needed
+func New[V any](db *bolt.DB, opts Options) (*Store[V], error) {
+	if opts.Bucket == "" {
+		opts.Bucket = "cache"
+	}
+	if opts.Clock == nil {
+		opts.Clock = realClock{}
+	}
+	s := &Store[V]{db: db, bucket: []byte(opts.Bucket), opts: opts}
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(s.bucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating the bucket %s : %w", opts.Bucket, err)
+	}
+	return s, nil
+}
+
+func (s *Store[V]) Get(key string) (sample1.Entry[V], bool) {
+	var entry sample1.Entry[V]
+	var ok bool
+	s.view(func(b *bolt.Bucket) {
+		entry, ok = s.read(b, key)
+	})
+	return entry, ok
+}
+
+// GetMany reads all the keys in a single transaction
+func (s *Store[V]) GetMany(keys []string) ([]sample1.Entry[V], []bool) {
+	entries := make([]sample1.Entry[V], len(keys))
+	found := make([]bool, len(keys))
+	s.view(func(b *bolt.Bucket) {
+		for i, key := range keys {
+			entries[i], found[i] = s.read(b, key)
+		}
+	})
+	return entries, found
+}
+
+func (s *Store[V]) Set(key string, entry sample1.Entry[V]) {
+	data, err := entrycodec.Marshal(entry)
+	if err != nil {
+		s.fail(fmt.Errorf("encoding [%s] : %w", key, err))
+		return
+	}
+	s.update(func(b *bolt.Bucket) error {
+		return b.Put([]byte(key), data)
+	})
+}
+
+func (s *Store[V]) Delete(key string) {
+	s.update(func(b *bolt.Bucket) error {
+		return b.Delete([]byte(key))
+	})
+}
+
+// Clear deletes every entry by recreating the bucket
+func (s *Store[V]) Clear() {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(s.bucket); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		_, err := tx.CreateBucket(s.bucket)
+		return err
+	})
+	if err != nil {
+		s.fail(fmt.Errorf("clearing : %w", err))
+	}
+}
+
+// Len returns the number of entries, the ones too old to be read included
+func (s *Store[V]) Len() int {
+	n := 0
+	s.view(func(b *bolt.Bucket) {
+		n = b.Stats().KeyN
+	})
+	return n
+}
+
+// Range calls fn for the entries that can still be read, within a read transaction
+func (s *Store[V]) Range(fn func(key string, entry sample1.Entry[V]) bool) {
+	now := s.opts.Clock.Now()
+	s.view(func(b *bolt.Bucket) {
+		c := b.Cursor()
+		for k, data := c.First(); k != nil; k, data = c.Next() {
+			entry, ok := s.decode(string(k), data, now)
+			if ok && !fn(string(k), entry) {
+				return
+			}
+		}
+	})
+}
+
+// Purge deletes the entries too old to be read and returns how many there were
+func (s *Store[V]) Purge() int {
+	now := s.opts.Clock.Now()
+	n := 0
+	s.update(func(b *bolt.Bucket) error {
+		c := b.Cursor()
+		for k, data := c.First(); k != nil; {
+			if _, ok := s.decode(string(k), data, now); ok {
+				k, data = c.Next()
+				continue
+			}
+			deleted := append([]byte(nil), k...)
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			n++
+			k, data = c.Seek(deleted) // Next would skip the key after the deleted one
+		}
+		return nil
+	})
+	return n
+}
+
+// Close closes the database if the store opened it
+func (s *Store[V]) Close() error {
+	if !s.owned {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// read returns the entry for key if it is there and not too old
+func (s *Store[V]) read(b *bolt.Bucket, key string) (sample1.Entry[V], bool) {
+	data := b.Get([]byte(key))
+	if data == nil {
+		return sample1.Entry[V]{}, false
+	}
+	return s.decode(key, data, s.opts.Clock.Now())
+}
+
+// decode decodes an entry, it is a miss if it is broken or too old at now
+func (s *Store[V]) decode(key string, data []byte, now time.Time) (sample1.Entry[V], bool) {
+	entry, err := entrycodec.Unmarshal[V](data)
+	if err != nil {
+		s.fail(fmt.Errorf("decoding [%s] : %w", key, err))
+		return sample1.Entry[V]{}, false
+	}
+	if entry.MaxAge > 0 && now.Sub(entry.FetchedAt) > entry.MaxAge+s.opts.ExtraTTL {
+		return sample1.Entry[V]{}, false
+	}
+	return entry, true
+}
+
+func (s *Store[V]) view(fn func(b *bolt.Bucket)) {
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		if b == nil {
+			return bolt.ErrBucketNotFound
+		}
+		fn(b)
+		return nil
+	})
+	if err != nil {
+		s.fail(fmt.Errorf("reading : %w", err))
+	}
+}
+
+func (s *Store[V]) update(fn func(b *bolt.Bucket) error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		if b == nil {
+			return bolt.ErrBucketNotFound
+		}
+		return fn(b)
+	})
+	if err != nil {
+		s.fail(fmt.Errorf("writing : %w", err))
+	}
+}
+
+func (s *Store[V]) fail(err error) {
+	if s.opts.OnError != nil {
+		s.opts.OnError(err)
+	}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
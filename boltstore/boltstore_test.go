@@ -0,0 +1,163 @@
+package boltstore
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type fakePriceService struct {
+	prices   map[string]float64
+	numCalls int
+}
+
+func (f *fakePriceService) GetPriceFor(itemCode string) (float64, error) {
+	f.numCalls++
+	price, ok := f.prices[itemCode]
+	if !ok {
+		return 0, fmt.Errorf("unknown item [%v] : %w", itemCode, sample1.ErrNotFound)
+	}
+	return price, nil
+}
+
+func openTestStore(t *testing.T, path string, opts Options) *Store[float64] {
+	t.Helper()
+	store, err := Open[float64](path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// Check that entries survive closing and reopening the file
+func TestStore_PersistsEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	store := openTestStore(t, path, Options{})
+	fetchedAt := time.Now().Round(0)
+	store.Set("p1", sample1.Entry[float64]{Value: 5, FetchedAt: fetchedAt, MaxAge: time.Minute})
+	store.Set("p2", sample1.Entry[float64]{Value: 7, FetchedAt: fetchedAt, MaxAge: time.Minute})
+	store.Delete("p2")
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store = openTestStore(t, path, Options{})
+	defer store.Close()
+	entry, ok := store.Get("p1")
+	if !ok || entry.Value != 5 || !entry.FetchedAt.Equal(fetchedAt) || entry.MaxAge != time.Minute {
+		t.Errorf("wrong entry read back: %+v, %v", entry, ok)
+	}
+	if _, ok := store.Get("p2"); ok {
+		t.Error("p2 should have been deleted")
+	}
+	entries, found := store.GetMany([]string{"p1", "p2"})
+	if !found[0] || found[1] || entries[0].Value != 5 {
+		t.Errorf("wrong entries read back: %+v, %v", entries, found)
+	}
+}
+
+// Check that entries older than their maxAge plus ExtraTTL aren't read and are purged
+func TestStore_HonorsMaxAge(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := openTestStore(t, filepath.Join(t.TempDir(), "cache.db"), Options{ExtraTTL: 30 * time.Second, Clock: clock})
+	defer store.Close()
+	for i := 0; i < 10; i++ {
+		store.Set(fmt.Sprintf("old%d", i), sample1.Entry[float64]{Value: 1, FetchedAt: clock.Now(), MaxAge: time.Minute})
+	}
+	clock.Advance(time.Minute)
+	store.Set("new", sample1.Entry[float64]{Value: 2, FetchedAt: clock.Now(), MaxAge: time.Minute})
+
+	clock.Advance(20 * time.Second)
+	if _, ok := store.Get("old0"); !ok {
+		t.Error("old0 should still be readable within ExtraTTL")
+	}
+	clock.Advance(20 * time.Second)
+	if _, ok := store.Get("old0"); ok {
+		t.Error("old0 should be too old to be read")
+	}
+	ranged := 0
+	store.Range(func(string, sample1.Entry[float64]) bool { ranged++; return true })
+	if ranged != 1 {
+		t.Errorf("only the new entry should have been ranged over, got %d", ranged)
+	}
+	if n := store.Purge(); n != 10 {
+		t.Errorf("wrong number of entries purged, expected 10 but got %d", n)
+	}
+	if n := store.Len(); n != 1 {
+		t.Errorf("wrong length, expected 1 but got %d", n)
+	}
+}
+
+// Check that Clear only empties the bucket of the store
+func TestStore_Clear(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	store := openTestStore(t, path, Options{Bucket: "prices"})
+	defer store.Close()
+	other, err := New[float64](store.db, Options{Bucket: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("p1", sample1.Entry[float64]{Value: 5, FetchedAt: time.Now(), MaxAge: time.Minute})
+	other.Set("p1", sample1.Entry[float64]{Value: 6, FetchedAt: time.Now(), MaxAge: time.Minute})
+	store.Clear()
+	if n := store.Len(); n != 0 {
+		t.Errorf("every entry should have been deleted but %d are left", n)
+	}
+	if entry, ok := other.Get("p1"); !ok || entry.Value != 6 {
+		t.Error("the entries of the other bucket should have been kept")
+	}
+}
+
+// Check that a restarted cache answers from the file, negative entries included
+func TestStore_WarmRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	service := &fakePriceService{prices: map[string]float64{"p1": 5, "p2": 7}}
+	negative := sample1.WithNegativeCaching(time.Minute, nil)
+
+	store := openTestStore(t, path, Options{})
+	cache := sample1.New(service, sample1.WithStore[string, float64](store), negative)
+	if _, err := cache.GetPricesFor("p1", "p2"); err != nil {
+		t.Fatal(err)
+	}
+	cache.GetPriceFor("p3")
+	store.Close()
+
+	store = openTestStore(t, path, Options{})
+	defer store.Close()
+	cache = sample1.New(service, sample1.WithStore[string, float64](store), negative)
+	prices, err := cache.GetPricesFor("p1", "p2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prices[0] != 5 || prices[1] != 7 {
+		t.Errorf("wrong prices returned: %v", prices)
+	}
+	if _, err := cache.GetPriceFor("p3"); !errors.Is(err, sample1.ErrNotFound) {
+		t.Errorf("expected a not found error but got %v", err)
+	}
+	if service.numCalls != 3 {
+		t.Errorf("the restarted cache should have answered from the file, but the service was called %d times", service.numCalls)
+	}
+}
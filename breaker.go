@@ -0,0 +1,175 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by a CircuitBreakerService while it doesn't let calls through
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreakerService
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // calls go through, their failures are counted
+	BreakerOpen                         // calls fail right away with ErrCircuitOpen until the cooldown is over
+	BreakerHalfOpen                     // a few trial calls go through to tell whether the service is back
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerPolicy tells when a CircuitBreakerService opens and closes, zero fields take their defaults
+type BreakerPolicy struct {
+	FailureRate      float64          // fraction of failed calls in a window that opens the breaker, 0.5 if zero
+	MinRequests      int              // calls needed in a window before its failure rate counts, 10 if zero
+	Window           time.Duration    // how long calls are counted before starting over, 10s if zero
+	Cooldown         time.Duration    // how long the breaker stays open before trying the service again, 5s if zero
+	HalfOpenRequests int              // trial calls that must succeed to close the breaker again, 1 if zero
+	IsFailure        func(error) bool // tells which errors count as failures, the Transient ones for DefaultErrorClassifier but cancellations if nil
+}
+
+// CircuitBreakerService is a PriceService that stops calling another one while it keeps failing, so that
+// misses fail fast with ErrCircuitOpen instead of waiting on doomed calls
+// Combined with WithStaleIfError, the cache keeps serving the prices it has while the breaker is open
+// A half-open trial whose context is cancelled or times out counts neither way, it lets another call try instead
+type CircuitBreakerService struct {
+	service ContextPriceService
+	policy  BreakerPolicy
+	clock   Clock // only swapped by tests
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	requests    int // calls finished in the current window
+	failures    int // failed calls in the current window
+	openedAt    time.Time
+	trials      int // calls let through since the breaker is half-open
+	successes   int // of those trials
+}
+
+// NewCircuitBreakerService wraps service with a circuit breaker following policy
+func NewCircuitBreakerService(service PriceService, policy BreakerPolicy) *CircuitBreakerService {
+	if policy.FailureRate <= 0 {
+		policy.FailureRate = 0.5
+	}
+	if policy.MinRequests <= 0 {
+		policy.MinRequests = 10
+	}
+	if policy.Window <= 0 {
+		policy.Window = 10 * time.Second
+	}
+	if policy.Cooldown <= 0 {
+		policy.Cooldown = 5 * time.Second
+	}
+	if policy.HalfOpenRequests <= 0 {
+		policy.HalfOpenRequests = 1
+	}
+	if policy.IsFailure == nil {
+		policy.IsFailure = func(err error) bool { return isTransient(err) && !errors.Is(err, context.Canceled) }
+	}
+	return &CircuitBreakerService{service: AsContextPriceService(service), policy: policy, clock: realClock{}}
+}
+
+func (b *CircuitBreakerService) GetPriceFor(itemCode string) (float64, error) {
+	return b.GetPriceForCtx(context.Background(), itemCode)
+}
+
+// GetPriceForCtx calls the service unless the breaker is open
+func (b *CircuitBreakerService) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	state, err := b.allow()
+	if err != nil {
+		return 0, err
+	}
+	price, err := b.service.GetPriceForCtx(ctx, itemCode)
+	b.record(state, err)
+	return price, err
+}
+
+// State returns the current state of the breaker
+func (b *CircuitBreakerService) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cooledDown(b.clock.Now())
+	return b.state
+}
+
+// allow tells whether a call can go through, and in which state it does
+func (b *CircuitBreakerService) allow() (BreakerState, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	b.cooledDown(now)
+	switch b.state {
+	case BreakerOpen:
+		return b.state, ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.trials >= b.policy.HalfOpenRequests {
+			return b.state, ErrCircuitOpen
+		}
+		b.trials++
+	default:
+		if now.Sub(b.windowStart) > b.policy.Window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+	}
+	return b.state, nil
+}
+
+// record counts the outcome of a call let through in the given state, that returned err
+func (b *CircuitBreakerService) record(state BreakerState, err error) {
+	failed := err != nil && b.policy.IsFailure(err)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if state != b.state {
+		return // the breaker moved on since the call started
+	}
+	now := b.clock.Now()
+	switch state {
+	case BreakerHalfOpen:
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			b.trials-- // the caller gave up, the service didn't answer either way: another call gets the trial
+			return
+		}
+		if failed {
+			b.open(now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.policy.HalfOpenRequests {
+			b.state, b.windowStart, b.requests, b.failures = BreakerClosed, now, 0, 0
+		}
+	case BreakerClosed:
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.policy.MinRequests && float64(b.failures) >= b.policy.FailureRate*float64(b.requests) {
+			b.open(now)
+		}
+	}
+}
+
+func (b *CircuitBreakerService) open(now time.Time) {
+	b.state, b.openedAt = BreakerOpen, now
+}
+
+// cooledDown moves an open breaker to half-open once the cooldown is over, b.mu must be held
+func (b *CircuitBreakerService) cooledDown(now time.Time) {
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.policy.Cooldown {
+		b.state, b.trials, b.successes = BreakerHalfOpen, 0, 0
+	}
+}
@@ -0,0 +1,142 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// switchablePriceService fails every call while down is set
+type switchablePriceService struct {
+	mockPriceService
+	down bool
+}
+
+func (s *switchablePriceService) GetPriceFor(itemCode string) (float64, error) {
+	price, err := s.mockPriceService.GetPriceFor(itemCode)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		return 0, errors.New("503 service unavailable")
+	}
+	return price, err
+}
+
+func (s *switchablePriceService) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+func newTestBreaker(service PriceService, policy BreakerPolicy) (*CircuitBreakerService, *fakeClock) {
+	breaker := NewCircuitBreakerService(service, policy)
+	clock := newFakeClock()
+	breaker.clock = clock
+	return breaker, clock
+}
+
+// Check that the breaker opens once the failure rate is reached and then fails fast
+func TestCircuitBreaker_OpensOnFailures(t *testing.T) {
+	service := &switchablePriceService{mockPriceService: mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}}}}
+	breaker, _ := newTestBreaker(service, BreakerPolicy{FailureRate: 0.5, MinRequests: 4})
+	breaker.GetPriceFor("p1")
+	breaker.GetPriceFor("p1")
+	service.setDown(true)
+	breaker.GetPriceFor("p1")
+	if breaker.State() != BreakerClosed {
+		t.Fatal("the breaker should stay closed until MinRequests calls are made")
+	}
+	breaker.GetPriceFor("p1")
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("the breaker should be open but is %v", breaker.State())
+	}
+	if _, err := breaker.GetPriceFor("p1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen but got %v", err)
+	}
+	assertInt(t, 4, service.getNumCalls(), "no call should go through while the breaker is open")
+}
+
+// Check that the breaker tries the service again after the cooldown, and closes or opens again
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	service := &switchablePriceService{mockPriceService: mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}}}}
+	service.setDown(true)
+	breaker, clock := newTestBreaker(service, BreakerPolicy{MinRequests: 1, Cooldown: time.Second})
+	breaker.GetPriceFor("p1")
+	clock.Advance(time.Second)
+	if breaker.State() != BreakerHalfOpen {
+		t.Fatalf("the breaker should be half-open but is %v", breaker.State())
+	}
+	breaker.GetPriceFor("p1")
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("a failed trial should open the breaker again, it is %v", breaker.State())
+	}
+
+	clock.Advance(time.Second)
+	service.setDown(false)
+	if price, err := breaker.GetPriceFor("p1"); err != nil || price != 5 {
+		t.Fatalf("the trial call should have gone through, got %v, %v", price, err)
+	}
+	if breaker.State() != BreakerClosed {
+		t.Errorf("a successful trial should close the breaker, it is %v", breaker.State())
+	}
+}
+
+// Check that a half-open trial the caller gave up on neither closes nor opens the breaker, and frees the trial
+func TestCircuitBreaker_CancelledTrial(t *testing.T) {
+	service := &ctxMockPriceService{
+		mockPriceService: mockPriceService{callDelay: time.Second, mockResults: map[string]mockResult{"p1": {price: 5}}},
+		cancelled:        make(chan string, 2),
+	}
+	breaker, clock := newTestBreaker(service, BreakerPolicy{MinRequests: 1, Cooldown: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	breaker.GetPriceForCtx(ctx, "p1")
+	if breaker.State() != BreakerOpen {
+		t.Fatalf("a timed out call should open the breaker, it is %v", breaker.State())
+	}
+	clock.Advance(time.Second)
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := breaker.GetPriceForCtx(ctx, "p1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the trial to be cancelled but got %v", err)
+	}
+	if breaker.State() != BreakerHalfOpen {
+		t.Fatalf("a cancelled trial should leave the breaker half-open, it is %v", breaker.State())
+	}
+	if price, err := breaker.GetPriceFor("p1"); err != nil || price != 5 {
+		t.Fatalf("the next call should have been let through as the trial, got %v, %v", price, err)
+	}
+	if breaker.State() != BreakerClosed {
+		t.Errorf("a successful trial should close the breaker, it is %v", breaker.State())
+	}
+}
+
+// Check that not found errors don't open the breaker
+func TestCircuitBreaker_IgnoresNotFound(t *testing.T) {
+	service := &mockPriceService{mockResults: map[string]mockResult{"p1": {err: ErrNotFound}}}
+	breaker, _ := newTestBreaker(service, BreakerPolicy{MinRequests: 1})
+	breaker.GetPriceFor("p1")
+	breaker.GetPriceFor("p1")
+	if breaker.State() != BreakerClosed {
+		t.Errorf("the breaker should be closed but is %v", breaker.State())
+	}
+}
+
+// Check that the cache serves stale prices while the breaker is open
+func TestCircuitBreaker_FallsBackToStaleValues(t *testing.T) {
+	service := &switchablePriceService{mockPriceService: mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}, "p2": {price: 7}}}}
+	breaker, clock := newTestBreaker(service, BreakerPolicy{MinRequests: 1})
+	cache := New(breaker, WithMaxAge(time.Minute), WithStaleIfError(time.Hour), WithClock(clock))
+	getPriceWithNoErr(t, cache, "p1")
+	service.setDown(true)
+	clock.Advance(2 * time.Minute)
+	if _, err := cache.GetPriceFor("p2"); err == nil {
+		t.Fatal("expected an error for p2")
+	}
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 2, service.getNumCalls(), "the open breaker should have failed fast")
+	if _, err := cache.GetPriceFor("p2"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen but got %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BulkLoaderFunc loads the values for several keys at once, returning them in the same order as the keys
+// If only some of the keys fail it can return a *BatchError[K] with their failures, any other error fails every key
+type BulkLoaderFunc[K comparable, V any] func(ctx context.Context, keys []K) ([]V, error)
+
+// WithBulkLoader makes GetMany load all its misses with a single call to fn (or one per WithMaxBulkSize keys)
+// instead of calling the loader for each of them, Get and background refreshes keep using the loader
+// fn must load keys and values of the same types as the cache, NewCache panics otherwise
+func WithBulkLoader[K comparable, V any](fn BulkLoaderFunc[K, V]) Option {
+	return func(c *config) {
+		c.bulkLoader = fn
+	}
+}
+
+// WithMaxBulkSize sets the max number of keys given to the bulk loader at once, bigger batches are split
+// and their parts loaded in parallel as WithMaxConcurrency allows
+// Zero or less means the whole batch is loaded at once
+func WithMaxBulkSize(n int) Option {
+	return func(c *config) {
+		c.maxBulkSize = n
+	}
+}
+
+// bulkLoaderFor returns the configured bulk loader for a cache of K and V, nil if none was configured
+func bulkLoaderFor[K comparable, V any](fn any) BulkLoaderFunc[K, V] {
+	if fn == nil {
+		return nil
+	}
+	bulkLoader, ok := fn.(BulkLoaderFunc[K, V])
+	if !ok {
+		panic(fmt.Sprintf("sample1: bulk loader %T can't be used for a cache of %T", fn, (*Cache[K, V])(nil)))
+	}
+	return bulkLoader
+}
+
+// bulkGet gets keys given their entries in the store and calls deliver with the result of each of them,
+// the keys that can be answered right away are looked up as usual and the others are loaded by the bulk loader
+// Keys already being loaded by someone else are waited for rather than loaded again
+func (c *Cache[K, V]) bulkGet(ctx context.Context, keys []K, entries []Entry[V], found []bool, deliver func(i int, value V, err error)) {
+	now := c.clock.Now()
+	var missing []int
+	for i := range keys {
+		if found[i] && c.answersRightAway(entries[i], now) {
+			value, _, err := c.lookup(ctx, keys[i], entries[i], true)
+			deliver(i, value, err)
+			continue
+		}
+		missing = append(missing, i)
+	}
+	calls := make([]*flightCall[V], len(missing))
+	ends := make([]func(hit bool, err error), len(missing))
+	var owned, joined []int // indexes in missing of the keys this batch loads, and of the ones others load
+	for j, i := range missing {
+		_, ends[j] = c.observer.StartLookup(ctx, keys[i])
+		c.requested(keys[i])
+		c.missed(keys[i])
+		var started bool
+		if calls[j], started = c.flights.join(keys[i], nil); started {
+			owned = append(owned, j)
+		} else {
+			joined = append(joined, j)
+		}
+	}
+	// once ctx is done the batch stops waiting: the keys left get ctx.Err(), their loads go on for the other
+	// callers waiting for them and are cancelled otherwise
+	var mu sync.Mutex
+	delivered := make([]bool, len(missing))
+	abandoned := false
+	done := func(j int, value V, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if abandoned || delivered[j] {
+			return
+		}
+		delivered[j] = true
+		i := missing[j]
+		stale := c.servesStaleOnError(entries[i], found[i], err)
+		if stale {
+			value, err = entries[i].Value, nil
+		}
+		ends[j](false, err)
+		c.looked(outcomeOf(false, stale, err), now)
+		deliver(i, value, err)
+	}
+	chunkSize := len(owned)
+	if c.maxBulkSize > 0 && c.maxBulkSize < chunkSize {
+		chunkSize = c.maxBulkSize
+	}
+	var chunks [][]int
+	var chunkCtxs []context.Context
+	var chunkCancels []context.CancelFunc
+	for start := 0; start < len(owned); start += chunkSize {
+		chunk := owned[start:min(start+chunkSize, len(owned))]
+		chunkCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		wanted := atomic.Int64{}
+		wanted.Store(int64(len(chunk)))
+		for _, j := range chunk {
+			// a chunk is cancelled once none of its keys has waiters left
+			c.flights.setCancel(calls[j], func() {
+				if wanted.Add(-1) == 0 {
+					cancel()
+				}
+			})
+		}
+		chunks = append(chunks, chunk)
+		chunkCtxs = append(chunkCtxs, chunkCtx)
+		chunkCancels = append(chunkCancels, cancel)
+	}
+	loaded := make(chan struct{})
+	go func() {
+		defer close(loaded)
+		c.parallel(len(chunks), func(n int) {
+			chunkKeys := make([]K, len(chunks[n]))
+			for k, j := range chunks[n] {
+				chunkKeys[k] = keys[missing[j]]
+			}
+			values, loadErrs := c.loadMany(chunkCtxs[n], chunkKeys)
+			chunkCancels[n]()
+			for k, j := range chunks[n] {
+				c.flights.finish(chunkKeys[k], calls[j], values[k], loadErrs[k])
+				done(j, values[k], loadErrs[k])
+			}
+		})
+	}()
+	// the calls of this batch never wait on the others, so waiting for the keys loaded by other callers can't deadlock
+	waited, gaveUp := 0, false
+	for ; waited < len(joined) && !gaveUp; waited++ {
+		j := joined[waited]
+		value, err := c.flights.wait(ctx, keys[missing[j]], calls[j])
+		if errors.Is(err, errRefreshLocked) {
+			// another instance does the refresh this key joined, see get
+			value, err = c.load(ctx, keys[missing[j]])
+		}
+		if gaveUp = ctx.Err() != nil && err == ctx.Err(); !gaveUp {
+			done(j, value, err)
+		}
+	}
+	select {
+	case <-loaded:
+		if !gaveUp {
+			return
+		}
+	case <-ctx.Done():
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	abandoned = true
+	var zero V
+	for _, j := range append(owned, joined[waited:]...) {
+		c.flights.leave(keys[missing[j]], calls[j])
+	}
+	for j, i := range missing {
+		if !delivered[j] {
+			ends[j](false, ctx.Err())
+			c.looked(OutcomeError, now)
+			deliver(i, zero, ctx.Err())
+		}
+	}
+}
+
+// answersRightAway tells if a lookup for the entry won't have to wait for a load: it is fresh or can be served stale
+func (c *Cache[K, V]) answersRightAway(entry Entry[V], now time.Time) bool {
+	if !c.expired(entry, entry.MaxAge, now) {
+		return true
+	}
+	return entry.Err == nil && c.maxStale > 0 && !c.expired(entry, entry.MaxAge+c.maxStale, now)
+}
+
+// loadErrors returns err as the load error of every key
+func loadErrors[K comparable](keys []K, err error) []error {
+	errs := make([]error, len(keys))
+	for i, key := range keys {
+		errs[i] = fmt.Errorf("loading [%v] : %w", key, err)
+	}
+	return errs
+}
+
+// loadMany gets the values for keys from the bulk loader and stores them in the cache, like load does for one key
+// Loads are counted once per key, and the observer sees a single load whose key is the slice of keys
+// If the bulk loader returns a *BatchError without the values of the keys that didn't fail (like
+// TransparentCache.GetPricesFor does), those keys are loaded again with the loader
+func (c *Cache[K, V]) loadMany(ctx context.Context, keys []K) ([]V, []error) {
+	if !c.drain.begin() {
+		return make([]V, len(keys)), loadErrors(keys, ErrClosed)
+	}
+	defer c.drain.end()
+	if err := c.limiter.wait(ctx); err != nil {
+		return make([]V, len(keys)), loadErrors(keys, err)
+	}
+	c.mu.RLock()
+	generation := c.generation
+	c.mu.RUnlock()
+	c.counters.loads.Add(uint64(len(keys)))
+	loadCtx, end := c.observer.StartLoad(ctx, keys)
+	loadCtx, sink := withSourceSink(loadCtx)
+	start := c.clock.Now()
+	values, err := callWithTimeout(loadCtx, c.loadTimeout, func(ctx context.Context) ([]V, error) {
+		return c.bulkLoader(ctx, keys)
+	})
+	loadTime := c.clock.Now().Sub(start)
+	c.counters.loaded(loadTime)
+	end(err)
+	if err == nil && len(values) != len(keys) {
+		err = fmt.Errorf("bulk loader returned %d values for %d keys", len(values), len(keys))
+	}
+	c.logLoad(ctx, slog.Int("keys", len(keys)), loadTime, err)
+	errs := make([]error, len(keys))
+	retry := make([]bool, len(keys)) // keys without an error nor a value, which are loaded one by one
+	var batchErr *BatchError[K]
+	switch {
+	case errors.As(err, &batchErr):
+		failed := map[K]error{}
+		for _, keyErr := range batchErr.Errors {
+			failed[keyErr.Key] = keyErr.Err
+		}
+		for i, key := range keys {
+			errs[i] = failed[key]
+			retry[i] = errs[i] == nil && len(values) != len(keys)
+		}
+	case err != nil:
+		for i := range keys {
+			errs[i] = err
+		}
+	}
+	if len(values) != len(keys) {
+		values = make([]V, len(keys))
+	}
+	var events []Event[K, V]
+	var replaced []K
+	c.mu.Lock()
+	for i, key := range keys {
+		if errs[i] != nil {
+			c.counters.loadErrors.Add(1)
+			errs[i] = fmt.Errorf("loading [%v] : %w", key, errs[i])
+		} else if !retry[i] {
+			events = append(events, Event[K, V]{Key: key, Value: values[i], Reason: Loaded})
+		} else {
+			continue
+		}
+		if c.generation == generation {
+			if previous, ok := c.replaces(key, errs[i]); ok {
+				c.adapt(key, values[i], previous)
+				replaced = append(replaced, key)
+				events[len(events)-1].Refresh = true
+				events = append(events, changed(key, values[i], previous)...)
+			}
+			events = append(events, c.saveLoaded(key, values[i], errs[i], loadTime, sink.get())...)
+		}
+	}
+	c.mu.Unlock()
+	c.publishInvalidation(replaced)
+	c.emit(events...)
+	for i, key := range keys {
+		if retry[i] {
+			values[i], errs[i] = c.load(ctx, key)
+		}
+	}
+	return values, errs
+}
@@ -0,0 +1,166 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bulkMockPriceService is a mockPriceService that can also price several items in one call
+type bulkMockPriceService struct {
+	mockPriceService
+	bulkMu    sync.Mutex
+	bulkCalls [][]string // the item codes of every bulk call
+}
+
+func (m *bulkMockPriceService) GetPricesFor(itemCodes ...string) ([]float64, error) {
+	m.bulkMu.Lock()
+	m.bulkCalls = append(m.bulkCalls, itemCodes)
+	m.bulkMu.Unlock()
+	var batchErr BatchError[string]
+	prices := make([]float64, len(itemCodes))
+	for i, itemCode := range itemCodes {
+		m.mu.Lock()
+		result, ok := m.mockResults[itemCode]
+		m.mu.Unlock()
+		if !ok {
+			panic(fmt.Errorf("bug in the tests, we didn't have a mock result for [%v]", itemCode))
+		}
+		prices[i] = result.price
+		if result.err != nil {
+			batchErr.Errors = append(batchErr.Errors, &KeyError[string]{Key: itemCode, Err: result.err})
+		}
+	}
+	if len(batchErr.Errors) > 0 {
+		return nil, &batchErr
+	}
+	return prices, nil
+}
+
+func (m *bulkMockPriceService) getBulkCalls() [][]string {
+	m.bulkMu.Lock()
+	defer m.bulkMu.Unlock()
+	return m.bulkCalls
+}
+
+// Check that a batch loads all its misses with one bulk call, and only its misses
+func TestGetPricesFor_UsesBulkService(t *testing.T) {
+	mockService := &bulkMockPriceService{mockPriceService: mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+			"p3": {price: 9, err: nil},
+		},
+	}}
+	cache := NewTransparentCache(mockService, time.Minute)
+	getPriceWithNoErr(t, cache, "p1")
+	assertFloats(t, []float64{5, 7, 9}, getPricesWithNoErr(t, cache, "p1", "p2", "p3"), "wrong price returned")
+	assertFloats(t, []float64{5, 7, 9}, getPricesWithNoErr(t, cache, "p1", "p2", "p3"), "wrong price returned")
+
+	assertInt(t, 1, mockService.getNumCalls(), "only the single lookup should have called GetPriceFor")
+	bulkCalls := mockService.getBulkCalls()
+	if len(bulkCalls) != 1 || len(bulkCalls[0]) != 2 {
+		t.Errorf("expected one bulk call for p2 and p3 but got %v", bulkCalls)
+	}
+	stats := cache.Stats()
+	assertInt(t, 3, int(stats.Loads), "loads should be counted per item")
+	assertInt(t, 3, int(stats.Misses), "wrong number of misses")
+}
+
+// Check that batches bigger than the max bulk size are split
+func TestGetPricesFor_RespectsMaxBulkSize(t *testing.T) {
+	mockService := &bulkMockPriceService{mockPriceService: mockPriceService{mockResults: map[string]mockResult{}}}
+	var itemCodes []string
+	for i := 0; i < 7; i++ {
+		itemCode := fmt.Sprintf("p%d", i)
+		itemCodes = append(itemCodes, itemCode)
+		mockService.mockResults[itemCode] = mockResult{price: float64(i)}
+	}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxBulkSize(3))
+	assertFloats(t, []float64{0, 1, 2, 3, 4, 5, 6}, getPricesWithNoErr(t, cache, itemCodes...), "wrong price returned")
+	bulkCalls := mockService.getBulkCalls()
+	if len(bulkCalls) != 3 {
+		t.Fatalf("expected 3 bulk calls but got %v", bulkCalls)
+	}
+	for _, call := range bulkCalls {
+		if len(call) > 3 {
+			t.Errorf("a bulk call priced %d items", len(call))
+		}
+	}
+}
+
+// Check that the items a bulk call fails are reported while the others are cached
+func TestGetPricesFor_BulkPartialFailures(t *testing.T) {
+	mockService := &bulkMockPriceService{mockPriceService: mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 0, err: ErrNotFound},
+		},
+	}}
+	cache := NewTransparentCache(mockService, time.Minute)
+	_, err := cache.GetPricesFor("p1", "p2")
+	var batchErr *BatchError[string]
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[0].Key != "p2" {
+		t.Fatalf("expected a batch error for p2 but got %v", err)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("the cause should have been kept, got %v", err)
+	}
+	if !cache.Contains("p1") {
+		t.Error("p1 should have been cached anyway")
+	}
+	assertInt(t, 1, mockService.getNumCalls(), "p1 had no price in the failed bulk call and should have been loaded alone")
+	assertInt(t, 1, int(cache.Stats().LoadErrors), "wrong number of load errors")
+}
+
+// Check that a failed bulk call fails every item, and stale values are served if allowed
+func TestGetPricesFor_BulkFailure(t *testing.T) {
+	clock := newFakeClock()
+	failing := errors.New("backend down")
+	var fail bool
+	cache := NewCache(func(_ context.Context, key string) (float64, error) { return 1, nil },
+		WithClock(clock), WithMaxAge(time.Minute), WithStaleIfError(time.Minute),
+		WithBulkLoader(func(_ context.Context, keys []string) ([]float64, error) {
+			if fail {
+				return nil, failing
+			}
+			return make([]float64, len(keys)), nil
+		}))
+	cache.Set("p1", 5)
+	clock.Advance(90 * time.Second)
+	fail = true
+	_, err := cache.GetMany(context.Background(), "p1", "p2")
+	var batchErr *BatchError[string]
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[0].Key != "p2" || !errors.Is(err, failing) {
+		t.Fatalf("expected only p2 to fail but got %v", err)
+	}
+	assertInt(t, 1, int(cache.Stats().StaleServed), "the stale value of p1 should have been served")
+}
+
+// Check that a key repeated in a batch doesn't block it
+func TestGetPricesFor_BulkRepeatedKeys(t *testing.T) {
+	mockService := &bulkMockPriceService{mockPriceService: mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}}
+	cache := NewTransparentCache(mockService, time.Minute)
+	assertFloats(t, []float64{5, 5}, getPricesWithNoErr(t, cache, "p1", "p1"), "wrong price returned")
+	if bulkCalls := mockService.getBulkCalls(); len(bulkCalls) != 1 || len(bulkCalls[0]) != 1 {
+		t.Errorf("expected one bulk call for p1 but got %v", bulkCalls)
+	}
+}
+
+// Check that a bulk loader of other types is rejected
+func TestWithBulkLoader_PanicsOnTypeMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	NewCache(func(_ context.Context, key string) (float64, error) { return 0, nil },
+		WithBulkLoader(func(_ context.Context, keys []int) ([]float64, error) { return nil, nil }))
+}
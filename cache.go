@@ -1,8 +1,8 @@
 package sample1
 
 import (
-	"fmt"
-	"sync"
+	"context"
+	"strings"
 	"time"
 )
 
@@ -15,57 +15,141 @@ type PriceService interface {
 // TransparentCache is a cache that wraps the actual service
 // The cache will remember prices we ask for, so that we don't have to wait on every call
 // Cache should only return a price if it is not older than "maxAge", so that we don't get stale prices
+// It is a thin price specific wrapper over Cache, and it is safe for concurrent use by multiple goroutines
 type TransparentCache struct {
-	actualPriceService PriceService
-	maxAge             time.Duration
-	time               time.Time
-	prices             map[string]float64
+	*Cache[string, float64]
+	namespaces *namespaces
 }
 
-func NewTransparentCache(actualPriceService PriceService, maxAge time.Duration) *TransparentCache {
+// New creates a cache in front of actualPriceService, configured with opts
+// Prices are kept for DefaultMaxAge unless another maxAge is given with WithMaxAge
+// If actualPriceService is a BulkPriceService (or a ContextBulkPriceService), it is used to load batches
+func New(actualPriceService PriceService, opts ...Option) *TransparentCache {
+	namespaces := &namespaces{actual: newNamespaceService(actualPriceService)}
+	if namespaces.actual.bulk != nil {
+		opts = append([]Option{WithBulkLoader(BulkLoaderFunc[string, float64](namespaces.loadMany))}, opts...)
+	}
 	return &TransparentCache{
-		actualPriceService: actualPriceService,
-		maxAge:             maxAge,
-		time:               time.Now(),
-		prices:             map[string]float64{},
+		Cache:      NewCache(namespaces.load, opts...),
+		namespaces: namespaces,
 	}
 }
 
+// NewTransparentCache creates a cache in front of actualPriceService that keeps prices for maxAge
+// It is the same as New(actualPriceService, WithMaxAge(maxAge), opts...)
+func NewTransparentCache(actualPriceService PriceService, maxAge time.Duration, opts ...Option) *TransparentCache {
+	return New(actualPriceService, append([]Option{WithMaxAge(maxAge)}, opts...)...)
+}
+
 // GetPriceFor gets the price for the item, either from the cache or the actual service if it was not cached or too old
 func (c *TransparentCache) GetPriceFor(itemCode string) (float64, error) {
-	getService := true
-	price, ok := c.prices[itemCode]
-	if ok {
-		maxAge := c.maxAge
-		maxtimecache := c.time.Add(maxAge)
-		getService = maxtimecache.Before(time.Now())
-	}
-	if getService {
-		price, err := c.actualPriceService.GetPriceFor(itemCode)
-		if err != nil {
-			return 0, fmt.Errorf("getting price from service : %v", err.Error())
-		}
-		c.prices[itemCode] = price
-		return price, nil
-	}
-	return price, nil
+	return c.GetPriceForCtx(context.Background(), itemCode)
+}
+
+// GetPriceForCtx is like GetPriceFor, but gives up waiting for the actual service when ctx is done
+// Concurrent calls for the same item share one fetch, a caller giving up returns ctx.Err() right away while the
+// fetch goes on to cache the price for the others, it is only cancelled once all of them gave up
+func (c *TransparentCache) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	return c.Get(ctx, itemCode)
+}
+
+// GetPriceForWith is like GetPriceFor, but opts change how this lookup uses the cache (ForceRefresh,
+// MaxStaleness, CacheOnly)
+func (c *TransparentCache) GetPriceForWith(itemCode string, opts ...CallOption) (float64, error) {
+	return c.GetPriceForCtxWith(context.Background(), itemCode, opts...)
+}
+
+// GetPriceForCtxWith is like GetPriceForCtx, but opts change how this lookup uses the cache
+func (c *TransparentCache) GetPriceForCtxWith(ctx context.Context, itemCode string, opts ...CallOption) (float64, error) {
+	return c.Get(ctx, itemCode, opts...)
+}
+
+// GetOrLoad is like GetPriceFor, but on a miss the price is fetched with load instead of the actual service,
+// for callers that have a cheaper way to get it. The price is cached as if the service had returned it
+func (c *TransparentCache) GetOrLoad(itemCode string, load func() (float64, error)) (float64, error) {
+	return c.GetOrLoadCtx(context.Background(), itemCode, load)
+}
+
+// GetOrLoadCtx is like GetOrLoad, but gives up waiting for load when ctx is done
+func (c *TransparentCache) GetOrLoadCtx(ctx context.Context, itemCode string, load func() (float64, error)) (float64, error) {
+	return c.GetWith(ctx, itemCode, func(ctx context.Context, itemCode string) (float64, error) {
+		return callWithContext(ctx, load)
+	})
+}
+
+// InvalidatePrefix drops the cached prices of every item whose code starts with prefix, so that whole families
+// of hierarchical item codes ("BOOKS/SCIFI/") can be dropped at once
+func (c *TransparentCache) InvalidatePrefix(prefix string) {
+	c.InvalidateMatching(func(itemCode string) bool {
+		return strings.HasPrefix(itemCode, prefix)
+	})
+}
+
+// OnPriceChange registers fn to be called whenever the actual service returns a price different from the one
+// cached, with the old price, the new one and how much it moved (newPrice - oldPrice), see Cache.OnChange
+func (c *TransparentCache) OnPriceChange(fn func(itemCode string, oldPrice, newPrice, delta float64)) {
+	c.OnChange(func(event Event[string, float64]) {
+		fn(event.Key, event.Previous, event.Value, event.Value-event.Previous)
+	})
+}
+
+// GetPriceWithInfo is like GetPriceFor, but also tells whether the price came from the cache, how old it is
+// and which source supplied it
+func (c *TransparentCache) GetPriceWithInfo(itemCode string) (float64, Info, error) {
+	return c.GetPriceWithInfoCtx(context.Background(), itemCode)
+}
+
+// GetPriceWithInfoCtx is like GetPriceWithInfo, but gives up waiting for the actual service when ctx is done
+func (c *TransparentCache) GetPriceWithInfoCtx(ctx context.Context, itemCode string) (float64, Info, error) {
+	return c.GetWithInfo(ctx, itemCode)
 }
 
 // GetPricesFor gets the prices for several items at once, some might be found in the cache, others might not
 // If any of the operations returns an error, it should return an error as well
+// The returned error is a *BatchError holding the failure of every item that could not be priced
 func (c *TransparentCache) GetPricesFor(itemCodes ...string) ([]float64, error) {
-	results := make([]float64, len(itemCodes))
-	var wg sync.WaitGroup
-	wg.Add(len(itemCodes))
-	for i, itemCode := range itemCodes {
-		go func(i int, itemCode string) {
-			defer wg.Done()
-			price, err := c.GetPriceFor(itemCode)
-			if err != nil {
-			}
-			results[i] = price
-		}(i, itemCode)
-	}
-	wg.Wait()
-	return results, nil
+	return c.GetPricesForCtx(context.Background(), itemCodes...)
+}
+
+// GetPricesForCtx is like GetPricesFor, but the outstanding fetches are cancelled when ctx is done
+func (c *TransparentCache) GetPricesForCtx(ctx context.Context, itemCodes ...string) ([]float64, error) {
+	return c.GetMany(ctx, itemCodes...)
+}
+
+// PriceFuture is the handle of a price lookup running in the background
+type PriceFuture = Future[float64]
+
+// GetPriceForAsync is like GetPriceFor, but returns right away with a handle to wait for the price later
+func (c *TransparentCache) GetPriceForAsync(itemCode string) *PriceFuture {
+	return c.GetPriceForAsyncCtx(context.Background(), itemCode)
+}
+
+// GetPriceForAsyncCtx is like GetPriceForAsync, but gives up waiting for the actual service when ctx is done
+func (c *TransparentCache) GetPriceForAsyncCtx(ctx context.Context, itemCode string) *PriceFuture {
+	return c.GetAsync(ctx, itemCode)
+}
+
+// PriceResult is the outcome of pricing one item of a batch
+type PriceResult = Result[string, float64]
+
+// GetPriceResultsFor is like GetPricesFor, but returns the price or the error of every item (in the same order)
+// so that callers can use the prices they got when some items fail
+func (c *TransparentCache) GetPriceResultsFor(itemCodes ...string) []PriceResult {
+	return c.GetPriceResultsForCtx(context.Background(), itemCodes...)
+}
+
+// GetPriceResultsForCtx is like GetPriceResultsFor, but the outstanding fetches are cancelled when ctx is done
+func (c *TransparentCache) GetPriceResultsForCtx(ctx context.Context, itemCodes ...string) []PriceResult {
+	return c.GetEach(ctx, itemCodes...)
+}
+
+// StreamPricesFor is like GetPriceResultsFor, but sends every result on the returned channel as soon as
+// the item is priced, so that big batches don't wait for the slowest item. The channel is closed after the last one
+func (c *TransparentCache) StreamPricesFor(itemCodes ...string) <-chan PriceResult {
+	return c.StreamPricesForCtx(context.Background(), itemCodes...)
+}
+
+// StreamPricesForCtx is like StreamPricesFor, but the outstanding fetches are cancelled when ctx is done
+func (c *TransparentCache) StreamPricesForCtx(ctx context.Context, itemCodes ...string) <-chan PriceResult {
+	return c.Stream(ctx, itemCodes...)
 }
@@ -1,8 +1,10 @@
 package sample1
 
 import (
+	"errors"
 	"fmt"
 	"sort"
+	"sync"
 	"testing"
 	"time"
 )
@@ -14,6 +16,7 @@ type mockResult struct {
 }
 
 type mockPriceService struct {
+	mu          sync.Mutex // guards numCalls and mockResults, the cache calls the service from several goroutines
 	numCalls    int
 	mockResults map[string]mockResult // what price and err to return for a particular itemCode
 	callDelay   time.Duration         // how long to sleep on each call so that we can simulate calls to be expensive
@@ -21,10 +24,14 @@ type mockPriceService struct {
 
 func (m *mockPriceService) GetPriceFor(itemCode string) (float64, error) {
 
-	m.numCalls++            // increase the number of calls
+	m.mu.Lock()
+	m.numCalls++ // increase the number of calls
+	m.mu.Unlock()
 	time.Sleep(m.callDelay) // sleep to simulate expensive call
 
+	m.mu.Lock()
 	result, ok := m.mockResults[itemCode]
+	m.mu.Unlock()
 	if !ok {
 		panic(fmt.Errorf("bug in the tests, we didn't have a mock result for [%v]", itemCode))
 	}
@@ -32,6 +39,8 @@ func (m *mockPriceService) GetPriceFor(itemCode string) (float64, error) {
 }
 
 func (m *mockPriceService) getNumCalls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	return m.numCalls
 }
 
@@ -172,3 +181,166 @@ func TestGetPricesFor_ParallelizeCalls(t *testing.T) {
 		t.Error("calls took too long, expected them to take a bit over one second")
 	}
 }
+
+// Check that the cache can be used from many goroutines at once (run with -race)
+func TestGetPriceFor_ConcurrentUse(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+			"p3": {price: 9, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+		}()
+		go func() {
+			defer wg.Done()
+			assertFloats(t, []float64{7, 9}, getPricesWithNoErr(t, cache, "p2", "p3"), "wrong price returned")
+		}()
+	}
+	wg.Wait()
+}
+
+// Check that a miss waiting on the service doesn't block hits, writes and other misses for other items
+func TestGetPriceFor_SlowMissDoesNotBlockOtherItems(t *testing.T) {
+	for name, opts := range map[string][]Option{"unbounded": nil, "bounded": {WithMaxEntries(10)}} {
+		t.Run(name, func(t *testing.T) {
+			mockService := &slowMockPriceService{
+				mockPriceService: mockPriceService{
+					mockResults: map[string]mockResult{
+						"p1": {price: 5, err: nil},
+						"p2": {price: 7, err: nil},
+						"p3": {price: 9, err: nil},
+					},
+				},
+				slow:    "p1",
+				release: make(chan struct{}),
+			}
+			cache := NewTransparentCache(mockService, time.Minute, opts...)
+			getPriceWithNoErr(t, cache, "p2")
+			slowDone := make(chan struct{})
+			go func() {
+				defer close(slowDone)
+				assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+			}()
+			for !cache.flights.inFlight("p1") {
+				time.Sleep(time.Millisecond)
+			}
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < 100; i++ {
+					assertFloat(t, 7, getPriceWithNoErr(t, cache, "p2"), "wrong price returned")
+				}
+				cache.Set("p4", 11)
+				assertFloat(t, 9, getPriceWithNoErr(t, cache, "p3"), "wrong price returned")
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("the other items were blocked by the slow miss")
+			}
+			select {
+			case <-slowDone:
+				t.Fatal("the slow miss should still be waiting on the service")
+			default:
+			}
+			close(mockService.release)
+			<-slowDone
+		})
+	}
+}
+
+// Check that a refreshed entry gets a new timestamp instead of expiring with the others
+func TestGetPriceFor_RefreshedEntryGetsNewTimestamp(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	maxAge := time.Millisecond * 100
+	cache := NewTransparentCache(mockService, maxAge)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	time.Sleep(maxAge + maxAge/2)
+	// entry is expired, this call refreshes it
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 2, mockService.getNumCalls(), "wrong number of service calls")
+	time.Sleep(maxAge / 2)
+	// the refreshed entry is still fresh, it should come from the cache
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 2, mockService.getNumCalls(), "wrong number of service calls")
+}
+
+// Check that repeated item codes cost a single call to the service
+func TestGetPricesFor_RepeatedItemCodes(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay: 10 * time.Millisecond,
+		mockResults: map[string]mockResult{
+			"A": {price: 5, err: nil},
+			"B": {price: 7, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxConcurrency(1))
+	assertFloats(t, []float64{5, 5, 7, 5}, getPricesWithNoErr(t, cache, "A", "A", "B", "A"), "wrong price returned")
+	assertInt(t, 2, mockService.getNumCalls(), "wrong number of service calls")
+	assertInt(t, 0, int(cache.Stats().Hits), "repeated item codes should not count as hits")
+}
+
+// Check that GetOrLoad uses the given loader on a miss only, and caches what it returns
+func TestGetOrLoad(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5, err: nil}}}
+	cache := NewTransparentCache(mockService, time.Minute)
+	loads := 0
+	load := func() (float64, error) {
+		loads++
+		return 7, nil
+	}
+	price, err := cache.GetOrLoad("p2", load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, 7, price, "wrong price returned")
+	assertFloat(t, 7, getPriceWithNoErr(t, cache, "p2"), "the loaded price should be cached")
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	price, _ = cache.GetOrLoad("p1", load)
+	assertFloat(t, 5, price, "a cached price should be returned without calling load")
+	assertInt(t, 1, loads, "wrong number of loads")
+	assertInt(t, 1, mockService.getNumCalls(), "wrong number of service calls")
+
+	if _, err := cache.GetOrLoad("p3", func() (float64, error) { return 0, ErrNotFound }); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the error of load but got %v", err)
+	}
+}
+
+// Check that concurrent GetOrLoad calls for the same item share one load
+func TestGetOrLoad_Coalesces(t *testing.T) {
+	cache := NewTransparentCache(&mockPriceService{}, time.Minute)
+	var mu sync.Mutex
+	loads := 0
+	load := func() (float64, error) {
+		mu.Lock()
+		loads++
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		return 7, nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if price, err := cache.GetOrLoad("p1", load); err != nil || price != 7 {
+				t.Errorf("expected 7 but got %v, %v", price, err)
+			}
+		}()
+	}
+	wg.Wait()
+	assertInt(t, 1, loads, "concurrent misses should share one load")
+}
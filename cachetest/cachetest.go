@@ -0,0 +1,208 @@
+// Package cachetest provides a fake price service for the tests of the code using a cache, so that they don't
+// write mocks of their own: its responses are scripted per item, its calls counted, and it tells how many calls
+// ran at once
+package cachetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// Response is what a Service answers to a call for an item
+type Response struct {
+	Price   float64
+	Err     error
+	Latency time.Duration // how long the call takes, on top of the latency of the item
+}
+
+// Service is a fake sample1.PriceService (and sample1.ContextPriceService) answering the responses scripted for
+// every item. The items that have none fail with sample1.ErrNotFound
+// It is safe for concurrent use by multiple goroutines
+type Service struct {
+	mu        sync.Mutex
+	responses map[string][]Response // answered in turn, the last one repeating
+	latencies map[string]time.Duration
+	calls     map[string]int
+	total     int
+	inFlight  map[string]int
+	maxByItem map[string]int
+	running   int // calls in flight, for every item
+	maxAtOnce int
+}
+
+// NewService creates a Service without responses
+func NewService() *Service {
+	return &Service{
+		responses: map[string][]Response{},
+		latencies: map[string]time.Duration{},
+		calls:     map[string]int{},
+		inFlight:  map[string]int{},
+		maxByItem: map[string]int{},
+	}
+}
+
+// SetPrice makes every call for itemCode return price, it returns s for chaining
+func (s *Service) SetPrice(itemCode string, price float64) *Service {
+	return s.Script(itemCode, Response{Price: price})
+}
+
+// SetError makes every call for itemCode fail with err, it returns s for chaining
+func (s *Service) SetError(itemCode string, err error) *Service {
+	return s.Script(itemCode, Response{Err: err})
+}
+
+// Script makes the next calls for itemCode answer responses in turn, the last one answering every call after
+// them. It replaces what was scripted for itemCode, and removes it without responses. It returns s for chaining
+func (s *Service) Script(itemCode string, responses ...Response) *Service {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(responses) == 0 {
+		delete(s.responses, itemCode)
+		return s
+	}
+	s.responses[itemCode] = responses
+	return s
+}
+
+// SetLatency makes every call for itemCode take latency, whatever it answers, it returns s for chaining
+func (s *Service) SetLatency(itemCode string, latency time.Duration) *Service {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies[itemCode] = latency
+	return s
+}
+
+// start counts a call for itemCode and returns its response, and how long it takes
+func (s *Service) start(itemCode string) (Response, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[itemCode]++
+	s.total++
+	s.inFlight[itemCode]++
+	s.maxByItem[itemCode] = max(s.maxByItem[itemCode], s.inFlight[itemCode])
+	s.running++
+	s.maxAtOnce = max(s.maxAtOnce, s.running)
+	responses := s.responses[itemCode]
+	if len(responses) == 0 {
+		return Response{Err: fmt.Errorf("pricing [%v] : %w", itemCode, sample1.ErrNotFound)}, s.latencies[itemCode]
+	}
+	response := responses[0]
+	if len(responses) > 1 {
+		s.responses[itemCode] = responses[1:]
+	}
+	return response, s.latencies[itemCode] + response.Latency
+}
+
+// end counts that a call for itemCode returned
+func (s *Service) end(itemCode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight[itemCode]--
+	s.running--
+}
+
+func (s *Service) GetPriceFor(itemCode string) (float64, error) {
+	return s.GetPriceForCtx(context.Background(), itemCode)
+}
+
+// GetPriceForCtx answers the next response scripted for itemCode, after its latency, unless ctx is done first
+func (s *Service) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	response, latency := s.start(itemCode)
+	defer s.end(itemCode)
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	return response.Price, response.Err
+}
+
+// Calls returns how many calls were made for itemCode
+func (s *Service) Calls(itemCode string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[itemCode]
+}
+
+// TotalCalls returns how many calls were made, for every item
+func (s *Service) TotalCalls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.total
+}
+
+// InFlight returns how many calls are running, for every item
+func (s *Service) InFlight() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// MaxConcurrency returns the most calls that ran at once, for every item
+func (s *Service) MaxConcurrency() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxAtOnce
+}
+
+// MaxConcurrencyFor returns the most calls for itemCode that ran at once
+func (s *Service) MaxConcurrencyFor(itemCode string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxByItem[itemCode]
+}
+
+// ResetCalls forgets the calls made, the responses left to answer are kept
+func (s *Service) ResetCalls() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.calls)
+	clear(s.maxByItem)
+	s.total = 0
+	s.maxAtOnce = s.running
+	for itemCode, n := range s.inFlight {
+		s.maxByItem[itemCode] = n
+	}
+}
+
+// AssertCalls fails t unless want calls were made for itemCode
+func (s *Service) AssertCalls(t testing.TB, itemCode string, want int) {
+	t.Helper()
+	if got := s.Calls(itemCode); got != want {
+		t.Errorf("expected %d calls for [%v] but got %d", want, itemCode, got)
+	}
+}
+
+// AssertTotalCalls fails t unless want calls were made, for every item
+func (s *Service) AssertTotalCalls(t testing.TB, want int) {
+	t.Helper()
+	if got := s.TotalCalls(); got != want {
+		t.Errorf("expected %d calls but got %d", want, got)
+	}
+}
+
+// AssertMaxConcurrency fails t if more than limit calls ran at once, to check a sample1.WithMaxConcurrency
+func (s *Service) AssertMaxConcurrency(t testing.TB, limit int) {
+	t.Helper()
+	if got := s.MaxConcurrency(); got > limit {
+		t.Errorf("expected at most %d calls at once but got %d", limit, got)
+	}
+}
+
+// AssertNoConcurrentCalls fails t if several calls for itemCode ran at once, which the cache is supposed to
+// coalesce into one
+func (s *Service) AssertNoConcurrentCalls(t testing.TB, itemCode string) {
+	t.Helper()
+	if got := s.MaxConcurrencyFor(itemCode); got > 1 {
+		t.Errorf("expected a single call at once for [%v] but got %d", itemCode, got)
+	}
+}
@@ -0,0 +1,116 @@
+package cachetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// recordingT is a testing.TB remembering its failures, to check the assertions that fail
+type recordingT struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+// Check that the scripted responses are answered in turn, the last one repeating, and that unknown items aren't found
+func TestService_Script(t *testing.T) {
+	someErr := errors.New("some error")
+	service := NewService().SetPrice("p1", 5).Script("p2", Response{Err: someErr}, Response{Price: 6})
+	for i, want := range []struct {
+		itemCode string
+		price    float64
+		err      error
+	}{{"p1", 5, nil}, {"p2", 0, someErr}, {"p2", 6, nil}, {"p2", 6, nil}, {"p3", 0, sample1.ErrNotFound}} {
+		price, err := service.GetPriceFor(want.itemCode)
+		if price != want.price || !errors.Is(err, want.err) || (want.err == nil && err != nil) {
+			t.Errorf("call %d for [%v] : expected %v, %v but got %v, %v", i, want.itemCode, want.price, want.err, price, err)
+		}
+	}
+	service.AssertCalls(t, "p2", 3)
+	service.AssertTotalCalls(t, 5)
+	service.ResetCalls()
+	service.AssertTotalCalls(t, 0)
+	if price, _ := service.GetPriceFor("p2"); price != 6 {
+		t.Errorf("the responses should outlive ResetCalls, got %v", price)
+	}
+}
+
+// Check that the latency of an item delays its calls, unless their context is done first
+func TestService_Latency(t *testing.T) {
+	service := NewService().SetPrice("p1", 5).SetLatency("p1", 20*time.Millisecond)
+	start := time.Now()
+	if _, err := service.GetPriceFor("p1"); err != nil || time.Since(start) < 20*time.Millisecond {
+		t.Errorf("the call should have taken the latency, took %v (err %v)", time.Since(start), err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := service.GetPriceForCtx(ctx, "p1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be exceeded but got %v", err)
+	}
+}
+
+// Check that the concurrency of the calls is measured, and checked by the assertions
+func TestService_Concurrency(t *testing.T) {
+	service := NewService().SetPrice("p1", 5).SetPrice("p2", 6).
+		SetLatency("p1", 20*time.Millisecond).SetLatency("p2", 20*time.Millisecond)
+	var wg sync.WaitGroup
+	for _, itemCode := range []string{"p1", "p1", "p2"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			service.GetPriceFor(itemCode)
+		}()
+	}
+	wg.Wait()
+	if got := service.MaxConcurrency(); got != 3 {
+		t.Errorf("expected 3 calls at once but got %d", got)
+	}
+	if got := service.InFlight(); got != 0 {
+		t.Errorf("expected no call in flight but got %d", got)
+	}
+	rt := &recordingT{TB: t}
+	service.AssertNoConcurrentCalls(rt, "p1")
+	service.AssertNoConcurrentCalls(rt, "p2")
+	service.AssertMaxConcurrency(rt, 2)
+	service.AssertCalls(rt, "p1", 1)
+	if len(rt.failures) != 3 {
+		t.Errorf("expected 3 failed assertions but got %v", rt.failures)
+	}
+}
+
+// Check that the Service shows a cache coalescing the lookups of an item and bounding the loads of a batch
+func TestService_WithCache(t *testing.T) {
+	service := NewService()
+	itemCodes := make([]string, 10)
+	for i := range itemCodes {
+		itemCodes[i] = fmt.Sprint("p", i)
+		service.SetPrice(itemCodes[i], float64(i)).SetLatency(itemCodes[i], 10*time.Millisecond)
+	}
+	cache := sample1.New(service, sample1.WithMaxConcurrency(2))
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.GetPriceFor("p0")
+		}()
+	}
+	wg.Wait()
+	service.AssertCalls(t, "p0", 1)
+	if _, err := cache.GetPricesFor(itemCodes...); err != nil {
+		t.Fatal(err)
+	}
+	service.AssertTotalCalls(t, 10)
+	service.AssertMaxConcurrency(t, 2)
+}
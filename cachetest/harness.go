@@ -0,0 +1,191 @@
+package cachetest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// Start is the time a Harness starts at
+var Start = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// settleTimeout bounds how long a Harness waits for the loads in flight
+const settleTimeout = 10 * time.Second
+
+// Clock is a sample1.TickingClock whose time only moves when told to, running the periodic work of the cache
+// as its time goes by. It is safe for concurrent use by multiple goroutines
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*ticker
+}
+
+// ticker is the periodic work a Clock runs
+type ticker struct {
+	interval time.Duration
+	next     time.Time
+	fn       func()
+	stopped  bool
+}
+
+// NewClock creates a Clock at start
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Every calls fn every interval of the time of the clock, from the goroutine moving it, until stop is called
+func (c *Clock) Every(interval time.Duration, fn func()) (stop func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &ticker{interval: interval, next: c.now.Add(interval), fn: fn}
+	c.tickers = append(c.tickers, t)
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		t.stopped = true
+		c.tickers = slices.DeleteFunc(c.tickers, func(other *ticker) bool { return other == t })
+	}
+}
+
+// Advance moves the time of the clock by d, running every tick due on the way in order, at its time
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	until := c.now.Add(d)
+	c.mu.Unlock()
+	for {
+		c.mu.Lock()
+		var due *ticker
+		for _, t := range c.tickers {
+			if !t.next.After(until) && (due == nil || t.next.Before(due.next)) {
+				due = t
+			}
+		}
+		if due == nil {
+			c.now = until
+			c.mu.Unlock()
+			return
+		}
+		c.now = due.next
+		due.next = due.next.Add(due.interval)
+		c.mu.Unlock()
+		due.fn()
+	}
+}
+
+// Tick runs the periodic work right away, without moving the time, as if every ticker was due
+func (c *Clock) Tick() {
+	c.mu.Lock()
+	tickers := slices.Clone(c.tickers)
+	c.mu.Unlock()
+	for _, t := range tickers {
+		c.mu.Lock()
+		stopped := t.stopped
+		c.mu.Unlock()
+		if !stopped {
+			t.fn()
+		}
+	}
+}
+
+// Harness is a cache in front of a Service, on a Clock, for deterministic tests of expiration and refreshes: time
+// only moves with Advance, the janitor and the periodic snapshots run as it does, and every step waits for the
+// loads it started, the background refreshes included, before returning
+type Harness struct {
+	Clock   *Clock
+	Service *Service
+	Cache   *sample1.TransparentCache
+	t       testing.TB
+}
+
+// NewHarness creates a Harness with a cache configured with opts, at Start, the cache being closed when the test
+// is over. The harness sets the clock of the cache, overriding a WithClock of opts
+func NewHarness(t testing.TB, opts ...sample1.Option) *Harness {
+	clock := NewClock(Start)
+	service := NewService()
+	cache := sample1.New(service, append(slices.Clone(opts), sample1.WithClock(clock))...)
+	t.Cleanup(func() { cache.Close() })
+	return &Harness{Clock: clock, Service: service, Cache: cache, t: t}
+}
+
+// Settle waits for the loads in flight, it fails the test if they take too long
+func (h *Harness) Settle() {
+	h.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), settleTimeout)
+	defer cancel()
+	if err := h.Cache.WaitForLoads(ctx); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// Advance moves the time by d, running the ticks due on the way, and settles
+func (h *Harness) Advance(d time.Duration) {
+	h.t.Helper()
+	h.Clock.Advance(d)
+	h.Settle()
+}
+
+// Tick runs the periodic work of the cache right away and settles
+func (h *Harness) Tick() {
+	h.t.Helper()
+	h.Clock.Tick()
+	h.Settle()
+}
+
+// Get looks up itemCode and settles, so that the refresh the lookup may have started is over
+func (h *Harness) Get(itemCode string) (float64, error) {
+	h.t.Helper()
+	price, err := h.Cache.GetPriceFor(itemCode)
+	h.Settle()
+	return price, err
+}
+
+// AssertPrice fails the test unless looking up itemCode returns price
+func (h *Harness) AssertPrice(itemCode string, price float64) {
+	h.t.Helper()
+	if got, err := h.Get(itemCode); err != nil || got != price {
+		h.t.Errorf("expected the price %v for [%v] but got %v (err %v)", price, itemCode, got, err)
+	}
+}
+
+// AssertCached fails the test unless price is cached for itemCode, fresh or not, and age old
+func (h *Harness) AssertCached(itemCode string, price float64, age time.Duration) {
+	h.t.Helper()
+	got, gotAge, ok := h.Cache.Peek(itemCode)
+	if !ok || got != price || gotAge != age {
+		h.t.Errorf("expected the price %v cached for [%v] %v ago but got %v %v ago (cached %v)", price, itemCode, age, got, gotAge, ok)
+	}
+}
+
+// AssertFresh fails the test unless itemCode has a fresh price cached
+func (h *Harness) AssertFresh(itemCode string) {
+	h.t.Helper()
+	if !h.Cache.Contains(itemCode) {
+		h.t.Errorf("expected a fresh price cached for [%v]", itemCode)
+	}
+}
+
+// AssertNotCached fails the test if a price is cached for itemCode, even a stale one
+func (h *Harness) AssertNotCached(itemCode string) {
+	h.t.Helper()
+	if _, _, ok := h.Cache.Peek(itemCode); ok {
+		h.t.Errorf("expected nothing cached for [%v]", itemCode)
+	}
+}
+
+// AssertLen fails the test unless the cache holds n entries
+func (h *Harness) AssertLen(n int) {
+	h.t.Helper()
+	if got := h.Cache.Len(); got != n {
+		h.t.Errorf("expected %d entries but got %d", n, got)
+	}
+}
@@ -0,0 +1,83 @@
+package cachetest
+
+import (
+	"testing"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// Check that Advance runs the ticks due on the way in order, each at its time, and that stopped ones don't run
+func TestClock_Advance(t *testing.T) {
+	clock := NewClock(Start)
+	var ticks []string
+	clock.Every(2*time.Second, func() { ticks = append(ticks, "2s@"+clock.Now().Sub(Start).String()) })
+	stop := clock.Every(3*time.Second, func() { ticks = append(ticks, "3s@"+clock.Now().Sub(Start).String()) })
+	clock.Advance(7 * time.Second)
+	want := []string{"2s@2s", "3s@3s", "2s@4s", "2s@6s", "3s@6s"}
+	if len(ticks) != len(want) {
+		t.Fatalf("expected the ticks %v but got %v", want, ticks)
+	}
+	for i := range want {
+		if ticks[i] != want[i] {
+			t.Errorf("expected the ticks %v but got %v", want, ticks)
+			break
+		}
+	}
+	if got := clock.Now().Sub(Start); got != 7*time.Second {
+		t.Errorf("expected the clock to be 7s later but it is %v", got)
+	}
+	stop()
+	ticks = nil
+	clock.Tick()
+	if len(ticks) != 1 {
+		t.Errorf("expected only the ticker that wasn't stopped to run but got %v", ticks)
+	}
+}
+
+// Check that the janitor runs as the time goes by, and on demand with Tick
+func TestHarness_Janitor(t *testing.T) {
+	h := NewHarness(t, sample1.WithMaxAge(time.Minute), sample1.WithJanitor(10*time.Minute))
+	h.Service.SetPrice("p1", 5).SetPrice("p2", 6)
+	h.AssertPrice("p1", 5)
+	h.Advance(9 * time.Minute)
+	h.AssertCached("p1", 5, 9*time.Minute)
+	h.AssertPrice("p2", 6)
+	h.Advance(time.Minute) // the janitor runs, only p1 expired
+	h.AssertNotCached("p1")
+	h.AssertCached("p2", 6, time.Minute)
+	h.Advance(time.Second)
+	h.AssertLen(1)
+	h.Tick()
+	h.AssertNotCached("p2")
+	h.AssertLen(0)
+}
+
+// Check that the refreshes ahead of the expiry are over once a lookup returns
+func TestHarness_RefreshAhead(t *testing.T) {
+	h := NewHarness(t, sample1.WithMaxAge(10*time.Minute), sample1.WithRefreshAhead(0.2))
+	h.Service.Script("p1", Response{Price: 5}, Response{Price: 6})
+	h.AssertPrice("p1", 5)
+	h.Advance(7 * time.Minute)
+	h.AssertPrice("p1", 5)
+	h.Service.AssertCalls(t, "p1", 1)
+	h.Advance(time.Minute)
+	h.AssertPrice("p1", 5) // served while the refresh goes on
+	h.Service.AssertCalls(t, "p1", 2)
+	h.AssertCached("p1", 6, 0)
+	h.Advance(9 * time.Minute)
+	h.AssertFresh("p1")
+}
+
+// Check that the stale prices are served while they are revalidated, until they are too old for that
+func TestHarness_StaleWhileRevalidate(t *testing.T) {
+	h := NewHarness(t, sample1.WithMaxAge(time.Minute), sample1.WithStaleWhileRevalidate(time.Minute))
+	h.Service.Script("p1", Response{Price: 5}, Response{Price: 6}, Response{Price: 7})
+	h.AssertPrice("p1", 5)
+	h.Advance(90 * time.Second)
+	h.AssertPrice("p1", 5)
+	h.AssertCached("p1", 6, 0)
+	h.Advance(3 * time.Minute)
+	h.AssertPrice("p1", 7)
+	h.Service.AssertTotalCalls(t, 3)
+}
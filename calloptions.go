@@ -0,0 +1,50 @@
+package sample1
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotCached is returned by the lookups made with CacheOnly when the cache has no value it can serve
+var ErrNotCached = errors.New("not cached")
+
+// CallOption changes how a single lookup uses the cache, see ForceRefresh, MaxStaleness and CacheOnly
+type CallOption func(*callConfig)
+
+type callConfig struct {
+	forceRefresh bool
+	maxStaleness time.Duration
+	cacheOnly    bool
+}
+
+func newCallConfig(opts []CallOption) callConfig {
+	var call callConfig
+	for _, opt := range opts {
+		opt(&call)
+	}
+	return call
+}
+
+// ForceRefresh makes the lookup ignore what is cached and wait for a new value, which is cached as usual
+// The lookup joins the load in flight for the key if there is one, it is ignored with CacheOnly
+func ForceRefresh() CallOption {
+	return func(c *callConfig) {
+		c.forceRefresh = true
+	}
+}
+
+// MaxStaleness makes the lookup accept a value up to maxStale past its maxAge, returned right away while it is
+// refreshed in the background, as WithStaleWhileRevalidate does for every lookup. The hard TTL still holds
+func MaxStaleness(maxStale time.Duration) CallOption {
+	return func(c *callConfig) {
+		c.maxStaleness = maxStale
+	}
+}
+
+// CacheOnly makes the lookup answer from the cache alone, for the latency critical paths: it fails with
+// ErrNotCached rather than waiting for a load when the cache has no value it can serve
+func CacheOnly() CallOption {
+	return func(c *callConfig) {
+		c.cacheOnly = true
+	}
+}
@@ -0,0 +1,103 @@
+package sample1
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// Check that ForceRefresh fetches the price again even if the cached one is fresh, and caches the new one
+func TestGetPriceFor_ForceRefresh(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	mockService.setPrice("p1", 6)
+	price, err := cache.GetPriceForWith("p1", ForceRefresh())
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	assertFloat(t, 6, price, "expected the refreshed price")
+	assertFloat(t, 6, getPriceWithNoErr(t, cache, "p1"), "the refreshed price should have been cached")
+	assertInt(t, 2, mockService.getNumCalls(), "wrong number of service calls")
+}
+
+// Check that MaxStaleness serves an expired price within the bound while refreshing it, and not past it
+func TestGetPriceFor_MaxStaleness(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	clock := newFakeClock()
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	clock.Advance(90 * time.Second)
+	mockService.setPrice("p1", 6)
+	price, err := cache.GetPriceForWith("p1", MaxStaleness(time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	assertFloat(t, 5, price, "expected the stale price")
+	for deadline := time.Now().Add(time.Second); ; {
+		if price, _, _ := cache.Peek("p1"); price == 6 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the stale price wasn't refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Advance(3 * time.Minute)
+	mockService.setPrice("p1", 7)
+	price, err = cache.GetPriceForWith("p1", MaxStaleness(time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	assertFloat(t, 7, price, "a price past the bound should be fetched synchronously")
+}
+
+// Check that CacheOnly answers from the cache and fails with ErrNotCached without calling the service
+func TestGetPriceFor_CacheOnly(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 6, err: nil},
+		},
+	}
+	clock := newFakeClock()
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock))
+	getPriceWithNoErr(t, cache, "p1")
+	price, err := cache.GetPriceForWith("p1", CacheOnly())
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	assertFloat(t, 5, price, "expected the cached price")
+	if _, err := cache.GetPriceForWith("p2", CacheOnly()); !errors.Is(err, ErrNotCached) {
+		t.Errorf("expected ErrNotCached for an item that isn't cached, got %v", err)
+	}
+	clock.Advance(2 * time.Minute)
+	if _, err := cache.GetPriceForWith("p1", CacheOnly()); !errors.Is(err, ErrNotCached) {
+		t.Errorf("expected ErrNotCached for an expired price, got %v", err)
+	}
+	assertInt(t, 1, mockService.getNumCalls(), "CacheOnly lookups shouldn't call the service")
+	if _, err := cache.GetPriceForWith("p1", CacheOnly(), MaxStaleness(time.Hour)); err != nil {
+		t.Errorf("the expired price should be served within MaxStaleness, got %v", err)
+	}
+}
+
+// Check that a cache is still a price service, so that it can be put in front of another cache
+func TestTransparentCache_StacksOverAnotherCache(t *testing.T) {
+	var _ ContextPriceService = (*TransparentCache)(nil)
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}}}
+	l2 := NewTransparentCache(mockService, time.Hour)
+	l1 := NewTransparentCache(l2, time.Minute)
+	assertFloat(t, 5, getPriceWithNoErr(t, l1, "p1"), "wrong price through both caches")
+	l1.Invalidate("p1")
+	getPriceWithNoErr(t, l1, "p1")
+	assertInt(t, 1, mockService.getNumCalls(), "the second cache should have served the price again")
+}
@@ -0,0 +1,152 @@
+package sample1
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Change tells a Subscription that the value of a key changed: it was loaded (or set) with a value different
+// from the last one the subscription saw
+type Change[K comparable, V any] struct {
+	Key       K
+	Value     V
+	Previous  V    // the last value the subscription saw, meaningless if HadValue is false
+	HadValue  bool // whether the subscription saw a value for the key before, it didn't if it wasn't cached
+	FetchedAt time.Time
+}
+
+// PriceChange is a Change of the price of an item
+type PriceChange = Change[string, float64]
+
+// Subscription receives the changes of the values of some keys on C, see Subscribe
+// Changes are dropped, rather than blocking the cache, while C is full: a subscriber falling behind misses
+// some of them (see Dropped), the next change it gets is relative to the last value it saw
+type Subscription[K comparable, V any] struct {
+	C        <-chan Change[K, V]
+	changes  chan Change[K, V]
+	cache    *Cache[K, V]
+	registry *changeRegistry[K, V]
+	last     map[K]*V // the last value seen for every key, nil if none yet, guarded by the mutex of the registry
+	closed   bool
+	dropped  atomic.Uint64
+}
+
+// changeRegistry keeps the subscriptions of every key
+type changeRegistry[K comparable, V any] struct {
+	mu    sync.Mutex
+	byKey map[K]map[*Subscription[K, V]]struct{}
+	keys  atomic.Int64 // the keys with subscriptions, so that saving the others skips the lock
+}
+
+// Subscribe returns a subscription to the changes of keys, with room for buffer changes on its channel
+// It starts from the values currently cached (a key that isn't cached gets a change once loaded), and it must
+// be closed once no longer needed
+func (c *Cache[K, V]) Subscribe(buffer int, keys ...K) *Subscription[K, V] {
+	changes := make(chan Change[K, V], max(buffer, 0))
+	s := &Subscription[K, V]{C: changes, changes: changes, cache: c, registry: &c.changes, last: map[K]*V{}}
+	s.Add(keys...)
+	return s
+}
+
+// Add adds keys to the keys of the subscription, starting from their values currently cached
+func (s *Subscription[K, V]) Add(keys ...K) {
+	// read before taking the lock of the registry, which is taken while the cache lock is held
+	current := make([]*V, len(keys))
+	for i, key := range keys {
+		if value, _, ok := s.cache.Peek(key); ok {
+			current[i] = &value
+		}
+	}
+	r := s.registry
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if r.byKey == nil {
+		r.byKey = map[K]map[*Subscription[K, V]]struct{}{}
+	}
+	for i, key := range keys {
+		if _, ok := s.last[key]; ok {
+			continue
+		}
+		s.last[key] = current[i]
+		if r.byKey[key] == nil {
+			r.byKey[key] = map[*Subscription[K, V]]struct{}{}
+			r.keys.Add(1)
+		}
+		r.byKey[key][s] = struct{}{}
+	}
+}
+
+// Remove stops following the changes of keys
+func (s *Subscription[K, V]) Remove(keys ...K) {
+	r := s.registry
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range keys {
+		s.remove(key)
+	}
+}
+
+// remove unregisters the subscription for key, the mutex of the registry must be held
+func (s *Subscription[K, V]) remove(key K) {
+	r := s.registry
+	if _, ok := s.last[key]; !ok {
+		return
+	}
+	delete(s.last, key)
+	delete(r.byKey[key], s)
+	if len(r.byKey[key]) == 0 {
+		delete(r.byKey, key)
+		r.keys.Add(-1)
+	}
+}
+
+// Close stops the subscription and closes C, calling it again does nothing
+func (s *Subscription[K, V]) Close() {
+	r := s.registry
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s.closed {
+		return
+	}
+	for key := range s.last {
+		s.remove(key)
+	}
+	s.closed = true
+	close(s.changes)
+}
+
+// Dropped returns how many changes were dropped because C was full
+func (s *Subscription[K, V]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// publish sends the change of the value of key to the subscriptions that saw another value, without blocking
+func (r *changeRegistry[K, V]) publish(key K, value V, fetchedAt time.Time) {
+	if r.keys.Load() == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for s := range r.byKey[key] {
+		last := s.last[key]
+		if last != nil && reflect.DeepEqual(*last, value) {
+			continue
+		}
+		change := Change[K, V]{Key: key, Value: value, HadValue: last != nil, FetchedAt: fetchedAt}
+		if last != nil {
+			change.Previous = *last
+		}
+		select {
+		case s.changes <- change:
+			s.last[key] = &value
+		default:
+			// s.last keeps the value the subscriber saw, so that a later change compares against it
+			s.dropped.Add(1)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package sample1
+
+import (
+	"testing"
+	"time"
+)
+
+// setPrice changes the price the mock service returns for itemCode
+func (m *mockPriceService) setPrice(itemCode string, price float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mockResults[itemCode] = mockResult{price: price}
+}
+
+func receiveChange(t *testing.T, subscription *Subscription[string, float64]) PriceChange {
+	t.Helper()
+	select {
+	case change := <-subscription.C:
+		return change
+	case <-time.After(time.Second):
+		t.Fatal("expected a change")
+		return PriceChange{}
+	}
+}
+
+func assertNoChange(t *testing.T, subscription *Subscription[string, float64]) {
+	t.Helper()
+	select {
+	case change, ok := <-subscription.C:
+		if ok {
+			t.Errorf("expected no change but got %+v", change)
+		}
+	default:
+	}
+}
+
+// Check that a subscription gets the reloads that changed the price, and only those
+func TestSubscribe_ReceivesChangedPrices(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}, "p2": {price: 7}}}
+	clock := newFakeClock()
+	cache := New(mockService, WithClock(clock), WithMaxAge(time.Minute))
+	getPriceWithNoErr(t, cache, "p1")
+	subscription := cache.Subscribe(4, "p1", "p2")
+	defer subscription.Close()
+
+	clock.Advance(2 * time.Minute)
+	getPriceWithNoErr(t, cache, "p1")
+	assertNoChange(t, subscription)
+
+	mockService.setPrice("p1", 6)
+	clock.Advance(2 * time.Minute)
+	getPriceWithNoErr(t, cache, "p1")
+	change := receiveChange(t, subscription)
+	if change.Key != "p1" || change.Value != 6 || change.Previous != 5 || !change.HadValue {
+		t.Errorf("unexpected change %+v", change)
+	}
+
+	getPriceWithNoErr(t, cache, "p2")
+	if change := receiveChange(t, subscription); change.Key != "p2" || change.Value != 7 || change.HadValue {
+		t.Errorf("an item that wasn't cached should get its first price as a change, got %+v", change)
+	}
+
+	cache.Set("p3", 1)
+	assertNoChange(t, subscription)
+}
+
+// Check that the changes a slow subscriber has no room for are dropped, and counted, rather than blocking the cache
+func TestSubscribe_DropsChangesWhenFull(t *testing.T) {
+	cache := New(&mockPriceService{})
+	subscription := cache.Subscribe(1, "p1")
+	defer subscription.Close()
+	cache.Set("p1", 5)
+	cache.Set("p1", 6)
+	assertInt(t, 1, int(subscription.Dropped()), "the second change should have been dropped")
+	if change := receiveChange(t, subscription); change.Value != 5 {
+		t.Errorf("unexpected change %+v", change)
+	}
+	cache.Set("p1", 6)
+	if change := receiveChange(t, subscription); change.Value != 6 || change.Previous != 5 {
+		t.Errorf("the change should be relative to the last price seen, got %+v", change)
+	}
+}
+
+// Check that removed keys and closed subscriptions don't get changes anymore
+func TestSubscribe_RemoveAndClose(t *testing.T) {
+	cache := New(&mockPriceService{})
+	subscription := cache.Subscribe(4, "p1", "p2")
+	subscription.Remove("p1")
+	cache.Set("p1", 5)
+	assertNoChange(t, subscription)
+	subscription.Add("p1")
+	cache.Set("p1", 6)
+	if change := receiveChange(t, subscription); change.Value != 6 || change.Previous != 5 {
+		t.Errorf("an added key should start from its cached price, got %+v", change)
+	}
+
+	subscription.Close()
+	subscription.Close()
+	cache.Set("p2", 7)
+	if _, ok := <-subscription.C; ok {
+		t.Error("expected the channel to be closed")
+	}
+	if cache.changes.keys.Load() != 0 {
+		t.Error("the closed subscription should have been unregistered")
+	}
+}
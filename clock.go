@@ -0,0 +1,30 @@
+package sample1
+
+import "time"
+
+// Clock tells the cache what time it is, so that tests can control how entries age
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the default Clock, the actual time
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithClock sets the clock the cache uses to timestamp entries and decide when they expire
+func WithClock(clock Clock) Option {
+	return func(c *config) {
+		c.clock = clock
+	}
+}
+
+// TickingClock is a Clock that also runs the periodic work of the cache (the janitor, the periodic snapshots), so
+// that a fake clock can run it as its time moves rather than on real tickers
+type TickingClock interface {
+	Clock
+	// Every calls fn every interval of the time of the clock until stop is called
+	Every(interval time.Duration, fn func()) (stop func())
+}
@@ -0,0 +1,85 @@
+package sample1
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when told to
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Same as TestGetPriceFor_DoesNotReturnOldResults, but moving a fake clock instead of sleeping
+func TestGetPriceFor_ExpiresWithFakeClock(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+		},
+	}
+	clock := newFakeClock()
+	cache := NewTransparentCache(mockService, time.Hour, WithClock(clock))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	clock.Advance(40 * time.Minute)
+	assertFloat(t, 7, getPriceWithNoErr(t, cache, "p2"), "wrong price returned")
+	clock.Advance(20 * time.Minute)
+	// exactly maxAge old is still fresh
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 2, mockService.getNumCalls(), "wrong number of service calls")
+	clock.Advance(time.Nanosecond)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertFloat(t, 7, getPriceWithNoErr(t, cache, "p2"), "wrong price returned")
+	assertInt(t, 3, mockService.getNumCalls(), "only p1 should have expired")
+
+	_, age, _ := cache.Peek("p2")
+	if age != 20*time.Minute+time.Nanosecond {
+		t.Errorf("expected the age to follow the fake clock, got %v", age)
+	}
+}
+
+// tickingClock is a fakeClock running the periodic work of the cache only when told to
+type tickingClock struct {
+	*fakeClock
+	fns     []func()
+	stopped int
+}
+
+func (c *tickingClock) Every(interval time.Duration, fn func()) func() {
+	c.fns = append(c.fns, fn)
+	return func() { c.stopped++ }
+}
+
+// Check that a TickingClock runs the janitor instead of a ticker, until the cache is closed
+func TestWithClock_Ticking(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5, err: nil}}}
+	clock := &tickingClock{fakeClock: newFakeClock()}
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock), WithJanitor(time.Millisecond))
+	getPriceWithNoErr(t, cache, "p1")
+	clock.Advance(2 * time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	assertInt(t, 1, cache.Len(), "the janitor should only run with the clock")
+	assertInt(t, 1, len(clock.fns), "the janitor should have been given to the clock")
+	clock.fns[0]()
+	assertInt(t, 0, cache.Len(), "the janitor should have run")
+	cache.Close()
+	assertInt(t, 1, clock.stopped, "closing the cache should stop the janitor")
+}
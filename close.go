@@ -0,0 +1,125 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by the lookups that would need a load once Shutdown was called
+var ErrClosed = errors.New("cache is closed")
+
+// every calls fn every interval from a background goroutine until the cache is closed, never if interval isn't positive
+// A TickingClock calls fn itself
+func (c *Cache[K, V]) every(interval time.Duration, fn func()) {
+	if interval <= 0 {
+		return
+	}
+	c.background.Add(1)
+	if clock, ok := c.clock.(TickingClock); ok {
+		stop := clock.Every(interval, fn)
+		go func() {
+			defer c.background.Done()
+			<-c.stop
+			stop()
+		}()
+		return
+	}
+	go func() {
+		defer c.background.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the background work of the cache (the janitor and the periodic snapshots) and, if WithSnapshots
+// was used, saves a last snapshot
+// The cache keeps answering lookups after Close, calling it again does nothing
+func (c *Cache[K, V]) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		c.background.Wait()
+		if c.snapshots != nil {
+			err = c.saveFile(c.snapshots.path)
+		}
+	})
+	return err
+}
+
+// Shutdown closes the cache gracefully: the loads that didn't start yet fail with ErrClosed (while the lookups
+// the cache can answer still succeed), the loads in flight are waited for until ctx is done, and the cache is
+// then closed as Close does, saving the last snapshot
+func (c *Cache[K, V]) Shutdown(ctx context.Context) error {
+	var err error
+	select {
+	case <-c.drain.close():
+	case <-ctx.Done():
+		err = fmt.Errorf("waiting for the loads in flight : %w", ctx.Err())
+	}
+	return errors.Join(err, c.Close())
+}
+
+// WaitForLoads waits until no load is in flight, the background refreshes included, so that tests can check what
+// they stored. It fails if ctx is done first
+// The loads that every caller gave up on aren't waited for
+func (c *Cache[K, V]) WaitForLoads(ctx context.Context) error {
+	if err := c.flights.settle(ctx); err != nil {
+		return fmt.Errorf("waiting for the loads in flight : %w", err)
+	}
+	return nil
+}
+
+// drain counts the loads in flight so that Shutdown can wait for them, and refuses new ones once closed
+// The zero value is ready to use
+type drain struct {
+	mu       sync.Mutex
+	inFlight int
+	closed   bool
+	idle     chan struct{} // closed once the drain is closed and no load is in flight anymore
+}
+
+// begin records that a load starts, unless the drain is closed
+func (d *drain) begin() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return false
+	}
+	d.inFlight++
+	return true
+}
+
+// end records that a load begin allowed is over
+func (d *drain) end() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight--
+	if d.closed && d.inFlight == 0 {
+		close(d.idle)
+	}
+}
+
+// close refuses the loads from now on and returns a channel closed once the ones in flight are over
+func (d *drain) close() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.closed {
+		d.closed = true
+		d.idle = make(chan struct{})
+		if d.inFlight == 0 {
+			close(d.idle)
+		}
+	}
+	return d.idle
+}
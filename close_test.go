@@ -0,0 +1,67 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Check that Shutdown waits for the loads in flight, and that afterwards only the cached prices are served
+func TestShutdown_DrainsLoads(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay: 50 * time.Millisecond,
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 6, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	loaded := make(chan float64)
+	go func() {
+		price, _ := cache.GetPriceFor("p1")
+		loaded <- price
+	}()
+	for mockService.getNumCalls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := cache.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if _, _, ok := cache.Peek("p1"); !ok {
+		t.Fatal("Shutdown should have waited for the load in flight")
+	}
+	assertFloat(t, 5, <-loaded, "the load in flight should have completed")
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "the cached price should still be served")
+	if _, err := cache.GetPriceFor("p2"); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed for a price that needs a load, got %v", err)
+	}
+	if _, err := cache.GetPricesFor("p2"); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed in the batch, got %v", err)
+	}
+	assertInt(t, 1, mockService.getNumCalls(), "no load should start after Shutdown")
+}
+
+// Check that Shutdown gives up waiting once its context is done
+func TestShutdown_HonorsContext(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay: time.Second,
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	go cache.GetPriceFor("p1")
+	for mockService.getNumCalls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := cache.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Shutdown should have given up, it took %v", elapsed)
+	}
+}
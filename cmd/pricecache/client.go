@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/MadHive/deviget_challenge/httpcache"
+)
+
+// client talks to the HTTP price API of a running pricecache
+type client struct {
+	server string
+	token  string
+}
+
+// newClientFlags returns the flags of a command talking to a server, the client is usable once they are parsed
+func newClientFlags(name string) (*flag.FlagSet, *client) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	c := &client{}
+	flags.StringVar(&c.server, "server", "http://localhost:8080", "URL of the pricecache server")
+	flags.StringVar(&c.token, "admin-token", os.Getenv("PRICECACHE_ADMIN_TOKEN"),
+		"bearer token of the admin routes (default $PRICECACHE_ADMIN_TOKEN)")
+	return flags, c
+}
+
+// do sends a request and decodes its JSON response into out (if not nil), responses that aren't a 2xx are errors
+func (c *client) do(method, path string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.server, "/")+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var failure httpcache.Error
+		json.NewDecoder(resp.Body).Decode(&failure)
+		return fmt.Errorf("%v %v : %v %v", method, path, resp.Status, failure.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// prices asks the prices of itemCodes with one batch request per batchSize items, and prints them to stdout
+// It returns an error if any item failed, after printing all of them
+func (c *client) prices(itemCodes []string, batchSize int, stdout io.Writer) error {
+	failed := 0
+	for len(itemCodes) > 0 {
+		batch := itemCodes[:min(batchSize, len(itemCodes))]
+		itemCodes = itemCodes[len(batch):]
+		var resp httpcache.BatchResponse
+		if err := c.do(http.MethodPost, "/prices:batch", httpcache.BatchRequest{ItemCodes: batch}, &resp); err != nil {
+			return err
+		}
+		for _, result := range resp.Results {
+			if result.Error != "" {
+				failed++
+				fmt.Fprintf(stdout, "%v\terror: %v\n", result.ItemCode, result.Error)
+				continue
+			}
+			fmt.Fprintf(stdout, "%v\t%v\n", result.ItemCode, result.Price)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d items failed", failed)
+	}
+	return nil
+}
+
+func get(args []string, stdout io.Writer) error {
+	flags, c := newClientFlags("get")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("missing item codes")
+	}
+	return c.prices(flags.Args(), 1000, stdout)
+}
+
+func invalidate(args []string, stdout io.Writer) error {
+	flags, c := newClientFlags("invalidate")
+	prefix := flags.String("prefix", "", "invalidates every item code with this prefix")
+	all := flags.Bool("all", false, "invalidates every item")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	switch {
+	case *all:
+		return c.do(http.MethodDelete, "/admin/keys", nil, nil)
+	case *prefix != "":
+		return c.do(http.MethodDelete, "/admin/keys?prefix="+url.QueryEscape(*prefix), nil, nil)
+	case flags.NArg() == 0:
+		return errors.New("missing item codes, -prefix or -all")
+	}
+	for _, itemCode := range flags.Args() {
+		if err := c.do(http.MethodDelete, "/admin/keys/"+url.PathEscape(itemCode), nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func warm(args []string, stdout io.Writer) error {
+	flags, c := newClientFlags("warm")
+	batchSize := flags.Int("batch-size", 1000, "item codes loaded per request")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("expected the file of item codes")
+	}
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var itemCodes []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if itemCode := strings.TrimSpace(scanner.Text()); itemCode != "" && !strings.HasPrefix(itemCode, "#") {
+			itemCodes = append(itemCodes, itemCode)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := c.prices(itemCodes, max(*batchSize, 1), io.Discard); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "warmed %d items\n", len(itemCodes))
+	return nil
+}
+
+func stats(args []string, stdout io.Writer) error {
+	flags, c := newClientFlags("stats")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	var s httpcache.StatsResponse
+	if err := c.do(http.MethodGet, "/admin/stats", nil, &s); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "entries\t%v\nmax age\t%v\nhits\t%v\nmisses\t%v\nhit ratio\t%.2f\nloads\t%v\nload errors\t%v\nevictions\t%v\nstale served\t%v\n",
+		s.Entries, s.MaxAge, s.Hits, s.Misses, s.HitRatio, s.Loads, s.LoadErrors, s.Evictions, s.StaleServed)
+	return nil
+}
@@ -0,0 +1,50 @@
+// Command pricecache runs a price cache as a standalone server, and talks to a running one
+//
+//	pricecache serve -prices prices.json             serves the prices of a file through a cache
+//	pricecache serve -upstream prices:9090            serves the prices of a remote cache (grpccache)
+//	pricecache get p1 p2                              prints the prices of items
+//	pricecache invalidate p1 p2 | -prefix store1: | -all
+//	pricecache warm codes.txt                         loads the item codes of a file (one per line)
+//	pricecache stats                                  prints the counters of the cache
+//	pricecache simulate -max-entries 1000,10000 trace.jsonl
+//	                                                  prints the hit ratios of other settings on a trace
+//
+// Run pricecache <command> -h for the flags of a command
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "pricecache:", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the command of args, writing its output to stdout
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command, one of serve, get, invalidate, warm, stats or simulate")
+	}
+	command, args := args[0], args[1:]
+	switch command {
+	case "serve":
+		return serve(args, stdout)
+	case "get":
+		return get(args, stdout)
+	case "invalidate":
+		return invalidate(args, stdout)
+	case "warm":
+		return warm(args, stdout)
+	case "stats":
+		return stats(args, stdout)
+	case "simulate":
+		return simulate(args, stdout)
+	default:
+		return fmt.Errorf("unknown command [%v], one of serve, get, invalidate, warm, stats or simulate", command)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+	"github.com/MadHive/deviget_challenge/httpcache"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *sample1.TransparentCache) {
+	cache := sample1.NewTransparentCache(filePriceService{"p1": 5, "p2": 7}, time.Minute)
+	server := httptest.NewServer(httpcache.NewHandler(cache, httpcache.Options{AdminAuth: bearerAuth("secret")}))
+	t.Cleanup(server.Close)
+	return server, cache
+}
+
+func runWithOutput(t *testing.T, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	err := run(args, &stdout)
+	return stdout.String(), err
+}
+
+// Check that get prints the prices, and fails if an item couldn't be priced
+func TestRun_Get(t *testing.T) {
+	server, _ := newTestServer(t)
+	out, err := runWithOutput(t, "get", "-server", server.URL, "p1", "p2")
+	if err != nil || out != "p1\t5\np2\t7\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+	out, err = runWithOutput(t, "get", "-server", server.URL, "p1", "unknown")
+	if err == nil || !strings.Contains(out, "unknown\terror:") {
+		t.Errorf("expected the unknown item to fail, got %q, %v", out, err)
+	}
+}
+
+// Check that warm loads every item code of the file, and that invalidate and stats go through the admin routes
+func TestRun_WarmInvalidateStats(t *testing.T) {
+	server, cache := newTestServer(t)
+	codes := filepath.Join(t.TempDir(), "codes.txt")
+	os.WriteFile(codes, []byte("p1\n# a comment\n\np2\n"), 0o644)
+	if out, err := runWithOutput(t, "warm", "-server", server.URL, "-batch-size", "1", codes); err != nil || out != "warmed 2 items\n" {
+		t.Fatalf("unexpected output %q, %v", out, err)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("expected 2 cached items, got %v", cache.Len())
+	}
+
+	if _, err := runWithOutput(t, "invalidate", "-server", server.URL, "-admin-token", "wrong", "p1"); err == nil {
+		t.Errorf("expected a wrong token to be refused")
+	}
+	if _, err := runWithOutput(t, "invalidate", "-server", server.URL, "-admin-token", "secret", "p1"); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Contains("p1") || !cache.Contains("p2") {
+		t.Errorf("expected only p1 to be invalidated")
+	}
+
+	out, err := runWithOutput(t, "stats", "-server", server.URL, "-admin-token", "secret")
+	if err != nil || !strings.Contains(out, "entries\t1\n") || !strings.Contains(out, "misses\t2\n") {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+}
+
+// Check that serve needs exactly one source of prices
+func TestRun_ServeNeedsOneSource(t *testing.T) {
+	if _, err := runWithOutput(t, "serve"); err == nil {
+		t.Errorf("expected an error without a source")
+	}
+	if _, err := runWithOutput(t, "serve", "-prices", "prices.json", "-upstream", "localhost:9090"); err == nil {
+		t.Errorf("expected an error with two sources")
+	}
+	if _, err := runWithOutput(t, "serve", "-prices", "prices.json", "-replay", "calls.jsonl"); err == nil {
+		t.Errorf("expected an error with a replay and another source")
+	}
+}
+
+// Check that simulate prints the hit ratio of every pair of settings on the trace
+func TestRun_Simulate(t *testing.T) {
+	trace := filepath.Join(t.TempDir(), "trace.jsonl")
+	os.WriteFile(trace, []byte(`{"itemCode":"p1","time":"2021-01-01T00:00:00Z"}
+{"itemCode":"p2","time":"2021-01-01T00:00:01Z"}
+{"itemCode":"p1","time":"2021-01-01T00:00:02Z"}
+{"itemCode":"p1","time":"2021-01-01T00:02:00Z"}
+`), 0o644)
+	out, err := runWithOutput(t, "simulate", "-max-entries", "0,1", "-max-age", "1m,1h", trace)
+	want := "max entries\tmax age\thit ratio\thits\tmisses\tevictions\n" +
+		"0\t1m0s\t0.250\t1\t3\t0\n" +
+		"0\t1h0m0s\t0.500\t2\t2\t0\n" +
+		"1\t1m0s\t0.000\t0\t4\t2\n" +
+		"1\t1h0m0s\t0.250\t1\t3\t2\n"
+	if err != nil || out != want {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+	if _, err := runWithOutput(t, "simulate", "-max-age", "never", trace); err == nil {
+		t.Errorf("expected an invalid max age to be refused")
+	}
+}
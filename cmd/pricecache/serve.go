@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+	"github.com/MadHive/deviget_challenge/config"
+	"github.com/MadHive/deviget_challenge/grpccache"
+	"github.com/MadHive/deviget_challenge/grpccache/pricepb"
+	"github.com/MadHive/deviget_challenge/httpcache"
+	"github.com/MadHive/deviget_challenge/sizing"
+)
+
+// filePriceService prices the items of a JSON file ({"p1": 5, "p2": 7}), handy to try the cache out
+type filePriceService map[string]float64
+
+func (f filePriceService) GetPriceFor(itemCode string) (float64, error) {
+	price, ok := f[itemCode]
+	if !ok {
+		return 0, fmt.Errorf("item [%v] : %w", itemCode, sample1.ErrNotFound)
+	}
+	return price, nil
+}
+
+func loadPrices(path string) (filePriceService, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var prices filePriceService
+	if err := json.Unmarshal(data, &prices); err != nil {
+		return nil, fmt.Errorf("decoding [%v] : %w", path, err)
+	}
+	return prices, nil
+}
+
+func serve(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", ":8080", "address of the HTTP price API")
+	grpcAddr := flags.String("grpc-addr", "", "address of the gRPC price API, not served if empty")
+	prices := flags.String("prices", "", "JSON file with the prices to serve")
+	upstream := flags.String("upstream", "", "address of the gRPC price API of another cache to serve the prices of")
+	replay := flags.String("replay", "", "file of the calls recorded with -record to serve the prices of")
+	record := flags.String("record", "", "file the calls to the price service are recorded in, for -replay")
+	tracePath := flags.String("trace", "", "file the lookups are recorded in, for simulate")
+	maxAge := flags.Duration("max-age", sample1.DefaultMaxAge, "how long prices are served from the cache")
+	maxEntries := flags.Int("max-entries", 0, "max prices kept in the cache, unbounded if zero")
+	configPath := flags.String("config", "", "configuration file of the cache, see package config, "+
+		"-max-age and -max-entries are ignored with it. It is reloaded on SIGHUP")
+	adminToken := flags.String("admin-token", os.Getenv("PRICECACHE_ADMIN_TOKEN"),
+		"bearer token of the admin routes, not served if empty (default $PRICECACHE_ADMIN_TOKEN)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var service sample1.PriceService
+	switch {
+	case countSet(*prices, *upstream, *replay) > 1:
+		return errors.New("only one of -prices, -upstream and -replay can be given")
+	case *prices != "":
+		fileService, err := loadPrices(*prices)
+		if err != nil {
+			return err
+		}
+		service = fileService
+	case *upstream != "":
+		conn, err := grpc.NewClient(*upstream, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return fmt.Errorf("connecting to [%v] : %w", *upstream, err)
+		}
+		defer conn.Close()
+		service = grpccache.NewClient(conn)
+	case *replay != "":
+		file, err := os.Open(*replay)
+		if err != nil {
+			return err
+		}
+		replayService, err := sample1.NewReplayService(file, true)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("reading [%v] : %w", *replay, err)
+		}
+		service = replayService
+	default:
+		return errors.New("missing -prices, -upstream or -replay")
+	}
+	if *record != "" {
+		file, err := os.OpenFile(*record, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		service = sample1.NewRecordingService(service, file)
+	}
+
+	cfg := config.Config{MaxAge: config.Duration(*maxAge), MaxEntries: *maxEntries}
+	if *configPath != "" {
+		var err error
+		if cfg, err = config.Load(*configPath, envPrefix); err != nil {
+			return err
+		}
+	}
+	var opts []sample1.Option
+	if *tracePath != "" {
+		file, err := os.OpenFile(*tracePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		opts = append(opts, sample1.WithObserver(sizing.NewTraceRecorder(file)))
+	}
+	cache, err := cfg.Build(service, opts...)
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	handlerOpts := httpcache.Options{}
+	if *adminToken != "" {
+		handlerOpts.AdminAuth = bearerAuth(*adminToken)
+	}
+	httpServer := &http.Server{Addr: *addr, Handler: httpcache.NewHandler(cache.TransparentCache, handlerOpts)}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *configPath != "" {
+		go reloadOnHangup(ctx, *configPath, cache, stdout)
+	}
+	errs := make(chan error, 2)
+	if *grpcAddr != "" {
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			return err
+		}
+		grpcServer := grpc.NewServer()
+		pricepb.RegisterPriceServiceServer(grpcServer, grpccache.NewServer(cache.TransparentCache))
+		go func() { errs <- grpcServer.Serve(listener) }()
+		defer grpcServer.GracefulStop()
+		fmt.Fprintf(stdout, "serving gRPC on %v\n", *grpcAddr)
+	}
+	go func() { errs <- httpServer.ListenAndServe() }()
+	fmt.Fprintf(stdout, "serving HTTP on %v\n", *addr)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return errors.Join(httpServer.Shutdown(shutdownCtx), cache.Shutdown(shutdownCtx))
+	}
+}
+
+// countSet returns how many of values aren't empty
+func countSet(values ...string) int {
+	n := 0
+	for _, value := range values {
+		if value != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// envPrefix names the environment variables overriding the configuration file, PRICECACHE_MAX_AGE...
+const envPrefix = "PRICECACHE"
+
+// reloadOnHangup reloads the configuration at path into cache on every SIGHUP until ctx is done, keeping what
+// is cached, and tells how it went on stdout
+func reloadOnHangup(ctx context.Context, path string, cache *config.Cache, stdout io.Writer) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+		}
+		cfg, err := config.Load(path, envPrefix)
+		if err == nil {
+			err = cache.Reload(cfg)
+		}
+		if err != nil {
+			fmt.Fprintf(stdout, "reloading %v : %v\n", path, err)
+			continue
+		}
+		fmt.Fprintf(stdout, "reloaded %v\n", path)
+	}
+}
+
+// bearerAuth authorizes the requests with the bearer token, compared in constant time
+func bearerAuth(token string) httpcache.AuthFunc {
+	want := []byte("Bearer " + token)
+	return func(r *http.Request) error {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			return errors.New("missing or wrong bearer token")
+		}
+		return nil
+	}
+}
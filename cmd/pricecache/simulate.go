@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+	"github.com/MadHive/deviget_challenge/sizing"
+)
+
+// simulate replays a trace recorded with serve -trace against every pair of -max-entries and -max-age, and prints
+// the hit ratio each of them gets
+func simulate(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	maxEntries := flags.String("max-entries", "0", "comma separated max prices kept in the cache, zero for unbounded")
+	maxAges := flags.String("max-age", sample1.DefaultMaxAge.String(), "comma separated max ages of the prices")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("expected the file of the trace")
+	}
+	var entries []int
+	for _, field := range strings.Split(*maxEntries, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid -max-entries [%v]", field)
+		}
+		entries = append(entries, n)
+	}
+	var ages []time.Duration
+	for _, field := range strings.Split(*maxAges, ",") {
+		maxAge, err := time.ParseDuration(strings.TrimSpace(field))
+		if err != nil || maxAge <= 0 {
+			return fmt.Errorf("invalid -max-age [%v]", field)
+		}
+		ages = append(ages, maxAge)
+	}
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	trace, err := sizing.ReadTrace(file)
+	if err != nil {
+		return fmt.Errorf("reading [%v] : %w", flags.Arg(0), err)
+	}
+	fmt.Fprintf(stdout, "max entries\tmax age\thit ratio\thits\tmisses\tevictions\n")
+	for _, result := range sizing.Simulate(trace, sizing.Grid(entries, ages)...) {
+		fmt.Fprintf(stdout, "%v\t%v\t%.3f\t%v\t%v\t%v\n", result.Setting.MaxEntries, result.Setting.MaxAge,
+			result.HitRatio(), result.Hits, result.Misses, result.Evictions)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+	"github.com/MadHive/deviget_challenge/boltstore"
+	"github.com/MadHive/deviget_challenge/expvarcache"
+	"github.com/MadHive/deviget_challenge/memcachestore"
+	"github.com/MadHive/deviget_challenge/promcache"
+	"github.com/MadHive/deviget_challenge/redisstore"
+)
+
+// Cache is a cache built from a Config, with the metrics to register and what to close with it
+type Cache struct {
+	*sample1.TransparentCache
+	// Collectors are the metrics of the cache and of its price service, empty unless Metrics.Prometheus is set
+	// They still have to be registered
+	Collectors []prometheus.Collector
+	closers    []func() error
+	closeOnce  sync.Once
+	mu         sync.Mutex // serializes the reloads
+	cfg        Config     // the configuration the cache was built with, as last reloaded
+}
+
+// Close stops the cache and closes its store, calling it again does nothing
+func (c *Cache) Close() error {
+	return c.closeStores(c.TransparentCache.Close())
+}
+
+// Shutdown closes the cache gracefully (see sample1.Cache.Shutdown), then closes its store
+func (c *Cache) Shutdown(ctx context.Context) error {
+	return c.closeStores(c.TransparentCache.Shutdown(ctx))
+}
+
+// closeStores closes the stores of the cache, once it was closed with err, calling it again only returns err
+func (c *Cache) closeStores(err error) error {
+	errs := []error{err}
+	c.closeOnce.Do(func() {
+		for _, closer := range c.closers {
+			errs = append(errs, closer())
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// Build validates the configuration and builds a cache in front of service, opts are added to the options it
+// sets (WithClock, WithObserver...) and win over them
+func (c Config) Build(service sample1.PriceService, opts ...sample1.Option) (*Cache, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	cache := &Cache{}
+	var cacheOpts []sample1.Option
+	if c.MaxAge > 0 {
+		cacheOpts = append(cacheOpts, sample1.WithMaxAge(time.Duration(c.MaxAge)))
+	}
+	if c.MaxEntries > 0 {
+		cacheOpts = append(cacheOpts, sample1.WithMaxEntries(c.MaxEntries))
+	}
+	if c.MaxConcurrency > 0 {
+		cacheOpts = append(cacheOpts, sample1.WithMaxConcurrency(c.MaxConcurrency))
+	}
+	if c.StaleWhileRevalidate > 0 {
+		cacheOpts = append(cacheOpts, sample1.WithStaleWhileRevalidate(time.Duration(c.StaleWhileRevalidate)))
+	}
+	if c.StaleIfError > 0 {
+		cacheOpts = append(cacheOpts, sample1.WithStaleIfError(time.Duration(c.StaleIfError)))
+	}
+	if c.LoadTimeout > 0 {
+		cacheOpts = append(cacheOpts, sample1.WithLoadTimeout(time.Duration(c.LoadTimeout)))
+	}
+	if c.TTLJitter > 0 {
+		cacheOpts = append(cacheOpts, sample1.WithTTLJitter(c.TTLJitter))
+	}
+	if c.Janitor > 0 {
+		cacheOpts = append(cacheOpts, sample1.WithJanitor(time.Duration(c.Janitor)))
+	}
+	storeOpt, err := c.Store.option(cache)
+	if err != nil {
+		return nil, err
+	}
+	if storeOpt != nil {
+		cacheOpts = append(cacheOpts, storeOpt)
+	}
+	if c.Metrics.Prometheus {
+		instrumented := promcache.InstrumentService(service, c.Metrics.opts())
+		cache.Collectors = append(cache.Collectors, instrumented)
+		service = instrumented
+	}
+	cache.TransparentCache = sample1.New(service, append(cacheOpts, opts...)...)
+	if c.Metrics.Prometheus {
+		cache.Collectors = append(cache.Collectors, promcache.NewCacheCollector(cache, c.Metrics.opts()))
+	}
+	if c.Metrics.Expvar != "" {
+		if err := expvarcache.Publish(c.Metrics.Expvar, cache); err != nil {
+			return nil, errors.Join(fmt.Errorf("publishing the metrics : %w", err), cache.Close())
+		}
+	}
+	for itemCode, item := range c.Items {
+		cache.SetMaxAgeFor(itemCode, time.Duration(item.MaxAge))
+	}
+	cache.cfg = c
+	return cache, nil
+}
+
+// Reload applies cfg to the running cache without dropping what it has cached: the maxAge, maxEntries,
+// maxConcurrency and the rules of the items change right away (see sample1.Cache.UpdateConfig)
+// The other settings can't change without building the cache again, if cfg changes any of them the error
+// names them, after the settings that can change were applied
+func (c *Cache) Reload(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	maxAge := time.Duration(cfg.MaxAge)
+	if maxAge == 0 {
+		maxAge = sample1.DefaultMaxAge
+	}
+	err := c.UpdateConfig(sample1.WithMaxAge(maxAge), sample1.WithMaxEntries(cfg.MaxEntries),
+		sample1.WithMaxConcurrency(cfg.MaxConcurrency))
+	if err != nil {
+		return err
+	}
+	for itemCode := range c.cfg.Items {
+		if _, ok := cfg.Items[itemCode]; !ok {
+			c.ResetMaxAgeFor(itemCode)
+		}
+	}
+	for itemCode, item := range cfg.Items {
+		c.SetMaxAgeFor(itemCode, time.Duration(item.MaxAge))
+	}
+	changed := fixedChanges(c.cfg, cfg)
+	c.cfg.MaxAge, c.cfg.MaxEntries, c.cfg.MaxConcurrency, c.cfg.Items = cfg.MaxAge, cfg.MaxEntries, cfg.MaxConcurrency, cfg.Items
+	if len(changed) > 0 {
+		return fmt.Errorf("%s changed, the cache has to be built again for that", strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+// fixedChanges returns the names of the settings that Reload can't change and that differ between old and new
+func fixedChanges(old, new Config) []string {
+	var changed []string
+	oldValue, newValue := reflect.ValueOf(old), reflect.ValueOf(new)
+	for i := 0; i < oldValue.NumField(); i++ {
+		name, _, _ := strings.Cut(oldValue.Type().Field(i).Tag.Get("json"), ",")
+		switch name {
+		case "maxAge", "maxEntries", "maxConcurrency", "items":
+			continue
+		}
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+func (m Metrics) opts() promcache.Opts {
+	return promcache.Opts{Namespace: m.Namespace, Subsystem: m.Subsystem}
+}
+
+// option returns the option giving the cache its store, nil for the default one, the stores to close are
+// added to cache
+func (s Store) option(cache *Cache) (sample1.Option, error) {
+	switch s.Backend {
+	case Redis:
+		client := redis.NewClient(&redis.Options{Addr: s.Redis.Addr, Password: s.Redis.Password, DB: s.Redis.DB})
+		cache.closers = append(cache.closers, client.Close)
+		return sample1.WithStore[string, float64](redisstore.New[float64](client, redisstore.Options{
+			Prefix: s.Redis.Prefix, Channel: s.Redis.Channel, ExtraTTL: time.Duration(s.ExtraTTL),
+			Timeout: time.Duration(s.Timeout),
+		})), nil
+	case Memcached:
+		store := memcachestore.New[float64](s.Memcached.Servers, memcachestore.Options{
+			Prefix: s.Memcached.Prefix, ExtraTTL: time.Duration(s.ExtraTTL), Timeout: time.Duration(s.Timeout),
+		})
+		cache.closers = append(cache.closers, store.Close)
+		return sample1.WithStore[string, float64](store), nil
+	case Bolt:
+		store, err := boltstore.Open[float64](s.Bolt.Path, boltstore.Options{
+			Bucket: s.Bolt.Bucket, ExtraTTL: time.Duration(s.ExtraTTL),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("opening the bolt store : %w", err)
+		}
+		cache.closers = append(cache.closers, store.Close)
+		return sample1.WithStore[string, float64](store), nil
+	}
+	if s.Shards > 0 {
+		return sample1.WithShards(s.Shards), nil
+	}
+	return nil, nil
+}
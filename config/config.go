@@ -0,0 +1,192 @@
+// Package config builds a TransparentCache from a configuration file (JSON or YAML) and the environment, so that
+// services don't hand-wire its options
+// It lives in its own package so that the cache itself doesn't depend on the clients of every backend
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The store backends
+const (
+	Memory    = "memory"    // a sample1.ShardedStore, the default
+	Redis     = "redis"     // a redisstore.Store
+	Memcached = "memcached" // a memcachestore.Store
+	Bolt      = "bolt"      // a boltstore.Store
+)
+
+// Duration is a time.Duration written as time.ParseDuration reads it ("30s", "5m")
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Config is the configuration of a cache, every field left out keeps the default of the cache
+// The env tags name the environment variables overriding the fields, see LoadEnv
+type Config struct {
+	MaxAge               Duration `json:"maxAge" yaml:"maxAge" env:"MAX_AGE"` // sample1.DefaultMaxAge if zero
+	MaxEntries           int      `json:"maxEntries" yaml:"maxEntries" env:"MAX_ENTRIES"`
+	MaxConcurrency       int      `json:"maxConcurrency" yaml:"maxConcurrency" env:"MAX_CONCURRENCY"`
+	StaleWhileRevalidate Duration `json:"staleWhileRevalidate" yaml:"staleWhileRevalidate" env:"STALE_WHILE_REVALIDATE"`
+	StaleIfError         Duration `json:"staleIfError" yaml:"staleIfError" env:"STALE_IF_ERROR"`
+	LoadTimeout          Duration `json:"loadTimeout" yaml:"loadTimeout" env:"LOAD_TIMEOUT"`
+	TTLJitter            float64  `json:"ttlJitter" yaml:"ttlJitter" env:"TTL_JITTER"`
+	Janitor              Duration `json:"janitor" yaml:"janitor" env:"JANITOR"` // interval of the janitor, none if zero
+	Store                Store    `json:"store" yaml:"store" env:"STORE"`
+	Metrics              Metrics  `json:"metrics" yaml:"metrics" env:"METRICS"`
+	// Items are the rules of single items, by item code, they can't be set from the environment
+	Items map[string]Item `json:"items" yaml:"items"`
+}
+
+// Item are the rules of a single item
+type Item struct {
+	MaxAge Duration `json:"maxAge" yaml:"maxAge"` // overrides the maxAge of the cache, see sample1.Cache.SetMaxAgeFor
+}
+
+// Store selects where the entries are kept, only the section of the selected backend is read
+type Store struct {
+	Backend   string         `json:"backend" yaml:"backend" env:"BACKEND"` // Memory if empty
+	Shards    int            `json:"shards" yaml:"shards" env:"SHARDS"`    // of the memory store, see sample1.WithShards
+	Redis     RedisStore     `json:"redis" yaml:"redis" env:"REDIS"`
+	Memcached MemcachedStore `json:"memcached" yaml:"memcached" env:"MEMCACHED"`
+	Bolt      BoltStore      `json:"bolt" yaml:"bolt" env:"BOLT"`
+	ExtraTTL  Duration       `json:"extraTTL" yaml:"extraTTL" env:"EXTRA_TTL"` // of the remote stores, see redisstore.Options
+	Timeout   Duration       `json:"timeout" yaml:"timeout" env:"TIMEOUT"`     // of the calls to the remote stores
+}
+
+// RedisStore configures the Redis backend
+type RedisStore struct {
+	Addr     string `json:"addr" yaml:"addr" env:"ADDR"`
+	Password string `json:"password" yaml:"password" env:"PASSWORD"`
+	DB       int    `json:"db" yaml:"db" env:"DB"`
+	Prefix   string `json:"prefix" yaml:"prefix" env:"PREFIX"`
+	Channel  string `json:"channel" yaml:"channel" env:"CHANNEL"` // where changes are published, see redisstore.Options
+}
+
+// MemcachedStore configures the memcached backend
+type MemcachedStore struct {
+	Servers []string `json:"servers" yaml:"servers" env:"SERVERS"` // comma separated in the environment
+	Prefix  string   `json:"prefix" yaml:"prefix" env:"PREFIX"`
+}
+
+// BoltStore configures the bbolt backend
+type BoltStore struct {
+	Path   string `json:"path" yaml:"path" env:"PATH"`
+	Bucket string `json:"bucket" yaml:"bucket" env:"BUCKET"`
+}
+
+// Metrics configures the Prometheus metrics of the cache and of its price service (see promcache), and its expvar
+type Metrics struct {
+	Prometheus bool   `json:"prometheus" yaml:"prometheus" env:"PROMETHEUS"`
+	Namespace  string `json:"namespace" yaml:"namespace" env:"NAMESPACE"`
+	Subsystem  string `json:"subsystem" yaml:"subsystem" env:"SUBSYSTEM"`
+	// Expvar is the name the counters of the cache are published as with expvar, see expvarcache, none if empty
+	Expvar string `json:"expvar" yaml:"expvar" env:"EXPVAR"`
+}
+
+// LoadFile reads the configuration at path, as YAML if it ends with .yaml or .yml and as JSON otherwise
+// Unknown fields are errors, so that typos don't go unnoticed. The configuration isn't validated yet
+func LoadFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading the configuration : %w", err)
+	}
+	var cfg Config
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&cfg)
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&cfg)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("decoding %s : %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Load reads the configuration at path (if not empty), overrides it with the environment variables named after
+// prefix (see LoadEnv) and validates it
+func Load(path, prefix string) (Config, error) {
+	var cfg Config
+	if path != "" {
+		var err error
+		if cfg, err = LoadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := LoadEnv(&cfg, prefix); err != nil {
+		return Config{}, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// Validate tells what is wrong with the configuration, every problem naming the field it is about
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, field string, value any, problem string) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s [%v] : %s", field, value, problem))
+		}
+	}
+	for _, d := range []struct {
+		field string
+		value Duration
+	}{
+		{"maxAge", c.MaxAge}, {"staleWhileRevalidate", c.StaleWhileRevalidate}, {"staleIfError", c.StaleIfError},
+		{"loadTimeout", c.LoadTimeout}, {"janitor", c.Janitor}, {"store.extraTTL", c.Store.ExtraTTL},
+		{"store.timeout", c.Store.Timeout},
+	} {
+		check(d.value >= 0, d.field, time.Duration(d.value), "must not be negative")
+	}
+	check(c.MaxEntries >= 0, "maxEntries", c.MaxEntries, "must not be negative")
+	check(c.MaxConcurrency >= 0, "maxConcurrency", c.MaxConcurrency, "must not be negative")
+	check(c.TTLJitter >= 0 && c.TTLJitter < 1, "ttlJitter", c.TTLJitter, "must be between 0 and 1")
+	check(c.Store.Shards >= 0, "store.shards", c.Store.Shards, "must not be negative")
+	itemCodes := make([]string, 0, len(c.Items))
+	for itemCode := range c.Items {
+		itemCodes = append(itemCodes, itemCode)
+	}
+	slices.Sort(itemCodes)
+	for _, itemCode := range itemCodes {
+		item := c.Items[itemCode]
+		check(item.MaxAge > 0, "items."+itemCode+".maxAge", time.Duration(item.MaxAge), "must be positive")
+	}
+	switch c.Store.Backend {
+	case "", Memory:
+	case Redis:
+		check(c.Store.Redis.Addr != "", "store.redis.addr", c.Store.Redis.Addr, "is required by the redis backend")
+	case Memcached:
+		check(len(c.Store.Memcached.Servers) > 0, "store.memcached.servers", c.Store.Memcached.Servers,
+			"is required by the memcached backend")
+		check(c.MaxEntries == 0, "maxEntries", c.MaxEntries, "can't be used with the memcached backend, which can't list its keys")
+	case Bolt:
+		check(c.Store.Bolt.Path != "", "store.bolt.path", c.Store.Bolt.Path, "is required by the bolt backend")
+	default:
+		check(false, "store.backend", c.Store.Backend, "must be one of memory, redis, memcached and bolt")
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"context"
+	"expvar"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+type fakePriceService map[string]float64
+
+func (s fakePriceService) GetPriceFor(itemCode string) (float64, error) {
+	price, ok := s[itemCode]
+	if !ok {
+		return 0, sample1.ErrNotFound
+	}
+	return price, nil
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Check that YAML and JSON files are read the same way
+func TestLoadFile(t *testing.T) {
+	yamlPath := writeFile(t, "cache.yaml", `
+maxAge: 30s
+maxEntries: 1000
+ttlJitter: 0.1
+store:
+  backend: redis
+  redis:
+    addr: localhost:6379
+    prefix: "prices:"
+metrics:
+  prometheus: true
+`)
+	jsonPath := writeFile(t, "cache.json", `{
+	"maxAge": "30s", "maxEntries": 1000, "ttlJitter": 0.1,
+	"store": {"backend": "redis", "redis": {"addr": "localhost:6379", "prefix": "prices:"}},
+	"metrics": {"prometheus": true}
+}`)
+	for _, path := range []string{yamlPath, jsonPath} {
+		cfg, err := LoadFile(path)
+		if err != nil {
+			t.Fatalf("unexpected error for %s : %v", path, err)
+		}
+		if time.Duration(cfg.MaxAge) != 30*time.Second || cfg.MaxEntries != 1000 || cfg.TTLJitter != 0.1 ||
+			cfg.Store.Backend != Redis || cfg.Store.Redis.Addr != "localhost:6379" || cfg.Store.Redis.Prefix != "prices:" ||
+			!cfg.Metrics.Prometheus {
+			t.Errorf("unexpected configuration from %s : %+v", path, cfg)
+		}
+	}
+}
+
+// Check that unknown fields and bad values are reported with what is wrong
+func TestLoadFile_Errors(t *testing.T) {
+	for name, content := range map[string]string{
+		"typo.yaml":     "maxAgee: 30s\n",
+		"typo.json":     `{"maxAgee": "30s"}`,
+		"duration.yaml": "maxAge: soon\n",
+	} {
+		if _, err := LoadFile(writeFile(t, name, content)); err == nil {
+			t.Errorf("expected an error for %s", name)
+		}
+	}
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+// Check that the environment overrides the file, and that a bad variable is named in the error
+func TestLoad_Env(t *testing.T) {
+	path := writeFile(t, "cache.yaml", "maxAge: 30s\nmaxEntries: 10\n")
+	t.Setenv("PRICECACHE_MAX_AGE", "2m")
+	t.Setenv("PRICECACHE_STORE_BACKEND", "memcached")
+	t.Setenv("PRICECACHE_STORE_MEMCACHED_SERVERS", "a:11211, b:11211")
+	t.Setenv("PRICECACHE_MAX_ENTRIES", "0")
+	cfg, err := Load(path, "PRICECACHE")
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if time.Duration(cfg.MaxAge) != 2*time.Minute || cfg.MaxEntries != 0 || cfg.Store.Backend != Memcached ||
+		strings.Join(cfg.Store.Memcached.Servers, " ") != "a:11211 b:11211" {
+		t.Errorf("unexpected configuration %+v", cfg)
+	}
+
+	t.Setenv("PRICECACHE_MAX_CONCURRENCY", "many")
+	if _, err := Load(path, "PRICECACHE"); err == nil || !strings.Contains(err.Error(), "PRICECACHE_MAX_CONCURRENCY") {
+		t.Errorf("expected an error naming the variable, got %v", err)
+	}
+}
+
+// Check that every problem of a configuration is reported with the field it is about
+func TestValidate(t *testing.T) {
+	cfg := Config{MaxAge: Duration(-time.Second), TTLJitter: 2, Store: Store{Backend: Bolt}}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, field := range []string{"maxAge", "ttlJitter", "store.bolt.path"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected the error to name %s, got %v", field, err)
+		}
+	}
+	if err := (Config{Store: Store{Backend: "cassandra"}}).Validate(); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+	if err := (Config{}).Validate(); err != nil {
+		t.Errorf("the empty configuration should be valid, got %v", err)
+	}
+}
+
+// Check that the built cache uses the configured settings and metrics
+func TestBuild(t *testing.T) {
+	cache, err := Config{MaxAge: Duration(time.Minute), MaxEntries: 1, Metrics: Metrics{Prometheus: true}}.
+		Build(fakePriceService{"p1": 5, "p2": 7})
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer cache.Close()
+	if cache.MaxAge() != time.Minute {
+		t.Errorf("expected a maxAge of 1m but got %v", cache.MaxAge())
+	}
+	cache.GetPricesFor("p1", "p2")
+	if cache.Len() != 1 {
+		t.Errorf("expected the cache to be bounded to 1 entry, got %v", cache.Len())
+	}
+	if len(cache.Collectors) != 2 {
+		t.Errorf("expected the metrics of the cache and of the service, got %v", cache.Collectors)
+	}
+	if _, err := (Config{MaxEntries: -1}).Build(fakePriceService{}); err == nil {
+		t.Error("expected an error for an invalid configuration")
+	}
+}
+
+// Check that the counters are published with expvar, under a name that can't be taken twice
+func TestBuild_Expvar(t *testing.T) {
+	cfg := Config{Metrics: Metrics{Expvar: "config_test_cache"}}
+	cache, err := cfg.Build(fakePriceService{"p1": 5})
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer cache.Close()
+	cache.GetPriceFor("p1")
+	if vars := expvar.Get("config_test_cache"); vars == nil || !strings.Contains(vars.String(), `"misses":1`) {
+		t.Errorf("expected the counters of the cache to be published, got %v", vars)
+	}
+	if _, err := cfg.Build(fakePriceService{}); err == nil {
+		t.Error("expected an error for a name already published")
+	}
+}
+
+// Check that the remote backends are built and closed with the cache
+func TestBuild_Backends(t *testing.T) {
+	server := miniredis.RunT(t)
+	for _, store := range []Store{
+		{Backend: Redis, Redis: RedisStore{Addr: server.Addr(), Prefix: "prices:"}},
+		{Backend: Bolt, Bolt: BoltStore{Path: filepath.Join(t.TempDir(), "cache.db")}},
+	} {
+		cache, err := Config{Store: store}.Build(fakePriceService{"p1": 5})
+		if err != nil {
+			t.Fatalf("unexpected error for %s : %v", store.Backend, err)
+		}
+		if price, err := cache.GetPriceFor("p1"); err != nil || price != 5 {
+			t.Errorf("%s: expected 5 but got %v, %v", store.Backend, price, err)
+		}
+		if err := cache.Close(); err != nil {
+			t.Errorf("%s: unexpected error closing : %v", store.Backend, err)
+		}
+	}
+	if !server.Exists("prices:p1") {
+		t.Error("the price should have been stored in Redis")
+	}
+}
+
+// Check that Reload applies the new limits and item rules without dropping the cached prices, and names the
+// settings it can't change
+func TestReload(t *testing.T) {
+	cfg := Config{MaxAge: Duration(time.Minute), Items: map[string]Item{"p1": {MaxAge: Duration(time.Hour)}}}
+	cache, err := cfg.Build(fakePriceService{"p1": 5, "p2": 7, "p3": 9})
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	defer cache.Close()
+	cache.GetPricesFor("p1", "p2", "p3")
+	if ttl, _ := cache.TTL("p1"); ttl <= time.Minute {
+		t.Errorf("expected the maxAge of p1 to be overridden, got a ttl of %v", ttl)
+	}
+
+	reloaded := Config{MaxAge: Duration(2 * time.Minute), MaxEntries: 2, Items: map[string]Item{"p2": {MaxAge: Duration(time.Hour)}}}
+	if err := cache.Reload(reloaded); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if cache.MaxAge() != 2*time.Minute || cache.Len() != 2 {
+		t.Errorf("expected a maxAge of 2m and 2 entries, got %v and %v", cache.MaxAge(), cache.Len())
+	}
+	if ttl, ok := cache.TTL("p1"); ok && ttl > 2*time.Minute {
+		t.Errorf("the rule of p1 should be gone, got a ttl of %v", ttl)
+	}
+
+	reloaded.Store = Store{Backend: Bolt, Bolt: BoltStore{Path: "cache.db"}}
+	reloaded.MaxEntries = 3
+	err = cache.Reload(reloaded)
+	if err == nil || !strings.Contains(err.Error(), "store") {
+		t.Errorf("expected an error naming the store, got %v", err)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("the cached prices should have been kept, got %v entries", cache.Len())
+	}
+	if err := cache.Reload(Config{TTLJitter: 5}); err == nil {
+		t.Error("expected an error for an invalid configuration")
+	}
+}
+
+// Check that Shutdown closes the store once, and that a later Close doesn't fail
+func TestShutdown(t *testing.T) {
+	cache, err := Config{Store: Store{Backend: Bolt, Bolt: BoltStore{Path: filepath.Join(t.TempDir(), "cache.db")}}}.
+		Build(fakePriceService{"p1": 5})
+	if err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	cache.GetPriceFor("p1")
+	if err := cache.Shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected error : %v", err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Errorf("unexpected error closing again : %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// LoadEnv overrides the fields of cfg with the environment variables that are set, named after prefix and the
+// env tags of the fields: PRICECACHE_MAX_AGE or PRICECACHE_STORE_REDIS_ADDR for the prefix PRICECACHE
+// Lists are comma separated. An unparsable variable is an error naming it
+func LoadEnv(cfg *Config, prefix string) error {
+	return loadEnv(reflect.ValueOf(cfg).Elem(), prefix)
+}
+
+// loadEnv sets the fields of the struct v from the variables named after prefix
+func loadEnv(v reflect.Value, prefix string) error {
+	for i := 0; i < v.NumField(); i++ {
+		tag := v.Type().Field(i).Tag.Get("env")
+		if tag == "" {
+			continue
+		}
+		name := tag
+		if prefix != "" {
+			name = prefix + "_" + tag
+		}
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := loadEnv(field, name); err != nil {
+				return err
+			}
+			continue
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("reading %s [%s] : %w", name, value, err)
+		}
+	}
+	return nil
+}
+
+// setField sets field from its text in the environment
+func setField(field reflect.Value, value string) error {
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %v", field.Type())
+	}
+	return nil
+}
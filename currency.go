@@ -0,0 +1,147 @@
+package sample1
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CurrencyPriceService is a service pricing items in the currency it is asked for
+type CurrencyPriceService interface {
+	GetPriceIn(ctx context.Context, itemCode, currency string) (float64, error)
+}
+
+// CurrencyCache caches the prices of items in several currencies, every (item, currency) pair being an entry
+// of its own, so that the price of an item in one currency is never served for another one
+// It is safe for concurrent use by multiple goroutines
+type CurrencyCache struct {
+	cache     *TransparentCache
+	keys      KeyBuilder[ItemCurrency]
+	converter *Converter // converts the prices from base, nil if the service prices every currency
+	base      string
+}
+
+// NewCurrencyCache creates a cache in front of service, configured with opts, whose keys are built by keys
+// (CurrencyKeys if nil). The options that see the keys (WithInvalidator, WithTagger, WithStore...) get the built ones
+// With WithConverter, the service is only asked for the prices in the base currency
+func NewCurrencyCache(service CurrencyPriceService, keys KeyBuilder[ItemCurrency], opts ...Option) *CurrencyCache {
+	if keys == nil {
+		keys = CurrencyKeys{}
+	}
+	cfg := newConfig(opts)
+	return &CurrencyCache{
+		cache:     New(currencyAdapter{service: service, keys: keys}, opts...),
+		keys:      keys,
+		converter: cfg.converter,
+		base:      cfg.baseCurrency,
+	}
+}
+
+// cached returns the currency the prices asked in currency are cached in
+func (c *CurrencyCache) cached(currency string) string {
+	if c.converter != nil {
+		return c.base
+	}
+	return currency
+}
+
+// key returns the key of the cache for the price of the item in currency
+func (c *CurrencyCache) key(itemCode, currency string) string {
+	return c.keys.Key(ItemCurrency{ItemCode: itemCode, Currency: currency})
+}
+
+// currencyAdapter is the price service of the cache behind a CurrencyCache, pricing its built keys
+type currencyAdapter struct {
+	service CurrencyPriceService
+	keys    KeyBuilder[ItemCurrency]
+}
+
+func (a currencyAdapter) GetPriceFor(key string) (float64, error) {
+	return a.GetPriceForCtx(context.Background(), key)
+}
+
+func (a currencyAdapter) GetPriceForCtx(ctx context.Context, key string) (float64, error) {
+	d, err := a.keys.Parse(key)
+	if err != nil {
+		return 0, fmt.Errorf("parsing the key : %w", err)
+	}
+	return a.service.GetPriceIn(ctx, d.ItemCode, d.Currency)
+}
+
+// Cache returns the cache keeping the prices, keyed by the built keys, for its stats, snapshots, admin...
+func (c *CurrencyCache) Cache() *TransparentCache {
+	return c.cache
+}
+
+// GetPriceFor gets the price of the item in currency, either from the cache or the service if it was not cached
+// or too old
+func (c *CurrencyCache) GetPriceFor(itemCode, currency string) (float64, error) {
+	return c.GetPriceForCtx(context.Background(), itemCode, currency)
+}
+
+// GetPriceForCtx is like GetPriceFor, but gives up waiting for the service when ctx is done
+func (c *CurrencyCache) GetPriceForCtx(ctx context.Context, itemCode, currency string) (float64, error) {
+	price, err := c.cache.GetPriceForCtx(ctx, c.key(itemCode, c.cached(currency)))
+	if err != nil || c.cached(currency) == currency {
+		return price, err
+	}
+	return c.converter.Convert(ctx, price, c.base, currency)
+}
+
+// GetPricesFor gets the prices of several items in currency at once
+// The keys of the returned *BatchError are the item codes
+func (c *CurrencyCache) GetPricesFor(currency string, itemCodes ...string) ([]float64, error) {
+	return c.GetPricesForCtx(context.Background(), currency, itemCodes...)
+}
+
+// GetPricesForCtx is like GetPricesFor, but the outstanding fetches are cancelled when ctx is done
+// With WithConverter the prices are converted with a single rate, the whole batch fails if it can't be had
+func (c *CurrencyCache) GetPricesForCtx(ctx context.Context, currency string, itemCodes ...string) ([]float64, error) {
+	keys := make([]string, len(itemCodes))
+	for i, itemCode := range itemCodes {
+		keys[i] = c.key(itemCode, c.cached(currency))
+	}
+	results := c.cache.GetEach(ctx, keys...)
+	rate := 1.0
+	if c.cached(currency) != currency {
+		var err error
+		if rate, err = c.converter.Rate(ctx, c.base, currency); err != nil {
+			return nil, fmt.Errorf("converting from %s to %s : %w", c.base, currency, err)
+		}
+	}
+	prices := make([]float64, len(results))
+	errs := make([]error, len(results))
+	for i, result := range results {
+		prices[i], errs[i] = result.Value*rate, result.Err
+	}
+	if batchErr := newBatchError(itemCodes, errs); batchErr != nil {
+		return nil, batchErr
+	}
+	return prices, nil
+}
+
+// Set stores the price of the item in currency as if it was just fetched
+// With WithConverter, only the prices in the base currency are ever read
+func (c *CurrencyCache) Set(itemCode, currency string, price float64) {
+	c.cache.Set(c.key(itemCode, currency), price)
+}
+
+// Peek is like Cache.Peek, for the price of the item in currency as it is cached: nothing is converted, and with
+// WithConverter only the prices in the base currency are cached
+func (c *CurrencyCache) Peek(itemCode, currency string) (price float64, age time.Duration, ok bool) {
+	return c.cache.Peek(c.key(itemCode, currency))
+}
+
+// Invalidate drops the cached price of the item in currency, with WithConverter it drops the price in the base
+// currency that the prices in currency are converted from
+func (c *CurrencyCache) Invalidate(itemCode, currency string) {
+	c.cache.Invalidate(c.key(itemCode, c.cached(currency)))
+}
+
+// InvalidateItem drops the cached prices of the item in every currency
+func (c *CurrencyCache) InvalidateItem(itemCode string) {
+	c.cache.InvalidateMatching(func(key string) bool {
+		d, err := c.keys.Parse(key)
+		return err == nil && d.ItemCode == itemCode
+	})
+}
@@ -0,0 +1,144 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockCurrencyService prices the items in the currencies it was given, counting the calls it gets
+type mockCurrencyService struct {
+	mu       sync.Mutex
+	numCalls int
+	prices   map[ItemCurrency]float64
+}
+
+func (m *mockCurrencyService) GetPriceIn(_ context.Context, itemCode, currency string) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.numCalls++
+	price, ok := m.prices[ItemCurrency{ItemCode: itemCode, Currency: currency}]
+	if !ok {
+		return 0, fmt.Errorf("no price for [%v] in %v : %w", itemCode, currency, ErrNotFound)
+	}
+	return price, nil
+}
+
+func (m *mockCurrencyService) getNumCalls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.numCalls
+}
+
+// Check that the prices of an item in different currencies are cached apart
+func TestCurrencyCache_KeepsCurrenciesApart(t *testing.T) {
+	service := &mockCurrencyService{prices: map[ItemCurrency]float64{
+		{"p1", "USD"}: 5,
+		{"p1", "EUR"}: 4,
+	}}
+	cache := NewCurrencyCache(service, nil, WithMaxAge(time.Minute))
+	for range 2 {
+		for currency, want := range map[string]float64{"USD": 5, "EUR": 4} {
+			price, err := cache.GetPriceFor("p1", currency)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertFloat(t, want, price, "wrong price for "+currency)
+		}
+	}
+	assertInt(t, 2, service.getNumCalls(), "every currency should have been fetched once")
+	if _, _, ok := cache.Cache().Peek("p1@EUR"); !ok {
+		t.Error("the price should have been cached under the built key")
+	}
+
+	cache.Invalidate("p1", "USD")
+	if _, _, ok := cache.Peek("p1", "USD"); ok {
+		t.Error("the price in USD should have been dropped")
+	}
+	if _, _, ok := cache.Peek("p1", "EUR"); !ok {
+		t.Error("the price in EUR should have been kept")
+	}
+}
+
+// Check that the keys of the errors of a batch are the item codes
+func TestCurrencyCache_GetPricesFor(t *testing.T) {
+	service := &mockCurrencyService{prices: map[ItemCurrency]float64{
+		{"p1", "EUR"}: 4,
+		{"p2", "EUR"}: 6,
+		{"p3", "USD"}: 7,
+	}}
+	cache := NewCurrencyCache(service, nil)
+	prices, err := cache.GetPricesFor("EUR", "p1", "p2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertFloats(t, []float64{4, 6}, prices, "wrong prices returned")
+
+	_, err = cache.GetPricesFor("EUR", "p1", "p3")
+	var batchErr *BatchError[string]
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[0].Key != "p3" {
+		t.Fatalf("expected a *BatchError for p3 but got %v", err)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got %v", err)
+	}
+}
+
+// Check that InvalidateItem drops the prices of the item in every currency, and only them
+func TestCurrencyCache_InvalidateItem(t *testing.T) {
+	cache := NewCurrencyCache(&mockCurrencyService{}, nil)
+	cache.Set("p1", "USD", 5)
+	cache.Set("p1", "EUR", 4)
+	cache.Set("p1@x", "USD", 3)
+	cache.Set("p2", "USD", 6)
+	cache.InvalidateItem("p1")
+	assertInt(t, 2, cache.Cache().Len(), "only the prices of p1 should have been dropped")
+	if _, _, ok := cache.Peek("p1@x", "USD"); !ok {
+		t.Error("an item whose code starts like p1 should have been kept")
+	}
+}
+
+// Check that the keys of CurrencyKeys parse back to what they were built from
+func TestCurrencyKeys(t *testing.T) {
+	for _, d := range []ItemCurrency{{"p1", "USD"}, {"a@b", "EUR"}, {"", "JPY"}} {
+		parsed, err := CurrencyKeys{}.Parse(CurrencyKeys{}.Key(d))
+		if err != nil || parsed != d {
+			t.Errorf("%+v parsed back as %+v, %v", d, parsed, err)
+		}
+	}
+	if _, err := (CurrencyKeys{}).Parse("p1"); err == nil {
+		t.Error("expected an error for a key without currency")
+	}
+}
+
+// currencyFirstKeys is a KeyBuilder putting the currency first
+type currencyFirstKeys struct{}
+
+func (currencyFirstKeys) Key(d ItemCurrency) string {
+	return d.Currency + "/" + d.ItemCode
+}
+
+func (currencyFirstKeys) Parse(key string) (ItemCurrency, error) {
+	currency, itemCode, ok := strings.Cut(key, "/")
+	if !ok {
+		return ItemCurrency{}, fmt.Errorf("key [%s] has no currency", key)
+	}
+	return ItemCurrency{ItemCode: itemCode, Currency: currency}, nil
+}
+
+// Check that a custom KeyBuilder decides the keys of the cache
+func TestCurrencyCache_CustomKeys(t *testing.T) {
+	service := &mockCurrencyService{prices: map[ItemCurrency]float64{{"p1", "USD"}: 5}}
+	cache := NewCurrencyCache(service, currencyFirstKeys{})
+	price, err := cache.GetPriceFor("p1", "USD")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertFloat(t, 5, price, "wrong price returned")
+	cache.Cache().InvalidatePrefix("USD/")
+	assertInt(t, 0, cache.Cache().Len(), "the prices in USD should have been dropped")
+}
@@ -0,0 +1,44 @@
+package sample1
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Check the XFetch decision: never without load time, more likely close to expiring
+func TestExpiresEarly_DependsOnRemainingTimeAndLoadTime(t *testing.T) {
+	cache := NewCache(func(ctx context.Context, key string) (float64, error) { return 0, nil },
+		WithMaxAge(time.Minute), WithEarlyExpiration(1))
+	cache.random = func() float64 { return 0.5 } // -ln(0.5) is about 0.69
+	now := time.Now()
+
+	seeded := Entry[float64]{FetchedAt: now.Add(-59 * time.Second), MaxAge: time.Minute}
+	if cache.expiresEarly(seeded, now) {
+		t.Error("entries without load time should never expire early")
+	}
+	young := Entry[float64]{FetchedAt: now, LoadTime: time.Second, MaxAge: time.Minute}
+	if cache.expiresEarly(young, now) {
+		t.Error("expected a just loaded entry not to expire early")
+	}
+	old := Entry[float64]{FetchedAt: now.Add(-59*time.Second - 500*time.Millisecond), LoadTime: time.Second, MaxAge: time.Minute}
+	if !cache.expiresEarly(old, now) {
+		t.Error("expected an entry half a second from expiring, that takes a second to load, to expire early")
+	}
+}
+
+// Check that a hit that wins the draw refreshes the price in the background
+func TestGetPriceFor_EarlyExpirationRefreshes(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay: 10 * time.Millisecond,
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute, WithEarlyExpiration(1))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	cache.random = func() float64 { return 1 } // makes the gap infinite, always refresh
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	time.Sleep(50 * time.Millisecond)
+	assertInt(t, 2, mockService.getNumCalls(), "expected an early refresh")
+}
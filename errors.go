@@ -0,0 +1,133 @@
+package sample1
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotFound is the error a loader (or a PriceService) should return, or wrap, when the key doesn't exist
+// It is Permanent, those are the errors WithNegativeCaching caches by default
+var ErrNotFound = errors.New("not found")
+
+// isNotFound tells if err is, or wraps, ErrNotFound
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// ErrUnavailable is the error a loader (or a PriceService) should return, or wrap, when the service can't answer
+// for now (it is down, overloaded...), it is Transient
+var ErrUnavailable = errors.New("service unavailable")
+
+// ErrorClass tells what a load error says: something about the key, or about the service
+type ErrorClass int
+
+const (
+	// Transient errors are failures of the service: they are worth a retry, and stale values are served instead
+	// of them with WithStaleIfError
+	Transient ErrorClass = iota
+	// Permanent errors are about the key, which doesn't exist or can't be priced: a retry would fail the same way,
+	// they are cached with WithNegativeCaching and no stale value is served instead of them
+	Permanent
+)
+
+func (c ErrorClass) String() string {
+	if c == Permanent {
+		return "permanent"
+	}
+	return "transient"
+}
+
+// ErrorClassifier tells the class of a load error, see WithErrorClassifier
+type ErrorClassifier func(err error) ErrorClass
+
+// DefaultErrorClassifier is the ErrorClassifier of the cache and of the services of the package unless told
+// otherwise: the errors matching ErrNotFound, or marked with MarkPermanent, are Permanent, the others (the
+// cancellations and timeouts included) are Transient
+func DefaultErrorClassifier(err error) ErrorClass {
+	var permanent *permanentError
+	if isNotFound(err) || errors.As(err, &permanent) {
+		return Permanent
+	}
+	return Transient
+}
+
+// isTransient tells if DefaultErrorClassifier classifies err as Transient
+func isTransient(err error) bool {
+	return DefaultErrorClassifier(err) == Transient
+}
+
+// MarkPermanent returns err marked as Permanent for DefaultErrorClassifier (an invalid item code, for instance),
+// errors.Is and errors.As still see err. It returns nil if err is nil
+func MarkPermanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// permanentError is an error marked with MarkPermanent
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// KeyError is the error we got while loading the value for a single key (the price for a single item)
+type KeyError[K comparable] struct {
+	Key K
+	Err error
+}
+
+func (e *KeyError[K]) Error() string {
+	return fmt.Sprintf("key [%v] : %v", e.Key, e.Err.Error())
+}
+
+// Unwrap returns the underlying error, so that errors.Is and errors.As can look into it
+func (e *KeyError[K]) Unwrap() error {
+	return e.Err
+}
+
+// BatchError aggregates all the per key failures of a GetMany (or GetPricesFor) call
+// Errors are kept in the same order the keys were requested
+type BatchError[K comparable] struct {
+	Errors []*KeyError[K]
+}
+
+func (e *BatchError[K]) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, keyErr := range e.Errors {
+		msgs[i] = keyErr.Error()
+	}
+	return fmt.Sprintf("loading %v keys : %v", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the key errors, so that errors.Is and errors.As look into every one of them
+func (e *BatchError[K]) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, keyErr := range e.Errors {
+		errs[i] = keyErr
+	}
+	return errs
+}
+
+// newBatchError returns a *BatchError for the keys whose errs aren't nil, nil if all of them are
+func newBatchError[K comparable](keys []K, errs []error) *BatchError[K] {
+	var batchErr *BatchError[K]
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if batchErr == nil {
+			batchErr = &BatchError[K]{}
+		}
+		batchErr.Errors = append(batchErr.Errors, &KeyError[K]{Key: keys[i], Err: err})
+	}
+	return batchErr
+}
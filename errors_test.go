@@ -0,0 +1,148 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Check that GetPricesFor returns an error if any of the items fails
+func TestGetPricesFor_ReturnsErrorOnServiceError(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 0, err: fmt.Errorf("some error")},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	prices, err := cache.GetPricesFor("p1", "p2")
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if prices != nil {
+		t.Errorf("expected no prices, got %v", prices)
+	}
+}
+
+// Check that every failed item is reported, in the order they were requested
+func TestGetPricesFor_AggregatesAllItemErrors(t *testing.T) {
+	errP1 := fmt.Errorf("p1 error")
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 0, err: errP1},
+			"p2": {price: 7, err: nil},
+			"p3": {price: 0, err: fmt.Errorf("p3 error")},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	_, err := cache.GetPricesFor("p3", "p2", "p1")
+	var batchErr *BatchError[string]
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected a *BatchError, got %v", err)
+	}
+	assertInt(t, 2, len(batchErr.Errors), "wrong number of item errors")
+	if batchErr.Errors[0].Key != "p3" || batchErr.Errors[1].Key != "p1" {
+		t.Errorf("wrong item codes in errors : %v", err)
+	}
+	if !errors.Is(err, errP1) {
+		t.Errorf("expected the service error to be wrapped, got %v", err)
+	}
+}
+
+// Check that the errors matching ErrNotFound or marked permanent are Permanent, and the others Transient
+func TestDefaultErrorClassifier(t *testing.T) {
+	errBadCode := errors.New("invalid item code")
+	for err, want := range map[error]ErrorClass{
+		ErrNotFound:                             Permanent,
+		fmt.Errorf("p1 : %w", ErrNotFound):      Permanent,
+		MarkPermanent(errBadCode):               Permanent,
+		fmt.Errorf("p1 : %w", ErrUnavailable):   Transient,
+		errors.New("503 service unavailable"):   Transient,
+		fmt.Errorf("p1 : %w", ErrCircuitOpen):   Transient,
+		fmt.Errorf("p1 : %w", context.Canceled): Transient,
+	} {
+		if got := DefaultErrorClassifier(err); got != want {
+			t.Errorf("[%v] : expected %v but got %v", err, want, got)
+		}
+	}
+	if err := MarkPermanent(errBadCode); !errors.Is(err, errBadCode) || err.Error() != errBadCode.Error() {
+		t.Errorf("expected the marked error to wrap the error but got %v", err)
+	}
+	if MarkPermanent(nil) != nil {
+		t.Error("expected no error marked for nil")
+	}
+}
+
+// Check that stale prices are served instead of transient errors only, and permanent errors are negative cached
+func TestWithErrorClassifier_StaleAndNegativeCaching(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}, "p2": {price: 7}, "p3": {price: 3}}}
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock), WithStaleIfError(time.Hour),
+		WithNegativeCaching(time.Hour, nil))
+	cache.GetPricesFor("p1", "p2", "p3")
+	mockService.mu.Lock()
+	mockService.mockResults = map[string]mockResult{
+		"p1": {err: fmt.Errorf("p1 : %w", ErrUnavailable)},
+		"p2": {err: ErrNotFound},
+		"p3": {err: MarkPermanent(errors.New("delisted"))},
+	}
+	mockService.mu.Unlock()
+	clock.Advance(2 * time.Minute)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "expected the stale price instead of the transient error")
+	for _, itemCode := range []string{"p2", "p3"} {
+		if _, err := cache.GetPriceFor(itemCode); err == nil {
+			t.Errorf("%v : expected the permanent error instead of the stale price", itemCode)
+		}
+		cache.GetPriceFor(itemCode)
+	}
+	assertInt(t, 6, mockService.getNumCalls(), "the permanent errors should have been negative cached")
+}
+
+// Check that a custom classifier decides which errors are negative cached and which are served stale
+func TestWithErrorClassifier_Custom(t *testing.T) {
+	clock := newFakeClock()
+	errDelisted := errors.New("delisted")
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}}}
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock), WithStaleIfError(time.Hour),
+		WithNegativeCaching(time.Hour, nil), WithErrorClassifier(func(err error) ErrorClass {
+			if errors.Is(err, errDelisted) {
+				return Permanent
+			}
+			return Transient // even the not found errors
+		}))
+	getPriceWithNoErr(t, cache, "p1")
+	mockService.mu.Lock()
+	mockService.mockResults = map[string]mockResult{"p1": {err: ErrNotFound}, "p2": {err: errDelisted}}
+	mockService.mu.Unlock()
+	clock.Advance(2 * time.Minute)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "expected the stale price instead of the not found error")
+	cache.GetPriceFor("p2")
+	cache.GetPriceFor("p2")
+	assertInt(t, 3, mockService.getNumCalls(), "the classified error should have been negative cached")
+}
+
+// Check that the errors marked permanent are not retried
+func TestRetryingService_DoesNotRetryMarkedErrors(t *testing.T) {
+	service := &mockPriceService{mockResults: map[string]mockResult{"p1": {err: MarkPermanent(errors.New("400 bad request"))}}}
+	retrying, _ := newTestRetryingService(service, RetryPolicy{MaxAttempts: 3})
+	retrying.GetPriceFor("p1")
+	assertInt(t, 1, service.getNumCalls(), "permanent errors should not be retried")
+}
+
+// Check that the key errors of a batch are seen through the batch, their class included
+func TestBatchError_Unwrap(t *testing.T) {
+	errBadCode := MarkPermanent(errors.New("invalid item code"))
+	err := error(newBatchError([]string{"p1", "p2"}, []error{ErrUnavailable, errBadCode}))
+	if !errors.Is(err, ErrUnavailable) || !errors.Is(err, errBadCode) {
+		t.Errorf("expected the batch to match both key errors, got %v", err)
+	}
+	if DefaultErrorClassifier(err) != Permanent {
+		t.Errorf("expected the marked key error to be seen through the batch, got %v", err)
+	}
+	var keyErr *KeyError[string]
+	if !errors.As(err, &keyErr) || keyErr.Key != "p1" {
+		t.Errorf("expected the first key error but got %v", keyErr)
+	}
+}
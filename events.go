@@ -0,0 +1,121 @@
+package sample1
+
+import (
+	"reflect"
+	"sync"
+)
+
+// EventReason tells why an Event was fired
+type EventReason int
+
+const (
+	Loaded      EventReason = iota + 1 // the loader returned the value
+	Evicted                            // the entry was dropped to make room for others, see WithMaxEntries
+	Expired                            // the janitor dropped the entry once it couldn't be served anymore, see WithJanitor
+	Invalidated                        // the entry was dropped by an invalidation (Invalidate, InvalidateTag, Clear...)
+	Changed                            // the loader returned a value different from the one cached
+)
+
+func (r EventReason) String() string {
+	switch r {
+	case Loaded:
+		return "loaded"
+	case Evicted:
+		return "evicted"
+	case Expired:
+		return "expired"
+	case Invalidated:
+		return "invalidated"
+	case Changed:
+		return "changed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event tells about a value coming into or leaving the cache
+type Event[K comparable, V any] struct {
+	Key    K
+	Value  V
+	Err    error // the load error of a negative entry, Value is meaningless if it is set
+	Reason EventReason
+	// Refresh tells, for a Loaded event, that the value replaced one that was cached (an expired one most often)
+	Refresh bool
+	// Previous is, for a Changed event, the value that was cached
+	Previous V
+}
+
+// listeners are the callbacks registered for each reason
+type listeners[K comparable, V any] struct {
+	mu       sync.RWMutex
+	byReason map[EventReason][]func(Event[K, V])
+}
+
+func (l *listeners[K, V]) add(reason EventReason, fn func(Event[K, V])) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.byReason == nil {
+		l.byReason = map[EventReason][]func(Event[K, V]){}
+	}
+	l.byReason[reason] = append(l.byReason[reason], fn)
+}
+
+// has tells if there are listeners for reason
+func (l *listeners[K, V]) has(reason EventReason) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.byReason[reason]) > 0
+}
+
+// OnLoad registers fn to be called with every value the loader returns, in the goroutine that loaded it
+// and before Get returns it, even if an invalidation during the load keeps it out of the cache
+func (c *Cache[K, V]) OnLoad(fn func(Event[K, V])) {
+	c.listeners.add(Loaded, fn)
+}
+
+// OnEvict registers fn to be called with every entry dropped to make room for others,
+// in the goroutine that inserted the entry that took its place
+func (c *Cache[K, V]) OnEvict(fn func(Event[K, V])) {
+	c.listeners.add(Evicted, fn)
+}
+
+// OnExpire registers fn to be called with every expired entry the janitor drops, in the janitor goroutine
+func (c *Cache[K, V]) OnExpire(fn func(Event[K, V])) {
+	c.listeners.add(Expired, fn)
+}
+
+// OnInvalidate registers fn to be called with every entry dropped by an invalidation, this instance's or one
+// received through WithInvalidator, in the goroutine that invalidated it
+func (c *Cache[K, V]) OnInvalidate(fn func(Event[K, V])) {
+	c.listeners.add(Invalidated, fn)
+}
+
+// OnChange registers fn to be called whenever the loader returns a value different from the one cached
+// (compared with reflect.DeepEqual), with both of them, in the goroutine that loaded it and before Get returns it
+// Values cached as errors, or loaded while nothing was cached, don't count as changes
+func (c *Cache[K, V]) OnChange(fn func(Event[K, V])) {
+	c.listeners.add(Changed, fn)
+}
+
+// changed returns the Changed event of a load of value replacing previous, none if it changed nothing
+func changed[K comparable, V any](key K, value V, previous Entry[V]) []Event[K, V] {
+	if previous.Err != nil || reflect.DeepEqual(previous.Value, value) {
+		return nil
+	}
+	return []Event[K, V]{{Key: key, Value: value, Previous: previous.Value, Reason: Changed}}
+}
+
+// emit calls the listeners of every event, c.mu must not be held so that they can use the cache
+func (c *Cache[K, V]) emit(events ...Event[K, V]) {
+	if len(events) == 0 {
+		return
+	}
+	c.logEvictions(events)
+	c.listeners.mu.RLock()
+	defer c.listeners.mu.RUnlock()
+	for _, event := range events {
+		for _, fn := range c.listeners.byReason[event.Reason] {
+			fn(event)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package sample1
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventRecorder keeps the events it is given
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event[string, float64]
+}
+
+func (r *eventRecorder) record(event Event[string, float64]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Check that loads fire OnLoad with the loaded value
+func TestOnLoad_FiresOnEveryLoad(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 0, err: ErrNotFound},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	recorder := &eventRecorder{}
+	cache.OnLoad(recorder.record)
+	getPriceWithNoErr(t, cache, "p1")
+	getPriceWithNoErr(t, cache, "p1")
+	cache.GetPriceFor("p2")
+
+	if len(recorder.events) != 1 {
+		t.Fatalf("only the successful load should have fired an event, got %v", recorder.events)
+	}
+	event := recorder.events[0]
+	if event.Key != "p1" || event.Value != 5 || event.Reason != Loaded {
+		t.Errorf("wrong event fired: %+v", event)
+	}
+}
+
+// Check that evictions fire OnEvict with the evicted value, and listeners can use the cache
+func TestOnEvict_FiresOnEveryEviction(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxEntries(1))
+	recorder := &eventRecorder{}
+	cache.OnEvict(recorder.record)
+	cache.OnEvict(func(event Event[string, float64]) {
+		if cache.Contains(event.Key) {
+			t.Errorf("%s should not be cached anymore", event.Key)
+		}
+	})
+	getPriceWithNoErr(t, cache, "p1")
+	getPriceWithNoErr(t, cache, "p2")
+
+	if len(recorder.events) != 1 {
+		t.Fatalf("one eviction should have been fired, got %v", recorder.events)
+	}
+	event := recorder.events[0]
+	if event.Key != "p1" || event.Value != 5 || event.Reason != Evicted {
+		t.Errorf("wrong event fired: %+v", event)
+	}
+}
+
+// Check that the entries dropped by the janitor fire OnExpire
+func TestOnExpire_FiresOnEveryExpiredEntry(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(func(_ context.Context, key string) (float64, error) { return 5, nil },
+		WithMaxAge(time.Minute), WithClock(clock))
+	recorder := &eventRecorder{}
+	cache.OnExpire(recorder.record)
+	cache.Set("p1", 3)
+	clock.Advance(2 * time.Minute)
+	cache.purgeExpired()
+
+	if len(recorder.events) != 1 {
+		t.Fatalf("one expiration should have been fired, got %v", recorder.events)
+	}
+	event := recorder.events[0]
+	if event.Key != "p1" || event.Value != 3 || event.Reason != Expired || event.Reason.String() != "expired" {
+		t.Errorf("wrong event fired: %+v", event)
+	}
+}
+
+// Check that the loads replacing a cached value are told apart from the first ones
+func TestOnLoad_TellsRefreshes(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}, "p2": {price: 7}}}
+	clock := newFakeClock()
+	cache := New(mockService, WithClock(clock), WithMaxAge(time.Minute))
+	recorder := &eventRecorder{}
+	cache.OnLoad(recorder.record)
+	getPriceWithNoErr(t, cache, "p1")
+	clock.Advance(2 * time.Minute)
+	getPriceWithNoErr(t, cache, "p1")
+	cache.GetMany(context.Background(), "p1", "p2")
+
+	if len(recorder.events) != 3 || recorder.events[0].Refresh || !recorder.events[1].Refresh || recorder.events[2].Refresh {
+		t.Errorf("expected a first load, a refresh then the first load of p2, got %+v", recorder.events)
+	}
+}
+
+// Check that every kind of invalidation fires OnInvalidate with the dropped values
+func TestOnInvalidate_FiresOnEveryInvalidation(t *testing.T) {
+	cache := New(&mockPriceService{}, WithTagger(TaggerFunc[string, float64](func(key string, _ float64) []string {
+		return []string{"tag-" + key}
+	})))
+	recorder := &eventRecorder{}
+	cache.OnInvalidate(recorder.record)
+	cache.SetMany(map[string]float64{"p1": 1, "p2": 2, "p3": 3, "p4": 4})
+	cache.Invalidate("p1")
+	cache.Invalidate("unknown")
+	cache.InvalidateTag("tag-p2")
+	assertInt(t, 2, len(recorder.events), "expected an event per dropped value")
+	if event := recorder.events[0]; event.Key != "p1" || event.Value != 1 || event.Reason != Invalidated {
+		t.Errorf("wrong event fired: %+v", event)
+	}
+	if event := recorder.events[1]; event.Key != "p2" || event.Value != 2 {
+		t.Errorf("wrong event fired: %+v", event)
+	}
+	cache.Clear()
+	assertInt(t, 4, len(recorder.events), "the clear should have fired an event per dropped value")
+}
+
+// Check that OnPriceChange fires when a load replaces a cached price with a different one, batches included
+func TestOnPriceChange_FiresOnChangedPrices(t *testing.T) {
+	mockService := &bulkMockPriceService{mockPriceService: mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 5},
+		"p2": {price: 7},
+	}}}
+	clock := newFakeClock()
+	cache := New(mockService, WithClock(clock), WithMaxAge(time.Minute))
+	type priceChange struct{ itemCode, old, new, delta string }
+	var changes []priceChange
+	cache.OnPriceChange(func(itemCode string, oldPrice, newPrice, delta float64) {
+		changes = append(changes, priceChange{itemCode, fmt.Sprint(oldPrice), fmt.Sprint(newPrice), fmt.Sprint(delta)})
+	})
+	getPricesWithNoErr(t, cache, "p1", "p2")
+	clock.Advance(2 * time.Minute)
+	mockService.setPrice("p1", 6)
+	getPriceWithNoErr(t, cache, "p1") // changed
+	getPriceWithNoErr(t, cache, "p2") // the same price
+	clock.Advance(2 * time.Minute)
+	mockService.setPrice("p2", 4)
+	getPricesWithNoErr(t, cache, "p1", "p2") // only p2 changed
+
+	want := []priceChange{{"p1", "5", "6", "1"}, {"p2", "7", "4", "-3"}}
+	if fmt.Sprint(changes) != fmt.Sprint(want) {
+		t.Errorf("expected the changes %v but got %v", want, changes)
+	}
+}
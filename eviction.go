@@ -0,0 +1,93 @@
+package sample1
+
+import "container/list"
+
+// EvictionPolicy decides which entry the cache drops when it grows past its max entries or its max weight
+// The cache calls the policy with its own lock held, so implementations don't need to be safe for concurrent use
+type EvictionPolicy[K comparable] interface {
+	// OnInsert is called when a value for key is stored in the cache
+	OnInsert(key K)
+	// OnAccess is called when the value for key was returned from the cache: the hits are buffered, and given to
+	// the policy in the order they were served before any other call (a few may be dropped under heavy load)
+	OnAccess(key K)
+	// OnRemove is called when key is no longer in the cache
+	OnRemove(key K)
+	// Victim returns the key that should be evicted next, it may be the one that was just inserted
+	Victim() (K, bool)
+}
+
+// LRUPolicy evicts the least recently used key
+type LRUPolicy[K comparable] struct {
+	order *lruList[K]
+}
+
+// NewLRUPolicy creates a least recently used eviction policy, the default one for WithMaxEntries
+func NewLRUPolicy[K comparable]() *LRUPolicy[K] {
+	return &LRUPolicy[K]{order: newLRUList[K]()}
+}
+
+func (p *LRUPolicy[K]) OnInsert(key K)    { p.order.touch(key) }
+func (p *LRUPolicy[K]) OnAccess(key K)    { p.order.touch(key) }
+func (p *LRUPolicy[K]) OnRemove(key K)    { p.order.remove(key) }
+func (p *LRUPolicy[K]) Victim() (K, bool) { return p.order.oldest() }
+
+// lruList keeps keys ordered by how recently they were used, most recent first
+type lruList[K comparable] struct {
+	order    *list.List
+	elements map[K]*list.Element
+}
+
+func newLRUList[K comparable]() *lruList[K] {
+	return &lruList[K]{
+		order:    list.New(),
+		elements: map[K]*list.Element{},
+	}
+}
+
+// touch marks key as the most recently used one, adding it if it was not there
+func (l *lruList[K]) touch(key K) {
+	if elem, ok := l.elements[key]; ok {
+		l.order.MoveToFront(elem)
+		return
+	}
+	l.elements[key] = l.order.PushFront(key)
+}
+
+// remove forgets about key
+func (l *lruList[K]) remove(key K) {
+	if elem, ok := l.elements[key]; ok {
+		l.order.Remove(elem)
+		delete(l.elements, key)
+	}
+}
+
+// contains tells if key is in the list
+func (l *lruList[K]) contains(key K) bool {
+	_, ok := l.elements[key]
+	return ok
+}
+
+// len returns the number of keys in the list
+func (l *lruList[K]) len() int {
+	return l.order.Len()
+}
+
+// newest returns the most recently used key
+func (l *lruList[K]) newest() (K, bool) {
+	elem := l.order.Front()
+	if elem == nil {
+		var zero K
+		return zero, false
+	}
+	return elem.Value.(K), true
+}
+
+// oldest returns the least recently used key
+func (l *lruList[K]) oldest() (K, bool) {
+	elem := l.order.Back()
+	if elem == nil {
+		var zero K
+		return zero, false
+	}
+	return elem.Value.(K), true
+}
@@ -0,0 +1,48 @@
+package sample1
+
+import (
+	"testing"
+	"time"
+)
+
+// Check that the cache never keeps more than maxEntries prices, dropping the least recently used
+func TestGetPriceFor_EvictsLeastRecentlyUsed(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+			"p3": {price: 9, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxEntries(2))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertFloat(t, 7, getPriceWithNoErr(t, cache, "p2"), "wrong price returned")
+	// use "p1" so that "p2" becomes the least recently used
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertFloat(t, 9, getPriceWithNoErr(t, cache, "p3"), "wrong price returned")
+	assertInt(t, 3, mockService.getNumCalls(), "wrong number of service calls")
+	assertInt(t, 2, cache.Len(), "wrong number of cached prices")
+
+	// "p1" is still cached, "p2" was evicted
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 3, mockService.getNumCalls(), "wrong number of service calls")
+	assertFloat(t, 7, getPriceWithNoErr(t, cache, "p2"), "wrong price returned")
+	assertInt(t, 4, mockService.getNumCalls(), "wrong number of service calls")
+}
+
+func TestLRUList_Oldest(t *testing.T) {
+	l := newLRUList[string]()
+	if _, ok := l.oldest(); ok {
+		t.Error("expected no oldest item in an empty list")
+	}
+	l.touch("p1")
+	l.touch("p2")
+	l.touch("p1")
+	if oldest, _ := l.oldest(); oldest != "p2" {
+		t.Errorf("expected p2 to be the oldest, got %v", oldest)
+	}
+	l.remove("p2")
+	if oldest, _ := l.oldest(); oldest != "p1" {
+		t.Errorf("expected p1 to be the oldest, got %v", oldest)
+	}
+}
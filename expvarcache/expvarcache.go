@@ -0,0 +1,51 @@
+// Package expvarcache publishes the counters of a cache with expvar, for the services that only scrape
+// /debug/vars
+// It lives in its own package since importing expvar serves /debug/vars on http.DefaultServeMux
+package expvarcache
+
+import (
+	"expvar"
+	"fmt"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// StatsSource is anything that reports cache counters, like a Cache or a TransparentCache
+type StatsSource interface {
+	Stats() sample1.Stats
+	Len() int
+}
+
+// Vars are the counters of a cache as published under their prefix, read from the cache every time
+// /debug/vars is served
+type Vars struct {
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	HitRatio      float64 `json:"hitRatio"`
+	Loads         uint64  `json:"loads"`
+	LoadErrors    uint64  `json:"loadErrors"`
+	LoadLatencyMs float64 `json:"loadLatencyMs"` // how long a call to the service takes on average
+	Evictions     uint64  `json:"evictions"`
+	StaleServed   uint64  `json:"staleServed"`
+	Entries       int     `json:"entries"`
+}
+
+// VarsOf returns the Vars of cache
+func VarsOf(cache StatsSource) Vars {
+	stats := cache.Stats()
+	return Vars{
+		Hits: stats.Hits, Misses: stats.Misses, HitRatio: stats.HitRatio(), Loads: stats.Loads,
+		LoadErrors: stats.LoadErrors, LoadLatencyMs: float64(stats.AverageLoadTime().Microseconds()) / 1000,
+		Evictions: stats.Evictions, StaleServed: stats.StaleServed, Entries: cache.Len(),
+	}
+}
+
+// Publish publishes the Vars of cache as the expvar named prefix ("pricecache" for instance)
+// expvar can't unpublish, so it fails if prefix is already published, by another cache or anything else
+func Publish(prefix string, cache StatsSource) error {
+	if expvar.Get(prefix) != nil {
+		return fmt.Errorf("the expvar [%v] is already published", prefix)
+	}
+	expvar.Publish(prefix, expvar.Func(func() any { return VarsOf(cache) }))
+	return nil
+}
@@ -0,0 +1,43 @@
+package expvarcache
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+type fakePriceService map[string]float64
+
+func (f fakePriceService) GetPriceFor(itemCode string) (float64, error) {
+	price, ok := f[itemCode]
+	if !ok {
+		return 0, fmt.Errorf("unknown item [%v]", itemCode)
+	}
+	return price, nil
+}
+
+// Check that the counters of the cache are published under the prefix, and that a prefix can't be reused
+func TestPublish(t *testing.T) {
+	cache := sample1.NewTransparentCache(fakePriceService{"p1": 5}, time.Minute)
+	if err := Publish("pricecache_test", cache); err != nil {
+		t.Fatal(err)
+	}
+	cache.GetPriceFor("p1")
+	cache.GetPriceFor("p1")
+	cache.GetPriceFor("p2")
+
+	var vars Vars
+	if err := json.Unmarshal([]byte(expvar.Get("pricecache_test").String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars.Hits != 1 || vars.Misses != 2 || vars.Loads != 2 || vars.LoadErrors != 1 || vars.Entries != 1 {
+		t.Errorf("unexpected vars %+v", vars)
+	}
+	if err := Publish("pricecache_test", cache); err == nil {
+		t.Error("publishing a prefix twice should fail")
+	}
+}
@@ -0,0 +1,70 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Fallback is one of the services a FallbackService asks, Name is reported as the source of the prices it supplies
+type Fallback struct {
+	Name    string
+	Service PriceService
+}
+
+// FallbackService is a PriceService that asks an ordered list of services for every price, moving on to the
+// next service when one fails, so that a backup provider takes over while the primary one is down
+// Given to the cache, it reports which service supplied every value, the cache keeps it (see SourceOf)
+type FallbackService struct {
+	names    []string
+	services []ContextPriceService
+}
+
+// NewFallbackService returns a service asking services in order, it panics if there are none
+func NewFallbackService(services ...Fallback) *FallbackService {
+	if len(services) == 0 {
+		panic("sample1: a fallback service needs at least one service")
+	}
+	s := &FallbackService{}
+	for _, fallback := range services {
+		s.names = append(s.names, fallback.Name)
+		s.services = append(s.services, AsContextPriceService(fallback.Service))
+	}
+	return s
+}
+
+func (s *FallbackService) GetPriceFor(itemCode string) (float64, error) {
+	return s.GetPriceForCtx(context.Background(), itemCode)
+}
+
+// GetPriceForCtx returns the price of the first service that has it, trying the next one after any error
+// (ErrNotFound included, a backup may know items the primary doesn't)
+// If every service fails, the error joins all of their errors, it only matches ErrNotFound if every service
+// said so, and is only Permanent if every service failed with a Permanent error, so that a missing item isn't
+// cached as such because its only other source was down
+func (s *FallbackService) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	errs := make([]error, 0, len(s.services))
+	notFound, permanent := true, true
+	for i, service := range s.services {
+		price, err := service.GetPriceForCtx(ctx, itemCode)
+		if err == nil {
+			ReportSource(ctx, s.names[i])
+			return price, nil
+		}
+		if ctx.Err() != nil {
+			return 0, err
+		}
+		notFound = notFound && isNotFound(err)
+		permanent = permanent && !isTransient(err)
+		errs = append(errs, err)
+	}
+	for i, err := range errs {
+		if isNotFound(err) && !notFound || !isTransient(err) && !permanent {
+			// keep the message, but don't let the services that lack the item make it a miss
+			errs[i] = fmt.Errorf("%s : %v", s.names[i], err)
+		} else {
+			errs[i] = fmt.Errorf("%s : %w", s.names[i], err)
+		}
+	}
+	return 0, errors.Join(errs...)
+}
@@ -0,0 +1,89 @@
+package sample1
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Check that the backup is only asked when the primary fails, and that the cache records who supplied each price
+func TestFallbackService_UsesBackupOnFailure(t *testing.T) {
+	primary := &switchablePriceService{mockPriceService: mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 5},
+		"p2": {price: 6},
+	}}}
+	backup := &mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 50},
+		"p2": {price: 60},
+	}}
+	cache := NewTransparentCache(NewFallbackService(
+		Fallback{Name: "primary", Service: primary},
+		Fallback{Name: "backup", Service: backup},
+	), time.Minute)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	primary.setDown(true)
+	assertFloat(t, 60, getPriceWithNoErr(t, cache, "p2"), "the backup price should be returned")
+	assertInt(t, 1, backup.getNumCalls(), "the backup should only be asked when the primary fails")
+	for itemCode, want := range map[string]string{"p1": "primary", "p2": "backup"} {
+		source, ok := cache.SourceOf(itemCode)
+		if !ok || source != want {
+			t.Errorf("expected [%v] to come from %v but got %q, %v", itemCode, want, source, ok)
+		}
+	}
+	if _, ok := cache.SourceOf("p3"); ok {
+		t.Error("there should be no source for an item that isn't cached")
+	}
+}
+
+// Check that the error joins the failures of every service, and only matches ErrNotFound (or is Permanent) if all
+// of them said so
+func TestFallbackService_AllFail(t *testing.T) {
+	down := errors.New("503 service unavailable")
+	missing := &mockPriceService{mockResults: map[string]mockResult{"p1": {err: ErrNotFound}}}
+	broken := &mockPriceService{mockResults: map[string]mockResult{"p1": {err: down}}}
+
+	_, err := NewFallbackService(Fallback{Name: "a", Service: missing}, Fallback{Name: "b", Service: missing}).GetPriceFor("p1")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound when every service lacks the item but got %v", err)
+	}
+	_, err = NewFallbackService(Fallback{Name: "a", Service: missing}, Fallback{Name: "b", Service: broken}).GetPriceFor("p1")
+	if errors.Is(err, ErrNotFound) {
+		t.Errorf("the item shouldn't be missing when a service failed, got %v", err)
+	}
+	if !errors.Is(err, down) {
+		t.Errorf("expected the error of the failed service but got %v", err)
+	}
+	if want := "a : not found\nb : 503 service unavailable"; err == nil || err.Error() != want {
+		t.Errorf("expected %q but got %v", want, err)
+	}
+
+	bad := &mockPriceService{mockResults: map[string]mockResult{"p1": {err: MarkPermanent(errors.New("invalid item code"))}}}
+	unavailable := &mockPriceService{mockResults: map[string]mockResult{"p1": {err: ErrUnavailable}}}
+	_, err = NewFallbackService(Fallback{Name: "a", Service: bad}, Fallback{Name: "b", Service: unavailable}).GetPriceFor("p1")
+	if DefaultErrorClassifier(err) != Transient {
+		t.Errorf("the error shouldn't be permanent when a service is down, got %v", err)
+	}
+	_, err = NewFallbackService(Fallback{Name: "a", Service: bad}, Fallback{Name: "b", Service: missing}).GetPriceFor("p1")
+	if DefaultErrorClassifier(err) != Permanent {
+		t.Errorf("the error should be permanent when every service said so, got %v", err)
+	}
+}
+
+// Check that the source survives a snapshot
+func TestFallbackService_SourceInSnapshot(t *testing.T) {
+	service := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}}}
+	cache := NewTransparentCache(NewFallbackService(Fallback{Name: "primary", Service: service}), time.Minute)
+	getPriceWithNoErr(t, cache, "p1")
+	var buf bytes.Buffer
+	if err := cache.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	restored := NewTransparentCache(service, time.Minute)
+	if err := restored.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if source, _ := restored.SourceOf("p1"); source != "primary" {
+		t.Errorf("expected the source to be restored but got %q", source)
+	}
+}
@@ -0,0 +1,123 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"path"
+	"sync"
+	"time"
+)
+
+// ErrInjected is the error of the calls a Fault makes fail, unless it has an Err of its own
+var ErrInjected = errors.New("injected fault")
+
+// Fault is what a FaultInjectingService does to the calls for the item codes matching Pattern
+type Fault struct {
+	Pattern   string        // a path.Match pattern of the item codes ("BOOKS/*", "p1?"), every item code if empty
+	Latency   time.Duration // added to every call
+	ErrorRate float64       // fraction of the calls failing with Err, after Latency
+	Err       error         // ErrInjected if nil
+	HangRate  float64       // fraction of the calls hanging until their context is done, forever without one
+}
+
+// matches tells if the fault applies to itemCode
+func (f Fault) matches(itemCode string) bool {
+	matched, _ := path.Match(f.Pattern, itemCode)
+	return f.Pattern == "" || matched
+}
+
+// FaultInjectingService is a PriceService injecting faults in the calls to another one, so that the timeouts,
+// retries and stale-if-error of the cache can be checked against a failing service. Its faults can be changed
+// while it is used, to start and stop an outage
+type FaultInjectingService struct {
+	service ContextPriceService
+	random  func() float64 // only swapped by tests
+
+	mu     sync.RWMutex
+	faults []Fault
+}
+
+// NewFaultInjectingService wraps service, the calls go through untouched until SetFaults is called
+func NewFaultInjectingService(service PriceService) *FaultInjectingService {
+	return &FaultInjectingService{service: AsContextPriceService(service), random: rand.Float64}
+}
+
+// FaultInjecting is a Decorator injecting faults in the calls to the service, see FaultInjectingService
+// It panics if the Pattern of a fault is malformed
+func FaultInjecting(faults ...Fault) Decorator {
+	if err := checkFaults(faults); err != nil {
+		panic(fmt.Sprintf("sample1: %v", err))
+	}
+	return func(service PriceService) PriceService {
+		s := NewFaultInjectingService(service)
+		s.SetFaults(faults...)
+		return s
+	}
+}
+
+// checkFaults tells which Pattern of faults is malformed, if any
+func checkFaults(faults []Fault) error {
+	for _, fault := range faults {
+		if _, err := path.Match(fault.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid fault pattern [%v] : %w", fault.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// SetFaults replaces the faults injected, every call gets the first fault whose Pattern matches its item code
+// and goes through untouched if none does. It fails, changing nothing, if a Pattern is malformed
+func (s *FaultInjectingService) SetFaults(faults ...Fault) error {
+	if err := checkFaults(faults); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = faults
+	return nil
+}
+
+// faultFor returns the fault to inject in a call for itemCode, ok is false if there is none
+func (s *FaultInjectingService) faultFor(itemCode string) (Fault, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, fault := range s.faults {
+		if fault.matches(itemCode) {
+			return fault, true
+		}
+	}
+	return Fault{}, false
+}
+
+func (s *FaultInjectingService) GetPriceFor(itemCode string) (float64, error) {
+	return s.GetPriceForCtx(context.Background(), itemCode)
+}
+
+func (s *FaultInjectingService) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	fault, ok := s.faultFor(itemCode)
+	if !ok {
+		return s.service.GetPriceForCtx(ctx, itemCode)
+	}
+	if fault.HangRate > 0 && s.random() < fault.HangRate {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	if fault.Latency > 0 {
+		timer := time.NewTimer(fault.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	if fault.ErrorRate > 0 && s.random() < fault.ErrorRate {
+		if fault.Err != nil {
+			return 0, fault.Err
+		}
+		return 0, fmt.Errorf("pricing [%v] : %w", itemCode, ErrInjected)
+	}
+	return s.service.GetPriceForCtx(ctx, itemCode)
+}
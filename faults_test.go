@@ -0,0 +1,97 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Check that the faults only apply to the item codes matching their pattern, the first matching one winning
+func TestFaultInjectingService_Patterns(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{
+		"BOOKS/1": {price: 5, err: nil},
+		"BOOKS/2": {price: 6, err: nil},
+		"p1":      {price: 7, err: nil},
+	}}
+	someErr := errors.New("some error")
+	service := NewFaultInjectingService(mockService)
+	if err := service.SetFaults(Fault{Pattern: "BOOKS/2", ErrorRate: 1, Err: someErr}, Fault{Pattern: "BOOKS/*", ErrorRate: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetPriceFor("BOOKS/1"); !errors.Is(err, ErrInjected) {
+		t.Errorf("expected an injected error but got %v", err)
+	}
+	if _, err := service.GetPriceFor("BOOKS/2"); err != someErr {
+		t.Errorf("expected the error of the first matching fault but got %v", err)
+	}
+	if price, err := service.GetPriceFor("p1"); err != nil || price != 7 {
+		t.Errorf("expected the price of the service but got %v, %v", price, err)
+	}
+	assertInt(t, 1, mockService.getNumCalls(), "the failed calls should not reach the service")
+	service.SetFaults()
+	if _, err := service.GetPriceFor("BOOKS/1"); err != nil {
+		t.Errorf("the faults should have been removed, got %v", err)
+	}
+	if err := service.SetFaults(Fault{Pattern: "["}); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+}
+
+// Check that the error and hang rates decide which calls fail
+func TestFaultInjectingService_Rates(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5, err: nil}}}
+	service := NewFaultInjectingService(mockService)
+	draws := []float64{0.1, 0.9, 0.4, 0.6}
+	service.random = func() float64 {
+		draw := draws[0]
+		draws = draws[1:]
+		return draw
+	}
+	service.SetFaults(Fault{ErrorRate: 0.5})
+	if _, err := service.GetPriceFor("p1"); !errors.Is(err, ErrInjected) {
+		t.Errorf("a draw below the error rate should fail, got %v", err)
+	}
+	if _, err := service.GetPriceFor("p1"); err != nil {
+		t.Errorf("a draw above the error rate should go through, got %v", err)
+	}
+	service.SetFaults(Fault{HangRate: 0.5})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := service.GetPriceForCtx(ctx, "p1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("a draw below the hang rate should hang until the deadline, got %v", err)
+	}
+	if _, err := service.GetPriceForCtx(context.Background(), "p1"); err != nil {
+		t.Errorf("a draw above the hang rate should go through, got %v", err)
+	}
+}
+
+// Check that the cache serves its stale prices when the service starts hanging
+func TestFaultInjecting_WithStaleIfError(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5, err: nil}}}
+	service := NewFaultInjectingService(mockService)
+	cache := New(service, WithMaxAge(time.Minute), WithClock(clock), WithLoadTimeout(10*time.Millisecond),
+		WithStaleIfError(time.Hour))
+	getPriceWithNoErr(t, cache, "p1")
+	service.SetFaults(Fault{HangRate: 1})
+	clock.Advance(time.Minute + time.Second)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "the stale price should have been served")
+	assertInt(t, 1, int(cache.Stats().StaleServed), "wrong number of stale prices served")
+}
+
+// Check that the latency is added to the calls, and that a malformed pattern is reported by FaultInjecting
+func TestFaultInjecting_Latency(t *testing.T) {
+	service := Chain(&mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5, err: nil}}},
+		FaultInjecting(Fault{Latency: 20 * time.Millisecond}))
+	start := time.Now()
+	if _, err := service.GetPriceFor("p1"); err != nil || time.Since(start) < 20*time.Millisecond {
+		t.Errorf("the call should have taken the latency, took %v (err %v)", time.Since(start), err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a malformed pattern")
+		}
+	}()
+	FaultInjecting(Fault{Pattern: "["})
+}
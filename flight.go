@@ -0,0 +1,161 @@
+package sample1
+
+import (
+	"context"
+	"sync"
+)
+
+// flightCall is an in-flight (or just finished) load for one key
+type flightCall[V any] struct {
+	done    chan struct{} // closed once value and err are set
+	value   V
+	err     error
+	waiters int    // callers that joined the call and didn't give up, guarded by the mutex of the group
+	cancel  func() // cancels the call once it has no waiters left, nil if it must go on anyway
+}
+
+// flightGroup coalesces concurrent loads for the same key, so that only one of them
+// reaches the loader and the others wait for its result (same idea as singleflight)
+type flightGroup[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*flightCall[V]
+}
+
+// do runs fn for key, unless there is already a call in flight for it, and waits for the result of the call
+// fn gets a context with the values of ctx that is only cancelled once every caller waiting for the call gave up:
+// a caller whose ctx is done returns ctx.Err() right away, while the call goes on for the others
+func (g *flightGroup[K, V]) do(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (V, error) {
+	if err := ctx.Err(); err != nil {
+		var zero V
+		return zero, err
+	}
+	if ctx.Done() == nil {
+		// this caller never gives up, the call can run with ctx and without a goroutine
+		call, started := g.join(key, nil)
+		if started {
+			g.run(key, call, func() (V, error) { return fn(ctx) })
+		}
+		return g.wait(ctx, key, call)
+	}
+	callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	call, started := g.join(key, cancel)
+	if !started {
+		cancel()
+		return g.wait(ctx, key, call)
+	}
+	go func() {
+		defer cancel()
+		g.run(key, call, func() (V, error) { return fn(callCtx) })
+	}()
+	return g.wait(ctx, key, call)
+}
+
+// start runs fn for key in a new goroutine, unless there is already a call in flight for it
+// It returns whether it started a new call, which nobody can cancel
+func (g *flightGroup[K, V]) start(key K, fn func() (V, error)) bool {
+	call, started := g.join(key, nil)
+	if !started {
+		// start doesn't wait for the call
+		g.leave(key, call)
+		return false
+	}
+	go g.run(key, call, fn)
+	return true
+}
+
+// join returns the call in flight for key and counts the caller as one of its waiters, registering a new call
+// (cancelled by cancel once it has no waiters left) if there was none
+// started tells whether the call is a new one, that the caller must run
+func (g *flightGroup[K, V]) join(key K, cancel func()) (call *flightCall[V], started bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.calls == nil {
+		g.calls = map[K]*flightCall[V]{}
+	}
+	if call, ok := g.calls[key]; ok {
+		call.waiters++
+		return call, false
+	}
+	call = &flightCall[V]{done: make(chan struct{}), waiters: 1, cancel: cancel}
+	g.calls[key] = call
+	return call, true
+}
+
+// setCancel sets how call is cancelled once it has no waiters left, for the callers that join before
+// they know how, the caller must still be waiting for the call
+func (g *flightGroup[K, V]) setCancel(call *flightCall[V], cancel func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	call.cancel = cancel
+}
+
+// wait waits for the result of call, or gives up on it and returns ctx.Err() if ctx is done first
+func (g *flightGroup[K, V]) wait(ctx context.Context, key K, call *flightCall[V]) (V, error) {
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		g.leave(key, call)
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// leave records that a caller stopped waiting for call, cancelling the call if it was the last one
+func (g *flightGroup[K, V]) leave(key K, call *flightCall[V]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	call.waiters--
+	if call.waiters > 0 || call.cancel == nil {
+		return
+	}
+	select {
+	case <-call.done:
+		return
+	default:
+	}
+	call.cancel()
+	if g.calls[key] == call {
+		// the callers coming next start a new call rather than join the cancelled one
+		delete(g.calls, key)
+	}
+}
+
+// run runs fn as the call for key, and lets the waiters know about its result
+func (g *flightGroup[K, V]) run(key K, call *flightCall[V], fn func() (V, error)) {
+	value, err := fn()
+	g.finish(key, call, value, err)
+}
+
+// finish records the result of a call started by join, and lets the waiters know about it
+func (g *flightGroup[K, V]) finish(key K, call *flightCall[V], value V, err error) {
+	call.value, call.err = value, err
+	close(call.done)
+
+	g.mu.Lock()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+}
+
+// settle waits until no call is in flight, the calls started meanwhile included, or until ctx is done
+func (g *flightGroup[K, V]) settle(ctx context.Context) error {
+	for {
+		var pending *flightCall[V]
+		g.mu.Lock()
+		for _, call := range g.calls {
+			pending = call
+			break
+		}
+		g.mu.Unlock()
+		if pending == nil {
+			return nil
+		}
+		select {
+		case <-pending.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
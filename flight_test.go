@@ -0,0 +1,181 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Check that concurrent misses for the same item make a single call to the service
+func TestGetPriceFor_CoalescesConcurrentMisses(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay: 100 * time.Millisecond,
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+		}()
+	}
+	wg.Wait()
+	assertInt(t, 1, mockService.getNumCalls(), "wrong number of service calls")
+}
+
+// Check that a finished flight does not keep answering for the item
+func TestFlightGroup_ForgetsFinishedCalls(t *testing.T) {
+	var g flightGroup[string, float64]
+	calls := 0
+	fn := func(context.Context) (float64, error) {
+		calls++
+		return float64(calls), nil
+	}
+	price, _ := g.do(context.Background(), "p1", fn)
+	assertFloat(t, 1, price, "wrong price returned")
+	price, _ = g.do(context.Background(), "p1", fn)
+	assertFloat(t, 2, price, "wrong price returned")
+}
+
+// inFlight tells if there is a call in flight for key
+func (g *flightGroup[K, V]) inFlight(key K) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.calls[key]
+	return ok
+}
+
+// Check that a caller giving up on a shared load returns right away, while the load goes on for the others
+func TestGetPriceForCtx_WaiterDetachesOnCancel(t *testing.T) {
+	for name, leaderGivesUp := range map[string]bool{"leader": true, "follower": false} {
+		t.Run(name, func(t *testing.T) {
+			mockService := &slowMockPriceService{
+				mockPriceService: mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5, err: nil}}},
+				slow:             "p1",
+				release:          make(chan struct{}),
+			}
+			cache := NewTransparentCache(mockService, time.Minute)
+			ctx, cancel := context.WithCancel(context.Background())
+			first, second := ctx, context.Background()
+			if !leaderGivesUp {
+				first, second = second, first
+			}
+			results := make(chan error, 2)
+			go func() {
+				_, err := cache.GetPriceForCtx(first, "p1")
+				results <- err
+			}()
+			for !cache.flights.inFlight("p1") {
+				time.Sleep(time.Millisecond)
+			}
+			go func() {
+				_, err := cache.GetPriceForCtx(second, "p1")
+				results <- err
+			}()
+			time.Sleep(10 * time.Millisecond) // let the second caller join the load
+			cancel()
+			select {
+			case err := <-results:
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("expected the caller that gave up to get context canceled, got %v", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("the caller that gave up should have returned right away")
+			}
+			close(mockService.release)
+			if err := <-results; err != nil {
+				t.Errorf("the other caller should have got the price, got %v", err)
+			}
+			assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+			assertInt(t, 1, mockService.getNumCalls(), "the load should have been shared and cached")
+		})
+	}
+}
+
+// Check that a shared load is cancelled once every caller gave up on it, and that the next caller starts a new one
+func TestGetPriceForCtx_LoadCancelledWhenEveryWaiterGaveUp(t *testing.T) {
+	mockService := &ctxMockPriceService{
+		mockPriceService: mockPriceService{
+			callDelay:   time.Second,
+			mockResults: map[string]mockResult{"p1": {price: 5, err: nil}},
+		},
+		cancelled: make(chan string, 1),
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.GetPriceForCtx(ctx, "p1"); !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context canceled, got %v", err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	wg.Wait()
+	select {
+	case <-mockService.cancelled:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("the load should have been cancelled")
+	}
+	if cache.flights.inFlight("p1") {
+		t.Error("the cancelled load should be forgotten")
+	}
+}
+
+// Check that a batch giving up on the keys it loads leaves the bulk load going for another caller
+func TestGetMany_BulkLoadGoesOnForOtherWaiters(t *testing.T) {
+	release := make(chan struct{})
+	cancelled := make(chan struct{})
+	bulkLoader := func(ctx context.Context, keys []string) ([]float64, error) {
+		select {
+		case <-release:
+			return make([]float64, len(keys)), nil
+		case <-ctx.Done():
+			close(cancelled)
+			return nil, ctx.Err()
+		}
+	}
+	cache := NewCache(func(ctx context.Context, key string) (float64, error) { return 0, nil },
+		WithBulkLoader(bulkLoader))
+	ctx, cancel := context.WithCancel(context.Background())
+	batchDone := make(chan error, 1)
+	go func() {
+		_, err := cache.GetMany(ctx, "p1", "p2")
+		batchDone <- err
+	}()
+	for !cache.flights.inFlight("p1") {
+		time.Sleep(time.Millisecond)
+	}
+	getDone := make(chan error, 1)
+	go func() {
+		_, err := cache.Get(context.Background(), "p1")
+		getDone <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-batchDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the batch to be cancelled, got %v", err)
+	}
+	select {
+	case <-cancelled:
+		t.Fatal("the bulk load should go on while p1 has a waiter")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if err := <-getDone; err != nil {
+		t.Errorf("the other caller should have got the value, got %v", err)
+	}
+	if !cache.Contains("p2") {
+		t.Error("the bulk load should have cached every key")
+	}
+}
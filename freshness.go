@@ -0,0 +1,54 @@
+package sample1
+
+import (
+	"fmt"
+	"time"
+)
+
+// FreshnessFunc tells how long a value stays fresh and when its source produced it, for values that carry
+// their own validity (see Price). A zero maxAge keeps the maxAge of the cache, a zero producedAt the time of the load
+type FreshnessFunc[K comparable, V any] func(key K, value V) (maxAge time.Duration, producedAt time.Time)
+
+// WithFreshness makes the cache ask fn how long every value it stores stays fresh, instead of using its maxAge
+// The age of a value produced before it was loaded counts from when it was produced, so that a value the
+// source had for a while doesn't stay fresh for longer than it should
+// A maxAge set for a key with SetMaxAgeFor still wins over fn
+// fn must take keys and values of the same types as the cache, NewCache panics otherwise
+func WithFreshness[K comparable, V any](fn FreshnessFunc[K, V]) Option {
+	return func(c *config) {
+		c.freshness = fn
+	}
+}
+
+// freshnessFor returns the configured freshness function for a cache of K and V, nil if none was configured
+func freshnessFor[K comparable, V any](fn any) FreshnessFunc[K, V] {
+	if fn == nil {
+		return nil
+	}
+	freshness, ok := fn.(FreshnessFunc[K, V])
+	if !ok {
+		panic(fmt.Sprintf("sample1: freshness function %T can't be used for a cache of %T", fn, (*Cache[K, V])(nil)))
+	}
+	return freshness
+}
+
+// valueEntry returns the entry of value loaded for key at fetchedAt, c.mu must be held
+func (c *Cache[K, V]) valueEntry(key K, value V, fetchedAt time.Time) Entry[V] {
+	entry := Entry[V]{Value: value, FetchedAt: fetchedAt, MaxAge: c.valueMaxAge(key, value)}
+	if c.freshness != nil {
+		if _, producedAt := c.freshness(key, value); !producedAt.IsZero() && producedAt.Before(fetchedAt) {
+			entry.FetchedAt = producedAt
+		}
+	}
+	return entry
+}
+
+// valueMaxAge returns how long value stays fresh once stored for key, c.mu must be held
+func (c *Cache[K, V]) valueMaxAge(key K, value V) time.Duration {
+	if _, overridden := c.maxAges[key]; !overridden && c.freshness != nil {
+		if maxAge, _ := c.freshness(key, value); maxAge > 0 {
+			return maxAge
+		}
+	}
+	return c.entryMaxAge(key)
+}
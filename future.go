@@ -0,0 +1,32 @@
+package sample1
+
+import "context"
+
+// Future is the handle of a lookup running in the background, see GetAsync
+type Future[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// GetAsync starts looking up key in the background and returns right away, so that callers can start many
+// lookups and join them later. It is Get in a goroutine, with the same loading and cancellation behavior
+func (c *Cache[K, V]) GetAsync(ctx context.Context, key K) *Future[V] {
+	f := &Future[V]{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		f.value, f.err = c.Get(ctx, key)
+	}()
+	return f
+}
+
+// Done returns a channel that is closed once the lookup is over
+func (f *Future[V]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Result waits for the lookup to be over and returns its value or its error, it can be called any number of times
+func (f *Future[V]) Result() (V, error) {
+	<-f.done
+	return f.value, f.err
+}
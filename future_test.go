@@ -0,0 +1,66 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Check that async lookups return right away and can be joined later
+func TestGetPriceForAsync(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay: 100 * time.Millisecond,
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 0, err: ErrNotFound},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	start := time.Now()
+	f1, f2 := cache.GetPriceForAsync("p1"), cache.GetPriceForAsync("p2")
+	if time.Since(start) > 50*time.Millisecond {
+		t.Error("the async lookups should return right away")
+	}
+	select {
+	case <-f1.Done():
+		t.Error("the lookup should not be over yet")
+	default:
+	}
+	price, err := f1.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, 5, price, "wrong price returned")
+	<-f2.Done()
+	if _, err := f2.Result(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got %v", err)
+	}
+	if time.Since(start) > 180*time.Millisecond {
+		t.Error("the lookups should have run in parallel")
+	}
+	price, _ = f1.Result()
+	assertFloat(t, 5, price, "Result should keep returning the price")
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 2, mockService.getNumCalls(), "the async lookup should have cached the price")
+}
+
+// Check that an async lookup gives up when its context is done
+func TestGetPriceForAsyncCtx_Cancel(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay:   time.Second,
+		mockResults: map[string]mockResult{"p1": {price: 5, err: nil}},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	f := cache.GetPriceForAsyncCtx(ctx, "p1")
+	cancel()
+	select {
+	case <-f.Done():
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("the lookup should have given up")
+	}
+	if _, err := f.Result(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled but got %v", err)
+	}
+}
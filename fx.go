@@ -0,0 +1,60 @@
+package sample1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RateService is a service returning exchange rates, the amount in to for one unit of from
+type RateService interface {
+	GetRate(ctx context.Context, from, to string) (float64, error)
+}
+
+// Converter converts amounts between currencies with the rates of a RateService, which it caches
+// It is safe for concurrent use by multiple goroutines
+type Converter struct {
+	rates *Cache[string, float64]
+}
+
+// NewConverter creates a converter asking service for the rates, opts configure the cache of the rates
+// (WithMaxAge for how long a rate is used, WithStaleWhileRevalidate...)
+func NewConverter(service RateService, opts ...Option) *Converter {
+	return &Converter{rates: NewCache(func(ctx context.Context, pair string) (float64, error) {
+		from, to, _ := strings.Cut(pair, "/")
+		return service.GetRate(ctx, from, to)
+	}, opts...)}
+}
+
+// Rate returns the rate from one currency to another, 1 for the same currency
+func (c *Converter) Rate(ctx context.Context, from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	return c.rates.Get(ctx, from+"/"+to)
+}
+
+// Convert returns amount, in from, in to
+func (c *Converter) Convert(ctx context.Context, amount float64, from, to string) (float64, error) {
+	rate, err := c.Rate(ctx, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("converting from %s to %s : %w", from, to, err)
+	}
+	return amount * rate, nil
+}
+
+// Rates returns the cache of the rates, for its stats or to invalidate them
+func (c *Converter) Rates() *Cache[string, float64] {
+	return c.rates
+}
+
+// WithConverter makes a CurrencyCache only ask its service for the prices in base, the prices in the other
+// currencies are converted from them with converter. Only the prices in base are cached, so a price is never
+// converted with a rate older than the maxAge of the rates nor from a price older than the maxAge of the cache
+// It is only used by NewCurrencyCache
+func WithConverter(converter *Converter, base string) Option {
+	return func(c *config) {
+		c.converter = converter
+		c.baseCurrency = base
+	}
+}
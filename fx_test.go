@@ -0,0 +1,100 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockRateService returns the rates it was given, counting the calls it gets
+type mockRateService struct {
+	mu       sync.Mutex
+	numCalls int
+	rates    map[string]float64 // by "from/to"
+}
+
+func (m *mockRateService) GetRate(_ context.Context, from, to string) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.numCalls++
+	rate, ok := m.rates[from+"/"+to]
+	if !ok {
+		return 0, fmt.Errorf("no rate from %v to %v : %w", from, to, ErrNotFound)
+	}
+	return rate, nil
+}
+
+func (m *mockRateService) getNumCalls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.numCalls
+}
+
+// Check that the prices in other currencies are converted from the cached prices in the base currency
+func TestCurrencyCache_ConvertsFromBase(t *testing.T) {
+	service := &mockCurrencyService{prices: map[ItemCurrency]float64{{"p1", "USD"}: 10}}
+	rates := &mockRateService{rates: map[string]float64{"USD/EUR": 0.5, "USD/GBP": 0.25}}
+	cache := NewCurrencyCache(service, nil, WithMaxAge(time.Minute), WithConverter(NewConverter(rates), "USD"))
+	for _, c := range []struct {
+		currency string
+		want     float64
+	}{{"USD", 10}, {"EUR", 5}, {"GBP", 2.5}, {"EUR", 5}} {
+		price, err := cache.GetPriceFor("p1", c.currency)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertFloat(t, c.want, price, "wrong price in "+c.currency)
+	}
+	assertInt(t, 1, service.getNumCalls(), "the service should only have been asked for the price in USD")
+	assertInt(t, 2, rates.getNumCalls(), "every rate should have been fetched once")
+
+	if _, err := cache.GetPriceFor("p1", "JPY"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the error of the rate service but got %v", err)
+	}
+}
+
+// Check that rates are cached for the maxAge of the converter, apart from the prices
+func TestConverter_CachesRates(t *testing.T) {
+	clock := newFakeClock()
+	rates := &mockRateService{rates: map[string]float64{"USD/EUR": 0.5}}
+	converter := NewConverter(rates, WithMaxAge(time.Hour), WithClock(clock))
+	for range 2 {
+		amount, err := converter.Convert(context.Background(), 10, "USD", "EUR")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertFloat(t, 5, amount, "wrong amount")
+	}
+	assertInt(t, 1, rates.getNumCalls(), "the rate should have been cached")
+	clock.Advance(2 * time.Hour)
+	if _, err := converter.Convert(context.Background(), 10, "USD", "EUR"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertInt(t, 2, rates.getNumCalls(), "the rate should have expired")
+
+	if rate, err := converter.Rate(context.Background(), "EUR", "EUR"); err != nil || rate != 1 {
+		t.Errorf("expected a rate of 1 within a currency but got %v, %v", rate, err)
+	}
+	assertInt(t, 2, rates.getNumCalls(), "the rate within a currency shouldn't be asked")
+}
+
+// Check that a batch is priced in the base currency and converted with a single rate
+func TestCurrencyCache_ConvertsBatches(t *testing.T) {
+	service := &mockCurrencyService{prices: map[ItemCurrency]float64{{"p1", "USD"}: 10, {"p2", "USD"}: 20}}
+	rates := &mockRateService{rates: map[string]float64{"USD/EUR": 0.5}}
+	cache := NewCurrencyCache(service, nil, WithConverter(NewConverter(rates), "USD"))
+	prices, err := cache.GetPricesFor("EUR", "p1", "p2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertFloats(t, []float64{5, 10}, prices, "wrong prices returned")
+	assertInt(t, 1, rates.getNumCalls(), "a single rate should have been asked")
+
+	cache.Invalidate("p1", "EUR")
+	if _, _, ok := cache.Peek("p1", "USD"); ok {
+		t.Error("invalidating the price in EUR should drop the price in USD it is converted from")
+	}
+}
@@ -0,0 +1,515 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LoaderFunc loads the value for a key from wherever the actual data lives
+// Calls to it are expected to be expensive, that's why the Cache sits in front of it
+type LoaderFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// Cache is a transparent cache in front of a loader function
+// The cache will remember the values we ask for, and only return them while they are not older than "maxAge"
+// It is safe for concurrent use by multiple goroutines
+type Cache[K comparable, V any] struct {
+	loader         LoaderFunc[K, V]
+	bulkLoader     BulkLoaderFunc[K, V] // loads the misses of GetMany together, nil if they are loaded one by one
+	maxBulkSize    int                  // max keys per bulk load, zero or less means unbounded
+	limiter        *rateLimiter         // limits the calls to the loaders, nil if they are not limited
+	loadTimeout    time.Duration        // bounds every call to the loaders, zero or less means unbounded
+	hedger         *hedger              // sends hedged calls to the loader, nil if they are not
+	maxAge         time.Duration
+	maxStale       time.Duration    // how long after maxAge values are still served while refreshing them
+	staleIfError   time.Duration    // how long after maxAge values are still served if the loader fails
+	hardTTL        time.Duration    // age after which entries are never served, zero if there is none
+	refreshAhead   float64          // last fraction of maxAge in which a hit refreshes the value, zero if disabled
+	jitter         float64          // max fraction of maxAge that is randomly taken off each entry
+	negativeMaxAge time.Duration    // how long load errors are cached, zero if they are not
+	isNegative     func(error) bool // tells which load errors are cached
+	classify       ErrorClassifier  // tells which load errors can be served stale
+	earlyBeta      float64          // XFetch beta for probabilistic early refreshes, zero if disabled
+	random         func() float64   // returns numbers in [0, 1), only swapped by tests
+	mu             sync.RWMutex     // guards policy, generation, maxAges, tags and the settings UpdateConfig changes, and keeps them consistent with store
+	store          Store[K, V]
+	maxAges        map[K]time.Duration  // per key maxAge overrides
+	generation     uint64               // increased on every invalidation, so that loads started before it are not stored
+	policy         *bufferedPolicy[K]   // picks the entries to evict, nil if the cache is unbounded
+	tagger         TaggerFunc[K, V]     // tags the stored values, nil if they are not
+	freshness      FreshnessFunc[K, V]  // tells how long the values stay fresh, nil if the maxAge decides
+	tags           tagIndex[K]          // the keys under every tag, empty without a tagger
+	weigher        WeigherFunc[K, V]    // weighs the stored values, nil if they all weigh 1
+	maxWeight      int64                // max total weight of the entries, zero or less means unbounded
+	weights        weights[K]           // the weight of every entry, empty without WithMaxWeight
+	pins           map[K]struct{}       // the keys exempt from eviction, see Pin
+	hotKeys        hotKeyTracker[K]     // the lookups of every key over a sliding window, see WithHotKeys
+	hitRatioAlert  *hitRatioAlerter     // nil unless WithHitRatioAlert was used
+	logger         *slog.Logger         // nil if nothing is logged, see WithLogger
+	slowLoad       time.Duration        // how long a load takes before it is logged as slow
+	invalidator    Invalidator          // tells the other instances about the invalidations, nil if there are none
+	changes        changeRegistry[K, V] // the subscriptions to the changes of the values, see Subscribe
+	history        historyIndex[K, V]   // the last values of every key, empty without WithHistory
+	adaptive       adaptiveMaxAges[K]   // the maxAge every key adapted to, empty without WithAdaptiveMaxAge
+	refreshLocker  RefreshLocker        // leases the background refreshes, nil if they aren't coordinated
+	refreshLockTTL time.Duration
+	maxEntries     int               // max number of entries kept, zero or less means unbounded
+	flights        flightGroup[K, V] // coalesces concurrent misses for the same key
+	maxConcurrency int               // max parallel loads in a batch, zero or less means unbounded
+	counters       counters
+	observer       Observer
+	clock          Clock
+	snapshots      *snapshotter   // nil unless WithSnapshots was used
+	stop           chan struct{}  // closed by Close to stop the background goroutines
+	background     sync.WaitGroup // the background goroutines, Close waits for them
+	closeOnce      sync.Once
+	listeners      listeners[K, V]
+	mode           atomic.Int32 // the Mode of the cache
+	drain          drain        // the loads in flight, for Shutdown
+}
+
+// NewCache creates a cache in front of loader, configured with opts
+// Values are kept for DefaultMaxAge unless another maxAge is given with WithMaxAge
+func NewCache[K comparable, V any](loader LoaderFunc[K, V], opts ...Option) *Cache[K, V] {
+	cfg := newConfig(opts)
+	checkStringKeys[K](cfg.invalidator, cfg.refreshLocker)
+	c := &Cache[K, V]{
+		loader:         loader,
+		bulkLoader:     bulkLoaderFor[K, V](cfg.bulkLoader),
+		tagger:         taggerFor[K, V](cfg.tagger),
+		weigher:        weigherFor[K, V](cfg.weigher),
+		maxWeight:      cfg.maxWeight,
+		hotKeys:        hotKeyTracker[K]{window: cfg.hotKeyWindow},
+		logger:         cfg.logger,
+		slowLoad:       cfg.slowLoad,
+		freshness:      freshnessFor[K, V](cfg.freshness),
+		history:        historyIndex[K, V]{size: cfg.historySize},
+		adaptive:       adaptiveMaxAges[K]{min: cfg.adaptiveMin, max: cfg.adaptiveMax},
+		invalidator:    cfg.invalidator,
+		refreshLocker:  cfg.refreshLocker,
+		refreshLockTTL: cfg.refreshLockTTL,
+		maxBulkSize:    cfg.maxBulkSize,
+		limiter:        limiterFor(cfg),
+		loadTimeout:    cfg.loadTimeout,
+		hedger:         newHedger(cfg.hedgeDelay, cfg.maxHedges),
+		maxAge:         cfg.maxAge,
+		maxStale:       cfg.maxStale,
+		staleIfError:   cfg.staleIfError,
+		hardTTL:        cfg.hardTTL,
+		refreshAhead:   cfg.refreshAhead,
+		earlyBeta:      cfg.earlyBeta,
+		jitter:         cfg.jitter,
+		negativeMaxAge: cfg.negativeMaxAge,
+		isNegative:     cfg.isNegative,
+		classify:       cfg.classifier,
+		random:         rand.Float64,
+		store:          storeFor[K, V](cfg.store, cfg.shards),
+		maxAges:        map[K]time.Duration{},
+		maxEntries:     cfg.maxEntries,
+		maxConcurrency: cfg.maxConcurrency,
+		observer:       cfg.observer,
+		clock:          cfg.clock,
+		stop:           make(chan struct{}),
+	}
+	if c.maxEntries > 0 || c.maxWeight > 0 {
+		c.policy = newBufferedPolicy(evictionPolicyFor[K](cfg.policy), &c.mu)
+	}
+	if cfg.hitRatioAlert != nil {
+		c.hitRatioAlert = &hitRatioAlerter{alert: *cfg.hitRatioAlert}
+	}
+	c.startSnapshots(cfg)
+	c.every(cfg.janitorInterval, func() { c.purgeExpired() })
+	c.watchInvalidations()
+	return c
+}
+
+// Get gets the value for the key, either from the cache or the loader if it was not cached or too old
+// Concurrent calls for the same key share one load, which gets the values of the context of the caller that
+// started it. A caller whose ctx is done returns ctx.Err() right away, the load goes on for the other callers
+// and is only cancelled once all of them gave up. opts change how this lookup uses the cache, see CallOption
+func (c *Cache[K, V]) Get(ctx context.Context, key K, opts ...CallOption) (V, error) {
+	entry, ok := c.store.Get(key)
+	value, _, err := c.lookupWith(ctx, key, entry, ok, c.loader, newCallConfig(opts))
+	return value, err
+}
+
+// GetWith is like Get, but loads the value with loader instead of the loader of the cache on a miss, for callers
+// that have a cheaper way to get it. The value is cached as usual, and a miss joins the load in flight for key
+// if there is one, whatever its loader. Background refreshes still use the loader of the cache
+func (c *Cache[K, V]) GetWith(ctx context.Context, key K, loader LoaderFunc[K, V]) (V, error) {
+	entry, ok := c.store.Get(key)
+	value, _, err := c.lookupWith(ctx, key, entry, ok, loader, callConfig{})
+	return value, err
+}
+
+// lookup is Get once the entry for key was read from the store, it notifies the observer
+// It also tells whether the value came from the cache
+func (c *Cache[K, V]) lookup(ctx context.Context, key K, entry Entry[V], ok bool) (V, bool, error) {
+	return c.lookupWith(ctx, key, entry, ok, c.loader, callConfig{})
+}
+
+// lookupWith is lookup using loader on a miss, as call asks
+func (c *Cache[K, V]) lookupWith(ctx context.Context, key K, entry Entry[V], ok bool, loader LoaderFunc[K, V], call callConfig) (V, bool, error) {
+	start := c.clock.Now()
+	ctx, end := c.observer.StartLookup(ctx, key)
+	c.requested(key)
+	value, hit, stale, err := c.get(ctx, key, entry, ok, loader, call)
+	end(hit, err)
+	c.looked(outcomeOf(hit, stale, err), start)
+	return value, hit, err
+}
+
+// get returns the value for key given what the store has for it, loading it with loader if necessary
+// It also tells whether the value came from the cache, and whether it was stale
+func (c *Cache[K, V]) get(ctx context.Context, key K, entry Entry[V], ok bool, loader LoaderFunc[K, V], call callConfig) (V, bool, bool, error) {
+	switch c.Mode() {
+	case Passthrough:
+		value, err := c.passthrough(ctx, key, loader, call)
+		return value, false, false, err
+	case Frozen:
+		value, err := c.frozen(key, entry, ok)
+		return value, err == nil, err == nil && c.expired(entry, entry.MaxAge, c.clock.Now()), err
+	}
+	now := c.clock.Now()
+	if call.forceRefresh && !call.cacheOnly {
+		ok = false // what is cached is neither served nor fallen back to
+	}
+	if ok && !c.expired(entry, entry.MaxAge, now) {
+		if entry.Err != nil {
+			c.hit(key)
+			var zero V
+			return zero, true, false, entry.Err
+		}
+		if c.refreshesAhead(entry, now) || c.expiresEarly(entry, now) {
+			c.refresh(key)
+		}
+		c.hit(key)
+		return entry.Value, true, false, nil
+	}
+	maxStale := max(c.maxStale, call.maxStaleness)
+	if ok && entry.Err == nil && maxStale > 0 && !c.expired(entry, entry.MaxAge+maxStale, now) {
+		c.refresh(key)
+		c.hit(key)
+		return entry.Value, true, true, nil
+	}
+	c.missed(key)
+	if call.cacheOnly {
+		var zero V
+		return zero, false, false, ErrNotCached
+	}
+	value, err := c.flights.do(ctx, key, func(ctx context.Context) (V, error) {
+		return c.loadWith(ctx, key, loader)
+	})
+	if errors.Is(err, errRefreshLocked) {
+		// the lookup joined a refresh that another instance does, it can't wait for it
+		value, err = c.loadWith(ctx, key, loader)
+	}
+	if c.servesStaleOnError(entry, ok, err) {
+		return entry.Value, false, true, nil
+	}
+	return value, false, false, err
+}
+
+// servesStaleOnError tells if the old entry should be served instead of the load error, see WithStaleIfError
+func (c *Cache[K, V]) servesStaleOnError(entry Entry[V], ok bool, err error) bool {
+	if err == nil || !ok || entry.Err != nil || c.staleIfError <= 0 || c.expired(entry, entry.MaxAge+c.staleIfError, c.clock.Now()) {
+		return false
+	}
+	if c.classify(err) == Permanent {
+		return false // the key is gone, its last value with it
+	}
+	c.counters.staleServed.Add(1)
+	return true
+}
+
+// refreshesAhead tells if a fresh entry is in the last part of its maxAge, where hits refresh it
+func (c *Cache[K, V]) refreshesAhead(entry Entry[V], now time.Time) bool {
+	if c.refreshAhead <= 0 {
+		return false
+	}
+	return now.Sub(entry.FetchedAt) >= entry.MaxAge-time.Duration(float64(entry.MaxAge)*c.refreshAhead)
+}
+
+// expiresEarly decides whether a fresh entry should be refreshed anyway, following the XFetch algorithm:
+// the closer the entry is to expiring and the longer it took to load, the more likely it is to be refreshed,
+// so that the refreshes of a popular key are spread out before it actually expires
+func (c *Cache[K, V]) expiresEarly(entry Entry[V], now time.Time) bool {
+	if c.earlyBeta <= 0 || entry.LoadTime <= 0 {
+		return false
+	}
+	remaining := entry.MaxAge - now.Sub(entry.FetchedAt)
+	gap := -float64(entry.LoadTime) * c.earlyBeta * math.Log(1-c.random())
+	return gap >= float64(remaining)
+}
+
+// refresh loads the value for key in the background, unless it is already being loaded
+func (c *Cache[K, V]) refresh(key K) {
+	c.flights.start(key, func() (V, error) {
+		return c.refreshLocked(key, func() (V, error) {
+			return c.load(context.Background(), key)
+		})
+	})
+}
+
+// hit records that the value for key was returned from the cache
+func (c *Cache[K, V]) hit(key K) {
+	c.mu.RLock()
+	policy := c.policy
+	c.mu.RUnlock()
+	if policy != nil {
+		policy.OnAccess(key) // buffered, the pinned and removed keys are skipped when it is replayed
+	}
+	now := c.clock.Now()
+	c.counters.hits.Add(1)
+	c.counters.recent.bucket(now).hits.Add(1)
+	c.checkHitRatio(now)
+}
+
+// load gets the value from the loader and stores it in the cache, see loadWith
+func (c *Cache[K, V]) load(ctx context.Context, key K) (V, error) {
+	return c.loadWith(ctx, key, c.loader)
+}
+
+// loadWith gets the value from loader and stores it in the cache
+// If the cache was invalidated while loading, the value is returned but not stored, as it may be outdated
+// No lock is held while the loader runs, only the callers of the same key wait for it (see flightGroup)
+func (c *Cache[K, V]) loadWith(ctx context.Context, key K, loader LoaderFunc[K, V]) (V, error) {
+	if !c.drain.begin() {
+		var zero V
+		return zero, fmt.Errorf("loading [%v] : %w", key, ErrClosed)
+	}
+	defer c.drain.end()
+	if err := c.limiter.wait(ctx); err != nil {
+		var zero V
+		return zero, fmt.Errorf("loading [%v] : %w", key, err)
+	}
+	c.mu.RLock()
+	generation := c.generation
+	c.mu.RUnlock()
+	value, loadTime, source, err := c.fetch(ctx, key, loader)
+	var evicted []Event[K, V]
+	var previous Entry[V]
+	var refresh bool
+	c.mu.Lock()
+	if c.generation == generation {
+		if previous, refresh = c.replaces(key, err); refresh {
+			c.adapt(key, value, previous)
+		}
+		evicted = c.saveLoaded(key, value, err, loadTime, source)
+	}
+	c.mu.Unlock()
+	if refresh {
+		c.publishInvalidation([]K{key})
+	}
+	if err != nil {
+		c.emit(evicted...)
+		var zero V
+		return zero, err
+	}
+	c.emit(Event[K, V]{Key: key, Value: value, Reason: Loaded, Refresh: refresh})
+	if refresh {
+		c.emit(changed(key, value, previous)...)
+	}
+	c.emit(evicted...)
+	return value, nil
+}
+
+// fetch calls loader for key and returns what it returned, with how long it took and the source that supplied
+// the value. Nothing is stored, the caller waits for the rate limiter first
+func (c *Cache[K, V]) fetch(ctx context.Context, key K, loader LoaderFunc[K, V]) (V, time.Duration, string, error) {
+	c.counters.loads.Add(1)
+	loadCtx, end := c.observer.StartLoad(ctx, key)
+	loadCtx, sink := withSourceSink(loadCtx)
+	start := c.clock.Now()
+	value, err := callWithTimeout(loadCtx, c.loadTimeout, func(ctx context.Context) (V, error) {
+		return hedge(ctx, c.hedger, c.allowsHedge, func(ctx context.Context) (V, error) {
+			return loader(ctx, key)
+		})
+	})
+	loadTime := c.clock.Now().Sub(start)
+	c.counters.loaded(loadTime)
+	end(err)
+	if err != nil {
+		c.counters.loadErrors.Add(1)
+		err = fmt.Errorf("loading [%v] : %w", key, err)
+	}
+	c.logLoad(ctx, slog.Any("key", key), loadTime, err)
+	return value, loadTime, sink.get(), err
+}
+
+// replaces tells if storing the value loaded for key (err is the load error) replaces a cached one, which the
+// other instances must drop, the Loaded and Changed events tell about and the adaptive maxAge adapts to, and
+// returns it. It is only checked when one of them needs it, c.mu must be held
+func (c *Cache[K, V]) replaces(key K, err error) (Entry[V], bool) {
+	if err != nil || (c.invalidator == nil && !c.adaptive.enabled() && !c.listeners.has(Loaded) && !c.listeners.has(Changed)) {
+		return Entry[V]{}, false
+	}
+	return c.store.Get(key)
+}
+
+// saveLoaded stores what the loader returned for key: the value, or the error if it is cached as a negative entry
+// c.mu must be held for writing
+func (c *Cache[K, V]) saveLoaded(key K, value V, err error, loadTime time.Duration, source string) (evicted []Event[K, V]) {
+	if err == nil {
+		entry := c.valueEntry(key, value, c.clock.Now())
+		entry.LoadTime, entry.Source = loadTime, source
+		return c.save(key, entry)
+	}
+	if c.negativeMaxAge > 0 && c.isNegative(err) {
+		return c.save(key, Entry[V]{Err: err, FetchedAt: c.clock.Now(), MaxAge: c.negativeMaxAge})
+	}
+	return nil
+}
+
+// save stores the entry, evicting the entries chosen by the policy if the cache grows past maxEntries or maxWeight
+// c.mu must be held for writing
+func (c *Cache[K, V]) save(key K, entry Entry[V]) (evicted []Event[K, V]) {
+	c.store.Set(key, entry)
+	c.tag(key, entry)
+	c.weigh(key, entry)
+	if entry.Err == nil {
+		c.changes.publish(key, entry.Value, entry.FetchedAt)
+		c.history.observe(key, entry.Value, entry.FetchedAt)
+	}
+	if c.policy == nil {
+		return nil
+	}
+	if !c.pinned(key) {
+		c.policy.OnInsert(key)
+	}
+	return c.evictOverflow()
+}
+
+// evictOverflow evicts the entries chosen by the policy until the cache is back within maxEntries and maxWeight,
+// or until only pinned entries are left
+// c.mu must be held for writing
+func (c *Cache[K, V]) evictOverflow() (evicted []Event[K, V]) {
+	for c.overflows() {
+		victim, ok := c.policy.Victim()
+		if !ok {
+			return evicted
+		}
+		c.policy.OnRemove(victim)
+		if entry, ok := c.store.Get(victim); ok {
+			evicted = append(evicted, Event[K, V]{Key: victim, Value: entry.Value, Err: entry.Err, Reason: Evicted})
+		}
+		c.store.Delete(victim)
+		c.forget(victim)
+		c.forgetPast(victim)
+		c.counters.evictions.Add(1)
+	}
+	return evicted
+}
+
+// GetMany gets the values for several keys at once, loading the missing ones in parallel
+// Values are returned in the same order as the keys
+// If any of the keys fails, it returns a *BatchError holding the failure of every key that could not be loaded
+func (c *Cache[K, V]) GetMany(ctx context.Context, keys ...K) ([]V, error) {
+	ctx, end := c.observer.StartBatch(ctx, len(keys))
+	results, err := c.getMany(ctx, keys)
+	end(err)
+	return results, err
+}
+
+// getMany is GetMany without notifying the observer about the batch
+func (c *Cache[K, V]) getMany(ctx context.Context, keys []K) ([]V, error) {
+	results, errs := c.getEach(ctx, keys)
+	if batchErr := newBatchError(keys, errs); batchErr != nil {
+		return nil, batchErr
+	}
+	return results, nil
+}
+
+// getEach gets the value or the error of every key, in the same order
+func (c *Cache[K, V]) getEach(ctx context.Context, keys []K) ([]V, []error) {
+	results := make([]V, len(keys))
+	errs := make([]error, len(keys))
+	c.forEach(ctx, keys, func(i int, value V, err error) {
+		results[i], errs[i] = value, err
+	})
+	return results, errs
+}
+
+// forEach gets every key and calls deliver with its index, value and error as soon as they are known
+// deliver is called from several goroutines, but only once per index, repeated keys are only looked up once
+func (c *Cache[K, V]) forEach(ctx context.Context, keys []K, deliver func(i int, value V, err error)) {
+	if unique, occurrences := dedupe(keys); len(unique) < len(keys) {
+		c.forEach(ctx, unique, func(u int, value V, err error) {
+			for _, i := range occurrences[u] {
+				deliver(i, value, err)
+			}
+		})
+		return
+	}
+	entries, found := c.getEntries(keys)
+	if c.bulkLoader != nil && c.Mode() == Normal {
+		c.bulkGet(ctx, keys, entries, found, deliver)
+		return
+	}
+	c.parallel(len(keys), func(i int) {
+		value, _, err := c.lookup(ctx, keys[i], entries[i], found[i])
+		deliver(i, value, err)
+	})
+}
+
+// dedupe returns the distinct keys in the order they first appear, and the indexes in keys of each of them
+func dedupe[K comparable](keys []K) (unique []K, occurrences [][]int) {
+	seen := make(map[K]int, len(keys))
+	for i, key := range keys {
+		u, ok := seen[key]
+		if !ok {
+			u = len(unique)
+			seen[key] = u
+			unique = append(unique, key)
+			occurrences = append(occurrences, nil)
+		}
+		occurrences[u] = append(occurrences[u], i)
+	}
+	return unique, occurrences
+}
+
+// parallel calls fn for every index below n from as many goroutines as maxConcurrency allows
+func (c *Cache[K, V]) parallel(n int, fn func(i int)) {
+	c.mu.RLock()
+	maxConcurrency := c.maxConcurrency
+	c.mu.RUnlock()
+	workers := n
+	if maxConcurrency > 0 && maxConcurrency < workers {
+		workers = maxConcurrency
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// getEntries reads the entries for keys from the store, in one call if the store supports it
+func (c *Cache[K, V]) getEntries(keys []K) ([]Entry[V], []bool) {
+	if batchStore, ok := c.store.(BatchStore[K, V]); ok {
+		return batchStore.GetMany(keys)
+	}
+	entries := make([]Entry[V], len(keys))
+	found := make([]bool, len(keys))
+	for i, key := range keys {
+		entries[i], found[i] = c.store.Get(key)
+	}
+	return entries, found
+}
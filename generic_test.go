@@ -0,0 +1,93 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingLoader is a loader for int keys that counts how many times it was called
+type countingLoader struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (l *countingLoader) load(ctx context.Context, key int) (string, error) {
+	l.mu.Lock()
+	l.calls++
+	l.mu.Unlock()
+	if key < 0 {
+		return "", fmt.Errorf("negative key")
+	}
+	return fmt.Sprintf("value %v", key), nil
+}
+
+func (l *countingLoader) getCalls() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.calls
+}
+
+// Check that the generic cache works with other key and value types
+func TestCache_CachesValuesOfAnyType(t *testing.T) {
+	loader := &countingLoader{}
+	cache := NewCache(loader.load, WithMaxAge(time.Minute))
+	for i := 0; i < 3; i++ {
+		value, err := cache.Get(context.Background(), 1)
+		if err != nil || value != "value 1" {
+			t.Errorf("expected value 1, got %v, %v", value, err)
+		}
+	}
+	values, err := cache.GetMany(context.Background(), 1, 2)
+	if err != nil || len(values) != 2 || values[0] != "value 1" || values[1] != "value 2" {
+		t.Errorf("expected values 1 and 2, got %v, %v", values, err)
+	}
+	assertInt(t, 2, loader.getCalls(), "wrong number of loader calls")
+}
+
+// Check that batch errors keep the key type of the cache
+func TestCache_GetManyReportsFailedKeys(t *testing.T) {
+	loader := &countingLoader{}
+	cache := NewCache(loader.load, WithMaxAge(time.Minute))
+	_, err := cache.GetMany(context.Background(), 1, -1)
+	var batchErr *BatchError[int]
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected a *BatchError[int], got %v", err)
+	}
+	if len(batchErr.Errors) != 1 || batchErr.Errors[0].Key != -1 {
+		t.Errorf("expected key -1 to fail, got %v", err)
+	}
+}
+
+// Check that a policy keyed by the wrong type is reported when the cache is created
+func TestNewCache_PanicsOnPolicyKeyMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	loader := &countingLoader{}
+	NewCache(loader.load, WithMaxAge(time.Minute), WithMaxEntries(10), WithEvictionPolicy(NewLRUPolicy[string]()))
+}
+
+// Check that a key repeated in a batch is only looked up once, and every occurrence gets its value
+func TestGetMany_DeduplicatesKeys(t *testing.T) {
+	loader := &countingLoader{}
+	cache := NewCache(loader.load)
+	values, err := cache.GetMany(context.Background(), 1, 2, 1, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"value 1", "value 2", "value 1", "value 1", "value 2"}
+	for i := range expected {
+		if values[i] != expected[i] {
+			t.Errorf("wrong value %d, expected %q but got %q", i, expected[i], values[i])
+		}
+	}
+	assertInt(t, 2, loader.getCalls(), "there should be one load per distinct key")
+	stats := cache.Stats()
+	assertInt(t, 2, int(stats.Misses+stats.Hits), "there should be one lookup per distinct key")
+}
@@ -0,0 +1,199 @@
+// Package grpccache serves the prices of a cache over gRPC, and provides a client that is itself a PriceService,
+// so that the cache of one process can sit in front of the cache of another. It lives in its own package so that
+// the cache itself doesn't depend on gRPC
+package grpccache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+	"github.com/MadHive/deviget_challenge/grpccache/pricepb"
+)
+
+// Server serves the prices of a cache, register it with pricepb.RegisterPriceServiceServer
+type Server struct {
+	pricepb.UnimplementedPriceServiceServer
+	cache *sample1.TransparentCache
+}
+
+// NewServer returns a server for the prices of cache
+func NewServer(cache *sample1.TransparentCache) *Server {
+	return &Server{cache: cache}
+}
+
+func (s *Server) GetPrice(ctx context.Context, req *pricepb.GetPriceRequest) (*pricepb.GetPriceResponse, error) {
+	price, info, err := s.cache.GetPriceWithInfoCtx(ctx, req.ItemCode)
+	if err != nil {
+		return nil, status.Error(codeOf(err), err.Error())
+	}
+	return &pricepb.GetPriceResponse{
+		ItemCode:  req.ItemCode,
+		Price:     price,
+		Cached:    info.Cached,
+		AgeMillis: info.Age.Milliseconds(),
+		Source:    info.Source,
+	}, nil
+}
+
+func (s *Server) GetPrices(ctx context.Context, req *pricepb.GetPricesRequest) (*pricepb.GetPricesResponse, error) {
+	results := s.cache.GetPriceResultsForCtx(ctx, req.ItemCodes...)
+	resp := &pricepb.GetPricesResponse{Results: make([]*pricepb.PriceResult, len(results))}
+	for i, result := range results {
+		resp.Results[i] = &pricepb.PriceResult{ItemCode: result.Key, Price: result.Value}
+		if result.Err != nil {
+			resp.Results[i] = &pricepb.PriceResult{ItemCode: result.Key, Code: int32(codeOf(result.Err)), Error: result.Err.Error()}
+		}
+	}
+	return resp, nil
+}
+
+// watchBuffer is how many updates a Watch stream that is slow to send them keeps, the next ones are dropped
+const watchBuffer = 64
+
+// Watch streams the changes of the prices of the items until the client goes away, see Cache.Subscribe
+func (s *Server) Watch(req *pricepb.WatchRequest, stream grpc.ServerStreamingServer[pricepb.PriceUpdate]) error {
+	subscription := s.cache.Subscribe(watchBuffer, req.ItemCodes...)
+	defer subscription.Close()
+	for {
+		select {
+		case change := <-subscription.C:
+			update := &pricepb.PriceUpdate{ItemCode: change.Key, Price: change.Value, FetchedAt: timestamppb.New(change.FetchedAt)}
+			if change.HadValue {
+				update.PreviousPrice = &change.Previous
+			}
+			if err := stream.Send(update); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// Client is a PriceService (and a BulkPriceService) pricing items through a remote Server
+type Client struct {
+	client pricepb.PriceServiceClient
+}
+
+// NewClient returns a client of the server at the other end of conn
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{client: pricepb.NewPriceServiceClient(conn)}
+}
+
+func (c *Client) GetPriceFor(itemCode string) (float64, error) {
+	return c.GetPriceForCtx(context.Background(), itemCode)
+}
+
+// GetPriceForCtx returns the price of itemCode, the errors of the server are mapped back to sample1.ErrNotFound,
+// sample1.ErrRateLimited, sample1.ErrUnavailable and the context errors, and the other Permanent errors come back
+// marked with sample1.MarkPermanent, so that the local cache handles them as its own
+// An open circuit of the server comes back as sample1.ErrUnavailable: it can't be told from a server that is down
+func (c *Client) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	resp, err := c.client.GetPrice(ctx, &pricepb.GetPriceRequest{ItemCode: itemCode})
+	if err != nil {
+		return 0, errorOf(status.Convert(err).Code(), status.Convert(err).Message())
+	}
+	return resp.Price, nil
+}
+
+func (c *Client) GetPricesFor(itemCodes ...string) ([]float64, error) {
+	return c.GetPricesForCtx(context.Background(), itemCodes...)
+}
+
+// GetPricesForCtx returns the prices of itemCodes with a single call, or a *sample1.BatchError[string] with the
+// items the server couldn't price
+func (c *Client) GetPricesForCtx(ctx context.Context, itemCodes ...string) ([]float64, error) {
+	resp, err := c.client.GetPrices(ctx, &pricepb.GetPricesRequest{ItemCodes: itemCodes})
+	if err != nil {
+		return nil, errorOf(status.Convert(err).Code(), status.Convert(err).Message())
+	}
+	if len(resp.Results) != len(itemCodes) {
+		return nil, fmt.Errorf("asked for %d prices but got %d", len(itemCodes), len(resp.Results))
+	}
+	prices := make([]float64, len(itemCodes))
+	var batchErr sample1.BatchError[string]
+	for i, result := range resp.Results {
+		if code := codes.Code(result.Code); code != codes.OK {
+			batchErr.Errors = append(batchErr.Errors, &sample1.KeyError[string]{Key: itemCodes[i], Err: errorOf(code, result.Error)})
+			continue
+		}
+		prices[i] = result.Price
+	}
+	if len(batchErr.Errors) > 0 {
+		return nil, &batchErr
+	}
+	return prices, nil
+}
+
+// Watch calls fn with every change of the prices of itemCodes that the server streams, until ctx is done or the
+// stream breaks, and returns why it stopped
+func (c *Client) Watch(ctx context.Context, itemCodes []string, fn func(sample1.PriceChange)) error {
+	stream, err := c.client.Watch(ctx, &pricepb.WatchRequest{ItemCodes: itemCodes})
+	if err != nil {
+		return errorOf(status.Convert(err).Code(), status.Convert(err).Message())
+	}
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			return err
+		}
+		if err != nil {
+			return errorOf(status.Convert(err).Code(), status.Convert(err).Message())
+		}
+		change := sample1.PriceChange{Key: update.ItemCode, Value: update.Price, FetchedAt: update.FetchedAt.AsTime()}
+		if update.PreviousPrice != nil {
+			change.Previous, change.HadValue = *update.PreviousPrice, true
+		}
+		fn(change)
+	}
+}
+
+// codeOf returns the gRPC code for a lookup that failed with err
+func codeOf(err error) codes.Code {
+	switch {
+	case errors.Is(err, sample1.ErrNotFound):
+		return codes.NotFound
+	case sample1.DefaultErrorClassifier(err) == sample1.Permanent:
+		return codes.FailedPrecondition
+	case errors.Is(err, sample1.ErrRateLimited):
+		return codes.ResourceExhausted
+	case errors.Is(err, sample1.ErrCircuitOpen), errors.Is(err, sample1.ErrUnavailable):
+		return codes.Unavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	default:
+		return codes.Unknown
+	}
+}
+
+// errorOf returns the error for a gRPC code and its message, the opposite of codeOf as far as it can be told
+func errorOf(code codes.Code, msg string) error {
+	var sentinel error
+	switch code {
+	case codes.NotFound:
+		sentinel = sample1.ErrNotFound
+	case codes.ResourceExhausted:
+		sentinel = sample1.ErrRateLimited
+	case codes.Unavailable:
+		sentinel = sample1.ErrUnavailable
+	case codes.DeadlineExceeded:
+		sentinel = context.DeadlineExceeded
+	case codes.Canceled:
+		sentinel = context.Canceled
+	case codes.FailedPrecondition:
+		return sample1.MarkPermanent(fmt.Errorf("remote cache : %s", msg))
+	default:
+		return fmt.Errorf("remote cache : %v : %s", code, msg)
+	}
+	return fmt.Errorf("remote cache : %s : %w", msg, sentinel)
+}
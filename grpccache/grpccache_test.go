@@ -0,0 +1,167 @@
+package grpccache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+	"github.com/MadHive/deviget_challenge/grpccache/pricepb"
+)
+
+type fakePriceService map[string]float64
+
+func (f fakePriceService) GetPriceFor(itemCode string) (float64, error) {
+	price, ok := f[itemCode]
+	if !ok {
+		return 0, fmt.Errorf("unknown item [%v] : %w", itemCode, sample1.ErrNotFound)
+	}
+	return price, nil
+}
+
+// newTestClient serves cache in memory and returns a client of it
+func newTestClient(t *testing.T, cache *sample1.TransparentCache) *Client {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	pricepb.RegisterPriceServiceServer(server, NewServer(cache))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewClient(conn)
+}
+
+// Check that a cache can sit in front of a remote cache, errors included
+func TestClient_ChainsCaches(t *testing.T) {
+	remote := sample1.NewTransparentCache(fakePriceService{"p1": 5, "p2": 7}, time.Minute)
+	local := sample1.NewTransparentCache(newTestClient(t, remote), time.Minute)
+
+	price, err := local.GetPriceFor("p1")
+	if err != nil || price != 5 {
+		t.Errorf("expected 5 but got %v, %v", price, err)
+	}
+	if _, err := local.GetPriceFor("unknown"); !errors.Is(err, sample1.ErrNotFound) {
+		t.Errorf("expected a not found error but got %v", err)
+	}
+	if _, _, ok := remote.Peek("p1"); !ok {
+		t.Errorf("expected the remote cache to have p1")
+	}
+}
+
+// Check that a batch is priced with a single call, and that its failures come back per item
+func TestClient_GetPricesFor(t *testing.T) {
+	remote := sample1.NewTransparentCache(fakePriceService{"p1": 5, "p2": 7}, time.Minute)
+	client := newTestClient(t, remote)
+
+	prices, err := client.GetPricesFor("p1", "p2")
+	if err != nil || len(prices) != 2 || prices[0] != 5 || prices[1] != 7 {
+		t.Errorf("expected [5 7] but got %v, %v", prices, err)
+	}
+
+	_, err = client.GetPricesFor("p1", "unknown")
+	var batchErr *sample1.BatchError[string]
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[0].Key != "unknown" {
+		t.Fatalf("expected a batch error for the unknown item but got %v", err)
+	}
+	if !errors.Is(batchErr.Errors[0], sample1.ErrNotFound) {
+		t.Errorf("expected a not found error but got %v", batchErr.Errors[0])
+	}
+}
+
+// Check that the server tells whether the price came from its cache
+func TestServer_GetPrice(t *testing.T) {
+	server := NewServer(sample1.NewTransparentCache(fakePriceService{"p1": 5}, time.Minute))
+	for _, wantCached := range []bool{false, true} {
+		resp, err := server.GetPrice(context.Background(), &pricepb.GetPriceRequest{ItemCode: "p1"})
+		if err != nil || resp.Price != 5 || resp.Cached != wantCached {
+			t.Errorf("expected 5 with cached %v but got %v, %v", wantCached, resp, err)
+		}
+	}
+}
+
+// Check that a watcher gets the changes of the prices it watches, and stops with its context
+func TestClient_Watch(t *testing.T) {
+	remote := sample1.NewTransparentCache(fakePriceService{"p1": 5}, time.Minute)
+	remote.Set("p1", 5)
+	client := newTestClient(t, remote)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan sample1.PriceChange, 100)
+	done := make(chan error)
+	go func() {
+		done <- client.Watch(ctx, []string{"p1"}, func(change sample1.PriceChange) { changes <- change })
+	}()
+
+	// the server may not have subscribed yet, change the price until the change comes through
+	var change sample1.PriceChange
+	for price, received := 6.0, false; !received; price++ {
+		remote.Set("p1", price)
+		select {
+		case change = <-changes:
+			received = true
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if change.Key != "p1" || !change.HadValue || change.Previous == change.Value || change.FetchedAt.IsZero() {
+		t.Errorf("unexpected change %+v", change)
+	}
+	remote.Set("other", 1)
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the watch to stop with its context but got %v", err)
+	}
+	close(changes)
+	for change := range changes {
+		if change.Key != "p1" {
+			t.Errorf("only p1 is watched, got %+v", change)
+		}
+	}
+}
+
+// failingPriceService fails every item with its error
+type failingPriceService map[string]error
+
+func (f failingPriceService) GetPriceFor(itemCode string) (float64, error) {
+	return 0, f[itemCode]
+}
+
+// Check that the permanent and transient errors of the server keep their class on the client
+func TestClient_ErrorClasses(t *testing.T) {
+	remote := sample1.NewTransparentCache(failingPriceService{
+		"bad":  sample1.MarkPermanent(errors.New("invalid item code")),
+		"down": fmt.Errorf("pricing : %w", sample1.ErrUnavailable),
+	}, time.Minute)
+	client := newTestClient(t, remote)
+	_, err := client.GetPriceFor("bad")
+	if sample1.DefaultErrorClassifier(err) != sample1.Permanent {
+		t.Errorf("expected a permanent error but got %v", err)
+	}
+	_, err = client.GetPriceFor("down")
+	if !errors.Is(err, sample1.ErrUnavailable) || sample1.DefaultErrorClassifier(err) != sample1.Transient {
+		t.Errorf("expected a transient unavailable error but got %v", err)
+	}
+	_, err = client.GetPricesFor("bad", "down")
+	var batchErr *sample1.BatchError[string]
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 2 {
+		t.Fatalf("expected a batch error for both items but got %v", err)
+	}
+	for i, want := range []sample1.ErrorClass{sample1.Permanent, sample1.Transient} {
+		if got := sample1.DefaultErrorClassifier(batchErr.Errors[i].Err); got != want {
+			t.Errorf("[%v] : expected a %v error but got %v", batchErr.Errors[i].Key, want, batchErr.Errors[i].Err)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+// Package pricepb holds the protocol buffers of the price API of grpccache, generated from pricecache.proto
+package pricepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative pricecache.proto
@@ -0,0 +1,213 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: pricecache.proto
+
+// The price API of a cache, served by grpccache.NewServer and consumed by grpccache.NewClient
+
+package pricepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PriceService_GetPrice_FullMethodName  = "/pricecache.v1.PriceService/GetPrice"
+	PriceService_GetPrices_FullMethodName = "/pricecache.v1.PriceService/GetPrices"
+	PriceService_Watch_FullMethodName     = "/pricecache.v1.PriceService/Watch"
+)
+
+// PriceServiceClient is the client API for PriceService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PriceServiceClient interface {
+	// GetPrice returns the price of one item, or a NOT_FOUND, RESOURCE_EXHAUSTED (rate limited),
+	// UNAVAILABLE (circuit open) or UNKNOWN status
+	GetPrice(ctx context.Context, in *GetPriceRequest, opts ...grpc.CallOption) (*GetPriceResponse, error)
+	// GetPrices returns the price or the error of several items, in the same order as the item codes
+	GetPrices(ctx context.Context, in *GetPricesRequest, opts ...grpc.CallOption) (*GetPricesResponse, error)
+	// Watch streams an update whenever one of the items is loaded with a different price, the first price
+	// loaded for an item that wasn't cached included
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PriceUpdate], error)
+}
+
+type priceServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPriceServiceClient(cc grpc.ClientConnInterface) PriceServiceClient {
+	return &priceServiceClient{cc}
+}
+
+func (c *priceServiceClient) GetPrice(ctx context.Context, in *GetPriceRequest, opts ...grpc.CallOption) (*GetPriceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPriceResponse)
+	err := c.cc.Invoke(ctx, PriceService_GetPrice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *priceServiceClient) GetPrices(ctx context.Context, in *GetPricesRequest, opts ...grpc.CallOption) (*GetPricesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPricesResponse)
+	err := c.cc.Invoke(ctx, PriceService_GetPrices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *priceServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PriceUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PriceService_ServiceDesc.Streams[0], PriceService_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, PriceUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PriceService_WatchClient = grpc.ServerStreamingClient[PriceUpdate]
+
+// PriceServiceServer is the server API for PriceService service.
+// All implementations must embed UnimplementedPriceServiceServer
+// for forward compatibility.
+type PriceServiceServer interface {
+	// GetPrice returns the price of one item, or a NOT_FOUND, RESOURCE_EXHAUSTED (rate limited),
+	// UNAVAILABLE (circuit open) or UNKNOWN status
+	GetPrice(context.Context, *GetPriceRequest) (*GetPriceResponse, error)
+	// GetPrices returns the price or the error of several items, in the same order as the item codes
+	GetPrices(context.Context, *GetPricesRequest) (*GetPricesResponse, error)
+	// Watch streams an update whenever one of the items is loaded with a different price, the first price
+	// loaded for an item that wasn't cached included
+	Watch(*WatchRequest, grpc.ServerStreamingServer[PriceUpdate]) error
+	mustEmbedUnimplementedPriceServiceServer()
+}
+
+// UnimplementedPriceServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPriceServiceServer struct{}
+
+func (UnimplementedPriceServiceServer) GetPrice(context.Context, *GetPriceRequest) (*GetPriceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPrice not implemented")
+}
+func (UnimplementedPriceServiceServer) GetPrices(context.Context, *GetPricesRequest) (*GetPricesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPrices not implemented")
+}
+func (UnimplementedPriceServiceServer) Watch(*WatchRequest, grpc.ServerStreamingServer[PriceUpdate]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedPriceServiceServer) mustEmbedUnimplementedPriceServiceServer() {}
+func (UnimplementedPriceServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafePriceServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PriceServiceServer will
+// result in compilation errors.
+type UnsafePriceServiceServer interface {
+	mustEmbedUnimplementedPriceServiceServer()
+}
+
+func RegisterPriceServiceServer(s grpc.ServiceRegistrar, srv PriceServiceServer) {
+	// If the following call pancis, it indicates UnimplementedPriceServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PriceService_ServiceDesc, srv)
+}
+
+func _PriceService_GetPrice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPriceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PriceServiceServer).GetPrice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PriceService_GetPrice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PriceServiceServer).GetPrice(ctx, req.(*GetPriceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PriceService_GetPrices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPricesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PriceServiceServer).GetPrices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PriceService_GetPrices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PriceServiceServer).GetPrices(ctx, req.(*GetPricesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PriceService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PriceServiceServer).Watch(m, &grpc.GenericServerStream[WatchRequest, PriceUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PriceService_WatchServer = grpc.ServerStreamingServer[PriceUpdate]
+
+// PriceService_ServiceDesc is the grpc.ServiceDesc for PriceService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PriceService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pricecache.v1.PriceService",
+	HandlerType: (*PriceServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetPrice",
+			Handler:    _PriceService_GetPrice_Handler,
+		},
+		{
+			MethodName: "GetPrices",
+			Handler:    _PriceService_GetPrices_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _PriceService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pricecache.proto",
+}
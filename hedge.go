@@ -0,0 +1,98 @@
+package sample1
+
+import (
+	"context"
+	"time"
+)
+
+// WithHedging makes a load that hasn't answered after delay send a second, identical call to the loader
+// and use whichever of them succeeds first, to cut the latency of the slowest calls
+// At most maxHedges hedged calls run at the same time across the cache (1 if zero or less), and a hedge is
+// skipped if WithRateLimit doesn't allow a call right away. Bulk loads are not hedged
+func WithHedging(delay time.Duration, maxHedges int) Option {
+	return func(c *config) {
+		c.hedgeDelay = delay
+		c.maxHedges = maxHedges
+	}
+}
+
+// hedger sends the hedged calls of a cache, a nil one never does
+type hedger struct {
+	delay time.Duration
+	slots chan struct{} // one per hedged call running
+}
+
+func newHedger(delay time.Duration, maxHedges int) *hedger {
+	if delay <= 0 {
+		return nil
+	}
+	if maxHedges <= 0 {
+		maxHedges = 1
+	}
+	return &hedger{delay: delay, slots: make(chan struct{}, maxHedges)}
+}
+
+// hedge calls fn, and calls it a second time if it hasn't answered after the hedger delay and allow agrees
+// It returns the first success, or the last error if both calls fail. The call that loses is cancelled
+func hedge[T any](ctx context.Context, h *hedger, allow func() bool, fn func(ctx context.Context) (T, error)) (T, error) {
+	if h == nil {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		value T
+		err   error
+	}
+	results := make(chan result, 2) // buffered, so that the losing call can finish if nobody is listening
+	call := func() {
+		value, err := fn(ctx)
+		results <- result{value: value, err: err}
+	}
+	go call()
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+	var zero T
+	select {
+	case r := <-results:
+		return r.value, r.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case <-timer.C:
+	}
+	pending := 1
+	select {
+	case h.slots <- struct{}{}:
+		if allow() {
+			pending++
+			go func() {
+				defer func() { <-h.slots }()
+				call()
+			}()
+		} else {
+			<-h.slots
+		}
+	default:
+	}
+	var r result
+	for ; pending > 0; pending-- {
+		select {
+		case r = <-results:
+			if r.err == nil {
+				return r.value, nil
+			}
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+	return r.value, r.err
+}
+
+// allowsHedge tells if the rate limit allows a hedged call right away, taking a token for it
+func (c *Cache[K, V]) allowsHedge() bool {
+	if c.limiter == nil {
+		return true
+	}
+	_, ok := c.limiter.reserve(false)
+	return ok
+}
@@ -0,0 +1,79 @@
+package sample1
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hedgedLoader blocks its first call until it is cancelled, and answers the others right away
+type hedgedLoader struct {
+	mu        sync.Mutex
+	calls     int
+	cancelled chan struct{}
+}
+
+func newHedgedLoader() *hedgedLoader {
+	return &hedgedLoader{cancelled: make(chan struct{})}
+}
+
+func (l *hedgedLoader) load(ctx context.Context, key string) (float64, error) {
+	l.mu.Lock()
+	l.calls++
+	first := l.calls == 1
+	l.mu.Unlock()
+	if first {
+		<-ctx.Done()
+		close(l.cancelled)
+		return 0, ctx.Err()
+	}
+	return 5, nil
+}
+
+func (l *hedgedLoader) getCalls() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.calls
+}
+
+// Check that a slow call is hedged, the hedge answers and the slow call is cancelled
+func TestWithHedging_HedgesSlowCalls(t *testing.T) {
+	loader := newHedgedLoader()
+	cache := NewCache(loader.load, WithHedging(10*time.Millisecond, 1))
+	value, err := cache.Get(context.Background(), "p1")
+	if err != nil || value != 5 {
+		t.Fatalf("expected the hedge to answer, got %v, %v", value, err)
+	}
+	assertInt(t, 2, loader.getCalls(), "wrong number of loader calls")
+	select {
+	case <-loader.cancelled:
+	case <-time.After(time.Second):
+		t.Error("the slow call should have been cancelled")
+	}
+	assertInt(t, 1, int(cache.Stats().Loads), "a hedged load should count once")
+}
+
+// Check that fast calls aren't hedged
+func TestWithHedging_FastCalls(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}, "p2": {err: ErrNotFound}}}
+	cache := New(mockService, WithHedging(time.Second, 1))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	cache.GetPriceFor("p2")
+	assertInt(t, 2, mockService.getNumCalls(), "no call should have been hedged")
+}
+
+// Check that no hedge is sent while the max number of hedges are running, or the rate limit is reached
+func TestWithHedging_RespectsLimits(t *testing.T) {
+	loader := newHedgedLoader()
+	cache := NewCache(loader.load, WithHedging(time.Millisecond, 1), WithLoadTimeout(50*time.Millisecond))
+	cache.hedger.slots <- struct{}{}
+	cache.Get(context.Background(), "p1")
+	assertInt(t, 1, loader.getCalls(), "no hedge should have been sent while the other one runs")
+
+	loader = newHedgedLoader()
+	cache = NewCache(loader.load, WithHedging(time.Millisecond, 1), WithLoadTimeout(50*time.Millisecond),
+		WithRateLimit(0.001, 1))
+	cache.Get(context.Background(), "p1")
+	assertInt(t, 1, loader.getCalls(), "no hedge should have been sent beyond the rate limit")
+}
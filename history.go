@@ -0,0 +1,94 @@
+package sample1
+
+import (
+	"reflect"
+	"time"
+)
+
+// Observation is a value a key had, and when it was first loaded (or set) with it
+type Observation[V any] struct {
+	Value     V
+	FetchedAt time.Time
+}
+
+// PriceObservation is an Observation of the price of an item
+type PriceObservation = Observation[float64]
+
+// WithHistory makes the cache remember the last n values of every key (see History), so that callers can tell
+// how a value changed without keeping a store of their own
+// Loads returning the value a key already had aren't new observations. The history of a key outlives its
+// invalidation, it only goes away when the key is evicted, dropped by the janitor or the cache is cleared
+func WithHistory(n int) Option {
+	return func(c *config) {
+		c.historySize = n
+	}
+}
+
+// historyIndex keeps the last values of every key, it is guarded by the mutex of the cache
+type historyIndex[K comparable, V any] struct {
+	size  int // values kept per key, no history is kept if zero or less
+	byKey map[K]*historyRing[V]
+}
+
+// historyRing is the history of a key, a ring buffer of at most size observations
+type historyRing[V any] struct {
+	observations []Observation[V]
+	start        int // where the oldest observation is once the ring is full
+}
+
+// observe records that key was stored with value at fetchedAt, unless value is the last one recorded
+func (h *historyIndex[K, V]) observe(key K, value V, fetchedAt time.Time) {
+	if h.size <= 0 {
+		return
+	}
+	if h.byKey == nil {
+		h.byKey = map[K]*historyRing[V]{}
+	}
+	ring := h.byKey[key]
+	if ring == nil {
+		ring = &historyRing[V]{observations: make([]Observation[V], 0, h.size)}
+		h.byKey[key] = ring
+	}
+	observation := Observation[V]{Value: value, FetchedAt: fetchedAt}
+	if n := len(ring.observations); n > 0 {
+		last := ring.observations[(ring.start+n-1)%n]
+		if reflect.DeepEqual(last.Value, value) {
+			return
+		}
+	}
+	if len(ring.observations) < h.size {
+		ring.observations = append(ring.observations, observation)
+		return
+	}
+	ring.observations[ring.start] = observation
+	ring.start = (ring.start + 1) % h.size
+}
+
+// get returns the history of key, oldest first
+func (h *historyIndex[K, V]) get(key K) []Observation[V] {
+	ring := h.byKey[key]
+	if ring == nil {
+		return nil
+	}
+	observations := make([]Observation[V], 0, len(ring.observations))
+	observations = append(observations, ring.observations[ring.start:]...)
+	return append(observations, ring.observations[:ring.start]...)
+}
+
+// remove forgets the history of key
+func (h *historyIndex[K, V]) remove(key K) {
+	delete(h.byKey, key)
+}
+
+// clear forgets every history
+func (h *historyIndex[K, V]) clear() {
+	h.byKey = nil
+}
+
+// History returns the last values key had, oldest first, the last one being the value currently cached unless it
+// was invalidated since. It is empty unless WithHistory is used
+func (c *Cache[K, V]) History(key K) []Observation[V] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.history.get(key)
+}
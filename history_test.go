@@ -0,0 +1,85 @@
+package sample1
+
+import (
+	"testing"
+	"time"
+)
+
+// Check that the history keeps the last values of an item, oldest first, skipping the loads that didn't change it
+func TestHistory_KeepsLastValues(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 1, err: nil},
+	}}
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock), WithHistory(3))
+	start := clock.Now()
+	for _, price := range []float64{1, 1, 2, 3, 4} {
+		mockService.setPrice("p1", price)
+		getPriceWithNoErr(t, cache, "p1")
+		clock.Advance(2 * time.Minute)
+	}
+	history := cache.History("p1")
+	if len(history) != 3 {
+		t.Fatalf("expected the last 3 prices but got %+v", history)
+	}
+	for i, want := range []float64{2, 3, 4} {
+		assertFloat(t, want, history[i].Value, "wrong price in the history")
+		if at := start.Add(time.Duration(i+2) * 2 * time.Minute); !history[i].FetchedAt.Equal(at) {
+			t.Errorf("expected price %v to be observed at %v but got %v", want, at, history[i].FetchedAt)
+		}
+	}
+	assertInt(t, 5, mockService.getNumCalls(), "every expired price should have been loaded")
+}
+
+// Check that the history outlives invalidations but not evictions nor clears
+func TestHistory_Lifetime(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 1, err: nil},
+		"p2": {price: 2, err: nil},
+		"p3": {price: 3, err: nil},
+	}}
+	cache := NewTransparentCache(mockService, time.Minute, WithHistory(2), WithMaxEntries(2))
+	getPricesWithNoErr(t, cache, "p1", "p2")
+	cache.Invalidate("p1")
+	mockService.setPrice("p1", 5)
+	getPriceWithNoErr(t, cache, "p1")
+	if history := cache.History("p1"); len(history) != 2 || history[0].Value != 1 || history[1].Value != 5 {
+		t.Errorf("the history should have outlived the invalidation, got %+v", history)
+	}
+
+	getPriceWithNoErr(t, cache, "p3") // evicts p2, the least recently used
+	if history := cache.History("p2"); len(history) != 0 {
+		t.Errorf("the history of an evicted item should be gone, got %+v", history)
+	}
+	cache.Clear()
+	if history := cache.History("p1"); len(history) != 0 {
+		t.Errorf("the history should be gone after a clear, got %+v", history)
+	}
+}
+
+// Check that no history is kept without WithHistory
+func TestHistory_Disabled(t *testing.T) {
+	cache := NewTransparentCache(&mockPriceService{}, time.Minute)
+	cache.Set("p1", 5)
+	if history := cache.History("p1"); history != nil {
+		t.Errorf("expected no history but got %+v", history)
+	}
+}
+
+// Check that the history and the adapted maxAge of an item go away when the janitor drops it
+func TestHistory_GoneWithExpiry(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 1, err: nil}}}
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock), WithHistory(2),
+		WithAdaptiveMaxAge(time.Second, time.Hour))
+	loadAndTTL(t, clock, cache, mockService, 1)
+	loadAndTTL(t, clock, cache, mockService, 2)
+	clock.Advance(time.Hour)
+	assertInt(t, 1, cache.purgeExpired(), "the expired item should have been dropped")
+	if history := cache.History("p1"); len(history) != 0 {
+		t.Errorf("the history of an expired item should be gone, got %+v", history)
+	}
+	if ttl := loadAndTTL(t, clock, cache, mockService, 3); ttl != time.Minute {
+		t.Errorf("the adapted maxAge of an expired item should be gone, got %v", ttl)
+	}
+}
@@ -0,0 +1,208 @@
+package sample1
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// hotKeyBuckets is the number of buckets the window of the hot keys is split into, the window slides by one
+// bucket at a time
+const hotKeyBuckets = 10
+
+// HotKey is how often a key was asked for over the window of WithHotKeys
+type HotKey[K comparable] struct {
+	Key      K
+	Requests uint64  // lookups for the key over the window, whether they were hits or misses
+	Misses   uint64  // lookups for the key over the window that weren't answered from the cache
+	Rate     float64 // requests per second, averaged over the window
+}
+
+// WithHotKeys makes the cache count the lookups and the misses of every key over a sliding window, see HotKeys,
+// MostMissed and OnHotKey, so that the keys dominating the traffic can be pinned or refreshed ahead, and the ones
+// missed the most warmed up or cached longer
+func WithHotKeys(window time.Duration) Option {
+	return func(c *config) {
+		c.hotKeyWindow = window
+	}
+}
+
+// hotKeyTracker counts the lookups of every key in the buckets of a sliding window
+type hotKeyTracker[K comparable] struct {
+	mu       sync.Mutex
+	window   time.Duration // nothing is counted if zero or less
+	start    time.Time     // when the current bucket started
+	current  int           // the index of the current bucket
+	counts   map[K]*keyCounts
+	watchers []*hotKeyWatcher[K]
+}
+
+// keyCounts are the lookups and the misses of a key in every bucket, and their totals
+type keyCounts struct {
+	requests, misses           [hotKeyBuckets]uint64
+	totalRequests, totalMisses uint64
+}
+
+// hotKeyWatcher is a callback of OnHotKey, with the keys that are above its threshold
+type hotKeyWatcher[K comparable] struct {
+	threshold float64
+	fn        func(HotKey[K])
+	hot       map[K]bool
+}
+
+func (t *hotKeyTracker[K]) enabled() bool {
+	return t.window > 0
+}
+
+// bucketWidth is how long each bucket counts
+func (t *hotKeyTracker[K]) bucketWidth() time.Duration {
+	return max(t.window/hotKeyBuckets, 1)
+}
+
+// slide moves the window to now, forgetting the buckets it leaves, and the keys that aren't asked for anymore
+// t.mu must be held
+func (t *hotKeyTracker[K]) slide(now time.Time) {
+	if t.start.IsZero() {
+		t.start = now
+		return
+	}
+	width := t.bucketWidth()
+	steps := int(min(now.Sub(t.start)/width, hotKeyBuckets))
+	if steps <= 0 {
+		return
+	}
+	for range steps {
+		t.current = (t.current + 1) % hotKeyBuckets
+		for key, counts := range t.counts {
+			counts.totalRequests -= counts.requests[t.current]
+			counts.totalMisses -= counts.misses[t.current]
+			counts.requests[t.current], counts.misses[t.current] = 0, 0
+			if counts.totalRequests == 0 && counts.totalMisses == 0 {
+				delete(t.counts, key)
+			}
+		}
+	}
+	if steps == hotKeyBuckets {
+		t.start = now
+	} else {
+		t.start = t.start.Add(time.Duration(steps) * width)
+	}
+	for _, watcher := range t.watchers {
+		for key := range watcher.hot {
+			if t.rate(t.counts[key]) < watcher.threshold {
+				delete(watcher.hot, key)
+			}
+		}
+	}
+}
+
+// rate returns the requests per second of counts over the window, t.mu must be held
+func (t *hotKeyTracker[K]) rate(counts *keyCounts) float64 {
+	if counts == nil {
+		return 0
+	}
+	return float64(counts.totalRequests) / t.window.Seconds()
+}
+
+// hotKey returns the HotKey of key, t.mu must be held
+func (t *hotKeyTracker[K]) hotKey(key K, counts *keyCounts) HotKey[K] {
+	return HotKey[K]{Key: key, Requests: counts.totalRequests, Misses: counts.totalMisses, Rate: t.rate(counts)}
+}
+
+// countsOf returns the counts of key at now, creating them if it wasn't counted yet, t.mu must be held
+func (t *hotKeyTracker[K]) countsOf(key K, now time.Time) *keyCounts {
+	t.slide(now)
+	if t.counts == nil {
+		t.counts = map[K]*keyCounts{}
+	}
+	counts := t.counts[key]
+	if counts == nil {
+		counts = &keyCounts{}
+		t.counts[key] = counts
+	}
+	return counts
+}
+
+// request counts a lookup of key at now, it returns the callbacks of OnHotKey to call as key just went past
+// their threshold
+func (t *hotKeyTracker[K]) request(key K, now time.Time) (calls []func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counts := t.countsOf(key, now)
+	counts.requests[t.current]++
+	counts.totalRequests++
+	for _, watcher := range t.watchers {
+		if watcher.hot[key] || t.rate(counts) < watcher.threshold {
+			continue
+		}
+		watcher.hot[key] = true
+		fn, hotKey := watcher.fn, t.hotKey(key, counts)
+		calls = append(calls, func() { fn(hotKey) })
+	}
+	return calls
+}
+
+// miss counts a lookup of key at now that wasn't answered from the cache
+func (t *hotKeyTracker[K]) miss(key K, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counts := t.countsOf(key, now)
+	counts.misses[t.current]++
+	counts.totalMisses++
+}
+
+// top returns the n keys for which by is the highest at now, highest first, leaving out those for which it is zero
+func (t *hotKeyTracker[K]) top(n int, now time.Time, by func(HotKey[K]) uint64) []HotKey[K] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.slide(now)
+	hotKeys := make([]HotKey[K], 0, len(t.counts))
+	for key, counts := range t.counts {
+		if hotKey := t.hotKey(key, counts); by(hotKey) > 0 {
+			hotKeys = append(hotKeys, hotKey)
+		}
+	}
+	slices.SortFunc(hotKeys, func(a, b HotKey[K]) int {
+		return cmp.Compare(by(b), by(a))
+	})
+	return hotKeys[:min(max(n, 0), len(hotKeys))]
+}
+
+// requested counts a lookup of key, if WithHotKeys is used, calling the callbacks of the keys that got hot
+// c.mu must not be held so that they can use the cache
+func (c *Cache[K, V]) requested(key K) {
+	if !c.hotKeys.enabled() {
+		return
+	}
+	for _, call := range c.hotKeys.request(key, c.clock.Now()) {
+		call()
+	}
+}
+
+// HotKeys returns the n keys asked for the most over the window of WithHotKeys, the most requested first
+// It is empty unless WithHotKeys is used
+func (c *Cache[K, V]) HotKeys(n int) []HotKey[K] {
+	if !c.hotKeys.enabled() {
+		return nil
+	}
+	return c.hotKeys.top(n, c.clock.Now(), func(hotKey HotKey[K]) uint64 { return hotKey.Requests })
+}
+
+// MostMissed returns the n keys missed the most over the window of WithHotKeys, the most missed first, the keys
+// worth warming up or caching longer. It is empty unless WithHotKeys is used
+func (c *Cache[K, V]) MostMissed(n int) []HotKey[K] {
+	if !c.hotKeys.enabled() {
+		return nil
+	}
+	return c.hotKeys.top(n, c.clock.Now(), func(hotKey HotKey[K]) uint64 { return hotKey.Misses })
+}
+
+// OnHotKey registers fn to be called when a key gets asked for more than threshold times per second over the
+// window of WithHotKeys, in the goroutine of the lookup that took it past the threshold. fn is called once, and
+// again only after the rate of the key went back below threshold. It is never called unless WithHotKeys is used
+func (c *Cache[K, V]) OnHotKey(threshold float64, fn func(HotKey[K])) {
+	c.hotKeys.mu.Lock()
+	defer c.hotKeys.mu.Unlock()
+	c.hotKeys.watchers = append(c.hotKeys.watchers, &hotKeyWatcher[K]{threshold: threshold, fn: fn, hot: map[K]bool{}})
+}
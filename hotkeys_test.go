@@ -0,0 +1,94 @@
+package sample1
+
+import (
+	"testing"
+	"time"
+)
+
+// pricesOfP123 is a service pricing p1, p2 and p3
+func pricesOfP123() *mockPriceService {
+	return &mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 5, err: nil},
+		"p2": {price: 6, err: nil},
+		"p3": {price: 7, err: nil},
+	}}
+}
+
+// Check that HotKeys returns the most requested keys over the window, and forgets the requests that left it
+func TestHotKeys(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewTransparentCache(pricesOfP123(), time.Minute, WithHotKeys(10*time.Second), WithClock(clock))
+	for range 4 {
+		getPriceWithNoErr(t, cache, "p1")
+	}
+	getPricesWithNoErr(t, cache, "p1", "p2", "p3")
+	getPriceWithNoErr(t, cache, "p2")
+	hotKeys := cache.HotKeys(2)
+	assertInt(t, 2, len(hotKeys), "wrong number of hot keys")
+	if hotKeys[0].Key != "p1" || hotKeys[1].Key != "p2" {
+		t.Fatalf("wrong hot keys %v", hotKeys)
+	}
+	assertInt(t, 5, int(hotKeys[0].Requests), "wrong number of requests")
+	assertFloat(t, 0.5, hotKeys[0].Rate, "wrong rate")
+	clock.Advance(5 * time.Second)
+	getPriceWithNoErr(t, cache, "p2")
+	clock.Advance(6 * time.Second)
+	hotKeys = cache.HotKeys(10)
+	assertInt(t, 1, len(hotKeys), "the requests that left the window should be forgotten")
+	assertInt(t, 1, int(hotKeys[0].Requests), "wrong number of requests")
+	if len(NewTransparentCache(&mockPriceService{}, time.Minute).HotKeys(10)) != 0 {
+		t.Error("nothing should be counted without WithHotKeys")
+	}
+}
+
+// Check that OnHotKey is called once when a key goes past the threshold, and again once it cooled down
+func TestOnHotKey(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewTransparentCache(pricesOfP123(), time.Minute, WithHotKeys(10*time.Second), WithClock(clock))
+	var hot []HotKey[string]
+	cache.OnHotKey(0.3, func(hotKey HotKey[string]) {
+		cache.Pin(hotKey.Key) // the cache can be used from the callback
+		hot = append(hot, hotKey)
+	})
+	for range 5 {
+		getPriceWithNoErr(t, cache, "p1")
+	}
+	getPriceWithNoErr(t, cache, "p2")
+	assertInt(t, 1, len(hot), "the callback should be called once")
+	assertInt(t, 3, int(hot[0].Requests), "the callback should be called on the request going past the threshold")
+	if !cache.Pinned("p1") {
+		t.Error("the hot key should have been pinned")
+	}
+	clock.Advance(11 * time.Second)
+	for range 3 {
+		getPriceWithNoErr(t, cache, "p1")
+	}
+	assertInt(t, 2, len(hot), "the callback should be called again once the key cooled down")
+}
+
+// Check that MostMissed returns the keys missed the most over the window, leaving out the ones never missed
+func TestMostMissed(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewTransparentCache(pricesOfP123(), time.Minute, WithHotKeys(10*time.Second), WithClock(clock))
+	for range 3 {
+		getPriceWithNoErr(t, cache, "p1")
+		cache.Invalidate("p1")
+	}
+	getPricesWithNoErr(t, cache, "p2", "p3")
+	getPriceWithNoErr(t, cache, "p3")
+	cache.Invalidate("p2")
+	cache.SetMode(Passthrough)
+	getPriceWithNoErr(t, cache, "p2")
+	cache.SetMode(Normal)
+	missed := cache.MostMissed(2)
+	assertInt(t, 2, len(missed), "wrong number of missed keys")
+	if missed[0].Key != "p1" || missed[1].Key != "p2" {
+		t.Fatalf("wrong missed keys %v", missed)
+	}
+	assertInt(t, 2, int(missed[1].Misses), "the misses of batches and passthrough lookups should be counted")
+	assertInt(t, 3, len(cache.MostMissed(10)), "wrong number of missed keys")
+	clock.Advance(11 * time.Second)
+	if len(cache.MostMissed(10)) != 0 {
+		t.Error("the misses that left the window should be forgotten")
+	}
+}
@@ -0,0 +1,201 @@
+package httpcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// The admin routes let operators look into the cache and fix stale prices without restarting the service
+//
+//	GET    /admin/keys                the cached items with their price, age and remaining ttl
+//	DELETE /admin/keys/{itemCode...}  drops the cached price of an item, its code may have slashes
+//	DELETE /admin/keys                drops every cached price, or only those of ?prefix=
+//	GET    /admin/stats               the cache counters
+//	GET    /admin/top-keys            the ?n= (10 by default) items requested and missed the most, see sample1.WithHotKeys
+//	PUT    /admin/max-age             changes the maxAge of the cache: {"maxAge": "30s"}
+//	GET    /admin/mode                the mode of the cache: {"mode": "normal"}
+//	PUT    /admin/mode                switches the mode of the cache: {"mode": "passthrough"}, see sample1.Mode
+//	GET    /admin/events              streams the events of the cache, see events.go
+
+// AuthFunc authorizes a request to the admin routes, the request is answered with 403 and the error if it isn't
+type AuthFunc func(r *http.Request) error
+
+// Key is a cached item, as listed by GET /admin/keys
+type Key struct {
+	ItemCode string  `json:"itemCode"`
+	Price    float64 `json:"price"`
+	Age      string  `json:"age"`
+	TTL      string  `json:"ttl"` // zero once the price expired
+}
+
+// StatsResponse is the body of GET /admin/stats
+type StatsResponse struct {
+	Hits        uint64  `json:"hits"`
+	Misses      uint64  `json:"misses"`
+	Loads       uint64  `json:"loads"`
+	LoadErrors  uint64  `json:"loadErrors"`
+	Evictions   uint64  `json:"evictions"`
+	StaleServed uint64  `json:"staleServed"`
+	HitRatio    float64 `json:"hitRatio"`
+	// HitRatios are the hit ratios of the last minute, 5 minutes and hour, by window ("1m", "5m" and "1h")
+	HitRatios map[string]float64 `json:"hitRatios"`
+	Entries   int                `json:"entries"`
+	MaxAge    string             `json:"maxAge"`
+}
+
+// hitRatioWindows are the windows of StatsResponse.HitRatios, by name
+var hitRatioWindows = map[string]time.Duration{"1m": time.Minute, "5m": 5 * time.Minute, "1h": time.Hour}
+
+// TopKey is an item requested or missed often, as listed by GET /admin/top-keys
+type TopKey struct {
+	ItemCode string  `json:"itemCode"`
+	Requests uint64  `json:"requests"`
+	Misses   uint64  `json:"misses"`
+	Rate     float64 `json:"rate"` // requests per second
+}
+
+// TopKeysResponse is the body of GET /admin/top-keys, both lists are empty unless the cache uses WithHotKeys
+type TopKeysResponse struct {
+	Requested []TopKey `json:"requested"` // the most requested first
+	Missed    []TopKey `json:"missed"`    // the most missed first
+}
+
+// MaxAge is the body of PUT /admin/max-age and of its response, in the format of time.ParseDuration
+type MaxAge struct {
+	MaxAge string `json:"maxAge"`
+}
+
+// ModeBody is the body of PUT /admin/mode and of the responses of the mode routes, a sample1.Mode name
+type ModeBody struct {
+	Mode string `json:"mode"`
+}
+
+func (h *Handler) handleAdmin() {
+	h.mux.HandleFunc("GET /admin/keys", h.authorized(h.listKeys))
+	h.mux.HandleFunc("DELETE /admin/keys/{itemCode...}", h.authorized(h.invalidate))
+	h.mux.HandleFunc("DELETE /admin/keys", h.authorized(h.clear))
+	h.mux.HandleFunc("GET /admin/stats", h.authorized(h.stats))
+	h.mux.HandleFunc("GET /admin/top-keys", h.authorized(h.topKeys))
+	h.mux.HandleFunc("PUT /admin/max-age", h.authorized(h.setMaxAge))
+	h.mux.HandleFunc("GET /admin/mode", h.authorized(h.mode))
+	h.mux.HandleFunc("PUT /admin/mode", h.authorized(h.setMode))
+	h.mux.HandleFunc("GET /admin/events", h.authorized(h.streamEvents))
+	h.events.listen(h.cache)
+}
+
+// authorized serves the request with handler once AdminAuth allowed it
+func (h *Handler) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.opts.AdminAuth(r); err != nil {
+			writeJSON(w, http.StatusForbidden, Error{Error: err.Error()})
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (h *Handler) listKeys(w http.ResponseWriter, r *http.Request) {
+	keys := []Key{}
+	h.cache.Range(func(itemCode string, price float64, fetchedAt time.Time) bool {
+		_, age, ok := h.cache.Peek(itemCode) // on the clock of the cache
+		if !ok {
+			return true // dropped since
+		}
+		ttl, _ := h.cache.TTL(itemCode)
+		keys = append(keys, Key{
+			ItemCode: itemCode, Price: price, Age: age.Round(time.Millisecond).String(),
+			TTL: ttl.Round(time.Millisecond).String(),
+		})
+		return true
+	})
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (h *Handler) invalidate(w http.ResponseWriter, r *http.Request) {
+	h.cache.Invalidate(r.PathValue("itemCode"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) clear(w http.ResponseWriter, r *http.Request) {
+	if prefix := r.URL.Query().Get("prefix"); prefix != "" {
+		h.cache.InvalidatePrefix(prefix)
+	} else {
+		h.cache.Clear()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) stats(w http.ResponseWriter, r *http.Request) {
+	stats := h.cache.Stats()
+	hitRatios := map[string]float64{}
+	for name, window := range hitRatioWindows {
+		hitRatios[name] = h.cache.StatsOver(window).HitRatio()
+	}
+	writeJSON(w, http.StatusOK, StatsResponse{
+		Hits: stats.Hits, Misses: stats.Misses, Loads: stats.Loads, LoadErrors: stats.LoadErrors,
+		Evictions: stats.Evictions, StaleServed: stats.StaleServed, HitRatio: stats.HitRatio(), HitRatios: hitRatios,
+		Entries: h.cache.Len(), MaxAge: h.cache.MaxAge().String(),
+	})
+}
+
+func (h *Handler) topKeys(w http.ResponseWriter, r *http.Request) {
+	n := 10
+	if param := r.URL.Query().Get("n"); param != "" {
+		var err error
+		if n, err = strconv.Atoi(param); err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, Error{Error: fmt.Sprintf("invalid number of keys [%v]", param)})
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, TopKeysResponse{
+		Requested: topKeysOf(h.cache.HotKeys(n)), Missed: topKeysOf(h.cache.MostMissed(n)),
+	})
+}
+
+// topKeysOf returns the TopKeys of hotKeys
+func topKeysOf(hotKeys []sample1.HotKey[string]) []TopKey {
+	topKeys := make([]TopKey, len(hotKeys))
+	for i, hotKey := range hotKeys {
+		topKeys[i] = TopKey{ItemCode: hotKey.Key, Requests: hotKey.Requests, Misses: hotKey.Misses, Rate: hotKey.Rate}
+	}
+	return topKeys
+}
+
+func (h *Handler) setMaxAge(w http.ResponseWriter, r *http.Request) {
+	var req MaxAge
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, Error{Error: fmt.Sprintf("decoding the request : %v", err)})
+		return
+	}
+	maxAge, err := time.ParseDuration(req.MaxAge)
+	if err != nil || maxAge <= 0 {
+		writeJSON(w, http.StatusBadRequest, Error{Error: fmt.Sprintf("invalid max age [%v]", req.MaxAge)})
+		return
+	}
+	h.cache.SetMaxAge(maxAge)
+	writeJSON(w, http.StatusOK, MaxAge{MaxAge: maxAge.String()})
+}
+
+func (h *Handler) mode(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ModeBody{Mode: h.cache.Mode().String()})
+}
+
+func (h *Handler) setMode(w http.ResponseWriter, r *http.Request) {
+	var req ModeBody
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, Error{Error: fmt.Sprintf("decoding the request : %v", err)})
+		return
+	}
+	mode, err := sample1.ParseMode(req.Mode)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Error{Error: err.Error()})
+		return
+	}
+	h.cache.SetMode(mode)
+	writeJSON(w, http.StatusOK, ModeBody{Mode: mode.String()})
+}
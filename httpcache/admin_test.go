@@ -0,0 +1,170 @@
+package httpcache
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+	"github.com/MadHive/deviget_challenge/cachetest"
+)
+
+func newAdminTestServer(t *testing.T, opts ...sample1.Option) (*httptest.Server, *sample1.TransparentCache) {
+	opts = append([]sample1.Option{sample1.WithHotKeys(time.Minute)}, opts...)
+	cache := sample1.NewTransparentCache(fakePriceService{"p1": 5, "p2": 7, "BOOKS/SCIFI/123": 9}, time.Minute, opts...)
+	server := httptest.NewServer(NewHandler(cache, Options{AdminAuth: func(r *http.Request) error {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			return errors.New("not an admin")
+		}
+		return nil
+	}}))
+	t.Cleanup(server.Close)
+	return server, cache
+}
+
+func doAdmin(t *testing.T, method, url, body string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// Check that the admin routes are refused to the requests the hook doesn't authorize, and not served without a hook
+func TestAdmin_Authorization(t *testing.T) {
+	server, _ := newAdminTestServer(t)
+	resp, err := http.Get(server.URL + "/admin/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 without credentials but got %v", resp.StatusCode)
+	}
+
+	if resp := doAdmin(t, http.MethodGet, newTestServer(t).URL+"/admin/stats", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 without an admin hook but got %v", resp.StatusCode)
+	}
+}
+
+// Check that keys can be listed and invalidated, one by one or all at once
+func TestAdmin_KeysAndInvalidation(t *testing.T) {
+	server, cache := newAdminTestServer(t)
+	cache.GetPricesFor("p1", "p2")
+
+	var keys []Key
+	json.NewDecoder(doAdmin(t, http.MethodGet, server.URL+"/admin/keys", "").Body).Decode(&keys)
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys but got %+v", keys)
+	}
+
+	if resp := doAdmin(t, http.MethodDelete, server.URL+"/admin/keys/p1", ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected 204 but got %v", resp.StatusCode)
+	}
+	if cache.Contains("p1") || !cache.Contains("p2") {
+		t.Errorf("expected only p1 to be invalidated")
+	}
+	doAdmin(t, http.MethodDelete, server.URL+"/admin/keys", "")
+	if cache.Len() != 0 {
+		t.Errorf("expected the cache to be cleared, it has %v entries", cache.Len())
+	}
+}
+
+// Check that the keys are listed with their age on the clock of the cache, and that codes with slashes can be
+// invalidated
+func TestAdmin_KeysOnTheClockOfTheCache(t *testing.T) {
+	clock := cachetest.NewClock(cachetest.Start)
+	server, cache := newAdminTestServer(t, sample1.WithClock(clock))
+	cache.GetPriceFor("BOOKS/SCIFI/123")
+	clock.Advance(20 * time.Second)
+
+	var keys []Key
+	json.NewDecoder(doAdmin(t, http.MethodGet, server.URL+"/admin/keys", "").Body).Decode(&keys)
+	if len(keys) != 1 || keys[0].Age != "20s" || keys[0].TTL != "40s" {
+		t.Fatalf("expected a key 20s old with 40s left but got %+v", keys)
+	}
+	if resp := doAdmin(t, http.MethodDelete, server.URL+"/admin/keys/BOOKS/SCIFI/123", ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected 204 but got %v", resp.StatusCode)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("expected the item to be invalidated, the cache has %v entries", cache.Len())
+	}
+}
+
+// Check that the stats are dumped and that the max age can be changed
+func TestAdmin_StatsAndMaxAge(t *testing.T) {
+	server, cache := newAdminTestServer(t)
+	cache.GetPriceFor("p1")
+	cache.GetPriceFor("p1")
+
+	var stats StatsResponse
+	json.NewDecoder(doAdmin(t, http.MethodGet, server.URL+"/admin/stats", "").Body).Decode(&stats)
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 || stats.MaxAge != "1m0s" {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.HitRatios["1m"] != 0.5 || stats.HitRatios["1h"] != 0.5 || len(stats.HitRatios) != 3 {
+		t.Errorf("unexpected hit ratios %v", stats.HitRatios)
+	}
+
+	if resp := doAdmin(t, http.MethodPut, server.URL+"/admin/max-age", `{"maxAge": "30s"}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 but got %v", resp.StatusCode)
+	}
+	if cache.MaxAge() != 30*time.Second {
+		t.Errorf("expected a max age of 30s but got %v", cache.MaxAge())
+	}
+	if resp := doAdmin(t, http.MethodPut, server.URL+"/admin/max-age", `{"maxAge": "soon"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid max age but got %v", resp.StatusCode)
+	}
+}
+
+// Check that the items requested and missed the most are listed
+func TestAdmin_TopKeys(t *testing.T) {
+	server, cache := newAdminTestServer(t)
+	cache.GetPriceFor("p1")
+	cache.GetPriceFor("p1")
+	cache.GetPriceFor("p1") // so that p1 doesn't tie with p2
+	cache.GetPriceFor("p2")
+	cache.Invalidate("p2")
+	cache.GetPriceFor("p2")
+
+	var topKeys TopKeysResponse
+	json.NewDecoder(doAdmin(t, http.MethodGet, server.URL+"/admin/top-keys?n=1", "").Body).Decode(&topKeys)
+	if len(topKeys.Requested) != 1 || topKeys.Requested[0].ItemCode != "p1" || topKeys.Requested[0].Requests != 3 {
+		t.Errorf("unexpected most requested items %+v", topKeys.Requested)
+	}
+	if len(topKeys.Missed) != 1 || topKeys.Missed[0].ItemCode != "p2" || topKeys.Missed[0].Misses != 2 {
+		t.Errorf("unexpected most missed items %+v", topKeys.Missed)
+	}
+	if resp := doAdmin(t, http.MethodGet, server.URL+"/admin/top-keys?n=many", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid number of keys but got %v", resp.StatusCode)
+	}
+}
+
+// Check that the mode of the cache can be read and switched
+func TestAdmin_Mode(t *testing.T) {
+	server, cache := newAdminTestServer(t)
+	if resp := doAdmin(t, http.MethodPut, server.URL+"/admin/mode", `{"mode": "frozen"}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 but got %v", resp.StatusCode)
+	}
+	if cache.Mode() != sample1.Frozen {
+		t.Errorf("expected the frozen mode but got %v", cache.Mode())
+	}
+	var mode ModeBody
+	json.NewDecoder(doAdmin(t, http.MethodGet, server.URL+"/admin/mode", "").Body).Decode(&mode)
+	if mode.Mode != "frozen" {
+		t.Errorf("expected the frozen mode but got %+v", mode)
+	}
+	if resp := doAdmin(t, http.MethodPut, server.URL+"/admin/mode", `{"mode": "paused"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown mode but got %v", resp.StatusCode)
+	}
+}
@@ -0,0 +1,138 @@
+package httpcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// GET /admin/events[?types=load,refresh] streams the events of the cache as Server-Sent Events, each one
+// named after its type and carrying a CacheEvent as JSON
+
+// Types of CacheEvent
+const (
+	LoadEvent       = "load"       // a price was fetched for an item that wasn't cached
+	RefreshEvent    = "refresh"    // a price was fetched again for an item that was cached
+	InvalidateEvent = "invalidate" // a cached price was invalidated
+	EvictEvent      = "evict"      // a cached price was dropped to make room for others
+	ExpireEvent     = "expire"     // an expired price was dropped by the janitor
+)
+
+// CacheEvent is an event of the cache, as streamed by GET /admin/events
+type CacheEvent struct {
+	Type     string    `json:"type"`
+	ItemCode string    `json:"itemCode"`
+	Price    float64   `json:"price,omitempty"`
+	Error    string    `json:"error,omitempty"` // the cached error of the dropped entry, if it was one
+	Time     time.Time `json:"time"`
+}
+
+// keepAliveInterval is how often an idle stream gets a comment, so that proxies don't close it
+const keepAliveInterval = 30 * time.Second
+
+// eventStreams fans the events of the cache out to the open streams
+// Streams that are slow to read miss events rather than slowing the cache down
+type eventStreams struct {
+	mu      sync.Mutex
+	streams map[chan CacheEvent]struct{}
+}
+
+// listen registers the streams with the listeners of cache
+func (s *eventStreams) listen(cache *sample1.TransparentCache) {
+	cache.OnLoad(s.publish)
+	cache.OnInvalidate(s.publish)
+	cache.OnEvict(s.publish)
+	cache.OnExpire(s.publish)
+}
+
+func (s *eventStreams) publish(event sample1.Event[string, float64]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.streams) == 0 {
+		return
+	}
+	cacheEvent := CacheEvent{ItemCode: event.Key, Price: event.Value, Time: time.Now()}
+	switch event.Reason {
+	case sample1.Loaded:
+		cacheEvent.Type = LoadEvent
+		if event.Refresh {
+			cacheEvent.Type = RefreshEvent
+		}
+	case sample1.Invalidated:
+		cacheEvent.Type = InvalidateEvent
+	case sample1.Evicted:
+		cacheEvent.Type = EvictEvent
+	case sample1.Expired:
+		cacheEvent.Type = ExpireEvent
+	}
+	if event.Err != nil {
+		cacheEvent.Price, cacheEvent.Error = 0, event.Err.Error()
+	}
+	for stream := range s.streams {
+		select {
+		case stream <- cacheEvent:
+		default:
+		}
+	}
+}
+
+func (s *eventStreams) open() chan CacheEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.streams == nil {
+		s.streams = map[chan CacheEvent]struct{}{}
+	}
+	stream := make(chan CacheEvent, 256)
+	s.streams[stream] = struct{}{}
+	return stream
+}
+
+func (s *eventStreams) close(stream chan CacheEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.streams, stream)
+}
+
+func (h *Handler) streamEvents(w http.ResponseWriter, r *http.Request) {
+	var types map[string]bool
+	if param := r.URL.Query().Get("types"); param != "" {
+		types = map[string]bool{}
+		for _, eventType := range strings.Split(param, ",") {
+			types[eventType] = true
+		}
+	}
+	stream := h.events.open()
+	defer h.events.close(stream)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	controller := http.NewResponseController(w)
+	if err := controller.Flush(); err != nil {
+		return
+	}
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event := <-stream:
+			if types != nil && !types[event.Type] {
+				continue
+			}
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package httpcache
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Check that the events of the cache are streamed, filtered by type
+func TestAdmin_StreamsEvents(t *testing.T) {
+	server, cache := newAdminTestServer(t)
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/admin/events?types=load,refresh,invalidate", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response %v %v", resp.Status, resp.Header)
+	}
+
+	cache.GetPriceFor("p1")
+	cache.Set("p2", 1)
+	cache.Invalidate("p1")
+
+	events := make(chan CacheEvent)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		var eventType string
+		for scanner.Scan() {
+			line := scanner.Text()
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				eventType = name
+			} else if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var event CacheEvent
+				json.Unmarshal([]byte(data), &event)
+				if event.Type != eventType {
+					t.Errorf("event named %v carries a %v", eventType, event.Type)
+				}
+				events <- event
+			}
+		}
+	}()
+	for _, want := range []CacheEvent{{Type: LoadEvent, ItemCode: "p1", Price: 5}, {Type: InvalidateEvent, ItemCode: "p1", Price: 5}} {
+		select {
+		case event := <-events:
+			if event.Type != want.Type || event.ItemCode != want.ItemCode || event.Price != want.Price || event.Time.IsZero() {
+				t.Errorf("expected %+v but got %+v", want, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected %+v", want)
+		}
+	}
+}
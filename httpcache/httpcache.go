@@ -0,0 +1,158 @@
+// Package httpcache serves the prices of a cache over HTTP, so that other services can consume them
+// without embedding the cache
+//
+//	GET  /prices/{itemCode...}  the price of one item, with Cache-Status and Age headers
+//	POST /prices:batch          the prices of several items: {"itemCodes": ["p1", "p2"]}
+//	GET  /prices:watch          a WebSocket pushing the changes of the prices of some items, see watch.go
+//
+// and, when Options.AdminAuth is set, the admin routes of admin.go
+// The item codes in the paths take the rest of the path, slashes included (/prices/BOOKS/SCIFI/123), and may
+// also be escaped (/prices/BOOKS%2FSCIFI%2F123)
+package httpcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// Options configures a Handler, zero fields take their defaults
+type Options struct {
+	Name         string   // names the cache in the Cache-Status header, "pricecache" if empty
+	MaxBatchSize int      // max item codes in a batch request, 1000 if zero
+	WatchBuffer  int      // changes kept for a WebSocket that is slow to read them, 64 if zero
+	WatchOrigins []string // the other origins allowed to open WebSockets (see websocket.AcceptOptions), none if empty
+	// AdminAuth authorizes the requests to the admin routes, which are only served when it is set
+	AdminAuth AuthFunc
+}
+
+// Handler serves the prices of a cache, see the package documentation for its routes
+type Handler struct {
+	cache  *sample1.TransparentCache
+	opts   Options
+	mux    *http.ServeMux
+	events eventStreams // the streams of GET /admin/events
+}
+
+// NewHandler returns a handler serving the prices of cache
+func NewHandler(cache *sample1.TransparentCache, opts Options) *Handler {
+	if opts.Name == "" {
+		opts.Name = "pricecache"
+	}
+	if opts.MaxBatchSize <= 0 {
+		opts.MaxBatchSize = 1000
+	}
+	if opts.WatchBuffer <= 0 {
+		opts.WatchBuffer = 64
+	}
+	h := &Handler{cache: cache, opts: opts, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /prices/{itemCode...}", h.getPrice)
+	h.mux.HandleFunc("POST /prices:batch", h.getPrices)
+	h.mux.HandleFunc("GET /prices:watch", h.watch)
+	if opts.AdminAuth != nil {
+		h.handleAdmin()
+	}
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Price is the body of a successful GET /prices/{itemCode...}
+type Price struct {
+	ItemCode string  `json:"itemCode"`
+	Price    float64 `json:"price"`
+	Source   string  `json:"source,omitempty"`
+}
+
+// BatchRequest is the body of POST /prices:batch
+type BatchRequest struct {
+	ItemCodes []string `json:"itemCodes"`
+}
+
+// BatchResult is the outcome for one item of a batch, either its price or its error
+type BatchResult struct {
+	ItemCode string  `json:"itemCode"`
+	Price    float64 `json:"price,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Status   int     `json:"status"` // the HTTP status a GET of the item alone would have had
+}
+
+// BatchResponse is the body of a POST /prices:batch, results are in the same order as the item codes
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// Error is the body of the responses that failed
+type Error struct {
+	Error string `json:"error"`
+}
+
+func (h *Handler) getPrice(w http.ResponseWriter, r *http.Request) {
+	itemCode := r.PathValue("itemCode")
+	price, info, err := h.cache.GetPriceWithInfoCtx(r.Context(), itemCode)
+	if err != nil {
+		w.Header().Set("Cache-Status", h.opts.Name+"; fwd=miss")
+		writeJSON(w, statusOf(err), Error{Error: err.Error()})
+		return
+	}
+	status := h.opts.Name + "; hit"
+	if !info.Cached {
+		status = h.opts.Name + "; fwd=miss; stored"
+	}
+	if ttl, ok := h.cache.TTL(itemCode); ok {
+		status += "; ttl=" + strconv.Itoa(int(ttl.Seconds()))
+	}
+	w.Header().Set("Cache-Status", status)
+	w.Header().Set("Age", strconv.Itoa(int(info.Age.Seconds())))
+	writeJSON(w, http.StatusOK, Price{ItemCode: itemCode, Price: price, Source: info.Source})
+}
+
+func (h *Handler) getPrices(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, Error{Error: fmt.Sprintf("decoding the request : %v", err)})
+		return
+	}
+	if len(req.ItemCodes) > h.opts.MaxBatchSize {
+		writeJSON(w, http.StatusRequestEntityTooLarge,
+			Error{Error: fmt.Sprintf("%d item codes asked, at most %d are allowed", len(req.ItemCodes), h.opts.MaxBatchSize)})
+		return
+	}
+	resp := BatchResponse{Results: make([]BatchResult, len(req.ItemCodes))}
+	for i, result := range h.cache.GetPriceResultsForCtx(r.Context(), req.ItemCodes...) {
+		resp.Results[i] = BatchResult{ItemCode: result.Key, Price: result.Value, Status: http.StatusOK}
+		if result.Err != nil {
+			resp.Results[i] = BatchResult{ItemCode: result.Key, Error: result.Err.Error(), Status: statusOf(result.Err)}
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// statusOf returns the HTTP status for a lookup that failed with err
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, sample1.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, sample1.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, sample1.ErrCircuitOpen), errors.Is(err, sample1.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
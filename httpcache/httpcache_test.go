@@ -0,0 +1,120 @@
+package httpcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+type fakePriceService map[string]float64
+
+func (f fakePriceService) GetPriceFor(itemCode string) (float64, error) {
+	price, ok := f[itemCode]
+	if !ok {
+		return 0, fmt.Errorf("unknown item [%v] : %w", itemCode, sample1.ErrNotFound)
+	}
+	return price, nil
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	cache := sample1.NewTransparentCache(fakePriceService{"p1": 5, "p2": 7}, time.Minute)
+	server := httptest.NewServer(NewHandler(cache, Options{MaxBatchSize: 3}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Check that a price is served with headers telling whether it came from the cache
+func TestHandler_GetPrice(t *testing.T) {
+	server := newTestServer(t)
+	for i, wantStatus := range []string{"pricecache; fwd=miss; stored; ttl=", "pricecache; hit; ttl="} {
+		resp, err := http.Get(server.URL + "/prices/p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var price Price
+		json.NewDecoder(resp.Body).Decode(&price)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || price.Price != 5 || price.ItemCode != "p1" {
+			t.Errorf("lookup %d: unexpected response %v %+v", i, resp.StatusCode, price)
+		}
+		if got := resp.Header.Get("Cache-Status"); !strings.HasPrefix(got, wantStatus) {
+			t.Errorf("lookup %d: expected a Cache-Status starting with %q but got %q", i, wantStatus, got)
+		}
+		if resp.Header.Get("Age") != "0" {
+			t.Errorf("lookup %d: expected an Age of 0 but got %q", i, resp.Header.Get("Age"))
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/prices/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown item but got %v", resp.StatusCode)
+	}
+}
+
+// Check that the item codes with slashes are served, escaped or not
+func TestHandler_GetPriceHierarchicalCode(t *testing.T) {
+	cache := sample1.NewTransparentCache(fakePriceService{"BOOKS/SCIFI/123": 9}, time.Minute)
+	server := httptest.NewServer(NewHandler(cache, Options{}))
+	t.Cleanup(server.Close)
+	for _, path := range []string{"/prices/BOOKS/SCIFI/123", "/prices/BOOKS%2FSCIFI%2F123"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var price Price
+		json.NewDecoder(resp.Body).Decode(&price)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || price.Price != 9 || price.ItemCode != "BOOKS/SCIFI/123" {
+			t.Errorf("%v : unexpected response %v %+v", path, resp.StatusCode, price)
+		}
+	}
+}
+
+// Check that a batch returns the price or the error of every item
+func TestHandler_GetPrices(t *testing.T) {
+	server := newTestServer(t)
+	resp, err := http.Post(server.URL+"/prices:batch", "application/json",
+		strings.NewReader(`{"itemCodes": ["p1", "unknown", "p2"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var batch BatchResponse
+	json.NewDecoder(resp.Body).Decode(&batch)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(batch.Results) != 3 {
+		t.Fatalf("unexpected response %v %+v", resp.StatusCode, batch)
+	}
+	if r := batch.Results[0]; r.ItemCode != "p1" || r.Price != 5 || r.Status != http.StatusOK {
+		t.Errorf("unexpected result for p1: %+v", r)
+	}
+	if r := batch.Results[1]; r.ItemCode != "unknown" || r.Error == "" || r.Status != http.StatusNotFound {
+		t.Errorf("unexpected result for the unknown item: %+v", r)
+	}
+	if r := batch.Results[2]; r.Price != 7 {
+		t.Errorf("unexpected result for p2: %+v", r)
+	}
+
+	for body, want := range map[string]int{
+		`{"itemCodes": ["p1", "p2", "p3", "p4"]}`: http.StatusRequestEntityTooLarge,
+		`not json`: http.StatusBadRequest,
+	} {
+		resp, err := http.Post(server.URL+"/prices:batch", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("expected %v for %q but got %v", want, body, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package httpcache
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// GET /prices:watch?itemCodes=p1,p2 upgrades to a WebSocket on which the server pushes a PriceUpdate whenever
+// the cache loads one of the watched items with a different price, and the client can send WatchRequests to
+// change the items it watches
+
+// PriceUpdate is pushed to the watchers of an item when its price changed
+type PriceUpdate struct {
+	ItemCode      string    `json:"itemCode"`
+	Price         float64   `json:"price"`
+	PreviousPrice *float64  `json:"previousPrice,omitempty"` // nil if the watcher didn't know the price before
+	FetchedAt     time.Time `json:"fetchedAt"`
+}
+
+// WatchRequest changes the items a WebSocket watches
+type WatchRequest struct {
+	Subscribe   []string `json:"subscribe,omitempty"`
+	Unsubscribe []string `json:"unsubscribe,omitempty"`
+}
+
+func (h *Handler) watch(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: h.opts.WatchOrigins})
+	if err != nil {
+		return // Accept already answered the request
+	}
+	defer conn.CloseNow()
+	var itemCodes []string
+	if param := r.URL.Query().Get("itemCodes"); param != "" {
+		itemCodes = strings.Split(param, ",")
+	}
+	subscription := h.cache.Subscribe(h.opts.WatchBuffer, itemCodes...)
+	defer subscription.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			var req WatchRequest
+			if err := wsjson.Read(ctx, conn, &req); err != nil {
+				return
+			}
+			subscription.Add(req.Subscribe...)
+			subscription.Remove(req.Unsubscribe...)
+		}
+	}()
+	for {
+		select {
+		case change := <-subscription.C:
+			if err := wsjson.Write(ctx, conn, priceUpdate(change)); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+		}
+	}
+}
+
+func priceUpdate(change sample1.PriceChange) PriceUpdate {
+	update := PriceUpdate{ItemCode: change.Key, Price: change.Value, FetchedAt: change.FetchedAt}
+	if change.HadValue {
+		update.PreviousPrice = &change.Previous
+	}
+	return update
+}
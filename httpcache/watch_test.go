@@ -0,0 +1,91 @@
+package httpcache
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// readUpdates forwards the updates pushed on conn
+func readUpdates(ctx context.Context, conn *websocket.Conn) <-chan PriceUpdate {
+	updates := make(chan PriceUpdate, 16)
+	go func() {
+		defer close(updates)
+		for {
+			var update PriceUpdate
+			if err := wsjson.Read(ctx, conn, &update); err != nil {
+				return
+			}
+			updates <- update
+		}
+	}()
+	return updates
+}
+
+// setUntilPushed sets the price of itemCode to new values until the update of one of them is pushed, since
+// the server may not have subscribed yet when the WebSocket is opened or asked for a new item
+// The updates of the values set before are skipped, so that the next update is the next change
+func setUntilPushed(t *testing.T, cache *sample1.TransparentCache, updates <-chan PriceUpdate, itemCode string) PriceUpdate {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for price := 1.0; ; price++ {
+		cache.Set(itemCode, price)
+		wait := time.After(50 * time.Millisecond)
+		for waiting := true; waiting; {
+			select {
+			case update := <-updates:
+				if update.ItemCode == itemCode && update.Price == price {
+					return update
+				}
+			case <-wait:
+				waiting = false
+			case <-deadline:
+				t.Fatalf("no update pushed for %v", itemCode)
+			}
+		}
+	}
+}
+
+// Check that a WebSocket gets the changes of the prices it watches, and can change what it watches
+func TestHandler_Watch(t *testing.T) {
+	cache := sample1.NewTransparentCache(fakePriceService{"p1": 5, "p2": 7}, time.Minute)
+	server := httptest.NewServer(NewHandler(cache, Options{}))
+	defer server.Close()
+
+	ctx := context.Background()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/prices:watch?itemCodes=p1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+	updates := readUpdates(ctx, conn)
+
+	if update := setUntilPushed(t, cache, updates, "p1"); update.ItemCode != "p1" || update.FetchedAt.IsZero() {
+		t.Errorf("unexpected update %+v", update)
+	}
+	if err := wsjson.Write(ctx, conn, WatchRequest{Subscribe: []string{"p2"}, Unsubscribe: []string{"p1"}}); err != nil {
+		t.Fatal(err)
+	}
+	update := setUntilPushed(t, cache, updates, "p2")
+	if update.ItemCode != "p2" {
+		t.Errorf("unexpected update %+v", update)
+	}
+	cache.Set("p1", 100)
+	cache.Set("p2", update.Price+1)
+	var update2 PriceUpdate
+	select {
+	case update2 = <-updates:
+	case <-time.After(time.Second):
+		t.Fatal("expected an update")
+	}
+	if update2.ItemCode != "p2" || update2.PreviousPrice == nil || *update2.PreviousPrice != update.Price {
+		t.Errorf("expected only the new price of p2 after unsubscribing from p1, got %+v", update2)
+	}
+}
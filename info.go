@@ -0,0 +1,38 @@
+package sample1
+
+import (
+	"context"
+	"time"
+)
+
+// Info tells where a value returned by GetWithInfo came from
+type Info struct {
+	Cached    bool          // the value was served from the cache, false if the lookup loaded it
+	FetchedAt time.Time     // when the value was loaded
+	Age       time.Duration // how old the value was when it was returned
+	Source    string        // where the value came from, as reported by the loader with ReportSource
+}
+
+// GetWithInfo is like Get, but also tells whether the value came from the cache, how old it is and its source
+// Values that were loaded but couldn't be kept (the cache was invalidated meanwhile) have no known source
+func (c *Cache[K, V]) GetWithInfo(ctx context.Context, key K) (V, Info, error) {
+	entry, ok := c.store.Get(key)
+	value, hit, err := c.lookup(ctx, key, entry, ok)
+	if err != nil {
+		return value, Info{}, err
+	}
+	now := c.clock.Now()
+	if !hit {
+		current, found := c.store.Get(key)
+		switch {
+		case found && current.Err == nil && ok && current.FetchedAt.Equal(entry.FetchedAt):
+			// nothing new was stored, the old value was served because the load failed (see WithStaleIfError)
+			hit = true
+		case found && current.Err == nil:
+			entry = current
+		default:
+			entry = Entry[V]{FetchedAt: now}
+		}
+	}
+	return value, Info{Cached: hit, FetchedAt: entry.FetchedAt, Age: now.Sub(entry.FetchedAt), Source: entry.Source}, nil
+}
@@ -0,0 +1,56 @@
+package sample1
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// Check that the info tells apart loaded and cached prices, with their age and source
+func TestGetPriceWithInfo(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5, err: nil}}}
+	cache := NewTransparentCache(NewFallbackService(Fallback{Name: "primary", Service: mockService}), time.Minute,
+		WithClock(clock))
+	price, info, err := cache.GetPriceWithInfo("p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, 5, price, "wrong price returned")
+	want := Info{Cached: false, FetchedAt: clock.Now(), Age: 0, Source: "primary"}
+	if info != want {
+		t.Errorf("expected %+v but got %+v", want, info)
+	}
+	clock.Advance(10 * time.Second)
+	_, info, _ = cache.GetPriceWithInfo("p1")
+	want = Info{Cached: true, FetchedAt: clock.Now().Add(-10 * time.Second), Age: 10 * time.Second, Source: "primary"}
+	if info != want {
+		t.Errorf("expected %+v but got %+v", want, info)
+	}
+}
+
+// Check that a stale price served because the service failed is reported as cached
+func TestGetPriceWithInfo_StaleIfError(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5, err: nil}}}
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock), WithStaleIfError(time.Minute))
+	getPriceWithNoErr(t, cache, "p1")
+	mockService.mu.Lock()
+	mockService.mockResults = map[string]mockResult{
+		"p1": {price: 0, err: errors.New("503 service unavailable")},
+		"p2": {price: 0, err: ErrNotFound},
+	}
+	mockService.mu.Unlock()
+	clock.Advance(90 * time.Second)
+	price, info, err := cache.GetPriceWithInfo("p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, 5, price, "expected the last known price")
+	if !info.Cached || info.Age != 90*time.Second {
+		t.Errorf("expected a cached price 90s old but got %+v", info)
+	}
+	if _, _, err := cache.GetPriceWithInfo("p2"); err == nil {
+		t.Error("expected the error of the lookup")
+	}
+}
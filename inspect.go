@@ -0,0 +1,136 @@
+package sample1
+
+import "time"
+
+// Peek returns the cached value for key and how old it is, without loading it and without
+// counting as a use for the eviction policy
+// Expired values are returned as well, ok is false only if there is no value cached for key
+// (cached load errors don't count as values)
+func (c *Cache[K, V]) Peek(key K) (value V, age time.Duration, ok bool) {
+	entry, ok := c.store.Get(key)
+	if !ok || entry.Err != nil {
+		return value, 0, false
+	}
+	return entry.Value, c.clock.Now().Sub(entry.FetchedAt), true
+}
+
+// Contains tells if there is a fresh (not expired) value cached for key, without loading it
+func (c *Cache[K, V]) Contains(key K) bool {
+	entry, ok := c.store.Get(key)
+	return ok && entry.Err == nil && !c.expired(entry, entry.MaxAge, c.clock.Now())
+}
+
+// TTL returns how long the value cached for key stays fresh, ok is false if there is no value cached for key
+// or it is expired already. Per key overrides, jitter and the hard TTL are taken into account, but not WithStaleWhileRevalidate
+// nor the early refreshes that may load the value before then
+func (c *Cache[K, V]) TTL(key K) (ttl time.Duration, ok bool) {
+	entry, ok := c.store.Get(key)
+	now := c.clock.Now()
+	if !ok || entry.Err != nil || c.expired(entry, entry.MaxAge, now) {
+		return 0, false
+	}
+	if c.hardTTL > 0 {
+		entry.MaxAge = min(entry.MaxAge, c.hardTTL)
+	}
+	return entry.MaxAge - now.Sub(entry.FetchedAt), true
+}
+
+// Len returns the number of cached entries, including the expired ones that were not dropped yet
+// and the cached load errors
+func (c *Cache[K, V]) Len() int {
+	return c.store.Len()
+}
+
+// KeyFilter selects the keys returned by Keys
+type KeyFilter int
+
+const (
+	AllKeys     KeyFilter = iota // every key with a cached value
+	FreshKeys                    // the keys whose value is fresh (not expired)
+	ExpiredKeys                  // the keys whose value is expired but not dropped yet
+)
+
+// Keys returns the keys with a cached value that pass filter, in no particular order
+// Keys whose load error is cached aren't returned
+func (c *Cache[K, V]) Keys(filter KeyFilter) []K {
+	now := c.clock.Now()
+	var keys []K
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.store.Range(func(key K, entry Entry[V]) bool {
+		if entry.Err != nil {
+			return true
+		}
+		expired := c.expired(entry, entry.MaxAge, now)
+		if filter == AllKeys || (filter == FreshKeys && !expired) || (filter == ExpiredKeys && expired) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	return keys
+}
+
+// Range calls fn for every cached value, expired ones included, until it returns false
+// fn sees the cache as it was when Range was called, it can call back into the cache but won't see its changes
+// Loaded errors aren't values, they are skipped
+func (c *Cache[K, V]) Range(fn func(key K, value V, fetchedAt time.Time) bool) {
+	type item struct {
+		key   K
+		entry Entry[V]
+	}
+	var items []item
+	c.mu.RLock()
+	c.store.Range(func(key K, entry Entry[V]) bool {
+		if entry.Err == nil {
+			items = append(items, item{key: key, entry: entry})
+		}
+		return true
+	})
+	c.mu.RUnlock()
+	for _, it := range items {
+		if !fn(it.key, it.entry.Value, it.entry.FetchedAt) {
+			return
+		}
+	}
+}
+
+// Set stores value for key as if it was just loaded, so that it stays fresh for maxAge
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	evicted := c.save(key, c.valueEntry(key, value, c.clock.Now()))
+	c.mu.Unlock()
+	c.emit(evicted...)
+}
+
+// Touch makes the value cached for key fresh again as if it was just loaded, without loading it, for callers that
+// know by other means that it is still valid. It returns false if there was no value cached for key
+func (c *Cache[K, V]) Touch(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.store.Get(key)
+	if !ok || entry.Err != nil {
+		return false
+	}
+	entry.FetchedAt = c.clock.Now()
+	entry.MaxAge = c.valueMaxAge(key, entry.Value)
+	c.store.Set(key, entry)
+	return true
+}
+
+// SetMany stores several values at once as if they were just loaded, see Set
+func (c *Cache[K, V]) SetMany(values map[K]V) {
+	c.SetManyAt(values, c.clock.Now())
+}
+
+// SetManyAt stores several values at once as if they were loaded at fetchedAt, so that values computed
+// earlier (by a batch job for instance) don't stay fresh for longer than they should
+// Every entry is written whole, lookups see either the old or the new value of a key
+func (c *Cache[K, V]) SetManyAt(values map[K]V, fetchedAt time.Time) {
+	var evicted []Event[K, V]
+	c.mu.Lock()
+	for key, value := range values {
+		evicted = append(evicted, c.save(key, c.valueEntry(key, value, fetchedAt))...)
+	}
+	c.mu.Unlock()
+	c.emit(evicted...)
+}
@@ -0,0 +1,184 @@
+package sample1
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Check that inspecting the cache never calls the service
+func TestPeek_DoesNotCallService(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	if _, _, ok := cache.Peek("p1"); ok {
+		t.Error("expected p1 not to be cached")
+	}
+	if cache.Contains("p1") {
+		t.Error("expected p1 not to be cached")
+	}
+	assertInt(t, 0, cache.Len(), "wrong number of cached prices")
+	assertInt(t, 0, mockService.getNumCalls(), "wrong number of service calls")
+
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	price, age, ok := cache.Peek("p1")
+	if !ok || age < 0 || age > time.Second {
+		t.Errorf("expected p1 to be cached recently, got ok %v and age %v", ok, age)
+	}
+	assertFloat(t, 5, price, "wrong price peeked")
+	if !cache.Contains("p1") {
+		t.Error("expected p1 to be cached")
+	}
+	assertInt(t, 1, cache.Len(), "wrong number of cached prices")
+	assertInt(t, 1, mockService.getNumCalls(), "wrong number of service calls")
+}
+
+// Check that expired prices can be peeked but are not reported as contained
+func TestPeek_ReturnsExpiredPrices(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	maxAge := 50 * time.Millisecond
+	cache := NewTransparentCache(mockService, maxAge)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	time.Sleep(maxAge * 2)
+	if cache.Contains("p1") {
+		t.Error("expected p1 to be expired")
+	}
+	price, age, ok := cache.Peek("p1")
+	if !ok || age <= maxAge {
+		t.Errorf("expected p1 to be peeked as expired, got ok %v and age %v", ok, age)
+	}
+	assertFloat(t, 5, price, "wrong price peeked")
+}
+
+// Check that manually set prices are served without calling the service
+func TestSet_SeedsPrices(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{}}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxEntries(1))
+	cache.Set("p1", 5)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	cache.Set("p2", 7)
+	assertFloat(t, 7, getPriceWithNoErr(t, cache, "p2"), "wrong price returned")
+	assertInt(t, 1, cache.Len(), "seeded prices should respect max entries")
+	assertInt(t, 0, mockService.getNumCalls(), "wrong number of service calls")
+}
+
+// Check that TTL tells how long a price stays fresh, and nothing for missing or expired ones
+func TestTTL(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5, err: nil}}}
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock))
+	if _, ok := cache.TTL("p1"); ok {
+		t.Error("expected no TTL for a missing price")
+	}
+	getPriceWithNoErr(t, cache, "p1")
+	clock.Advance(20 * time.Second)
+	if ttl, ok := cache.TTL("p1"); !ok || ttl != 40*time.Second {
+		t.Errorf("expected 40s left but got %v, %v", ttl, ok)
+	}
+	cache.SetMaxAgeFor("p1", 30*time.Second)
+	if ttl, ok := cache.TTL("p1"); !ok || ttl != 10*time.Second {
+		t.Errorf("expected the override to be used, got %v, %v", ttl, ok)
+	}
+	clock.Advance(11 * time.Second)
+	if _, ok := cache.TTL("p1"); ok {
+		t.Error("expected no TTL for an expired price")
+	}
+	assertInt(t, 1, mockService.getNumCalls(), "TTL should not call the service")
+}
+
+// Check that a touched price stays fresh for another maxAge without calling the service
+func TestTouch(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5, err: nil}}}
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock))
+	if cache.Touch("p1") {
+		t.Error("a missing price should not be touched")
+	}
+	getPriceWithNoErr(t, cache, "p1")
+	clock.Advance(50 * time.Second)
+	if !cache.Touch("p1") {
+		t.Error("expected the cached price to be touched")
+	}
+	clock.Advance(50 * time.Second)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	if ttl, _ := cache.TTL("p1"); ttl != 10*time.Second {
+		t.Errorf("expected the price to be fresh for another minute from the touch, %v left", ttl)
+	}
+	assertInt(t, 1, mockService.getNumCalls(), "a touched price should not be loaded again")
+}
+
+// Check that Range goes through the cached prices, skipping the cached errors, and can call back into the cache
+func TestRange(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p3": {price: 0, err: ErrNotFound}}}
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock), WithNegativeCaching(time.Minute, nil))
+	cache.Set("p1", 5)
+	clock.Advance(time.Second)
+	cache.Set("p2", 7)
+	cache.GetPriceFor("p3")
+	seen := map[string]float64{}
+	cache.Range(func(itemCode string, price float64, fetchedAt time.Time) bool {
+		seen[itemCode] = price
+		if itemCode == "p1" && !fetchedAt.Equal(clock.Now().Add(-time.Second)) {
+			t.Errorf("wrong fetchedAt for p1: %v", fetchedAt)
+		}
+		cache.Invalidate(itemCode)
+		return true
+	})
+	if len(seen) != 2 || seen["p1"] != 5 || seen["p2"] != 7 {
+		t.Errorf("expected p1 and p2 to be seen, got %v", seen)
+	}
+	stops := 0
+	cache.Set("p1", 5)
+	cache.Set("p2", 7)
+	cache.Range(func(string, float64, time.Time) bool {
+		stops++
+		return false
+	})
+	assertInt(t, 1, stops, "range should stop when fn returns false")
+}
+
+// Check that Keys lists the cached items, filtered by freshness
+func TestKeys(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewTransparentCache(&mockPriceService{}, time.Minute, WithClock(clock))
+	cache.Set("p1", 5)
+	clock.Advance(50 * time.Second)
+	cache.Set("p2", 7)
+	clock.Advance(20 * time.Second)
+	for filter, want := range map[KeyFilter][]string{
+		AllKeys:     {"p1", "p2"},
+		FreshKeys:   {"p2"},
+		ExpiredKeys: {"p1"},
+	} {
+		keys := cache.Keys(filter)
+		sort.Strings(keys)
+		if fmt.Sprint(keys) != fmt.Sprint(want) {
+			t.Errorf("expected %v for filter %v but got %v", want, filter, keys)
+		}
+	}
+}
+
+// Check that seeded prices are served without calling the service, and expire from their own timestamp
+func TestSetMany(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p3": {price: 10, err: nil}}}
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock))
+	cache.SetMany(map[string]float64{"p1": 5, "p2": 7})
+	assertFloats(t, []float64{5, 7}, getPricesWithNoErr(t, cache, "p1", "p2"), "wrong prices returned")
+	cache.SetManyAt(map[string]float64{"p3": 9}, clock.Now().Add(-50*time.Second))
+	if ttl, ok := cache.TTL("p3"); !ok || ttl != 10*time.Second {
+		t.Errorf("expected p3 to be fresh for 10s, got %v, %v", ttl, ok)
+	}
+	clock.Advance(11 * time.Second)
+	assertFloat(t, 10, getPriceWithNoErr(t, cache, "p3"), "the seeded price should have expired")
+	assertInt(t, 1, mockService.getNumCalls(), "wrong number of service calls")
+}
@@ -0,0 +1,68 @@
+// Package entrycodec encodes cache entries for the stores that keep them outside the process
+package entrycodec
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// record is how an entry is encoded, errors of negative entries are kept as their message
+type record[V any] struct {
+	Value     V             `json:"value"`
+	FetchedAt time.Time     `json:"fetchedAt"`
+	LoadTime  time.Duration `json:"loadTime,omitempty"`
+	MaxAge    time.Duration `json:"maxAge"`
+	Err       string        `json:"err,omitempty"`
+	NotFound  bool          `json:"notFound,omitempty"`
+	Permanent bool          `json:"permanent,omitempty"` // marked with sample1.MarkPermanent, not found errors aside
+	Source    string        `json:"source,omitempty"`
+}
+
+// remoteError is the error of a negative entry once decoded
+type remoteError struct {
+	msg      string
+	notFound bool
+}
+
+func (e *remoteError) Error() string {
+	return e.msg
+}
+
+// Is keeps errors.Is(err, sample1.ErrNotFound) working for decoded negative entries
+func (e *remoteError) Is(target error) bool {
+	return e.notFound && target == sample1.ErrNotFound
+}
+
+// Marshal encodes entry as JSON
+func Marshal[V any](entry sample1.Entry[V]) ([]byte, error) {
+	r := record[V]{
+		Value: entry.Value, FetchedAt: entry.FetchedAt, LoadTime: entry.LoadTime, MaxAge: entry.MaxAge, Source: entry.Source,
+	}
+	if entry.Err != nil {
+		r.Err = entry.Err.Error()
+		r.NotFound = errors.Is(entry.Err, sample1.ErrNotFound)
+		r.Permanent = !r.NotFound && sample1.DefaultErrorClassifier(entry.Err) == sample1.Permanent
+	}
+	return json.Marshal(r)
+}
+
+// Unmarshal decodes an entry encoded by Marshal
+func Unmarshal[V any](data []byte) (sample1.Entry[V], error) {
+	var r record[V]
+	if err := json.Unmarshal(data, &r); err != nil {
+		return sample1.Entry[V]{}, err
+	}
+	entry := sample1.Entry[V]{
+		Value: r.Value, FetchedAt: r.FetchedAt, LoadTime: r.LoadTime, MaxAge: r.MaxAge, Source: r.Source,
+	}
+	if r.Err != "" {
+		entry.Err = &remoteError{msg: r.Err, notFound: r.NotFound}
+		if r.Permanent {
+			entry.Err = sample1.MarkPermanent(entry.Err)
+		}
+	}
+	return entry, nil
+}
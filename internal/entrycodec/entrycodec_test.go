@@ -0,0 +1,37 @@
+package entrycodec
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// Check that the errors of negative entries keep their message and their class once decoded
+func TestMarshal_NegativeEntries(t *testing.T) {
+	for _, err := range []error{
+		fmt.Errorf("unknown item [p1] : %w", sample1.ErrNotFound),
+		sample1.MarkPermanent(errors.New("invalid item code")),
+		fmt.Errorf("pricing : %w", sample1.ErrUnavailable),
+	} {
+		data, marshalErr := Marshal(sample1.Entry[float64]{Err: err, FetchedAt: time.Now(), MaxAge: time.Minute})
+		if marshalErr != nil {
+			t.Fatal(marshalErr)
+		}
+		entry, unmarshalErr := Unmarshal[float64](data)
+		if unmarshalErr != nil {
+			t.Fatal(unmarshalErr)
+		}
+		if entry.Err == nil || entry.Err.Error() != err.Error() {
+			t.Errorf("[%v] : wrong error read back: %v", err, entry.Err)
+		}
+		if got, want := sample1.DefaultErrorClassifier(entry.Err), sample1.DefaultErrorClassifier(err); got != want {
+			t.Errorf("[%v] : expected a %v error read back but got a %v one", err, want, got)
+		}
+		if errors.Is(entry.Err, sample1.ErrNotFound) != errors.Is(err, sample1.ErrNotFound) {
+			t.Errorf("[%v] : the error read back should match ErrNotFound the same way", err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Package invalidation encodes the messages the invalidators of the cache publish for the other processes
+package invalidation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Message is one invalidation, of Keys or of every key if Clear is set
+type Message struct {
+	Origin string   `json:"origin"` // the invalidator that published it, so that it can ignore its own messages
+	Keys   []string `json:"keys,omitempty"`
+	Clear  bool     `json:"clear,omitempty"`
+}
+
+// Encode returns the message as JSON
+func Encode(msg Message) []byte {
+	data, _ := json.Marshal(msg) // can't fail, it only has strings and a bool
+	return data
+}
+
+// Decode returns the message encoded in data
+func Decode(data []byte) (Message, error) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return Message{}, fmt.Errorf("decoding invalidation %q : %w", data, err)
+	}
+	return msg, nil
+}
+
+// Deliver calls onInvalidate or onClear for msg, unless origin published it
+func Deliver(msg Message, origin string, onInvalidate func(keys []string), onClear func()) {
+	switch {
+	case msg.Origin == origin:
+	case msg.Clear:
+		onClear()
+	case len(msg.Keys) > 0:
+		onInvalidate(msg.Keys)
+	}
+}
+
+// NewOrigin returns a random identifier for an invalidator
+func NewOrigin() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,119 @@
+package sample1
+
+// Invalidate drops the cached value for key, so that the next Get loads it again
+func (c *Cache[K, V]) Invalidate(key K) {
+	c.InvalidateMany(key)
+}
+
+// InvalidateMany drops the cached values for all the given keys
+func (c *Cache[K, V]) InvalidateMany(keys ...K) {
+	c.invalidateMany(keys)
+	c.publishInvalidation(keys)
+}
+
+// invalidateMany drops the cached values for keys without telling the other instances
+func (c *Cache[K, V]) invalidateMany(keys []K) {
+	listening := c.listeners.has(Invalidated)
+	var events []Event[K, V]
+	c.mu.Lock()
+	c.generation++
+	for _, key := range keys {
+		if entry, ok := c.remove(key); ok && listening {
+			events = append(events, invalidated(key, entry))
+		}
+	}
+	c.mu.Unlock()
+	c.emit(events...)
+}
+
+// invalidated returns the Invalidated event of the entry for key
+func invalidated[K comparable, V any](key K, entry Entry[V]) Event[K, V] {
+	return Event[K, V]{Key: key, Value: entry.Value, Err: entry.Err, Reason: Invalidated}
+}
+
+// InvalidateMatching drops the cached values (and cached errors) of every key for which match returns true
+// match is called without holding any lock of the cache, so it can call back into it
+func (c *Cache[K, V]) InvalidateMatching(match func(key K) bool) {
+	var keys []K
+	c.mu.RLock()
+	c.store.Range(func(key K, _ Entry[V]) bool {
+		keys = append(keys, key)
+		return true
+	})
+	c.mu.RUnlock()
+	matching := keys[:0]
+	for _, key := range keys {
+		if match(key) {
+			matching = append(matching, key)
+		}
+	}
+	if len(matching) > 0 {
+		c.InvalidateMany(matching...)
+	}
+}
+
+// Clear drops every cached value
+func (c *Cache[K, V]) Clear() {
+	c.clear()
+	c.publishClear()
+}
+
+// clear drops every cached value without telling the other instances
+func (c *Cache[K, V]) clear() {
+	listening := c.listeners.has(Invalidated)
+	var events []Event[K, V]
+	c.mu.Lock()
+	c.generation++
+	if c.policy != nil || listening {
+		c.store.Range(func(key K, entry Entry[V]) bool {
+			if c.policy != nil {
+				c.policy.OnRemove(key)
+			}
+			if listening {
+				events = append(events, invalidated(key, entry))
+			}
+			return true
+		})
+	}
+	c.store.Clear()
+	c.forgetAll()
+	c.mu.Unlock()
+	c.emit(events...)
+}
+
+// remove deletes the entry for key and returns it, c.mu must be held for writing
+func (c *Cache[K, V]) remove(key K) (Entry[V], bool) {
+	entry, ok := c.store.Get(key)
+	if !ok {
+		return entry, false
+	}
+	c.store.Delete(key)
+	c.forget(key)
+	if c.policy != nil {
+		c.policy.OnRemove(key)
+	}
+	return entry, true
+}
+
+// forget drops the state kept on the side of the entry for key (tags and weight), once the entry is gone, c.mu
+// must be held for writing
+func (c *Cache[K, V]) forget(key K) {
+	c.tags.remove(key)
+	c.weights.remove(key)
+}
+
+// forgetPast drops the history and the adapted maxAge of key, which outlive its invalidations (a refresh
+// published by WithInvalidator is one) but not its eviction nor its expiry, c.mu must be held for writing
+func (c *Cache[K, V]) forgetPast(key K) {
+	c.history.remove(key)
+	c.adaptive.remove(key)
+}
+
+// forgetAll drops the state kept on the side of every entry, once the store is cleared, c.mu must be held for
+// writing
+func (c *Cache[K, V]) forgetAll() {
+	c.tags.clear()
+	c.weights.clear()
+	c.history.clear()
+	c.adaptive.clear()
+}
@@ -0,0 +1,89 @@
+package sample1
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Check that an invalidated item is fetched again from the service
+func TestInvalidate_DropsEntries(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+			"p3": {price: 9, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxEntries(10))
+	assertFloats(t, []float64{5, 7, 9}, getPricesWithNoErr(t, cache, "p1", "p2", "p3"), "wrong price returned")
+	cache.Invalidate("p1")
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 4, mockService.getNumCalls(), "wrong number of service calls")
+	cache.InvalidateMany("p2", "p3", "unknown")
+	assertInt(t, 1, cache.Len(), "wrong number of cached prices")
+	assertFloats(t, []float64{5, 7, 9}, getPricesWithNoErr(t, cache, "p1", "p2", "p3"), "wrong price returned")
+	assertInt(t, 6, mockService.getNumCalls(), "wrong number of service calls")
+}
+
+// Check that Clear drops everything, including the eviction policy state
+func TestClear_DropsAllEntries(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxEntries(10))
+	assertFloats(t, []float64{5, 7}, getPricesWithNoErr(t, cache, "p1", "p2"), "wrong price returned")
+	cache.Clear()
+	assertInt(t, 0, cache.Len(), "wrong number of cached prices")
+	if _, ok := cache.policy.Victim(); ok {
+		t.Error("expected the eviction policy to be empty")
+	}
+	assertFloats(t, []float64{5, 7}, getPricesWithNoErr(t, cache, "p1", "p2"), "wrong price returned")
+	assertInt(t, 4, mockService.getNumCalls(), "wrong number of service calls")
+}
+
+// Check that a fetch that was in flight when the cache was invalidated doesn't store its outdated price
+func TestInvalidate_DiscardsInFlightFetch(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay: 100 * time.Millisecond,
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cache.Invalidate("p1")
+	wg.Wait()
+	assertInt(t, 0, cache.Len(), "wrong number of cached prices")
+}
+
+// Check that InvalidatePrefix drops a whole family of items, and InvalidateMatching any item it matches
+func TestInvalidatePrefixAndMatching(t *testing.T) {
+	cache := NewTransparentCache(&mockPriceService{}, time.Minute, WithMaxEntries(10))
+	for _, itemCode := range []string{"BOOKS/SCIFI/1", "BOOKS/SCIFI/2", "BOOKS/CRIME/1", "MUSIC/JAZZ/1"} {
+		cache.Set(itemCode, 5)
+	}
+	cache.InvalidatePrefix("BOOKS/SCIFI/")
+	if cache.Contains("BOOKS/SCIFI/1") || cache.Contains("BOOKS/SCIFI/2") {
+		t.Error("the items under the prefix should have been dropped")
+	}
+	assertInt(t, 2, cache.Len(), "the other items should stay")
+	cache.InvalidateMatching(func(itemCode string) bool {
+		return cache.Contains(itemCode) && strings.HasSuffix(itemCode, "/1") && !strings.HasPrefix(itemCode, "MUSIC")
+	})
+	if cache.Contains("BOOKS/CRIME/1") || !cache.Contains("MUSIC/JAZZ/1") {
+		t.Error("only the matching items should have been dropped")
+	}
+	cache.InvalidateMatching(func(string) bool { return false })
+	assertInt(t, 1, cache.Len(), "nothing should be dropped when nothing matches")
+}
@@ -0,0 +1,79 @@
+package sample1
+
+import "fmt"
+
+// Invalidator carries invalidations between the instances of a cache running in several processes, so that
+// when one of them invalidates or reloads a value the others drop their copy instead of serving it until it expires
+// Implementations report their failures by their own means, a lost message only delays the invalidation until
+// the value expires
+type Invalidator interface {
+	// Invalidate tells the other instances that keys were invalidated
+	Invalidate(keys []string)
+	// Clear tells the other instances that every key was invalidated
+	Clear()
+	// Watch calls onInvalidate and onClear with what the other instances published, until stop is called
+	// What this invalidator published itself isn't reported
+	Watch(onInvalidate func(keys []string), onClear func()) (stop func())
+}
+
+// WithInvalidator makes the cache publish its invalidations (Invalidate, InvalidateMany, InvalidateTag, Clear...)
+// and the loads replacing a cached value through inv, and drop what the other instances publish
+// Keys are sent as they are, the cache must be keyed by strings (a TransparentCache is), NewCache panics otherwise
+func WithInvalidator(inv Invalidator) Option {
+	return func(c *config) {
+		c.invalidator = inv
+	}
+}
+
+// checkStringKeys panics if the options that send the keys as strings (inv, locker) are used for a cache of K
+func checkStringKeys[K comparable](inv Invalidator, locker RefreshLocker) {
+	var key K
+	if _, ok := any(key).(string); ok {
+		return
+	}
+	if inv != nil {
+		panic(fmt.Sprintf("sample1: invalidator %T can't be used for keys of type %T", inv, key))
+	}
+	if locker != nil {
+		panic(fmt.Sprintf("sample1: refresh locker %T can't be used for keys of type %T", locker, key))
+	}
+}
+
+// watchInvalidations drops the keys the other instances invalidate, until Close
+func (c *Cache[K, V]) watchInvalidations() {
+	if c.invalidator == nil {
+		return
+	}
+	stop := c.invalidator.Watch(func(keys []string) {
+		converted := make([]K, len(keys))
+		for i, key := range keys {
+			converted[i] = any(key).(K)
+		}
+		c.invalidateMany(converted)
+	}, c.clear)
+	c.background.Add(1)
+	go func() {
+		defer c.background.Done()
+		<-c.stop
+		stop()
+	}()
+}
+
+// publishInvalidation tells the other instances that keys were invalidated, if there is an invalidator
+func (c *Cache[K, V]) publishInvalidation(keys []K) {
+	if c.invalidator == nil || len(keys) == 0 {
+		return
+	}
+	converted := make([]string, len(keys))
+	for i, key := range keys {
+		converted[i] = any(key).(string)
+	}
+	c.invalidator.Invalidate(converted)
+}
+
+// publishClear tells the other instances that every key was invalidated, if there is an invalidator
+func (c *Cache[K, V]) publishClear() {
+	if c.invalidator != nil {
+		c.invalidator.Clear()
+	}
+}
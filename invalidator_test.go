@@ -0,0 +1,146 @@
+package sample1
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// invalidationBus carries the invalidations between the peers that joined it, like Redis pub/sub would
+type invalidationBus struct {
+	mu    sync.Mutex
+	peers []*busPeer
+}
+
+// busPeer is how one process sees the bus, what it publishes isn't delivered to it
+type busPeer struct {
+	bus          *invalidationBus
+	onInvalidate func(keys []string)
+	onClear      func()
+	published    int
+}
+
+func (b *invalidationBus) join() *busPeer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	peer := &busPeer{bus: b}
+	b.peers = append(b.peers, peer)
+	return peer
+}
+
+func (p *busPeer) Invalidate(keys []string) {
+	p.deliver(func(other *busPeer) { other.onInvalidate(keys) })
+}
+
+func (p *busPeer) Clear() {
+	p.deliver(func(other *busPeer) { other.onClear() })
+}
+
+func (p *busPeer) deliver(fn func(other *busPeer)) {
+	p.bus.mu.Lock()
+	p.published++
+	var others []*busPeer
+	for _, other := range p.bus.peers {
+		if other != p && other.onInvalidate != nil {
+			others = append(others, other)
+		}
+	}
+	p.bus.mu.Unlock()
+	for _, other := range others {
+		fn(other)
+	}
+}
+
+func (p *busPeer) Watch(onInvalidate func(keys []string), onClear func()) func() {
+	p.bus.mu.Lock()
+	defer p.bus.mu.Unlock()
+	p.onInvalidate, p.onClear = onInvalidate, onClear
+	return func() {
+		p.bus.mu.Lock()
+		defer p.bus.mu.Unlock()
+		p.onInvalidate, p.onClear = nil, nil
+	}
+}
+
+func (p *busPeer) getPublished() int {
+	p.bus.mu.Lock()
+	defer p.bus.mu.Unlock()
+	return p.published
+}
+
+func newPeerCaches(t *testing.T, opts ...Option) (first, second *TransparentCache, firstPeer, secondPeer *busPeer) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}, "p2": {price: 7}}}
+	bus := &invalidationBus{}
+	firstPeer, secondPeer = bus.join(), bus.join()
+	first = New(mockService, append(opts, WithInvalidator(firstPeer))...)
+	second = New(mockService, append(opts, WithInvalidator(secondPeer))...)
+	t.Cleanup(func() {
+		first.Close()
+		second.Close()
+	})
+	return first, second, firstPeer, secondPeer
+}
+
+// Check that invalidating an item or clearing one cache drops the copies of the others
+func TestWithInvalidator_PublishesInvalidations(t *testing.T) {
+	first, second, _, secondPeer := newPeerCaches(t)
+	getPricesWithNoErr(t, first, "p1", "p2")
+	getPricesWithNoErr(t, second, "p1", "p2")
+
+	first.Invalidate("p1")
+	if second.Contains("p1") || !second.Contains("p2") {
+		t.Error("only p1 should have been dropped by the other cache")
+	}
+	first.Clear()
+	if second.Len() != 0 {
+		t.Errorf("the other cache should have been cleared, it has %v entries", second.Len())
+	}
+	assertInt(t, 0, secondPeer.getPublished(), "what the other cache dropped for the first one shouldn't be published again")
+}
+
+// Check that a load replacing a cached value drops the copies of the others, while a plain miss doesn't
+func TestWithInvalidator_PublishesReplacedValues(t *testing.T) {
+	clock := newFakeClock()
+	first, second, firstPeer, _ := newPeerCaches(t, WithClock(clock), WithMaxAge(time.Minute))
+	getPriceWithNoErr(t, first, "p1")
+	getPriceWithNoErr(t, second, "p1")
+	assertInt(t, 0, firstPeer.getPublished(), "a miss shouldn't be published")
+
+	clock.Advance(2 * time.Minute)
+	getPriceWithNoErr(t, first, "p1")
+	assertInt(t, 1, firstPeer.getPublished(), "the reload should have been published")
+	if second.Contains("p1") {
+		t.Error("the other cache should have dropped its copy of p1")
+	}
+
+	first.GetMany(context.Background(), "p1", "p2")
+	assertInt(t, 1, firstPeer.getPublished(), "fresh values aren't loaded again, so nothing should be published")
+}
+
+// Check that the cache stops watching the invalidations once closed
+func TestWithInvalidator_StopsWatchingOnClose(t *testing.T) {
+	first, second, _, secondPeer := newPeerCaches(t)
+	getPriceWithNoErr(t, second, "p1")
+	second.Close()
+	secondPeer.bus.mu.Lock()
+	watching := secondPeer.onInvalidate != nil
+	secondPeer.bus.mu.Unlock()
+	if watching {
+		t.Error("the closed cache should have stopped watching")
+	}
+	first.Invalidate("p1")
+	if !second.Contains("p1") {
+		t.Error("the closed cache shouldn't drop p1 anymore")
+	}
+}
+
+// Check that an invalidator is refused for keys that aren't strings
+func TestWithInvalidator_PanicsOnKeysThatArentStrings(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	NewCache(func(context.Context, int) (float64, error) { return 0, nil }, WithInvalidator((&invalidationBus{}).join()))
+}
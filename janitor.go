@@ -0,0 +1,45 @@
+package sample1
+
+import "time"
+
+// WithJanitor makes the cache sweep its entries every interval and delete the expired ones, so that keys that
+// are not asked for anymore don't keep memory forever. Close stops it
+// Entries are only deleted once they can't be served at all, stale-while-revalidate and stale-if-error included
+func WithJanitor(interval time.Duration) Option {
+	return func(c *config) {
+		c.janitorInterval = interval
+	}
+}
+
+// purgeExpired deletes the entries that can't be served anymore and returns how many there were
+func (c *Cache[K, V]) purgeExpired() int {
+	now := c.clock.Now()
+	var expired []K
+	c.store.Range(func(key K, entry Entry[V]) bool {
+		if c.unservable(entry, now) {
+			expired = append(expired, key)
+		}
+		return true
+	})
+	var events []Event[K, V]
+	c.mu.Lock()
+	for _, key := range expired {
+		// the entry may have been loaded again since the sweep
+		if entry, ok := c.store.Get(key); ok && c.unservable(entry, now) {
+			c.remove(key)
+			c.forgetPast(key)
+			events = append(events, Event[K, V]{Key: key, Value: entry.Value, Err: entry.Err, Reason: Expired})
+		}
+	}
+	c.mu.Unlock()
+	c.emit(events...)
+	return len(events)
+}
+
+// unservable tells if an entry is too old to be returned in any way
+func (c *Cache[K, V]) unservable(entry Entry[V], now time.Time) bool {
+	if entry.Err != nil {
+		return c.expired(entry, entry.MaxAge, now)
+	}
+	return c.expired(entry, entry.MaxAge+max(c.maxStale, c.staleIfError), now)
+}
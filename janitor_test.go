@@ -0,0 +1,58 @@
+package sample1
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Check that the sweep deletes the expired entries and keeps the ones that can still be served
+func TestPurgeExpired_DeletesExpiredEntries(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+			"p3": {price: 0, err: ErrNotFound},
+		},
+	}
+	cache := New(mockService, WithMaxAge(time.Minute), WithClock(clock), WithMaxEntries(10),
+		WithStaleIfError(time.Minute), WithNegativeCaching(30*time.Second, nil))
+	getPriceWithNoErr(t, cache, "p1")
+	cache.GetPriceFor("p3")
+	clock.Advance(90 * time.Second)
+	getPriceWithNoErr(t, cache, "p2")
+
+	assertInt(t, 1, cache.purgeExpired(), "only the negative entry should have been deleted")
+	clock.Advance(time.Minute)
+	assertInt(t, 1, cache.purgeExpired(), "p1 should have been deleted once not even stale")
+	assertInt(t, 1, cache.Len(), "wrong number of entries left")
+	assertInt(t, 1, cache.policy.EvictionPolicy.(*LRUPolicy[string]).order.len(), "the policy should have forgotten the deleted keys")
+	if _, _, ok := cache.Peek("p2"); !ok {
+		t.Error("p2 should have been kept")
+	}
+}
+
+// Check that the janitor runs in the background until the cache is closed
+func TestWithJanitor_SweepsUntilClosed(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(func(_ context.Context, key string) (float64, error) { return 5, nil },
+		WithMaxAge(time.Minute), WithClock(clock), WithJanitor(time.Millisecond))
+	cache.Set("p1", 5)
+	clock.Advance(2 * time.Minute)
+	for deadline := time.Now().Add(time.Second); cache.Len() > 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	assertInt(t, 0, cache.Len(), "the janitor should have deleted the expired entry")
+
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+	cache.Set("p2", 5)
+	clock.Advance(2 * time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	assertInt(t, 1, cache.Len(), "the janitor should have been stopped")
+}
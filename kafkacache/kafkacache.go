@@ -0,0 +1,122 @@
+// Package kafkacache keeps a cache up to date with the price-change events of a Kafka topic, so that changed
+// prices are dropped (or replaced) within milliseconds instead of being served until they expire
+// It lives in its own package so that the cache itself doesn't depend on the Kafka client
+package kafkacache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// PriceChange is a price-change event, by default the JSON value of a record: {"itemCode": "p1", "price": 5.5}
+// The item code is taken from the record key when the value doesn't have one
+type PriceChange struct {
+	ItemCode string   `json:"itemCode"`
+	Price    *float64 `json:"price,omitempty"` // the new price, nil if the event only says that it changed
+}
+
+// Mode tells what the consumer does with the cached price of a changed item
+type Mode int
+
+const (
+	Invalidate Mode = iota // drops the cached price, so that the next lookup loads it from the service
+	Update                 // stores the price of the event, drops the cached price if the event has none
+)
+
+// Options configures a Consumer
+type Options struct {
+	Mode    Mode
+	Decode  func(record *kgo.Record) (PriceChange, error) // decodes the events, DecodeJSON if nil
+	OnError func(error)                                   // called with every fetch error and undecodable record
+}
+
+// Consumer applies the events fetched by a Kafka client to a cache
+type Consumer struct {
+	client *kgo.Client
+	cache  *sample1.TransparentCache
+	opts   Options
+}
+
+// NewConsumer returns a consumer of the topics client consumes (see kgo.ConsumeTopics)
+// Every instance of the cache must see every event: give each of them its own consumer group, or none
+func NewConsumer(client *kgo.Client, cache *sample1.TransparentCache, opts Options) *Consumer {
+	if opts.Decode == nil {
+		opts.Decode = DecodeJSON
+	}
+	return &Consumer{client: client, cache: cache, opts: opts}
+}
+
+// DecodeJSON decodes a PriceChange from the JSON value of record, its key being the default item code
+func DecodeJSON(record *kgo.Record) (PriceChange, error) {
+	var change PriceChange
+	if len(record.Value) > 0 {
+		if err := json.Unmarshal(record.Value, &change); err != nil {
+			return PriceChange{}, err
+		}
+	}
+	if change.ItemCode == "" {
+		change.ItemCode = string(record.Key)
+	}
+	if change.ItemCode == "" {
+		return PriceChange{}, errors.New("no item code")
+	}
+	return change, nil
+}
+
+// Run applies the events until ctx is done, which it returns, or until the client is closed, returning nil
+// The events of each fetch are applied together, the last event of an item winning
+func (c *Consumer) Run(ctx context.Context) error {
+	for {
+		fetches := c.client.PollFetches(ctx)
+		if fetches.IsClientClosed() {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			c.fail(fmt.Errorf("fetching %s/%d : %w", topic, partition, err))
+		})
+		c.apply(fetches)
+	}
+}
+
+// apply applies the events of fetches to the cache
+func (c *Consumer) apply(fetches kgo.Fetches) {
+	changes := map[string]*float64{}
+	fetches.EachRecord(func(record *kgo.Record) {
+		change, err := c.opts.Decode(record)
+		if err != nil {
+			c.fail(fmt.Errorf("decoding %s/%d@%d : %w", record.Topic, record.Partition, record.Offset, err))
+			return
+		}
+		changes[change.ItemCode] = change.Price
+	})
+	prices := map[string]float64{}
+	var invalidated []string
+	for itemCode, price := range changes {
+		if c.opts.Mode == Update && price != nil {
+			prices[itemCode] = *price
+		} else {
+			invalidated = append(invalidated, itemCode)
+		}
+	}
+	if len(invalidated) > 0 {
+		c.cache.InvalidateMany(invalidated...)
+	}
+	if len(prices) > 0 {
+		c.cache.SetMany(prices)
+	}
+}
+
+func (c *Consumer) fail(err error) {
+	if c.opts.OnError != nil {
+		c.opts.OnError(err)
+	}
+}
@@ -0,0 +1,97 @@
+package kafkacache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+type fakePriceService map[string]float64
+
+func (f fakePriceService) GetPriceFor(itemCode string) (float64, error) {
+	price, ok := f[itemCode]
+	if !ok {
+		return 0, fmt.Errorf("unknown item [%v] : %w", itemCode, sample1.ErrNotFound)
+	}
+	return price, nil
+}
+
+// startConsumer runs a consumer of the "prices" topic of a fake cluster in front of a new cache, and returns
+// the cache with a client producing on the topic
+func startConsumer(t *testing.T, opts Options) (*sample1.TransparentCache, *kgo.Client) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "prices"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cluster.Close)
+	producer, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...), kgo.DefaultProduceTopic("prices"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(producer.Close)
+	consumerClient, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...), kgo.ConsumeTopics("prices"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := sample1.New(fakePriceService{"p1": 5, "p2": 7})
+	opts.OnError = func(err error) { t.Error(err) }
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- NewConsumer(consumerClient, cache, opts).Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Errorf("expected the consumer to stop with the context but got %v", err)
+		}
+		consumerClient.Close()
+	})
+	return cache, producer
+}
+
+func produce(t *testing.T, producer *kgo.Client, key, value string) {
+	t.Helper()
+	record := &kgo.Record{Key: []byte(key), Value: []byte(value)}
+	if err := producer.ProduceSync(context.Background(), record).FirstErr(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Check that price-change events drop the cached prices of their items
+func TestConsumer_Invalidates(t *testing.T) {
+	cache, producer := startConsumer(t, Options{})
+	cache.GetPricesFor("p1", "p2")
+	produce(t, producer, "p1", "")
+	waitFor(t, func() bool { return !cache.Contains("p1") }, "p1 should have been invalidated")
+	produce(t, producer, "", `{"itemCode": "p2", "price": 8}`)
+	waitFor(t, func() bool { return !cache.Contains("p2") }, "p2 should have been invalidated, not updated")
+}
+
+// Check that in update mode the price of the event is stored
+func TestConsumer_Updates(t *testing.T) {
+	cache, producer := startConsumer(t, Options{Mode: Update})
+	cache.GetPricesFor("p1", "p2")
+	produce(t, producer, "p1", `{"price": 6}`)
+	waitFor(t, func() bool {
+		price, _, ok := cache.Peek("p1")
+		return ok && price == 6
+	}, "p1 should have been updated")
+	produce(t, producer, "p2", "")
+	waitFor(t, func() bool { return !cache.Contains("p2") }, "p2 should have been invalidated, its event has no price")
+}
+
+func waitFor(t *testing.T, condition func() bool, msg string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Error(msg)
+}
@@ -0,0 +1,37 @@
+package sample1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KeyBuilder turns the dimensions of a lookup (an item code and a currency for instance) into a key of the cache
+// and back, so that lookups along several dimensions share a cache keyed by strings, along with its stores and
+// invalidators, without their values colliding
+// Parse(Key(d)) must return d, and different dimensions must give different keys
+type KeyBuilder[D any] interface {
+	Key(d D) string
+	Parse(key string) (D, error)
+}
+
+// ItemCurrency is what a price in a given currency is looked up by
+type ItemCurrency struct {
+	ItemCode string
+	Currency string
+}
+
+// CurrencyKeys is the KeyBuilder of the ItemCurrency lookups, keys are the item code and the currency separated by
+// an "@" ("p1@EUR"). Item codes can contain "@" themselves, the currency can't
+type CurrencyKeys struct{}
+
+func (CurrencyKeys) Key(d ItemCurrency) string {
+	return d.ItemCode + "@" + d.Currency
+}
+
+func (CurrencyKeys) Parse(key string) (ItemCurrency, error) {
+	i := strings.LastIndex(key, "@")
+	if i < 0 {
+		return ItemCurrency{}, fmt.Errorf("key [%s] has no currency", key)
+	}
+	return ItemCurrency{ItemCode: key[:i], Currency: key[i+1:]}, nil
+}
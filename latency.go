@@ -0,0 +1,133 @@
+package sample1
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Outcome is how a lookup was answered, the latencies of the lookups are kept by outcome (see Stats.Latencies)
+type Outcome int
+
+const (
+	OutcomeHit   Outcome = iota // a fresh value was returned from the cache
+	OutcomeLoad                 // the value was loaded, the lookup missed
+	OutcomeStale                // a stale value was returned, while it is revalidated or because loading it failed
+	OutcomeError                // the lookup failed, cached errors included
+	numOutcomes
+)
+
+// Outcomes are every Outcome, in the order of Stats.Latencies
+var Outcomes = [numOutcomes]Outcome{OutcomeHit, OutcomeLoad, OutcomeStale, OutcomeError}
+
+var outcomeNames = [numOutcomes]string{"hit", "load", "stale", "error"}
+
+func (o Outcome) String() string {
+	if o < 0 || o >= numOutcomes {
+		return "unknown"
+	}
+	return outcomeNames[o]
+}
+
+// outcomeOf returns the outcome of a lookup that returned err, hit and stale telling where its value came from
+func outcomeOf(hit, stale bool, err error) Outcome {
+	switch {
+	case err != nil:
+		return OutcomeError
+	case stale:
+		return OutcomeStale
+	case hit:
+		return OutcomeHit
+	default:
+		return OutcomeLoad
+	}
+}
+
+// LatencyBounds are the upper bounds of the buckets of a LatencyHistogram, from the hits served from memory to the
+// slowest loads
+var LatencyBounds = [...]time.Duration{
+	10 * time.Microsecond, 25 * time.Microsecond, 50 * time.Microsecond, 100 * time.Microsecond,
+	250 * time.Microsecond, 500 * time.Microsecond, time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond,
+	250 * time.Millisecond, 500 * time.Millisecond, time.Second, 2500 * time.Millisecond, 5 * time.Second,
+	10 * time.Second,
+}
+
+// LatencyHistogram counts lookups by how long they took
+type LatencyHistogram struct {
+	// Counts are the lookups of every bucket: Counts[i] took at most LatencyBounds[i] (and more than the bound
+	// before it), the last one took more than every bound
+	Counts [len(LatencyBounds) + 1]uint64
+	Count  uint64        // lookups counted
+	Sum    time.Duration // time they took, over all of them
+}
+
+// Mean returns how long a lookup took on average, zero if there was none
+func (h LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns the upper bound of the bucket the q-th quantile of the latencies (0.99 for the p99) falls in
+// It is zero without lookups, and the largest bound if the quantile is in the last bucket
+func (h LatencyHistogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(h.Count))
+	var seen uint64
+	for i, count := range h.Counts[:len(LatencyBounds)] {
+		if seen += count; seen > rank || seen == h.Count {
+			return LatencyBounds[i]
+		}
+	}
+	return LatencyBounds[len(LatencyBounds)-1]
+}
+
+// TimeSaved estimates how much time waiting for the loader the cache saved: every hit and stale value returned
+// would have taken as long as the lookups that loaded their value on average, instead of as long as they did
+func (s Stats) TimeSaved() time.Duration {
+	load := s.Latencies[OutcomeLoad].Mean()
+	if load == 0 {
+		return 0
+	}
+	var saved time.Duration
+	for _, outcome := range []Outcome{OutcomeHit, OutcomeStale} {
+		h := s.Latencies[outcome]
+		saved += time.Duration(h.Count)*load - h.Sum
+	}
+	return max(saved, 0)
+}
+
+// latencyHistogram is a LatencyHistogram updated atomically
+type latencyHistogram struct {
+	counts [len(LatencyBounds) + 1]atomic.Uint64
+	count  atomic.Uint64
+	sum    atomic.Int64 // in nanoseconds
+}
+
+// observe counts a lookup that took latency
+func (h *latencyHistogram) observe(latency time.Duration) {
+	i := 0
+	for i < len(LatencyBounds) && latency > LatencyBounds[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(latency))
+}
+
+// snapshot returns the current value of the histogram, its buckets may be a few lookups ahead of its count
+func (h *latencyHistogram) snapshot() LatencyHistogram {
+	s := LatencyHistogram{Count: h.count.Load(), Sum: time.Duration(h.sum.Load())}
+	for i := range h.counts {
+		s.Counts[i] = h.counts[i].Load()
+	}
+	return s
+}
+
+// looked records that a lookup started at start had outcome
+func (c *Cache[K, V]) looked(outcome Outcome, start time.Time) {
+	c.counters.latencies[outcome].observe(c.clock.Now().Sub(start))
+}
@@ -0,0 +1,74 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Check that every lookup is timed under its outcome: hits, loads, stale values and errors
+func TestStats_Latencies(t *testing.T) {
+	clock := newFakeClock()
+	failing := errors.New("some error")
+	fail := false
+	cache := NewCache(func(_ context.Context, key int) (int, error) {
+		clock.Advance(100 * time.Millisecond)
+		if fail || key < 0 {
+			return 0, failing
+		}
+		return key, nil
+	}, WithClock(clock), WithMaxAge(time.Minute), WithStaleIfError(time.Hour), WithBulkLoader(func(_ context.Context, keys []int) ([]int, error) {
+		clock.Advance(time.Second)
+		return keys, nil
+	}))
+	ctx := context.Background()
+	cache.Get(ctx, 1)        // load
+	cache.Get(ctx, 1)        // hit
+	cache.Get(ctx, -1)       // error
+	cache.GetMany(ctx, 2, 3) // two loads, of the batch
+	clock.Advance(2 * time.Minute)
+	fail = true
+	cache.Get(ctx, 1) // stale, the load failed
+	stats := cache.Stats()
+	for _, want := range []struct {
+		outcome Outcome
+		count   int
+		sum     time.Duration
+	}{
+		{OutcomeHit, 1, 0},
+		{OutcomeLoad, 3, 100*time.Millisecond + 2*time.Second},
+		{OutcomeStale, 1, 100 * time.Millisecond},
+		{OutcomeError, 1, 100 * time.Millisecond},
+	} {
+		h := stats.Latencies[want.outcome]
+		if int(h.Count) != want.count || h.Sum != want.sum {
+			t.Errorf("%v : expected %d lookups taking %v but got %d taking %v", want.outcome, want.count, want.sum, h.Count, h.Sum)
+		}
+	}
+	load := stats.Latencies[OutcomeLoad]
+	assertInt(t, 1, int(load.Counts[12]), "the load should be in the bucket of 100ms")
+	assertInt(t, 2, int(load.Counts[15]), "the loads of the batch should be in the bucket of 1s")
+	assertInt(t, int(time.Second), int(load.Quantile(0.5)), "wrong median")
+	assertInt(t, int(100*time.Millisecond), int(load.Quantile(0.1)), "wrong first decile")
+	assertInt(t, int(700*time.Millisecond), int(load.Mean()), "wrong mean")
+	// the hit and the stale value would have taken 700ms each
+	assertInt(t, int(1300*time.Millisecond), int(stats.TimeSaved()), "wrong time saved")
+}
+
+// Check the quantiles and means of histograms without lookups or with latencies over every bound
+func TestLatencyHistogram_Bounds(t *testing.T) {
+	var h latencyHistogram
+	assertInt(t, 0, int(h.snapshot().Quantile(0.5)), "no lookups should have a zero quantile")
+	assertInt(t, 0, int(h.snapshot().Mean()), "no lookups should have a zero mean")
+	assertInt(t, 0, int(Stats{}.TimeSaved()), "no loads should save nothing")
+	h.observe(time.Minute)
+	h.observe(0)
+	s := h.snapshot()
+	assertInt(t, 1, int(s.Counts[len(LatencyBounds)]), "a minute should be over every bound")
+	assertInt(t, 1, int(s.Counts[0]), "no latency should be in the first bucket")
+	assertInt(t, int(LatencyBounds[len(LatencyBounds)-1]), int(s.Quantile(0.99)), "wrong p99")
+	if OutcomeStale.String() != "stale" || Outcome(42).String() != "unknown" {
+		t.Errorf("wrong names of the outcomes")
+	}
+}
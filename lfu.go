@@ -0,0 +1,93 @@
+package sample1
+
+import "container/heap"
+
+// LFUPolicy evicts the least frequently used key, the least recently used one among equals
+type LFUPolicy[K comparable] struct {
+	items map[K]*lfuItem[K]
+	heap  lfuHeap[K]
+	clock uint64 // increases on every use, to break ties between items with the same frequency
+}
+
+// NewLFUPolicy creates a least frequently used eviction policy
+func NewLFUPolicy[K comparable]() *LFUPolicy[K] {
+	return &LFUPolicy[K]{items: map[K]*lfuItem[K]{}}
+}
+
+func (p *LFUPolicy[K]) OnInsert(key K) {
+	if _, ok := p.items[key]; ok {
+		p.OnAccess(key)
+		return
+	}
+	p.clock++
+	item := &lfuItem[K]{key: key, frequency: 1, lastUse: p.clock}
+	p.items[key] = item
+	heap.Push(&p.heap, item)
+}
+
+func (p *LFUPolicy[K]) OnAccess(key K) {
+	item, ok := p.items[key]
+	if !ok {
+		return
+	}
+	p.clock++
+	item.frequency++
+	item.lastUse = p.clock
+	heap.Fix(&p.heap, item.index)
+}
+
+func (p *LFUPolicy[K]) OnRemove(key K) {
+	item, ok := p.items[key]
+	if !ok {
+		return
+	}
+	heap.Remove(&p.heap, item.index)
+	delete(p.items, key)
+}
+
+func (p *LFUPolicy[K]) Victim() (K, bool) {
+	if len(p.heap) == 0 {
+		var zero K
+		return zero, false
+	}
+	return p.heap[0].key, true
+}
+
+type lfuItem[K comparable] struct {
+	key       K
+	frequency uint64
+	lastUse   uint64
+	index     int // position in the heap
+}
+
+// lfuHeap is a min heap of items, by frequency and then by last use
+type lfuHeap[K comparable] []*lfuItem[K]
+
+func (h lfuHeap[K]) Len() int { return len(h) }
+
+func (h lfuHeap[K]) Less(i, j int) bool {
+	if h[i].frequency != h[j].frequency {
+		return h[i].frequency < h[j].frequency
+	}
+	return h[i].lastUse < h[j].lastUse
+}
+
+func (h lfuHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap[K]) Push(x any) {
+	item := x.(*lfuItem[K])
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *lfuHeap[K]) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,44 @@
+package sample1
+
+import (
+	"testing"
+	"time"
+)
+
+// Check that the LFU policy keeps the most used prices, even if they were not used recently
+func TestGetPriceFor_LFUPolicyEvictsLeastFrequentlyUsed(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+			"p3": {price: 9, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxEntries(2), WithEvictionPolicy(NewLFUPolicy[string]()))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertFloat(t, 7, getPriceWithNoErr(t, cache, "p2"), "wrong price returned")
+	// "p2" is the most recently used, but "p1" is used more often
+	assertFloat(t, 9, getPriceWithNoErr(t, cache, "p3"), "wrong price returned")
+	assertInt(t, 3, mockService.getNumCalls(), "wrong number of service calls")
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 3, mockService.getNumCalls(), "wrong number of service calls")
+}
+
+func TestLFUPolicy_TiesGoToLeastRecentlyUsed(t *testing.T) {
+	p := NewLFUPolicy[string]()
+	p.OnInsert("p1")
+	p.OnInsert("p2")
+	p.OnInsert("p3")
+	if victim, _ := p.Victim(); victim != "p1" {
+		t.Errorf("expected p1 to be the victim, got %v", victim)
+	}
+	p.OnAccess("p1")
+	if victim, _ := p.Victim(); victim != "p2" {
+		t.Errorf("expected p2 to be the victim, got %v", victim)
+	}
+	p.OnRemove("p2")
+	if victim, _ := p.Victim(); victim != "p3" {
+		t.Errorf("expected p3 to be the victim, got %v", victim)
+	}
+}
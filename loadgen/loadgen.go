@@ -0,0 +1,237 @@
+// Package loadgen puts a cache under a synthetic load, with a chosen key distribution, rate and hit rate, and
+// reports its throughput and latencies, so that changes to the locking or the eviction of the cache can be
+// compared across versions under the same load
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// Distribution returns how a worker picks which of n keys its requests ask for, drawing from r
+type Distribution func(r *rand.Rand, n int) (next func() int)
+
+// Uniform asks for every key as often
+func Uniform(r *rand.Rand, n int) func() int {
+	return func() int { return r.IntN(n) }
+}
+
+// Zipf asks for the keys following a Zipf distribution of exponent s, the first keys being the most asked for, as
+// the popular items of a catalog are. It panics unless s is more than 1
+func Zipf(s float64) Distribution {
+	if s <= 1 {
+		panic(fmt.Sprintf("loadgen: the exponent of a Zipf distribution must be more than 1, got %v", s))
+	}
+	return func(r *rand.Rand, n int) func() int {
+		zipf := rand.NewZipf(r, s, 1, uint64(n-1))
+		return func() int { return int(zipf.Uint64()) }
+	}
+}
+
+// Config is the load to generate
+type Config struct {
+	Keys         int           // how many item codes the requests ask for, see ItemCode
+	Distribution Distribution  // Uniform if nil
+	QPS          float64       // requests per second over every worker, as fast as they go if zero
+	Duration     time.Duration // how long the load lasts at most, until Requests are done if zero
+	Requests     int           // how many requests are made at most, until Duration is over if zero
+	Workers      int           // how many requests are made at once, runtime.GOMAXPROCS(0) if zero
+	// HitRate, if positive, is the fraction of the requests asking for the Keys, the others asking for item codes
+	// never asked before, that the cache can't have. The hit rate of a cache large enough for the Keys tends to it
+	HitRate float64
+	Seed    uint64 // of the random draws, so that runs with the same Config ask for the same keys
+}
+
+// ItemCode returns the item code of the i-th key
+func ItemCode(i int) string {
+	return fmt.Sprintf("item-%d", i)
+}
+
+// check tells what is wrong with the configuration
+func (c Config) check() error {
+	switch {
+	case c.Keys <= 0:
+		return fmt.Errorf("keys [%v] : must be positive", c.Keys)
+	case c.Duration <= 0 && c.Requests <= 0:
+		return fmt.Errorf("either a duration or a number of requests is required")
+	case c.QPS < 0 || c.Duration < 0 || c.Requests < 0 || c.Workers < 0:
+		return fmt.Errorf("qps, duration, requests and workers must not be negative")
+	case c.HitRate < 0 || c.HitRate > 1:
+		return fmt.Errorf("hit rate [%v] : must be between 0 and 1", c.HitRate)
+	}
+	return nil
+}
+
+// Report is how the target held the load
+type Report struct {
+	Requests int
+	Errors   int
+	Elapsed  time.Duration
+	P50      time.Duration // latencies of the requests
+	P90      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+	// Hits and Misses are counted by the cache during the load, they are zero if the target has no Stats
+	Hits   uint64
+	Misses uint64
+}
+
+// Throughput returns the requests made per second
+func (r Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+// HitRatio returns the fraction of the lookups that were hits, zero if there was none
+func (r Report) HitRatio() float64 {
+	if r.Hits+r.Misses == 0 {
+		return 0
+	}
+	return float64(r.Hits) / float64(r.Hits+r.Misses)
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf("%d requests (%d errors) in %v, %.0f/s, hit ratio %.3f, latency p50 %v p90 %v p99 %v max %v",
+		r.Requests, r.Errors, r.Elapsed.Round(time.Millisecond), r.Throughput(), r.HitRatio(), r.P50, r.P90, r.P99, r.Max)
+}
+
+// Target is what Run puts the load on
+//
+// Deprecated: Run takes a sample1.ContextPriceService, which a TransparentCache is again now that its lookups
+// take no call options
+type Target = sample1.ContextPriceService
+
+// Uncached adapts service to Run, to put the service itself under the load, as a baseline for the cache
+// Its hits and misses aren't counted, even if service is a cache
+func Uncached(service sample1.PriceService) sample1.ContextPriceService {
+	return uncached{service: sample1.AsContextPriceService(service)}
+}
+
+type uncached struct {
+	service sample1.ContextPriceService
+}
+
+func (u uncached) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	return u.service.GetPriceForCtx(ctx, itemCode)
+}
+
+// statsSource is a target whose hits and misses can be counted, like a TransparentCache
+type statsSource interface {
+	Stats() sample1.Stats
+}
+
+// Run puts target, a TransparentCache for instance, under the load of cfg and reports how it held it. The load
+// stops early once ctx is done
+// Failed requests are counted in the report, only an invalid cfg is an error
+func Run(ctx context.Context, target sample1.ContextPriceService, cfg Config) (Report, error) {
+	if err := cfg.check(); err != nil {
+		return Report{}, fmt.Errorf("invalid load : %w", err)
+	}
+	if cfg.Distribution == nil {
+		cfg.Distribution = Uniform
+	}
+	workers := cfg.Workers
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+	stats, _ := target.(statsSource)
+	var before sample1.Stats
+	if stats != nil {
+		before = stats.Stats()
+	}
+	var (
+		next      atomic.Int64 // index of the next request
+		fresh     atomic.Int64 // how many never asked item codes were asked for
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		failed    int
+		latencies []time.Duration
+	)
+	start := time.Now()
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := rand.New(rand.NewPCG(cfg.Seed, uint64(w)))
+			pick := cfg.Distribution(r, cfg.Keys)
+			var workerErrors int
+			var workerLatencies []time.Duration
+			for {
+				i := next.Add(1) - 1
+				if cfg.Requests > 0 && i >= int64(cfg.Requests) {
+					break
+				}
+				if !waitTurn(ctx, start, i, cfg.QPS) {
+					break
+				}
+				itemCode := ItemCode(pick())
+				if cfg.HitRate > 0 && r.Float64() >= cfg.HitRate {
+					itemCode = fmt.Sprintf("fresh-%d", fresh.Add(1))
+				}
+				requestStart := time.Now()
+				if _, err := target.GetPriceForCtx(ctx, itemCode); err != nil {
+					if ctx.Err() != nil {
+						break // cut short by the end of the load
+					}
+					workerErrors++
+				}
+				workerLatencies = append(workerLatencies, time.Since(requestStart))
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			failed += workerErrors
+			latencies = append(latencies, workerLatencies...)
+		}()
+	}
+	wg.Wait()
+	report := Report{Requests: len(latencies), Errors: failed, Elapsed: time.Since(start)}
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		report.P50, report.P90, report.P99 = percentile(latencies, 0.5), percentile(latencies, 0.9), percentile(latencies, 0.99)
+		report.Max = latencies[len(latencies)-1]
+	}
+	if stats != nil {
+		after := stats.Stats()
+		report.Hits, report.Misses = after.Hits-before.Hits, after.Misses-before.Misses
+	}
+	return report, nil
+}
+
+// waitTurn waits until the i-th request is due at qps from start, it returns false if ctx is done first
+func waitTurn(ctx context.Context, start time.Time, i int64, qps float64) bool {
+	if qps <= 0 {
+		return ctx.Err() == nil
+	}
+	wait := time.Until(start.Add(time.Duration(float64(i) / qps * float64(time.Second))))
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// percentile returns the p-th percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	return sorted[min(len(sorted)-1, int(p*float64(len(sorted))))]
+}
@@ -0,0 +1,156 @@
+package loadgen
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// countingService prices every item at 1 and counts the calls for every item code
+type countingService struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (s *countingService) GetPriceFor(itemCode string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.calls == nil {
+		s.calls = map[string]int{}
+	}
+	s.calls[itemCode]++
+	return 1, nil
+}
+
+// Check that every request is made and reported, with the hits and misses of the cache
+func TestRun_Requests(t *testing.T) {
+	cache := sample1.New(&countingService{}, sample1.WithMaxAge(time.Hour))
+	report, err := Run(context.Background(), cache, Config{Keys: 10, Requests: 1000, Workers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Requests != 1000 || report.Errors != 0 {
+		t.Errorf("expected 1000 requests without errors but got %v", report)
+	}
+	if report.Hits+report.Misses != 1000 || report.HitRatio() < 0.9 {
+		t.Errorf("expected the lookups to be hits once the keys are cached, got %v", report)
+	}
+	if report.P50 > report.P99 || report.P99 > report.Max || report.Throughput() <= 0 {
+		t.Errorf("inconsistent latencies or throughput in %v", report)
+	}
+}
+
+// Check that the hit rate of a large enough cache follows the target
+func TestRun_HitRate(t *testing.T) {
+	cache := sample1.New(&countingService{}, sample1.WithMaxAge(time.Hour))
+	report, err := Run(context.Background(), cache, Config{Keys: 10, Requests: 4000, Workers: 1, HitRate: 0.7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(report.HitRatio()-0.7) > 0.05 {
+		t.Errorf("expected a hit ratio close to 0.7 but got %v", report.HitRatio())
+	}
+}
+
+// Check that the Zipf distribution asks for the first keys the most, and that the same seed asks for the same keys
+func TestRun_Zipf(t *testing.T) {
+	run := func() map[string]int {
+		service := &countingService{}
+		if _, err := Run(context.Background(), Uncached(service), Config{Keys: 100, Requests: 5000, Workers: 1, Distribution: Zipf(1.2), Seed: 7}); err != nil {
+			t.Fatal(err)
+		}
+		return service.calls
+	}
+	calls := run()
+	if calls[ItemCode(0)] <= calls[ItemCode(1)] || calls[ItemCode(1)] <= calls[ItemCode(50)] {
+		t.Errorf("expected the first keys to be asked for the most, got %v", calls)
+	}
+	again := run()
+	for itemCode, n := range calls {
+		if again[itemCode] != n {
+			t.Fatalf("expected the same seed to ask for the same keys, [%v] was asked %d then %d times", itemCode, n, again[itemCode])
+		}
+	}
+}
+
+// Check that the requests are paced at the QPS, and that the load stops with its duration
+func TestRun_QPSAndDuration(t *testing.T) {
+	report, err := Run(context.Background(), Uncached(&countingService{}), Config{Keys: 1, Requests: 21, QPS: 200, Workers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Requests != 21 || report.Elapsed < 100*time.Millisecond {
+		t.Errorf("expected 21 requests in at least 100ms at 200/s, got %v", report)
+	}
+	report, err = Run(context.Background(), Uncached(&countingService{}), Config{Keys: 1, Duration: 50 * time.Millisecond, QPS: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Elapsed > time.Second || report.Requests < 3 || report.Requests > 7 {
+		t.Errorf("expected about 5 requests in 50ms at 100/s, got %v", report)
+	}
+}
+
+// Check that an invalid load is refused
+func TestRun_Invalid(t *testing.T) {
+	for _, cfg := range []Config{
+		{Requests: 1},
+		{Keys: 1},
+		{Keys: 1, Requests: 1, QPS: -1},
+		{Keys: 1, Requests: 1, HitRate: 2},
+	} {
+		if _, err := Run(context.Background(), Uncached(&countingService{}), cfg); err == nil || !strings.HasPrefix(err.Error(), "invalid load") {
+			t.Errorf("expected %+v to be refused, got %v", cfg, err)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a Zipf exponent of 1")
+		}
+	}()
+	Zipf(1)
+}
+
+// benchmarkLoad runs b.N requests of cfg against a cache configured with opts, reporting its hit ratio
+func benchmarkLoad(b *testing.B, cfg Config, opts ...sample1.Option) {
+	cache := sample1.New(&countingService{}, append([]sample1.Option{sample1.WithMaxAge(time.Hour)}, opts...)...)
+	cfg.Requests = b.N
+	b.ResetTimer()
+	report, err := Run(context.Background(), cache, cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportMetric(report.HitRatio(), "hits/op")
+}
+
+// Compare with go test ./loadgen -bench . -cpu 1,8,16, and across versions with benchstat
+func BenchmarkZipf_Unbounded(b *testing.B) {
+	benchmarkLoad(b, Config{Keys: 100_000, Distribution: Zipf(1.1)})
+}
+
+func BenchmarkZipf_LRU(b *testing.B) {
+	benchmarkLoad(b, Config{Keys: 100_000, Distribution: Zipf(1.1)}, sample1.WithMaxEntries(10_000))
+}
+
+func BenchmarkZipf_LFU(b *testing.B) {
+	benchmarkLoad(b, Config{Keys: 100_000, Distribution: Zipf(1.1)}, sample1.WithMaxEntries(10_000),
+		sample1.WithEvictionPolicy[string](sample1.NewLFUPolicy[string]()))
+}
+
+func BenchmarkZipf_TinyLFU(b *testing.B) {
+	benchmarkLoad(b, Config{Keys: 100_000, Distribution: Zipf(1.1)}, sample1.WithMaxEntries(10_000),
+		sample1.WithEvictionPolicy[string](sample1.NewTinyLFUPolicy[string](10_000)))
+}
+
+func BenchmarkUniform_LRU(b *testing.B) {
+	benchmarkLoad(b, Config{Keys: 100_000}, sample1.WithMaxEntries(10_000))
+}
+
+func BenchmarkHitRate90_Unbounded(b *testing.B) {
+	benchmarkLoad(b, Config{Keys: 10_000, HitRate: 0.9})
+}
@@ -0,0 +1,60 @@
+package sample1
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// DefaultSlowLoad is how long a call to the loader takes before WithLogger logs it as slow, unless
+// WithSlowLoadThreshold says otherwise
+const DefaultSlowLoad = time.Second
+
+// WithLogger makes the cache log what it does with logger: the misses and the evictions at the debug level,
+// the failed and the slow loads (see WithSlowLoadThreshold) at the warn level. The cache logs nothing by default
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithSlowLoadThreshold sets how long a call to the loader takes before WithLogger logs it as slow,
+// DefaultSlowLoad if zero, a bulk load being a single call
+func WithSlowLoadThreshold(threshold time.Duration) Option {
+	return func(c *config) {
+		c.slowLoad = threshold
+	}
+}
+
+// logMiss logs a lookup of key that wasn't answered from the cache
+func (c *Cache[K, V]) logMiss(key K) {
+	if c.logger != nil {
+		c.logger.LogAttrs(context.Background(), slog.LevelDebug, "cache miss", slog.Any("key", key))
+	}
+}
+
+// logLoad logs a call to the loader that took loadTime and failed with err, if it failed or was slow
+// what tells what was loaded, the key or the number of keys of a bulk load
+func (c *Cache[K, V]) logLoad(ctx context.Context, what slog.Attr, loadTime time.Duration, err error) {
+	if c.logger == nil {
+		return
+	}
+	if err != nil {
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "cache load failed", what, slog.Duration("duration", loadTime),
+			slog.Any("err", err))
+	} else if loadTime >= c.slowLoad {
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "cache load slow", what, slog.Duration("duration", loadTime))
+	}
+}
+
+// logEvictions logs the evictions among events
+func (c *Cache[K, V]) logEvictions(events []Event[K, V]) {
+	if c.logger == nil {
+		return
+	}
+	for _, event := range events {
+		if event.Reason == Evicted {
+			c.logger.LogAttrs(context.Background(), slog.LevelDebug, "cache eviction", slog.Any("key", event.Key))
+		}
+	}
+}
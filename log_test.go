@@ -0,0 +1,62 @@
+package sample1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logBuffer is where a test logger writes, safe for concurrent use
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// Check that the misses, the evictions and the failed and slow loads are logged
+func TestWithLogger(t *testing.T) {
+	logs := &logBuffer{}
+	logger := slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	clock := newFakeClock()
+	cache := NewCache(func(_ context.Context, key int) (int, error) {
+		if key == 3 {
+			return 0, errors.New("unknown key")
+		}
+		if key == 2 {
+			clock.Advance(2 * time.Second)
+		}
+		return key, nil
+	}, WithClock(clock), WithMaxEntries(1), WithLogger(logger), WithSlowLoadThreshold(time.Second))
+	cache.Get(context.Background(), 1)
+	cache.Get(context.Background(), 2) // evicts 1
+	cache.Get(context.Background(), 3)
+	for _, line := range []string{
+		`level=DEBUG msg="cache miss" key=1`,
+		`level=DEBUG msg="cache eviction" key=1`,
+		`level=WARN msg="cache load slow" key=2 duration=2s`,
+		`level=WARN msg="cache load failed" key=3 duration=0s err="loading [3] : unknown key"`,
+	} {
+		if !strings.Contains(logs.String(), line) {
+			t.Errorf("expected %s in the logs, got\n%s", line, logs)
+		}
+	}
+	if strings.Contains(logs.String(), "key=1 duration") {
+		t.Errorf("a fast load should not be logged, got\n%s", logs)
+	}
+}
@@ -0,0 +1,284 @@
+// Package memcachestore keeps the entries of a cache in a memcached fleet, so that several processes can share them
+// It speaks the memcached binary protocol and spreads the keys over the servers with consistent hashing
+package memcachestore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+	"github.com/MadHive/deviget_challenge/internal/entrycodec"
+)
+
+// maxKeyLen is the longest key memcached accepts, longer keys are replaced by their hash
+const maxKeyLen = 250
+
+// Options configures how a Store talks to memcached
+type Options struct {
+	Prefix       string        // prepended to every key, so that several caches can share the fleet
+	ExtraTTL     time.Duration // kept on top of the entry maxAge, so that stale values can still be served
+	Timeout      time.Duration // of every call to a server, dialing included, no timeout if zero
+	MaxIdleConns int           // idle connections kept per server, 2 if zero
+	Replicas     int           // points of the hash ring per server, 160 if zero
+	OnError      func(error)   // called with every memcached failure, which the store treats as a miss
+}
+
+// Store is a sample1.Store keeping its entries in memcached, encoded as JSON
+// Entries expire in memcached once they are older than their maxAge plus ExtraTTL
+// Memcached can't list its keys, so Len always returns 0 and Range sees no entries: leave WithMaxEntries unset
+// and let memcached evict the entries. Clear makes the entries written so far unreachable for this store only,
+// memcached ages them out
+type Store[V any] struct {
+	servers   []*server
+	ring      *ring
+	opts      Options
+	namespace atomic.Uint64 // part of every key, bumped by Clear
+}
+
+// New creates a store spreading its keys over the servers, given as host:port
+func New[V any](servers []string, opts Options) *Store[V] {
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = 2
+	}
+	if opts.Replicas <= 0 {
+		opts.Replicas = 160
+	}
+	s := &Store[V]{ring: newRing(servers, opts.Replicas), opts: opts}
+	for _, addr := range servers {
+		s.servers = append(s.servers, &server{addr: addr, timeout: opts.Timeout, idle: make(chan *conn, opts.MaxIdleConns)})
+	}
+	return s
+}
+
+func (s *Store[V]) Get(key string) (sample1.Entry[V], bool) {
+	if len(s.servers) == 0 {
+		return sample1.Entry[V]{}, false
+	}
+	var data []byte
+	k := s.key(key)
+	err := s.serverFor(k).with(func(c *conn) (err error) {
+		data, err = c.get(k)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, errNotFound) {
+			s.fail(fmt.Errorf("getting [%s] : %w", key, err))
+		}
+		return sample1.Entry[V]{}, false
+	}
+	return s.decode(key, data)
+}
+
+// GetMany reads the keys of every server with a single pipeline, servers are asked in parallel
+func (s *Store[V]) GetMany(keys []string) ([]sample1.Entry[V], []bool) {
+	entries := make([]sample1.Entry[V], len(keys))
+	found := make([]bool, len(keys))
+	if len(s.servers) == 0 {
+		return entries, found
+	}
+	byServer := map[*server][]int{}
+	for i, key := range keys {
+		srv := s.serverFor(s.key(key))
+		byServer[srv] = append(byServer[srv], i)
+	}
+	var wg sync.WaitGroup
+	for srv, indexes := range byServer {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serverKeys := make([]string, len(indexes))
+			for j, i := range indexes {
+				serverKeys[j] = s.key(keys[i])
+			}
+			var values [][]byte
+			err := srv.with(func(c *conn) (err error) {
+				values, err = c.getMany(serverKeys)
+				return err
+			})
+			if err != nil {
+				s.fail(fmt.Errorf("getting %d keys from %s : %w", len(indexes), srv.addr, err))
+				return
+			}
+			for j, i := range indexes { // every goroutine writes different indexes
+				if values[j] != nil {
+					entries[i], found[i] = s.decode(keys[i], values[j])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return entries, found
+}
+
+func (s *Store[V]) Set(key string, entry sample1.Entry[V]) {
+	if len(s.servers) == 0 {
+		return
+	}
+	data, err := entrycodec.Marshal(entry)
+	if err != nil {
+		s.fail(fmt.Errorf("encoding [%s] : %w", key, err))
+		return
+	}
+	k := s.key(key)
+	err = s.serverFor(k).with(func(c *conn) error {
+		return c.set(k, data, s.ttl(entry))
+	})
+	if err != nil {
+		s.fail(fmt.Errorf("setting [%s] : %w", key, err))
+	}
+}
+
+func (s *Store[V]) Delete(key string) {
+	if len(s.servers) == 0 {
+		return
+	}
+	k := s.key(key)
+	err := s.serverFor(k).with(func(c *conn) error {
+		return c.delete(k)
+	})
+	if err != nil {
+		s.fail(fmt.Errorf("deleting [%s] : %w", key, err))
+	}
+}
+
+// Clear moves the store to a new namespace, the entries written so far are no longer reachable from it
+func (s *Store[V]) Clear() {
+	s.namespace.Add(1)
+}
+
+// Len always returns 0, memcached can't count the keys of a store
+func (s *Store[V]) Len() int {
+	return 0
+}
+
+// Range sees no entries, memcached can't list the keys of a store
+func (s *Store[V]) Range(fn func(key string, entry sample1.Entry[V]) bool) {}
+
+// Close closes the idle connections, the store must not be used afterwards
+func (s *Store[V]) Close() error {
+	var errs []error
+	for _, srv := range s.servers {
+		errs = append(errs, srv.close())
+	}
+	return errors.Join(errs...)
+}
+
+// key is the memcached key for a cache key, hashed if it is too long (or has characters memcached rejects)
+func (s *Store[V]) key(key string) string {
+	k := fmt.Sprintf("%s%d:%s", s.opts.Prefix, s.namespace.Load(), key)
+	if len(k) > maxKeyLen || !validKey(k) {
+		sum := sha256.Sum256([]byte(k))
+		k = fmt.Sprintf("%s%d:#%s", s.opts.Prefix, s.namespace.Load(), hex.EncodeToString(sum[:]))
+	}
+	return k
+}
+
+func (s *Store[V]) serverFor(key string) *server {
+	return s.servers[s.ring.server(key)]
+}
+
+func (s *Store[V]) decode(key string, data []byte) (sample1.Entry[V], bool) {
+	entry, err := entrycodec.Unmarshal[V](data)
+	if err != nil {
+		s.fail(fmt.Errorf("decoding [%s] : %w", key, err))
+		return sample1.Entry[V]{}, false
+	}
+	return entry, true
+}
+
+// ttl is how long memcached keeps an entry, entries without a maxAge are kept until evicted
+func (s *Store[V]) ttl(entry sample1.Entry[V]) time.Duration {
+	if entry.MaxAge <= 0 {
+		return 0
+	}
+	return entry.MaxAge + s.opts.ExtraTTL
+}
+
+func (s *Store[V]) fail(err error) {
+	if s.opts.OnError != nil {
+		s.opts.OnError(err)
+	}
+}
+
+// validKey tells if memcached accepts key as is, control characters and spaces are rejected by most servers
+func validKey(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// server is a memcached server and its idle connections
+type server struct {
+	addr    string
+	timeout time.Duration
+	idle    chan *conn
+}
+
+// with runs fn on an idle connection, or a new one, which is kept for later unless fn failed on it
+func (s *server) with(fn func(c *conn) error) error {
+	c, err := s.conn()
+	if err != nil {
+		return err
+	}
+	if s.timeout > 0 {
+		c.SetDeadline(time.Now().Add(s.timeout))
+	}
+	if err := fn(c); err != nil {
+		if errors.Is(err, errNotFound) {
+			s.release(c)
+		} else {
+			c.Close() // the connection may be left in the middle of a response
+		}
+		return err
+	}
+	s.release(c)
+	return nil
+}
+
+func (s *server) conn() (*conn, error) {
+	select {
+	case c := <-s.idle:
+		return c, nil
+	default:
+	}
+	var c net.Conn
+	var err error
+	if s.timeout > 0 {
+		c, err = net.DialTimeout("tcp", s.addr, s.timeout)
+	} else {
+		c, err = net.Dial("tcp", s.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newConn(c), nil
+}
+
+func (s *server) release(c *conn) {
+	select {
+	case s.idle <- c:
+	default:
+		c.Close()
+	}
+}
+
+func (s *server) close() error {
+	var errs []error
+	for {
+		select {
+		case c := <-s.idle:
+			errs = append(errs, c.Close())
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package memcachestore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// fakeServer is a memcached server speaking the part of the binary protocol the store uses
+type fakeServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	items    map[string][]byte
+	ttls     map[string]uint32
+	requests map[byte]int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeServer{listener: listener, items: map[string][]byte{}, ttls: map[string]uint32{}, requests: map[byte]int{}}
+	t.Cleanup(func() { listener.Close() })
+	go f.serve()
+	return f
+}
+
+func (f *fakeServer) addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeServer) serve() {
+	for {
+		c, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(newConn(c))
+	}
+}
+
+func (f *fakeServer) handle(c *conn) {
+	defer c.Close()
+	for {
+		var h [headerLen]byte
+		if _, err := io.ReadFull(c.r, h[:]); err != nil {
+			return
+		}
+		keyLen := int(binary.BigEndian.Uint16(h[2:4]))
+		extrasLen := int(h[4])
+		body := make([]byte, binary.BigEndian.Uint32(h[8:12]))
+		if _, err := io.ReadFull(c.r, body); err != nil {
+			return
+		}
+		opcode, opaque := h[1], binary.BigEndian.Uint32(h[12:16])
+		key := string(body[extrasLen : extrasLen+keyLen])
+		f.mu.Lock()
+		f.requests[opcode]++
+		value, ok := f.items[key]
+		switch opcode {
+		case opSet:
+			f.items[key] = body[extrasLen+keyLen:]
+			f.ttls[key] = binary.BigEndian.Uint32(body[4:8])
+		case opDelete:
+			delete(f.items, key)
+		}
+		f.mu.Unlock()
+		switch {
+		case opcode == opGetKQ && !ok:
+			continue
+		case (opcode == opGet || opcode == opGetKQ || opcode == opDelete) && !ok:
+			f.respond(c, opcode, opaque, statusKeyNotFound, []byte("Not found"))
+		case opcode == opGet || opcode == opGetKQ:
+			f.respond(c, opcode, opaque, statusOK, value)
+		default:
+			f.respond(c, opcode, opaque, statusOK, nil)
+		}
+		if c.w.Flush() != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeServer) respond(c *conn, opcode byte, opaque uint32, status uint16, value []byte) {
+	var h [headerLen]byte
+	h[0] = magicResponse
+	h[1] = opcode
+	binary.BigEndian.PutUint16(h[6:8], status)
+	binary.BigEndian.PutUint32(h[8:12], uint32(len(value)))
+	binary.BigEndian.PutUint32(h[12:16], opaque)
+	c.w.Write(h[:])
+	c.w.Write(value)
+}
+
+func (f *fakeServer) numItems() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.items)
+}
+
+func (f *fakeServer) numRequests(opcode byte) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[opcode]
+}
+
+type fakePriceService struct {
+	prices   map[string]float64
+	numCalls int
+}
+
+func (f *fakePriceService) GetPriceFor(itemCode string) (float64, error) {
+	f.numCalls++
+	price, ok := f.prices[itemCode]
+	if !ok {
+		return 0, fmt.Errorf("unknown item [%v] : %w", itemCode, sample1.ErrNotFound)
+	}
+	return price, nil
+}
+
+// Check that entries round trip through memcached
+func TestStore_SetGetAndDelete(t *testing.T) {
+	server := newFakeServer(t)
+	store := New[float64]([]string{server.addr()}, Options{Prefix: "prices:"})
+	defer store.Close()
+	fetchedAt := time.Now().Round(0)
+	store.Set("p1", sample1.Entry[float64]{Value: 5, FetchedAt: fetchedAt, MaxAge: time.Minute})
+
+	entry, ok := store.Get("p1")
+	if !ok || entry.Value != 5 || !entry.FetchedAt.Equal(fetchedAt) || entry.MaxAge != time.Minute {
+		t.Errorf("wrong entry read back: %+v, %v", entry, ok)
+	}
+	if _, ok := store.Get("p2"); ok {
+		t.Error("p2 should not be found")
+	}
+	store.Delete("p1")
+	if _, ok := store.Get("p1"); ok {
+		t.Error("p1 should have been deleted")
+	}
+}
+
+// Check that memcached expires entries after their maxAge plus ExtraTTL
+func TestStore_MapsMaxAgeToExpiration(t *testing.T) {
+	server := newFakeServer(t)
+	store := New[float64]([]string{server.addr()}, Options{ExtraTTL: 30 * time.Second})
+	defer store.Close()
+	store.Set("p1", sample1.Entry[float64]{Value: 5, FetchedAt: time.Now(), MaxAge: time.Minute})
+	server.mu.Lock()
+	ttl := server.ttls[store.key("p1")]
+	server.mu.Unlock()
+	if ttl != 90 {
+		t.Errorf("wrong expiration, expected 90 seconds but got %d", ttl)
+	}
+	if exp := expiration(40 * 24 * time.Hour); int64(exp) < time.Now().Unix() {
+		t.Errorf("expirations over 30 days should be unix times, got %d", exp)
+	}
+}
+
+// Check that GetMany asks every server once and finds every key wherever it is
+func TestStore_GetManyPipelinesPerServer(t *testing.T) {
+	servers := []*fakeServer{newFakeServer(t), newFakeServer(t), newFakeServer(t)}
+	store := New[float64]([]string{servers[0].addr(), servers[1].addr(), servers[2].addr()}, Options{})
+	defer store.Close()
+	var keys []string
+	for i := 0; i < 300; i++ {
+		key := fmt.Sprintf("p%d", i)
+		keys = append(keys, key)
+		if i%2 == 0 {
+			store.Set(key, sample1.Entry[float64]{Value: float64(i), FetchedAt: time.Now(), MaxAge: time.Minute})
+		}
+	}
+	entries, found := store.GetMany(keys)
+	for i := range keys {
+		if found[i] != (i%2 == 0) || (found[i] && entries[i].Value != float64(i)) {
+			t.Errorf("wrong entry for %s: %+v, %v", keys[i], entries[i], found[i])
+		}
+	}
+	for i, server := range servers {
+		if server.numItems() == 0 {
+			t.Errorf("server %d got no keys", i)
+		}
+		if n := server.numRequests(opNoop); n != 1 {
+			t.Errorf("server %d should have been asked once but got %d batches", i, n)
+		}
+	}
+}
+
+// Check that Clear makes the previous entries unreachable
+func TestStore_Clear(t *testing.T) {
+	server := newFakeServer(t)
+	store := New[float64]([]string{server.addr()}, Options{})
+	defer store.Close()
+	store.Set("p1", sample1.Entry[float64]{Value: 5, FetchedAt: time.Now(), MaxAge: time.Minute})
+	store.Clear()
+	if _, ok := store.Get("p1"); ok {
+		t.Error("p1 should not be reachable after Clear")
+	}
+}
+
+// Check that long keys and keys with spaces are hashed into valid memcached keys
+func TestStore_HashesInvalidKeys(t *testing.T) {
+	server := newFakeServer(t)
+	store := New[float64]([]string{server.addr()}, Options{})
+	defer store.Close()
+	for _, key := range []string{strings.Repeat("p", 300), "p 1"} {
+		store.Set(key, sample1.Entry[float64]{Value: 5, FetchedAt: time.Now(), MaxAge: time.Minute})
+		if entry, ok := store.Get(key); !ok || entry.Value != 5 {
+			t.Errorf("wrong entry read back for %q: %+v, %v", key, entry, ok)
+		}
+		if k := store.key(key); len(k) > maxKeyLen || !validKey(k) {
+			t.Errorf("invalid memcached key %q", k)
+		}
+	}
+}
+
+// Check that memcached failures are reported and treated as misses
+func TestStore_FailuresAreMisses(t *testing.T) {
+	var failures []error
+	server := newFakeServer(t)
+	store := New[float64]([]string{server.addr()}, Options{Timeout: time.Second, OnError: func(err error) { failures = append(failures, err) }})
+	defer store.Close()
+	server.listener.Close()
+	store.Close()
+	if _, ok := store.Get("p1"); ok {
+		t.Error("p1 should be a miss while memcached is down")
+	}
+	store.Set("p1", sample1.Entry[float64]{Value: 5, FetchedAt: time.Now(), MaxAge: time.Minute})
+	if len(failures) != 2 {
+		t.Errorf("wrong number of failures reported, expected 2 but got %d: %v", len(failures), failures)
+	}
+}
+
+// Check that two caches share their entries, negative ones included, through memcached
+func TestStore_SharedBetweenCaches(t *testing.T) {
+	server := newFakeServer(t)
+	store := New[float64]([]string{server.addr()}, Options{Prefix: "prices:"})
+	defer store.Close()
+	service := &fakePriceService{prices: map[string]float64{"p1": 5, "p2": 7}}
+	negative := sample1.WithNegativeCaching(time.Minute, nil)
+	first := sample1.New(service, sample1.WithStore[string, float64](store), negative)
+	second := sample1.New(service, sample1.WithStore[string, float64](store), negative)
+
+	if _, err := first.GetPricesFor("p1", "p2"); err != nil {
+		t.Fatal(err)
+	}
+	first.GetPriceFor("p3")
+	prices, err := second.GetPricesFor("p1", "p2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prices[0] != 5 || prices[1] != 7 {
+		t.Errorf("wrong prices returned: %v", prices)
+	}
+	if _, err := second.GetPriceFor("p3"); !errors.Is(err, sample1.ErrNotFound) {
+		t.Errorf("expected a not found error but got %v", err)
+	}
+	if service.numCalls != 3 {
+		t.Errorf("the second cache should have used the shared entries, but the service was called %d times", service.numCalls)
+	}
+}
@@ -0,0 +1,204 @@
+package memcachestore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Opcodes and statuses of the memcached binary protocol used by the store
+const (
+	magicRequest  = 0x80
+	magicResponse = 0x81
+
+	opGet    = 0x00
+	opSet    = 0x01
+	opDelete = 0x04
+	opNoop   = 0x0a
+	opGetKQ  = 0x0d
+
+	statusOK          = 0x0000
+	statusKeyNotFound = 0x0001
+
+	headerLen = 24
+
+	// maxRelativeExpiration is the longest expiration memcached takes as relative, longer ones are unix times
+	maxRelativeExpiration = 30 * 24 * time.Hour
+)
+
+// errNotFound is returned by get when the server doesn't have the key
+var errNotFound = errors.New("key not found")
+
+// header is the fixed part of every request and response
+type header struct {
+	magic     byte
+	opcode    byte
+	keyLen    uint16
+	extrasLen byte
+	status    uint16 // vbucket id in requests
+	bodyLen   uint32
+	opaque    uint32
+}
+
+// response is a response as read from the server
+type response struct {
+	header
+	key   []byte
+	value []byte
+}
+
+// conn is a connection to a server speaking the binary protocol
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func newConn(c net.Conn) *conn {
+	return &conn{Conn: c, r: bufio.NewReader(c), w: bufio.NewWriter(c)}
+}
+
+// write buffers a request, flush sends it
+func (c *conn) write(opcode byte, opaque uint32, extras, key, value []byte) error {
+	var h [headerLen]byte
+	h[0] = magicRequest
+	h[1] = opcode
+	binary.BigEndian.PutUint16(h[2:4], uint16(len(key)))
+	h[4] = byte(len(extras))
+	binary.BigEndian.PutUint32(h[8:12], uint32(len(extras)+len(key)+len(value)))
+	binary.BigEndian.PutUint32(h[12:16], opaque)
+	for _, part := range [][]byte{h[:], extras, key, value} {
+		if _, err := c.w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *conn) read() (response, error) {
+	var h [headerLen]byte
+	if _, err := io.ReadFull(c.r, h[:]); err != nil {
+		return response{}, err
+	}
+	res := response{header: header{
+		magic:     h[0],
+		opcode:    h[1],
+		keyLen:    binary.BigEndian.Uint16(h[2:4]),
+		extrasLen: h[4],
+		status:    binary.BigEndian.Uint16(h[6:8]),
+		bodyLen:   binary.BigEndian.Uint32(h[8:12]),
+		opaque:    binary.BigEndian.Uint32(h[12:16]),
+	}}
+	if res.magic != magicResponse {
+		return response{}, fmt.Errorf("bad response magic %#x", res.magic)
+	}
+	body := make([]byte, res.bodyLen)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return response{}, err
+	}
+	keyEnd := int(res.extrasLen) + int(res.keyLen)
+	if keyEnd > len(body) {
+		return response{}, fmt.Errorf("bad response lengths")
+	}
+	res.key = body[res.extrasLen:keyEnd]
+	res.value = body[keyEnd:]
+	return res, nil
+}
+
+// call sends a single request and reads its response
+func (c *conn) call(opcode byte, extras, key, value []byte) (response, error) {
+	if err := c.write(opcode, 0, extras, key, value); err != nil {
+		return response{}, err
+	}
+	if err := c.w.Flush(); err != nil {
+		return response{}, err
+	}
+	return c.read()
+}
+
+func (c *conn) get(key string) ([]byte, error) {
+	res, err := c.call(opGet, nil, []byte(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	switch res.status {
+	case statusOK:
+		return res.value, nil
+	case statusKeyNotFound:
+		return nil, errNotFound
+	default:
+		return nil, statusError(res)
+	}
+}
+
+// getMany pipelines a quiet get for every key with a noop at the end, the server only answers the keys it has
+// values[i] is nil if the server doesn't have keys[i]
+func (c *conn) getMany(keys []string) ([][]byte, error) {
+	for i, key := range keys {
+		if err := c.write(opGetKQ, uint32(i), nil, []byte(key), nil); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.write(opNoop, uint32(len(keys)), nil, nil, nil); err != nil {
+		return nil, err
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(keys))
+	for {
+		res, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+		if res.opcode == opNoop {
+			return values, nil
+		}
+		if res.status == statusOK && int(res.opaque) < len(keys) {
+			values[res.opaque] = res.value
+		}
+	}
+}
+
+func (c *conn) set(key string, value []byte, ttl time.Duration) error {
+	extras := make([]byte, 8) // flags, then expiration
+	binary.BigEndian.PutUint32(extras[4:], expiration(ttl))
+	res, err := c.call(opSet, extras, []byte(key), value)
+	if err != nil {
+		return err
+	}
+	if res.status != statusOK {
+		return statusError(res)
+	}
+	return nil
+}
+
+func (c *conn) delete(key string) error {
+	res, err := c.call(opDelete, nil, []byte(key), nil)
+	if err != nil {
+		return err
+	}
+	if res.status != statusOK && res.status != statusKeyNotFound {
+		return statusError(res)
+	}
+	return nil
+}
+
+// expiration encodes ttl the way memcached expects it, zero means it never expires
+func expiration(ttl time.Duration) uint32 {
+	if ttl <= 0 {
+		return 0
+	}
+	if ttl > maxRelativeExpiration {
+		return uint32(time.Now().Add(ttl).Unix())
+	}
+	return uint32((ttl + time.Second - 1) / time.Second) // rounded up, so entries never expire early
+}
+
+func statusError(res response) error {
+	return fmt.Errorf("memcached status %#04x : %s", res.status, res.value)
+}
@@ -0,0 +1,49 @@
+package memcachestore
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// ring spreads keys over the servers with consistent hashing, every server owns several points of the ring
+// so that adding or removing a server only moves the keys of its neighbours
+// Points are laid out like ketama does (4 points per md5 digest), which is what most memcached clients use
+type ring struct {
+	points  []uint32
+	servers []int // servers[i] is the index of the server owning points[i]
+}
+
+func newRing(servers []string, replicas int) *ring {
+	r := &ring{}
+	for server, addr := range servers {
+		for i := 0; i < (replicas+3)/4; i++ {
+			digest := md5.Sum([]byte(addr + "-" + strconv.Itoa(i)))
+			for j := 0; j < 4; j++ {
+				r.points = append(r.points, binary.LittleEndian.Uint32(digest[j*4:]))
+				r.servers = append(r.servers, server)
+			}
+		}
+	}
+	sort.Sort(r)
+	return r
+}
+
+// server returns the index of the server owning key, the one owning the first point after the key hash
+func (r *ring) server(key string) int {
+	digest := md5.Sum([]byte(key))
+	hash := binary.LittleEndian.Uint32(digest[:4])
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= hash })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.servers[i]
+}
+
+func (r *ring) Len() int           { return len(r.points) }
+func (r *ring) Less(i, j int) bool { return r.points[i] < r.points[j] }
+func (r *ring) Swap(i, j int) {
+	r.points[i], r.points[j] = r.points[j], r.points[i]
+	r.servers[i], r.servers[j] = r.servers[j], r.servers[i]
+}
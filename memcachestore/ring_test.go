@@ -0,0 +1,39 @@
+package memcachestore
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Check that keys are spread over every server
+func TestRing_SpreadsKeys(t *testing.T) {
+	r := newRing([]string{"a:11211", "b:11211", "c:11211"}, 160)
+	counts := make([]int, 3)
+	for i := 0; i < 3000; i++ {
+		counts[r.server(fmt.Sprintf("key%d", i))]++
+	}
+	for server, count := range counts {
+		if count < 600 || count > 1400 {
+			t.Errorf("server %d got %d of 3000 keys", server, count)
+		}
+	}
+}
+
+// Check that adding a server only moves the keys it takes over
+func TestRing_AddingAServerMovesFewKeys(t *testing.T) {
+	before := newRing([]string{"a:11211", "b:11211", "c:11211"}, 160)
+	after := newRing([]string{"a:11211", "b:11211", "c:11211", "d:11211"}, 160)
+	moved := 0
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if s := after.server(key); s != before.server(key) {
+			if s != 3 {
+				t.Fatalf("%s moved between two old servers", key)
+			}
+			moved++
+		}
+	}
+	if moved < 400 || moved > 1200 {
+		t.Errorf("%d of 3000 keys moved, expected about a quarter", moved)
+	}
+}
@@ -0,0 +1,102 @@
+package sample1
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Decorator wraps a PriceService into another one adding some behavior around its calls (retries, logging...)
+type Decorator func(PriceService) PriceService
+
+// Chain wraps service with decorators, the first decorator is the outermost one: it sees the calls first
+// and the errors last. Chain(s, Logging(l), Retrying(p)) logs once per price, however many attempts it took
+// The services returned by the decorators of this package pass the context of the calls down, but they can't
+// price several items at once, put the cache's bulk loading aside when decorating a BulkPriceService
+func Chain(service PriceService, decorators ...Decorator) PriceService {
+	for i := len(decorators) - 1; i >= 0; i-- {
+		service = decorators[i](service)
+	}
+	return service
+}
+
+// Retrying is a Decorator retrying the failed calls following policy, see NewRetryingService
+func Retrying(policy RetryPolicy) Decorator {
+	return func(service PriceService) PriceService {
+		return NewRetryingService(service, policy)
+	}
+}
+
+// CircuitBreaking is a Decorator that stops calling the service while it keeps failing, see NewCircuitBreakerService
+func CircuitBreaking(policy BreakerPolicy) Decorator {
+	return func(service PriceService) PriceService {
+		return NewCircuitBreakerService(service, policy)
+	}
+}
+
+// RateLimiting is a Decorator keeping the calls to the service within perSecond on average, with bursts of up
+// to burst calls. Calls wait for their turn, or fail with ErrRateLimited if their context was made with FailFast
+// Unlike WithRateLimit, it limits the calls that actually reach the service, retries included when it is
+// chained inside Retrying
+func RateLimiting(perSecond float64, burst int) Decorator {
+	return func(service PriceService) PriceService {
+		return &rateLimitedService{
+			service: AsContextPriceService(service),
+			limiter: newRateLimiter(perSecond, burst, realClock{}),
+		}
+	}
+}
+
+// rateLimitedService is the PriceService returned by RateLimiting
+type rateLimitedService struct {
+	service ContextPriceService
+	limiter *rateLimiter
+}
+
+func (s *rateLimitedService) GetPriceFor(itemCode string) (float64, error) {
+	return s.GetPriceForCtx(context.Background(), itemCode)
+}
+
+func (s *rateLimitedService) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	if err := s.limiter.wait(ctx); err != nil {
+		return 0, err
+	}
+	return s.service.GetPriceForCtx(ctx, itemCode)
+}
+
+// Observing is a Decorator calling fn after every call to the service with how long it took and how it
+// ended, it is the place to hook metrics
+func Observing(fn func(itemCode string, duration time.Duration, err error)) Decorator {
+	return func(service PriceService) PriceService {
+		return &observedService{service: AsContextPriceService(service), observe: fn}
+	}
+}
+
+// observedService is the PriceService returned by Observing
+type observedService struct {
+	service ContextPriceService
+	observe func(itemCode string, duration time.Duration, err error)
+}
+
+func (s *observedService) GetPriceFor(itemCode string) (float64, error) {
+	return s.GetPriceForCtx(context.Background(), itemCode)
+}
+
+func (s *observedService) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	start := time.Now()
+	price, err := s.service.GetPriceForCtx(ctx, itemCode)
+	s.observe(itemCode, time.Since(start), err)
+	return price, err
+}
+
+// Logging is a Decorator logging every call to the service with logger, failures at the warning level and
+// the others at the debug level
+func Logging(logger *slog.Logger) Decorator {
+	return Observing(func(itemCode string, duration time.Duration, err error) {
+		if err != nil {
+			logger.Warn("price service call failed", "item", itemCode, "duration", duration, "err", err)
+			return
+		}
+		logger.Debug("price service call", "item", itemCode, "duration", duration)
+	})
+}
@@ -0,0 +1,85 @@
+package sample1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingDecorator appends name to calls before and after every call to the service it wraps
+func recordingDecorator(name string, calls *[]string) Decorator {
+	return func(service PriceService) PriceService {
+		return &observedService{
+			service: AsContextPriceService(service),
+			observe: func(string, time.Duration, error) { *calls = append(*calls, name) },
+		}
+	}
+}
+
+// Check that Chain applies the first decorator outermost
+func TestChain_Order(t *testing.T) {
+	service := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}}}
+	var calls []string
+	chained := Chain(service, recordingDecorator("outer", &calls), recordingDecorator("inner", &calls))
+	cache := NewTransparentCache(chained, time.Minute)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	if strings.Join(calls, ",") != "inner,outer" {
+		t.Errorf("expected the inner decorator to finish first but got %v", calls)
+	}
+	if Chain(service) != PriceService(service) {
+		t.Error("Chain without decorators should return the service")
+	}
+}
+
+// Check that the decorators pass the context down to the service
+func TestChain_PassesContext(t *testing.T) {
+	service := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}}, callDelay: time.Second}
+	chained := Chain(service, Logging(slog.New(slog.DiscardHandler)), Retrying(RetryPolicy{MaxAttempts: 1}))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := chained.(ContextPriceService).GetPriceForCtx(ctx, "p1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline error but got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("the call should have given up with the context")
+	}
+}
+
+// Check that retries chained outside of the rate limit are limited too
+func TestRateLimiting(t *testing.T) {
+	service := &flakyPriceService{
+		mockPriceService: mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}}},
+		failures:         1,
+		err:              errors.New("503 service unavailable"),
+	}
+	chained := Chain(service, Retrying(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}), RateLimiting(0.001, 1))
+	_, err := chained.(ContextPriceService).GetPriceForCtx(FailFast(context.Background()), "p1")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected the retry to be rate limited but got %v", err)
+	}
+	assertInt(t, 1, service.getNumCalls(), "the retry shouldn't reach the service")
+}
+
+// Check that Logging logs failures as warnings and successes at the debug level
+func TestLogging(t *testing.T) {
+	service := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}, "p2": {err: ErrNotFound}}}
+	var buf bytes.Buffer
+	logged := Chain(service, Logging(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	logged.GetPriceFor("p1")
+	logged.GetPriceFor("p2")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines but got %q", buf.String())
+	}
+	if !strings.Contains(lines[0], "level=DEBUG") || !strings.Contains(lines[0], "item=p1") {
+		t.Errorf("unexpected log line for the success: %v", lines[0])
+	}
+	if !strings.Contains(lines[1], "level=WARN") || !strings.Contains(lines[1], `err="not found"`) {
+		t.Errorf("unexpected log line for the failure: %v", lines[1])
+	}
+}
@@ -0,0 +1,157 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MinorUnits is an amount in the minor units of its currency (cents for USD, yens for JPY), so that prices add
+// up exactly where float64 amounts round. The exponent of the currency (2 for USD, 0 for JPY, 3 for BHD) tells
+// how many minor units make a major one
+type MinorUnits int64
+
+// maxExactFloat is the largest integer below which every integer has an exact float64
+const maxExactFloat = 1 << 53
+
+// MinorUnitsOf returns amount in minor units for a currency of the given exponent, rounded to the nearest
+// minor unit (halves away from zero). It fails for NaN, infinities and amounts too large to be exact as a float64
+func MinorUnitsOf(amount float64, exponent int) (MinorUnits, error) {
+	scaled := math.Round(amount * math.Pow10(exponent))
+	if math.IsNaN(scaled) || math.Abs(scaled) >= maxExactFloat {
+		return 0, fmt.Errorf("%v can't be converted to minor units with exponent %d", amount, exponent)
+	}
+	return MinorUnits(scaled), nil
+}
+
+// Float returns the amount in major units for a currency of the given exponent, for display or for the APIs
+// that take a float64, it is only exact if the amount is
+func (m MinorUnits) Float(exponent int) float64 {
+	return float64(m) / math.Pow10(exponent)
+}
+
+// Format returns the amount in major units with exponent decimals ("12.30" for 1230 with exponent 2)
+func (m MinorUnits) Format(exponent int) string {
+	if exponent <= 0 {
+		return strconv.FormatInt(int64(m), 10)
+	}
+	sign, units := "", strconv.FormatUint(uint64(m), 10)
+	if m < 0 {
+		sign, units = "-", strconv.FormatUint(uint64(-m), 10)
+	}
+	if len(units) <= exponent {
+		units = strings.Repeat("0", exponent-len(units)+1) + units
+	}
+	return sign + units[:len(units)-exponent] + "." + units[len(units)-exponent:]
+}
+
+// MinorUnitsService is a service pricing items in minor units, see MinorUnitsCache
+type MinorUnitsService interface {
+	GetMinorUnitsFor(ctx context.Context, itemCode string) (MinorUnits, error)
+}
+
+// BulkMinorUnitsService is a MinorUnitsService that can price several items in one call, returning the prices
+// in the same order as the item codes, it can return a *BatchError[string] if only some items failed
+type BulkMinorUnitsService interface {
+	GetMinorUnitsForMany(ctx context.Context, itemCodes ...string) ([]MinorUnits, error)
+}
+
+// AsMinorUnitsService returns a MinorUnitsService for service, whose prices are in a currency of the given
+// exponent, see MinorUnitsOf for how they are rounded
+// If service is a BulkPriceService (or a ContextBulkPriceService), so is the returned service
+func AsMinorUnitsService(service PriceService, exponent int) MinorUnitsService {
+	adapter := minorUnitsAdapter{service: AsContextPriceService(service), exponent: exponent}
+	if bulkLoader := bulkLoaderForService(service); bulkLoader != nil {
+		return bulkMinorUnitsAdapter{minorUnitsAdapter: adapter, bulkLoader: bulkLoader}
+	}
+	return adapter
+}
+
+// minorUnitsAdapter makes a PriceService a MinorUnitsService
+type minorUnitsAdapter struct {
+	service  ContextPriceService
+	exponent int
+}
+
+func (a minorUnitsAdapter) GetMinorUnitsFor(ctx context.Context, itemCode string) (MinorUnits, error) {
+	price, err := a.service.GetPriceForCtx(ctx, itemCode)
+	if err != nil {
+		return 0, err
+	}
+	return MinorUnitsOf(price, a.exponent)
+}
+
+// bulkMinorUnitsAdapter makes a BulkPriceService a BulkMinorUnitsService
+type bulkMinorUnitsAdapter struct {
+	minorUnitsAdapter
+	bulkLoader BulkLoaderFunc[string, float64]
+}
+
+func (a bulkMinorUnitsAdapter) GetMinorUnitsForMany(ctx context.Context, itemCodes ...string) ([]MinorUnits, error) {
+	prices, err := a.bulkLoader(ctx, itemCodes)
+	if err == nil && len(prices) != len(itemCodes) {
+		err = fmt.Errorf("asked for %d prices but got %d", len(itemCodes), len(prices))
+	}
+	var batchErr *BatchError[string]
+	if (err != nil && !errors.As(err, &batchErr)) || len(prices) != len(itemCodes) {
+		return nil, err // without prices, the cache loads the items the batch error doesn't name one by one
+	}
+	failed := map[string]error{}
+	if batchErr != nil {
+		for _, keyErr := range batchErr.Errors {
+			failed[keyErr.Key] = keyErr.Err
+		}
+	}
+	amounts := make([]MinorUnits, len(itemCodes))
+	errs := make([]error, len(itemCodes))
+	for i, itemCode := range itemCodes {
+		if errs[i] = failed[itemCode]; errs[i] == nil {
+			amounts[i], errs[i] = MinorUnitsOf(prices[i], a.exponent)
+		}
+	}
+	if batchErr := newBatchError(itemCodes, errs); batchErr != nil {
+		return amounts, batchErr
+	}
+	return amounts, nil
+}
+
+// MinorUnitsCache is a cache of prices in minor units in front of a MinorUnitsService, for the callers that need
+// exact amounts. It is safe for concurrent use by multiple goroutines
+type MinorUnitsCache struct {
+	*Cache[string, MinorUnits]
+}
+
+// NewMinorUnitsCache creates a cache in front of service, configured with opts
+// If service is a BulkMinorUnitsService, it is used to load batches
+func NewMinorUnitsCache(service MinorUnitsService, opts ...Option) *MinorUnitsCache {
+	if bulkService, ok := service.(BulkMinorUnitsService); ok {
+		opts = append([]Option{WithBulkLoader(func(ctx context.Context, itemCodes []string) ([]MinorUnits, error) {
+			return bulkService.GetMinorUnitsForMany(ctx, itemCodes...)
+		})}, opts...)
+	}
+	return &MinorUnitsCache{Cache: NewCache(service.GetMinorUnitsFor, opts...)}
+}
+
+// GetPriceFor gets the price for the item, either from the cache or the service if it was not cached or too old
+func (c *MinorUnitsCache) GetPriceFor(itemCode string) (MinorUnits, error) {
+	return c.GetPriceForCtx(context.Background(), itemCode)
+}
+
+// GetPriceForCtx is like GetPriceFor, but gives up waiting for the service when ctx is done
+func (c *MinorUnitsCache) GetPriceForCtx(ctx context.Context, itemCode string) (MinorUnits, error) {
+	return c.Get(ctx, itemCode)
+}
+
+// GetPricesFor gets the prices for several items at once, the returned error is a *BatchError holding the
+// failure of every item that could not be priced
+func (c *MinorUnitsCache) GetPricesFor(itemCodes ...string) ([]MinorUnits, error) {
+	return c.GetPricesForCtx(context.Background(), itemCodes...)
+}
+
+// GetPricesForCtx is like GetPricesFor, but the outstanding fetches are cancelled when ctx is done
+func (c *MinorUnitsCache) GetPricesForCtx(ctx context.Context, itemCodes ...string) ([]MinorUnits, error) {
+	return c.GetMany(ctx, itemCodes...)
+}
@@ -0,0 +1,89 @@
+package sample1
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// Check that float prices are rounded to the nearest minor unit, and that amounts that can't be exact are refused
+func TestMinorUnitsOf(t *testing.T) {
+	for _, c := range []struct {
+		amount   float64
+		exponent int
+		want     MinorUnits
+	}{
+		{0.1 + 0.2, 2, 30},
+		{19.99, 2, 1999},
+		{1.005, 2, 100}, // 1.005 is 1.00499999999999989... as a float64
+		{-2.5, 0, -3},
+		{1234, 0, 1234},
+		{1.2345, 3, 1235},
+	} {
+		got, err := MinorUnitsOf(c.amount, c.exponent)
+		if err != nil || got != c.want {
+			t.Errorf("%v with exponent %d: expected %d but got %d, %v", c.amount, c.exponent, c.want, got, err)
+		}
+	}
+	for _, amount := range []float64{math.NaN(), math.Inf(1), 1e17} {
+		if _, err := MinorUnitsOf(amount, 2); err == nil {
+			t.Errorf("expected an error for %v", amount)
+		}
+	}
+}
+
+// Check that amounts are formatted with the decimals of their currency
+func TestMinorUnits_Format(t *testing.T) {
+	for _, c := range []struct {
+		amount   MinorUnits
+		exponent int
+		want     string
+	}{{1230, 2, "12.30"}, {5, 2, "0.05"}, {-5, 2, "-0.05"}, {-1999, 2, "-19.99"}, {1234, 0, "1234"}, {7, 3, "0.007"}} {
+		if got := c.amount.Format(c.exponent); got != c.want {
+			t.Errorf("%d with exponent %d: expected %q but got %q", c.amount, c.exponent, c.want, got)
+		}
+	}
+	assertFloat(t, 12.3, MinorUnits(1230).Float(2), "wrong float amount")
+}
+
+// Check that a PriceService adapted to minor units is cached in minor units
+func TestMinorUnitsCache(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 0.1, err: nil},
+		"p2": {price: 0.2, err: nil},
+	}}
+	cache := NewMinorUnitsCache(AsMinorUnitsService(mockService, 2), WithMaxAge(time.Minute))
+	prices, err := cache.GetPricesFor("p1", "p2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sum := prices[0] + prices[1]; sum != 30 {
+		t.Errorf("expected the prices to add up to 30 cents but got %d", sum)
+	}
+	if price, err := cache.GetPriceFor("p1"); err != nil || price != 10 {
+		t.Errorf("expected 10 cents but got %d, %v", price, err)
+	}
+	assertInt(t, 2, mockService.getNumCalls(), "the prices should have been cached")
+}
+
+// Check that a bulk service stays one, and that the items it fails or can't convert are reported each
+func TestAsMinorUnitsService_Bulk(t *testing.T) {
+	bulkService := &bulkMockPriceService{mockPriceService: mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 1.5, err: nil},
+		"p2": {price: math.NaN(), err: nil},
+		"p3": {price: 0, err: ErrNotFound},
+	}}}
+	cache := NewMinorUnitsCache(AsMinorUnitsService(bulkService, 2))
+	results := cache.GetEach(t.Context(), "p1", "p2", "p3")
+	if results[0].Err != nil || results[0].Value != 150 {
+		t.Errorf("wrong result for p1: %+v", results[0])
+	}
+	if results[1].Err == nil {
+		t.Error("expected an error for a price that can't be converted")
+	}
+	if !errors.Is(results[2].Err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for p3 but got %v", results[2].Err)
+	}
+	assertInt(t, 1, len(bulkService.bulkCalls), "the items should have been asked in bulk")
+}
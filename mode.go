@@ -0,0 +1,89 @@
+package sample1
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Mode tells how a cache answers its lookups, operators switch it with SetMode to respond to pricing incidents
+// without a redeploy
+type Mode int32
+
+const (
+	Normal      Mode = iota // lookups are answered from the cache, and loaded when it can't answer them
+	Passthrough             // every lookup calls the loader, nothing is read from the cache nor stored in it
+	Frozen                  // lookups are only answered from the cache, the loader is never called
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Normal:
+		return "normal"
+	case Passthrough:
+		return "passthrough"
+	case Frozen:
+		return "frozen"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseMode returns the Mode named s, as returned by Mode.String
+func ParseMode(s string) (Mode, error) {
+	for _, mode := range []Mode{Normal, Passthrough, Frozen} {
+		if mode.String() == s {
+			return mode, nil
+		}
+	}
+	return Normal, fmt.Errorf("unknown mode [%s]", s)
+}
+
+// forever is a maxAge no entry is ever older than
+const forever = time.Duration(math.MaxInt64)
+
+// SetMode switches how the cache answers its lookups from now on, lookups already waiting for a load still get it
+// In Passthrough, lookups call the loader one key at a time (GetMany included) without joining each other, and
+// nothing is stored. In Frozen, lookups are served whatever cached value there is, expired or not (the hard TTL
+// of WithSoftTTL still holds), and fail with ErrNotCached when there is none. The lookups made with CacheOnly
+// fail with ErrNotCached in Passthrough, ForceRefresh is ignored in Frozen
+func (c *Cache[K, V]) SetMode(mode Mode) {
+	c.mode.Store(int32(mode))
+}
+
+// Mode returns how the cache answers its lookups, Normal unless SetMode changed it
+func (c *Cache[K, V]) Mode() Mode {
+	return Mode(c.mode.Load())
+}
+
+// passthrough is a lookup in Passthrough mode, as call asks, the ones made with CacheOnly fail right away
+func (c *Cache[K, V]) passthrough(ctx context.Context, key K, loader LoaderFunc[K, V], call callConfig) (V, error) {
+	c.missed(key)
+	if call.cacheOnly {
+		var zero V
+		return zero, ErrNotCached
+	}
+	if !c.drain.begin() {
+		var zero V
+		return zero, fmt.Errorf("loading [%v] : %w", key, ErrClosed)
+	}
+	defer c.drain.end()
+	if err := c.limiter.wait(ctx); err != nil {
+		var zero V
+		return zero, fmt.Errorf("loading [%v] : %w", key, err)
+	}
+	value, _, _, err := c.fetch(ctx, key, loader)
+	return value, err
+}
+
+// frozen is a lookup in Frozen mode of key given what the store has for it
+func (c *Cache[K, V]) frozen(key K, entry Entry[V], ok bool) (V, error) {
+	if !ok || entry.Err != nil || c.expired(entry, forever, c.clock.Now()) {
+		c.missed(key)
+		var zero V
+		return zero, ErrNotCached
+	}
+	c.hit(key)
+	return entry.Value, nil
+}
@@ -0,0 +1,72 @@
+package sample1
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// Check that in Passthrough every lookup calls the service and nothing is cached, and that Normal caches again
+func TestSetMode_Passthrough(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 6, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	getPriceWithNoErr(t, cache, "p1")
+	cache.SetMode(Passthrough)
+	mockService.setPrice("p1", 7)
+	assertFloat(t, 7, getPriceWithNoErr(t, cache, "p1"), "expected the price of the service")
+	assertFloats(t, []float64{7, 6}, getPricesWithNoErr(t, cache, "p1", "p2"), "expected the prices of the service")
+	assertInt(t, 4, mockService.getNumCalls(), "every lookup should call the service")
+	if _, _, ok := cache.Peek("p2"); ok {
+		t.Error("nothing should be cached in passthrough")
+	}
+	if _, err := cache.GetPriceForWith("p1", CacheOnly()); !errors.Is(err, ErrNotCached) {
+		t.Errorf("expected ErrNotCached for a CacheOnly lookup, got %v", err)
+	}
+
+	cache.SetMode(Normal)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "expected the price cached before the passthrough")
+	assertInt(t, 4, mockService.getNumCalls(), "the cached price should have been served")
+}
+
+// Check that in Frozen lookups are served the cached prices, even expired, without ever calling the service
+func TestSetMode_Frozen(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 6, err: nil},
+		},
+	}
+	clock := newFakeClock()
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock))
+	getPriceWithNoErr(t, cache, "p1")
+	cache.SetMode(Frozen)
+	if cache.Mode() != Frozen {
+		t.Errorf("expected the frozen mode but got %v", cache.Mode())
+	}
+	clock.Advance(time.Hour)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "expected the expired price")
+	if _, err := cache.GetPriceFor("p2"); !errors.Is(err, ErrNotCached) {
+		t.Errorf("expected ErrNotCached for an item that isn't cached, got %v", err)
+	}
+	if _, err := cache.GetPricesFor("p1", "p2"); !errors.Is(err, ErrNotCached) {
+		t.Errorf("expected ErrNotCached in the batch, got %v", err)
+	}
+	assertInt(t, 1, mockService.getNumCalls(), "the service shouldn't be called while frozen")
+}
+
+// Check that the modes are parsed from their names
+func TestParseMode(t *testing.T) {
+	for _, mode := range []Mode{Normal, Passthrough, Frozen} {
+		if parsed, err := ParseMode(mode.String()); err != nil || parsed != mode {
+			t.Errorf("expected %v but got %v, %v", mode, parsed, err)
+		}
+	}
+	if _, err := ParseMode("paused"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,212 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Namespace is a view of a TransparentCache whose item codes are prefixed with the name of the namespace and
+// a colon ("store1:p1") in the cache, so that several namespaces (one per store for instance) share the storage,
+// the limits and the options of one cache without mixing their prices
+// The prefix never reaches the price services, they are called with the item codes of the namespace ("p1"),
+// background refreshes included
+type Namespace struct {
+	cache   *TransparentCache
+	prefix  string
+	service namespaceService
+}
+
+// Namespace returns the view of the cache for name, whose prices come from the actual service of the cache
+// Views with the same name share their prices
+func (c *TransparentCache) Namespace(name string) *Namespace {
+	return c.namespaces.add(c, name+":", c.namespaces.actual)
+}
+
+// NamespaceWith is like Namespace, but the prices of the namespace come from service, the actual service of the
+// cache is not called for them. The last service given for a name is used by all its views
+func (c *TransparentCache) NamespaceWith(name string, service PriceService) *Namespace {
+	return c.namespaces.add(c, name+":", newNamespaceService(service))
+}
+
+// Namespace returns a namespace nested in this one, its item codes are prefixed by both names ("store1:eu:p1")
+// and its prices come from the service of this one
+func (n *Namespace) Namespace(name string) *Namespace {
+	return n.cache.namespaces.add(n.cache, n.prefix+name+":", n.service)
+}
+
+// NamespaceWith is like Namespace, but the prices of the nested namespace come from service
+func (n *Namespace) NamespaceWith(name string, service PriceService) *Namespace {
+	return n.cache.namespaces.add(n.cache, n.prefix+name+":", newNamespaceService(service))
+}
+
+// key returns the item code of the cache for an item code of the namespace
+func (n *Namespace) key(itemCode string) string {
+	return n.prefix + itemCode
+}
+
+func (n *Namespace) GetPriceFor(itemCode string) (float64, error) {
+	return n.GetPriceForCtx(context.Background(), itemCode)
+}
+
+// GetPriceForCtx is like TransparentCache.GetPriceForCtx, for an item of the namespace
+func (n *Namespace) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	return n.cache.GetPriceForCtx(ctx, n.key(itemCode))
+}
+
+func (n *Namespace) GetPricesFor(itemCodes ...string) ([]float64, error) {
+	return n.GetPricesForCtx(context.Background(), itemCodes...)
+}
+
+// GetPricesForCtx is like TransparentCache.GetPricesForCtx, for items of the namespace
+// The keys of the returned *BatchError are the item codes of the namespace, without its prefix
+func (n *Namespace) GetPricesForCtx(ctx context.Context, itemCodes ...string) ([]float64, error) {
+	keys := make([]string, len(itemCodes))
+	for i, itemCode := range itemCodes {
+		keys[i] = n.key(itemCode)
+	}
+	results := n.cache.GetEach(ctx, keys...)
+	prices := make([]float64, len(results))
+	errs := make([]error, len(results))
+	for i, result := range results {
+		prices[i], errs[i] = result.Value, result.Err
+	}
+	if batchErr := newBatchError(itemCodes, errs); batchErr != nil {
+		return nil, batchErr
+	}
+	return prices, nil
+}
+
+// Set stores the price of an item of the namespace as if it was just fetched
+func (n *Namespace) Set(itemCode string, price float64) {
+	n.cache.Set(n.key(itemCode), price)
+}
+
+// Peek is like Cache.Peek, for an item of the namespace
+func (n *Namespace) Peek(itemCode string) (price float64, age time.Duration, ok bool) {
+	return n.cache.Peek(n.key(itemCode))
+}
+
+// Invalidate drops the cached price of an item of the namespace
+func (n *Namespace) Invalidate(itemCode string) {
+	n.cache.Invalidate(n.key(itemCode))
+}
+
+// Clear drops every cached price of the namespace (nested namespaces included), leaving the others alone
+func (n *Namespace) Clear() {
+	n.cache.InvalidatePrefix(n.prefix)
+}
+
+// namespaceService is the service prices of a namespace come from
+type namespaceService struct {
+	service ContextPriceService
+	bulk    BulkLoaderFunc[string, float64] // nil if the service can't price several items at once
+}
+
+func newNamespaceService(service PriceService) namespaceService {
+	return namespaceService{service: AsContextPriceService(service), bulk: bulkLoaderForService(service)}
+}
+
+// namespaces routes the loads of a TransparentCache to the service of the namespace of each item code, called with
+// the item code without the prefix of the namespace, the item codes of no namespace go to the actual service as is
+type namespaces struct {
+	actual namespaceService
+
+	mu       sync.RWMutex
+	services map[string]namespaceService // by prefix
+}
+
+func (r *namespaces) add(cache *TransparentCache, prefix string, service namespaceService) *Namespace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.services == nil {
+		r.services = map[string]namespaceService{}
+	}
+	r.services[prefix] = service
+	return &Namespace{cache: cache, prefix: prefix, service: service}
+}
+
+// route returns the prefix and the service of the innermost namespace of the item code of the cache, and the item
+// code for that service. The prefix is empty for the item codes of no namespace
+func (r *namespaces) route(key string) (prefix string, service namespaceService, itemCode string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := strings.LastIndexByte(key, ':'); i >= 0; i = strings.LastIndexByte(key[:i], ':') {
+		if service, ok := r.services[key[:i+1]]; ok {
+			return key[:i+1], service, key[i+1:]
+		}
+	}
+	return "", r.actual, key
+}
+
+// load is the loader of the cache
+func (r *namespaces) load(ctx context.Context, key string) (float64, error) {
+	_, service, itemCode := r.route(key)
+	return service.service.GetPriceForCtx(ctx, itemCode)
+}
+
+// loadMany is the bulk loader of the cache when the actual service can price several items at once
+// The keys are grouped by namespace, the items of a namespace whose service can't price several at once are loaded
+// one after another, as are those a bulk call returned no price for
+func (r *namespaces) loadMany(ctx context.Context, keys []string) ([]float64, error) {
+	type group struct {
+		service   namespaceService
+		itemCodes []string
+		indexes   []int
+	}
+	var prefixes []string
+	groups := map[string]*group{}
+	for i, key := range keys {
+		prefix, service, itemCode := r.route(key)
+		g, ok := groups[prefix]
+		if !ok {
+			g = &group{service: service}
+			groups[prefix] = g
+			prefixes = append(prefixes, prefix)
+		}
+		g.itemCodes = append(g.itemCodes, itemCode)
+		g.indexes = append(g.indexes, i)
+	}
+	if len(prefixes) == 1 && prefixes[0] == "" {
+		return r.actual.bulk(ctx, keys)
+	}
+	prices := make([]float64, len(keys))
+	errs := make([]error, len(keys))
+	for _, prefix := range prefixes {
+		g := groups[prefix]
+		var groupPrices []float64
+		failed := map[string]error{}
+		if g.service.bulk != nil {
+			var err error
+			groupPrices, err = g.service.bulk(ctx, g.itemCodes)
+			var batchErr *BatchError[string]
+			switch {
+			case errors.As(err, &batchErr):
+				for _, keyErr := range batchErr.Errors {
+					failed[keyErr.Key] = keyErr.Err
+				}
+			case err != nil:
+				for _, i := range g.indexes {
+					errs[i] = err
+				}
+				continue
+			}
+		}
+		for j, itemCode := range g.itemCodes {
+			switch i := g.indexes[j]; {
+			case failed[itemCode] != nil:
+				errs[i] = failed[itemCode]
+			case len(groupPrices) == len(g.itemCodes):
+				prices[i] = groupPrices[j]
+			default:
+				prices[i], errs[i] = g.service.service.GetPriceForCtx(ctx, itemCode)
+			}
+		}
+	}
+	if batchErr := newBatchError(keys, errs); batchErr != nil {
+		return prices, batchErr
+	}
+	return prices, nil
+}
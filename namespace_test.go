@@ -0,0 +1,136 @@
+package sample1
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// Check that namespaces keep their prices apart while sharing one cache, and can be cleared on their own
+func TestNamespace(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 4, err: nil}}}
+	store1Service := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5, err: nil}}}
+	store2Service := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 6, err: nil}}}
+	euService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 7, err: nil}}}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxEntries(10))
+	store1, store2 := cache.NamespaceWith("store1", store1Service), cache.NamespaceWith("store2", store2Service)
+	eu := store1.NamespaceWith("eu", euService)
+	for _, c := range []struct {
+		namespace *Namespace
+		want      float64
+	}{{store1, 5}, {store2, 6}, {eu, 7}, {cache.NamespaceWith("store1", store1Service), 5}, {cache.Namespace("shop"), 4}} {
+		price, err := c.namespace.GetPriceFor("p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFloat(t, c.want, price, "wrong price for "+c.namespace.prefix)
+	}
+	assertInt(t, 1, store1Service.getNumCalls(), "views with the same name should share their prices")
+	assertInt(t, 1, mockService.getNumCalls(), "the actual service should only be called for its namespace")
+	assertInt(t, 4, cache.Len(), "the namespaces should share the cache")
+
+	store1.Clear()
+	if _, _, ok := store1.Peek("p1"); ok {
+		t.Error("the prices of store1 should have been dropped")
+	}
+	if _, _, ok := eu.Peek("p1"); ok {
+		t.Error("the prices of the nested namespace should have been dropped")
+	}
+	if _, _, ok := store2.Peek("p1"); !ok {
+		t.Error("the prices of store2 should stay")
+	}
+}
+
+// Check that the services are called with the item codes of the namespace, background refreshes included
+func TestNamespace_CallsTheServiceWithoutThePrefix(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5, err: nil}}}
+	clock := newFakeClock()
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock), WithStaleWhileRevalidate(time.Hour))
+	store1 := cache.Namespace("store1")
+	eu := store1.Namespace("eu")
+	for _, namespace := range []*Namespace{store1, eu} {
+		price, err := namespace.GetPriceFor("p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFloat(t, 5, price, "wrong price for "+namespace.prefix)
+	}
+
+	mockService.mu.Lock()
+	mockService.mockResults = map[string]mockResult{"p1": {price: 6, err: nil}}
+	mockService.mu.Unlock()
+	clock.Advance(2 * time.Minute)
+	price, err := store1.GetPriceFor("p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloat(t, 5, price, "expected the stale price")
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		if price, _, _ := store1.Peek("p1"); price == 6 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the price of store1 should have been refreshed")
+		}
+	}
+	assertInt(t, 3, mockService.getNumCalls(), "wrong number of calls to the service")
+}
+
+// Check that the misses of a batch are loaded together by the service of their namespace
+func TestNamespace_BulkLoadsPerNamespace(t *testing.T) {
+	mockService := &bulkMockPriceService{mockPriceService: mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 4, err: nil},
+	}}}
+	storeService := &bulkMockPriceService{mockPriceService: mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 5, err: nil},
+		"p2": {price: 0, err: ErrNotFound},
+	}}}
+	plainService := &mockPriceService{mockResults: map[string]mockResult{"p3": {price: 6, err: nil}}}
+	cache := New(mockService)
+	cache.NamespaceWith("store", storeService)
+	cache.NamespaceWith("plain", plainService)
+	prices, err := cache.GetPricesFor("p1", "store:p1", "plain:p3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloats(t, []float64{4, 5, 6}, prices, "wrong prices returned")
+	assertInt(t, 1, len(mockService.getBulkCalls()), "the actual service should get one bulk call")
+	assertInt(t, 1, len(storeService.getBulkCalls()), "the service of the namespace should get one bulk call")
+	if calls := storeService.getBulkCalls(); len(calls[0]) != 1 || calls[0][0] != "p1" {
+		t.Errorf("expected the service of the namespace to be asked for p1 but got %v", calls)
+	}
+	assertInt(t, 1, plainService.getNumCalls(), "the service without bulk calls should be called for its item")
+
+	_, err = cache.GetPricesFor("p1", "store:p2")
+	var batchErr *BatchError[string]
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[0].Key != "store:p2" {
+		t.Fatalf("expected store:p2 to fail but got %v", err)
+	}
+}
+
+// Check that the batch errors of a namespace use its own item codes
+func TestNamespace_GetPricesFor(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 5, err: nil},
+		"p2": {price: 0, err: ErrNotFound},
+	}}
+	store1 := NewTransparentCache(mockService, time.Minute).Namespace("store1")
+	store1.Set("p3", 9)
+	prices, err := store1.GetPricesFor("p1", "p3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFloats(t, []float64{5, 9}, prices, "wrong prices returned")
+	_, err = store1.GetPricesFor("p1", "p2")
+	var batchErr *BatchError[string]
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[0].Key != "p2" {
+		t.Fatalf("expected p2 to fail but got %v", err)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got %v", err)
+	}
+	store1.Invalidate("p1")
+	if _, _, ok := store1.Peek("p1"); ok {
+		t.Error("p1 should have been invalidated")
+	}
+}
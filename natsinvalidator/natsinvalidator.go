@@ -0,0 +1,101 @@
+// Package natsinvalidator carries the invalidations of a cache between processes over NATS
+// It lives in its own package so that the cache itself doesn't depend on the NATS client
+package natsinvalidator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+	"github.com/MadHive/deviget_challenge/internal/invalidation"
+)
+
+// DefaultSubject is where invalidations are published when Options.Subject is empty
+const DefaultSubject = "pricecache.invalidations"
+
+// Options configures how an Invalidator talks to NATS
+type Options struct {
+	Subject string        // where invalidations are published, DefaultSubject if empty
+	Timeout time.Duration // of the subscription round trip, 5s if zero
+	OnError func(error)   // called with every NATS failure and every message that can't be decoded
+}
+
+// Invalidator is a sample1.Invalidator publishing on a NATS subject, every cache sharing the subject gets the
+// invalidations of the others
+// Core NATS delivers messages at most once: an instance that is disconnected while an invalidation is
+// published serves its copy until it expires
+type Invalidator struct {
+	conn   *nats.Conn
+	opts   Options
+	origin string
+}
+
+var _ sample1.Invalidator = (*Invalidator)(nil)
+
+// New returns an invalidator publishing through conn
+func New(conn *nats.Conn, opts Options) *Invalidator {
+	if opts.Subject == "" {
+		opts.Subject = DefaultSubject
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	return &Invalidator{conn: conn, opts: opts, origin: invalidation.NewOrigin()}
+}
+
+func (i *Invalidator) Invalidate(keys []string) {
+	i.publish(invalidation.Message{Origin: i.origin, Keys: keys})
+}
+
+func (i *Invalidator) Clear() {
+	i.publish(invalidation.Message{Origin: i.origin, Clear: true})
+}
+
+func (i *Invalidator) publish(msg invalidation.Message) {
+	if err := i.conn.Publish(i.opts.Subject, invalidation.Encode(msg)); err != nil {
+		i.fail(fmt.Errorf("publishing on %s : %w", i.opts.Subject, err))
+	}
+}
+
+// Watch subscribes to the subject, it returns once the server confirmed the subscription
+// Once stop returned, onInvalidate and onClear aren't called anymore
+func (i *Invalidator) Watch(onInvalidate func(keys []string), onClear func()) (stop func()) {
+	var mu sync.Mutex // held while delivering, so that stop waits for the delivery in progress
+	stopped := false
+	sub, err := i.conn.Subscribe(i.opts.Subject, func(natsMsg *nats.Msg) {
+		msg, err := invalidation.Decode(natsMsg.Data)
+		if err != nil {
+			i.fail(fmt.Errorf("on %s : %w", i.opts.Subject, err))
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if !stopped {
+			invalidation.Deliver(msg, i.origin, onInvalidate, onClear)
+		}
+	})
+	if err != nil {
+		i.fail(fmt.Errorf("subscribing to %s : %w", i.opts.Subject, err))
+		return func() {}
+	}
+	if err := i.conn.FlushTimeout(i.opts.Timeout); err != nil {
+		i.fail(fmt.Errorf("subscribing to %s : %w", i.opts.Subject, err))
+	}
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		stopped = true
+		if err := sub.Unsubscribe(); err != nil {
+			i.fail(fmt.Errorf("unsubscribing from %s : %w", i.opts.Subject, err))
+		}
+	}
+}
+
+func (i *Invalidator) fail(err error) {
+	if i.opts.OnError != nil {
+		i.opts.OnError(err)
+	}
+}
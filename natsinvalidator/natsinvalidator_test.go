@@ -0,0 +1,73 @@
+package natsinvalidator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+type fakePriceService map[string]float64
+
+func (f fakePriceService) GetPriceFor(itemCode string) (float64, error) {
+	price, ok := f[itemCode]
+	if !ok {
+		return 0, fmt.Errorf("unknown item [%v] : %w", itemCode, sample1.ErrNotFound)
+	}
+	return price, nil
+}
+
+func newTestServer(t *testing.T) *server.Server {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	srv := natstest.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+	return srv
+}
+
+func newTestCache(t *testing.T, srv *server.Server) *sample1.TransparentCache {
+	conn, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := sample1.New(fakePriceService{"p1": 5, "p2": 7}, sample1.WithInvalidator(New(conn, Options{
+		OnError: func(err error) { t.Error(err) },
+	})))
+	t.Cleanup(func() {
+		cache.Close()
+		conn.Close()
+	})
+	return cache
+}
+
+// Check that caches sharing a subject drop the items the others invalidate
+func TestInvalidator_InvalidatesOtherCaches(t *testing.T) {
+	srv := newTestServer(t)
+	first, second := newTestCache(t, srv), newTestCache(t, srv)
+	first.GetPricesFor("p1", "p2")
+	second.GetPricesFor("p1", "p2")
+
+	first.Invalidate("p1")
+	waitFor(t, func() bool { return !second.Contains("p1") }, "the other cache should have dropped p1")
+	if !second.Contains("p2") || !first.Contains("p2") {
+		t.Error("p2 should still be cached")
+	}
+
+	second.Clear()
+	waitFor(t, func() bool { return first.Len() == 0 }, "the clear should have emptied the other cache")
+}
+
+func waitFor(t *testing.T, condition func() bool, msg string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Error(msg)
+}
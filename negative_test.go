@@ -0,0 +1,55 @@
+package sample1
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Check that "not found" errors are cached for the negative max age
+func TestGetPriceFor_CachesNotFoundErrors(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 0, err: fmt.Errorf("no such item : %w", ErrNotFound)},
+			"p2": {price: 0, err: fmt.Errorf("some error")},
+		},
+	}
+	negativeMaxAge := 50 * time.Millisecond
+	cache := NewTransparentCache(mockService, time.Minute, WithNegativeCaching(negativeMaxAge, nil))
+	for i := 0; i < 3; i++ {
+		if _, err := cache.GetPriceFor("p1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected a not found error, got %v", err)
+		}
+	}
+	assertInt(t, 1, mockService.getNumCalls(), "the not found error should have been cached")
+	if _, _, ok := cache.Peek("p1"); ok {
+		t.Error("a cached error should not be peeked as a price")
+	}
+
+	// other errors are not cached
+	cache.GetPriceFor("p2")
+	cache.GetPriceFor("p2")
+	assertInt(t, 3, mockService.getNumCalls(), "wrong number of service calls")
+
+	time.Sleep(negativeMaxAge * 2)
+	cache.GetPriceFor("p1")
+	assertInt(t, 4, mockService.getNumCalls(), "the cached error should have expired")
+}
+
+// Check that a custom predicate picks which errors are cached
+func TestGetPriceFor_NegativeCachingWithPredicate(t *testing.T) {
+	errDiscontinued := errors.New("discontinued")
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 0, err: errDiscontinued},
+		},
+	}
+	isDiscontinued := func(err error) bool { return errors.Is(err, errDiscontinued) }
+	cache := NewTransparentCache(mockService, time.Minute, WithNegativeCaching(time.Minute, isDiscontinued))
+	cache.GetPriceFor("p1")
+	if _, err := cache.GetPriceFor("p1"); !errors.Is(err, errDiscontinued) {
+		t.Errorf("expected the cached error, got %v", err)
+	}
+	assertInt(t, 1, mockService.getNumCalls(), "wrong number of service calls")
+}
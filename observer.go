@@ -0,0 +1,37 @@
+package sample1
+
+import "context"
+
+// Observer is told about the work the cache does, so that other packages can instrument it (tracing, for example)
+// Every Start method returns the context to use for the rest of the operation and a function
+// the cache calls once the operation is done
+type Observer interface {
+	// StartLookup is called when a single key is looked up, hit tells whether it was answered from the cache
+	StartLookup(ctx context.Context, key any) (context.Context, func(hit bool, err error))
+	// StartBatch is called when several keys are looked up at once, each of them is also a lookup
+	StartBatch(ctx context.Context, keys int) (context.Context, func(err error))
+	// StartLoad is called right before calling the loader (the actual service) for key
+	StartLoad(ctx context.Context, key any) (context.Context, func(err error))
+}
+
+// WithObserver sets the Observer that is told about lookups, batches and loads
+func WithObserver(observer Observer) Option {
+	return func(c *config) {
+		c.observer = observer
+	}
+}
+
+// noopObserver is the observer used when none is configured
+type noopObserver struct{}
+
+func (noopObserver) StartLookup(ctx context.Context, key any) (context.Context, func(bool, error)) {
+	return ctx, func(bool, error) {}
+}
+
+func (noopObserver) StartBatch(ctx context.Context, keys int) (context.Context, func(error)) {
+	return ctx, func(error) {}
+}
+
+func (noopObserver) StartLoad(ctx context.Context, key any) (context.Context, func(error)) {
+	return ctx, func(error) {}
+}
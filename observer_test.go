@@ -0,0 +1,58 @@
+package sample1
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingObserver keeps a line for every event it is told about
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (o *recordingObserver) record(format string, args ...any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, fmt.Sprintf(format, args...))
+}
+
+func (o *recordingObserver) StartLookup(ctx context.Context, key any) (context.Context, func(bool, error)) {
+	return ctx, func(hit bool, err error) { o.record("lookup %v hit=%v err=%v", key, hit, err != nil) }
+}
+
+func (o *recordingObserver) StartBatch(ctx context.Context, keys int) (context.Context, func(error)) {
+	return ctx, func(err error) { o.record("batch %v err=%v", keys, err != nil) }
+}
+
+func (o *recordingObserver) StartLoad(ctx context.Context, key any) (context.Context, func(error)) {
+	return ctx, func(err error) { o.record("load %v err=%v", key, err != nil) }
+}
+
+// Check that the observer is told about every lookup, load and batch
+func TestObserver_IsToldAboutLookupsAndLoads(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 0, err: fmt.Errorf("some error")},
+		},
+	}
+	observer := &recordingObserver{}
+	cache := NewTransparentCache(mockService, time.Minute, WithObserver(observer), WithMaxConcurrency(1))
+	getPriceWithNoErr(t, cache, "p1")
+	cache.GetPricesFor("p1", "p2")
+	expected := []string{
+		"load p1 err=false",
+		"lookup p1 hit=false err=false",
+		"lookup p1 hit=true err=false",
+		"load p2 err=true",
+		"lookup p2 hit=false err=true",
+		"batch 2 err=true",
+	}
+	if fmt.Sprint(expected) != fmt.Sprint(observer.events) {
+		t.Errorf("expected events %v, got %v", expected, observer.events)
+	}
+}
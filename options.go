@@ -0,0 +1,218 @@
+package sample1
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// DefaultMaxAge is how long values are kept when no WithMaxAge option is given
+const DefaultMaxAge = time.Minute
+
+// Option configures a Cache or a TransparentCache, see New and NewCache
+type Option func(*config)
+
+// config holds everything that can be set through an Option
+type config struct {
+	maxAge           time.Duration
+	maxConcurrency   int
+	maxEntries       int
+	policy           any // an EvictionPolicy[K], checked against the key type by NewCache
+	observer         Observer
+	maxStale         time.Duration
+	staleIfError     time.Duration
+	hardTTL          time.Duration
+	refreshAhead     float64
+	earlyBeta        float64
+	jitter           float64
+	negativeMaxAge   time.Duration
+	isNegative       func(error) bool
+	classifier       ErrorClassifier
+	clock            Clock
+	store            any // a Store[K, V], checked against the cache types by NewCache
+	shards           int
+	snapshotPath     string
+	snapshotInterval time.Duration
+	onSnapshotError  func(error)
+	janitorInterval  time.Duration
+	bulkLoader       any // a BulkLoaderFunc[K, V], checked against the cache types by NewCache
+	maxBulkSize      int
+	tagger           any // a TaggerFunc[K, V], checked against the cache types by NewCache
+	weigher          any // a WeigherFunc[K, V], checked against the cache types by NewCache
+	maxWeight        int64
+	hotKeyWindow     time.Duration
+	hitRatioAlert    *HitRatioAlert
+	logger           *slog.Logger
+	slowLoad         time.Duration
+	freshness        any // a FreshnessFunc[K, V], checked against the cache types by NewCache
+	historySize      int
+	adaptiveMin      time.Duration
+	adaptiveMax      time.Duration
+	invalidator      Invalidator
+	refreshLocker    RefreshLocker
+	refreshLockTTL   time.Duration
+	ratePerSecond    float64
+	rateBurst        int
+	sharedLimiter    *rateLimiter // used when no WithRateLimit applies to this cache, see Tenants
+	converter        *Converter   // converts the prices of a CurrencyCache from baseCurrency, see WithConverter
+	baseCurrency     string
+	loadTimeout      time.Duration
+	hedgeDelay       time.Duration
+	maxHedges        int
+}
+
+// newConfig applies opts over the defaults
+func newConfig(opts []Option) config {
+	cfg := config{maxAge: DefaultMaxAge}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.maxAge < 0 {
+		cfg.maxAge = 0
+	}
+	if cfg.refreshAhead <= 0 || cfg.refreshAhead >= 1 {
+		cfg.refreshAhead = 0
+	}
+	if cfg.observer == nil {
+		cfg.observer = noopObserver{}
+	}
+	if cfg.clock == nil {
+		cfg.clock = realClock{}
+	}
+	if cfg.classifier == nil {
+		cfg.classifier = DefaultErrorClassifier
+	}
+	if cfg.isNegative == nil {
+		classifier := cfg.classifier
+		cfg.isNegative = func(err error) bool { return classifier(err) == Permanent }
+	}
+	if cfg.slowLoad <= 0 {
+		cfg.slowLoad = DefaultSlowLoad
+	}
+	return cfg
+}
+
+// WithMaxAge sets how long values are served from the cache before they are loaded again
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(c *config) {
+		c.maxAge = maxAge
+	}
+}
+
+// WithMaxConcurrency bounds how many keys GetMany (and GetPricesFor) loads in parallel
+// A value of zero or less means no limit, one goroutine per key
+func WithMaxConcurrency(n int) Option {
+	return func(c *config) {
+		c.maxConcurrency = n
+	}
+}
+
+// WithMaxEntries bounds how many entries the cache keeps, evicting the least recently used ones
+// unless another policy is given with WithEvictionPolicy
+// A value of zero or less means no limit
+func WithMaxEntries(n int) Option {
+	return func(c *config) {
+		c.maxEntries = n
+	}
+}
+
+// WithEvictionPolicy sets the policy that picks which entries to drop once the cache holds maxEntries entries, or
+// entries weighing maxWeight. It has no effect unless WithMaxEntries or WithMaxWeight is used as well
+// The policy must be keyed by the same type as the cache, NewCache panics otherwise
+func WithEvictionPolicy[K comparable](policy EvictionPolicy[K]) Option {
+	return func(c *config) {
+		c.policy = policy
+	}
+}
+
+// evictionPolicyFor returns the configured policy for a cache keyed by K, LRU if none was configured
+func evictionPolicyFor[K comparable](policy any) EvictionPolicy[K] {
+	if policy == nil {
+		return NewLRUPolicy[K]()
+	}
+	p, ok := policy.(EvictionPolicy[K])
+	if !ok {
+		var key K
+		panic(fmt.Sprintf("sample1: eviction policy %T can't be used for a cache keyed by %T", policy, key))
+	}
+	return p
+}
+
+// WithStaleWhileRevalidate makes the cache return expired values right away, as long as they are not
+// older than maxAge + maxStale, while they are refreshed in the background
+// Only one refresh per key runs at a time, and callers never wait on it
+func WithStaleWhileRevalidate(maxStale time.Duration) Option {
+	return func(c *config) {
+		c.maxStale = maxStale
+	}
+}
+
+// WithSoftTTL sets a soft and a hard TTL: values are fresh for soft, then served while they are refreshed in the
+// background (as with WithStaleWhileRevalidate) until they are hard old, after which they are never served, not
+// even by WithStaleIfError nor with a longer maxAge set for the key, callers then wait for a new value
+// It replaces WithMaxAge and WithStaleWhileRevalidate, hard must not be shorter than soft
+func WithSoftTTL(soft, hard time.Duration) Option {
+	return func(c *config) {
+		c.maxAge = soft
+		c.maxStale = max(hard-soft, 0)
+		c.hardTTL = hard
+	}
+}
+
+// WithStaleIfError makes the cache fall back to the last known value when loading a key fails with a Transient
+// error (the service fails or the context times out, see WithErrorClassifier), as long as that value is not older
+// than maxAge + maxStale, such fallbacks are counted in Stats as StaleServed
+func WithStaleIfError(maxStale time.Duration) Option {
+	return func(c *config) {
+		c.staleIfError = maxStale
+	}
+}
+
+// WithRefreshAhead makes the cache refresh values in the background when they are accessed during
+// the last fraction of their maxAge (0.2 means the last 20%), so that frequently used values never expire
+// Only one refresh per key runs at a time, the fraction must be between 0 and 1 (exclusive) to take effect
+func WithRefreshAhead(fraction float64) Option {
+	return func(c *config) {
+		c.refreshAhead = fraction
+	}
+}
+
+// WithEarlyExpiration enables probabilistic early refreshes (the XFetch algorithm) to prevent stampedes
+// when a popular key expires: every hit may start a background refresh, with a probability that grows
+// as the value gets closer to maxAge and with how long it took to load, beta scales it (1 is a good default)
+func WithEarlyExpiration(beta float64) Option {
+	return func(c *config) {
+		c.earlyBeta = beta
+	}
+}
+
+// WithTTLJitter takes a random part, up to fraction, off the maxAge of every entry (0.1 means up to 10%),
+// so that values loaded together don't all expire at the same instant
+// Jitter only ever shortens maxAge, so values are still never served older than it
+func WithTTLJitter(fraction float64) Option {
+	return func(c *config) {
+		c.jitter = fraction
+	}
+}
+
+// WithErrorClassifier sets how the cache tells the load errors about a key (Permanent, cached by
+// WithNegativeCaching) from the failures of the service (Transient, served stale by WithStaleIfError),
+// DefaultErrorClassifier if nil
+// It doesn't flow down to the services under the cache: RetryingService, CircuitBreakerService and RingService
+// classify the errors with their own policy (Retryable, IsFailure and Classifier)
+func WithErrorClassifier(classifier ErrorClassifier) Option {
+	return func(c *config) {
+		c.classifier = classifier
+	}
+}
+
+// WithNegativeCaching makes the cache remember load errors for maxAge, so that keys that don't exist
+// don't hit the loader on every call
+// Only the errors for which isNegative returns true are cached, if it is nil the Permanent ones (see
+// WithErrorClassifier)
+func WithNegativeCaching(maxAge time.Duration, isNegative func(error) bool) Option {
+	return func(c *config) {
+		c.negativeMaxAge = maxAge
+		c.isNegative = isNegative
+	}
+}
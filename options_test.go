@@ -0,0 +1,65 @@
+package sample1
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrencyMockPriceService records the max number of calls running at the same time
+type concurrencyMockPriceService struct {
+	mockPriceService
+	mu         sync.Mutex
+	running    int
+	maxRunning int
+}
+
+func (m *concurrencyMockPriceService) GetPriceFor(itemCode string) (float64, error) {
+	m.mu.Lock()
+	m.running++
+	if m.running > m.maxRunning {
+		m.maxRunning = m.running
+	}
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.running--
+		m.mu.Unlock()
+	}()
+	return m.mockPriceService.GetPriceFor(itemCode)
+}
+
+// Check that a batch never runs more service calls in parallel than the configured limit
+func TestGetPricesFor_RespectsMaxConcurrency(t *testing.T) {
+	mockService := &concurrencyMockPriceService{
+		mockPriceService: mockPriceService{
+			callDelay:   20 * time.Millisecond,
+			mockResults: map[string]mockResult{},
+		},
+	}
+	itemCodes := []string{"p1", "p2", "p3", "p4", "p5", "p6", "p7"}
+	for i, itemCode := range itemCodes {
+		mockService.mockResults[itemCode] = mockResult{price: float64(i)}
+	}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxConcurrency(2))
+	assertFloats(t, []float64{0, 1, 2, 3, 4, 5, 6}, getPricesWithNoErr(t, cache, itemCodes...), "wrong price returned")
+	assertInt(t, 7, mockService.getNumCalls(), "wrong number of service calls")
+	assertInt(t, 2, mockService.maxRunning, "wrong number of parallel calls")
+}
+
+// Check that New uses the default max age unless WithMaxAge is given
+func TestNew_MaxAgeOption(t *testing.T) {
+	cache := New(&mockPriceService{})
+	if cache.maxAge != DefaultMaxAge {
+		t.Errorf("expected the default max age, got %v", cache.maxAge)
+	}
+	cache = New(&mockPriceService{}, WithMaxAge(time.Hour), WithMaxEntries(10))
+	if cache.maxAge != time.Hour || cache.maxEntries != 10 {
+		t.Errorf("expected the options to be applied, got max age %v and max entries %v", cache.maxAge, cache.maxEntries)
+	}
+	// the positional max age of NewTransparentCache can still be overridden by an option
+	cache = NewTransparentCache(&mockPriceService{}, time.Hour, WithMaxAge(time.Second))
+	if cache.maxAge != time.Second {
+		t.Errorf("expected the option to win, got %v", cache.maxAge)
+	}
+}
@@ -0,0 +1,79 @@
+// Package otelcache traces the lookups of a cache with OpenTelemetry
+// It lives in its own package so that the cache itself doesn't depend on OpenTelemetry
+//
+// Give the observer to the cache with sample1.WithObserver, every lookup gets a span telling whether it
+// was a cache hit, every call to the actual service gets a child span, and batches get a parent span
+package otelcache
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// instrumentationName identifies the spans created by this package
+const instrumentationName = "github.com/MadHive/deviget_challenge/otelcache"
+
+// Attribute keys set on the spans
+const (
+	KeyAttribute       = attribute.Key("cache.key")
+	HitAttribute       = attribute.Key("cache.hit")
+	BatchSizeAttribute = attribute.Key("cache.batch_size")
+)
+
+// Observer is a sample1.Observer that creates a span for every lookup, batch and load
+type Observer struct {
+	tracer trace.Tracer
+}
+
+var _ sample1.Observer = (*Observer)(nil)
+
+// NewObserver creates an observer using the spans of provider, the global one if provider is nil
+func NewObserver(provider trace.TracerProvider) *Observer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &Observer{tracer: provider.Tracer(instrumentationName)}
+}
+
+// StartLookup implements sample1.Observer
+func (o *Observer) StartLookup(ctx context.Context, key any) (context.Context, func(bool, error)) {
+	ctx, span := o.tracer.Start(ctx, "cache.Get", trace.WithAttributes(KeyAttribute.String(fmt.Sprint(key))))
+	return ctx, func(hit bool, err error) {
+		span.SetAttributes(HitAttribute.Bool(hit))
+		end(span, err)
+	}
+}
+
+// StartBatch implements sample1.Observer
+func (o *Observer) StartBatch(ctx context.Context, keys int) (context.Context, func(error)) {
+	ctx, span := o.tracer.Start(ctx, "cache.GetMany", trace.WithAttributes(BatchSizeAttribute.Int(keys)))
+	return ctx, func(err error) {
+		end(span, err)
+	}
+}
+
+// StartLoad implements sample1.Observer
+func (o *Observer) StartLoad(ctx context.Context, key any) (context.Context, func(error)) {
+	ctx, span := o.tracer.Start(ctx, "cache.Load",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(KeyAttribute.String(fmt.Sprint(key))))
+	return ctx, func(err error) {
+		end(span, err)
+	}
+}
+
+// end records err on the span, if any, and ends it
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
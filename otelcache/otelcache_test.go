@@ -0,0 +1,61 @@
+package otelcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+type fakePriceService map[string]float64
+
+func (f fakePriceService) GetPriceFor(itemCode string) (float64, error) {
+	price, ok := f[itemCode]
+	if !ok {
+		return 0, fmt.Errorf("unknown item [%v]", itemCode)
+	}
+	return price, nil
+}
+
+func TestObserver_TracesBatchLookupsAndLoads(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	cache := sample1.NewTransparentCache(fakePriceService{"p1": 5}, time.Minute,
+		sample1.WithObserver(NewObserver(provider)), sample1.WithMaxConcurrency(1))
+	cache.GetPriceFor("p1")
+	cache.GetPricesFor("p1", "p2")
+
+	spans := recorder.Ended()
+	if len(spans) != 6 {
+		t.Fatalf("expected 6 spans, got %v", len(spans))
+	}
+	batch := spans[len(spans)-1]
+	if batch.Name() != "cache.GetMany" || batch.Status().Code != codes.Error {
+		t.Errorf("expected a failed batch span, got %v with status %v", batch.Name(), batch.Status())
+	}
+	hits := map[string]bool{}
+	for _, span := range spans {
+		if span.Name() != "cache.Get" {
+			continue
+		}
+		if span.Parent().SpanID() == batch.SpanContext().SpanID() {
+			for _, attr := range span.Attributes() {
+				if attr.Key == HitAttribute {
+					hits[span.Attributes()[0].Value.AsString()] = attr.Value.AsBool()
+				}
+			}
+		}
+	}
+	if !hits["p1"] || hits["p2"] {
+		t.Errorf("expected p1 to be a hit and p2 a miss in the batch, got %v", hits)
+	}
+	load := spans[3]
+	if load.Name() != "cache.Load" || load.Status().Code != codes.Error {
+		t.Errorf("expected the failed load of p2, got %v with status %v", load.Name(), load.Status())
+	}
+}
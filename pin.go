@@ -0,0 +1,47 @@
+package sample1
+
+// Pin exempts key from eviction: its entry is never dropped to keep the cache within WithMaxEntries or
+// WithMaxWeight, the other entries are evicted instead. It still counts towards these limits, and it still expires,
+// is refreshed and can be invalidated as any other entry
+// Keys can be pinned before they are cached, they stay pinned until Unpin, even across invalidations and Clear
+func (c *Cache[K, V]) Pin(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pins == nil {
+		c.pins = map[K]struct{}{}
+	}
+	c.pins[key] = struct{}{}
+	if c.policy != nil {
+		c.policy.OnRemove(key) // the policy never sees the pinned keys, so it never picks them
+	}
+}
+
+// Unpin makes key subject to eviction again, evicting right away if the cache is over its limits
+func (c *Cache[K, V]) Unpin(key K) {
+	c.mu.Lock()
+	if _, ok := c.pins[key]; !ok {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.pins, key)
+	var evicted []Event[K, V]
+	if _, ok := c.store.Get(key); ok && c.policy != nil {
+		c.policy.OnInsert(key)
+		evicted = c.evictOverflow()
+	}
+	c.mu.Unlock()
+	c.emit(evicted...)
+}
+
+// Pinned tells if key is pinned, see Pin
+func (c *Cache[K, V]) Pinned(key K) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.pinned(key)
+}
+
+// pinned is Pinned with c.mu held
+func (c *Cache[K, V]) pinned(key K) bool {
+	_, ok := c.pins[key]
+	return ok
+}
@@ -0,0 +1,48 @@
+package sample1
+
+import (
+	"testing"
+	"time"
+)
+
+// Check that a pinned entry is never evicted, the other entries being evicted in its place
+func TestPin_ExemptsFromEviction(t *testing.T) {
+	cache := NewTransparentCache(&mockPriceService{}, time.Minute, WithMaxEntries(2))
+	cache.Pin("p1")
+	cache.Set("p1", 5)
+	cache.Set("p2", 6)
+	cache.Set("p3", 7) // p1 is the least recently used, p2 is evicted instead
+	if !cache.Contains("p1") || cache.Contains("p2") {
+		t.Error("the pinned price should have been kept and the other one evicted")
+	}
+	cache.Pin("p3")
+	cache.Set("p4", 8)
+	if cache.Contains("p4") {
+		t.Error("with only pinned prices left, the new price should have been evicted")
+	}
+	cache.Pin("p4")
+	cache.Set("p4", 8)
+	assertInt(t, 3, cache.Len(), "the pinned prices may go past maxEntries")
+	cache.Unpin("p3")
+	assertInt(t, 2, cache.Len(), "unpinning should evict right away")
+	if cache.Contains("p3") || cache.Pinned("p3") || !cache.Pinned("p1") {
+		t.Error("p3 should have been unpinned and evicted, p1 should still be pinned")
+	}
+}
+
+// Check that a pinned entry still expires and is loaded again
+func TestPin_StillExpires(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5, err: nil}}}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxEntries(1), WithClock(clock))
+	cache.Pin("p1")
+	getPriceWithNoErr(t, cache, "p1")
+	clock.Advance(time.Minute + time.Second)
+	mockService.setPrice("p1", 6)
+	assertFloat(t, 6, getPriceWithNoErr(t, cache, "p1"), "the expired pinned price should have been loaded again")
+	assertInt(t, 2, mockService.getNumCalls(), "wrong number of calls")
+	cache.Invalidate("p1")
+	if !cache.Pinned("p1") {
+		t.Error("invalidating should not unpin")
+	}
+}
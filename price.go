@@ -0,0 +1,103 @@
+package sample1
+
+import (
+	"context"
+	"time"
+)
+
+// Price is a price along with what the service knows about it
+type Price struct {
+	Amount    float64
+	Currency  string        // ISO 4217 code, empty if the service doesn't tell
+	TTL       time.Duration // how long the price is valid from FetchedAt, the maxAge of the cache applies if zero
+	FetchedAt time.Time     // when the service priced the item, the time of the load if zero
+}
+
+// PriceServiceV2 is a service returning Prices rather than bare amounts, so that the cache keeps every price for
+// as long as the service says it is valid. Use AsPriceServiceV2 for a PriceService
+type PriceServiceV2 interface {
+	GetPrice(ctx context.Context, itemCode string) (Price, error)
+}
+
+// BulkPriceServiceV2 is a PriceServiceV2 that can price several items in one call, returning the prices
+// in the same order as the item codes, it can return a *BatchError[string] if only some items failed
+type BulkPriceServiceV2 interface {
+	GetPrices(ctx context.Context, itemCodes ...string) ([]Price, error)
+}
+
+// AsPriceServiceV2 returns a PriceServiceV2 for service, whose prices only have an amount
+// If service is a BulkPriceService (or a ContextBulkPriceService), so is the returned service
+func AsPriceServiceV2(service PriceService) PriceServiceV2 {
+	adapter := priceAdapter{service: AsContextPriceService(service)}
+	if bulkLoader := bulkLoaderForService(service); bulkLoader != nil {
+		return bulkPriceAdapter{priceAdapter: adapter, bulkLoader: bulkLoader}
+	}
+	return adapter
+}
+
+// priceAdapter makes a PriceService a PriceServiceV2
+type priceAdapter struct {
+	service ContextPriceService
+}
+
+func (a priceAdapter) GetPrice(ctx context.Context, itemCode string) (Price, error) {
+	amount, err := a.service.GetPriceForCtx(ctx, itemCode)
+	if err != nil {
+		return Price{}, err
+	}
+	return Price{Amount: amount}, nil
+}
+
+// bulkPriceAdapter makes a BulkPriceService a BulkPriceServiceV2
+type bulkPriceAdapter struct {
+	priceAdapter
+	bulkLoader BulkLoaderFunc[string, float64]
+}
+
+func (a bulkPriceAdapter) GetPrices(ctx context.Context, itemCodes ...string) ([]Price, error) {
+	amounts, err := a.bulkLoader(ctx, itemCodes)
+	if amounts == nil {
+		return nil, err
+	}
+	prices := make([]Price, len(amounts))
+	for i, amount := range amounts {
+		prices[i] = Price{Amount: amount}
+	}
+	return prices, err
+}
+
+// PriceCache is a cache of Prices in front of a PriceServiceV2, the TTL and the FetchedAt of every price decide
+// how long it stays fresh (see WithFreshness), prices without them are kept for the maxAge of the cache
+// Prices are cached as the service returned them. It is safe for concurrent use by multiple goroutines
+type PriceCache struct {
+	*Cache[string, Price]
+}
+
+// NewPriceCache creates a cache in front of service, configured with opts
+// If service is a BulkPriceServiceV2, it is used to load batches
+func NewPriceCache(service PriceServiceV2, opts ...Option) *PriceCache {
+	opts = append([]Option{WithFreshness(priceFreshness)}, opts...)
+	if bulkService, ok := service.(BulkPriceServiceV2); ok {
+		opts = append([]Option{WithBulkLoader(func(ctx context.Context, itemCodes []string) ([]Price, error) {
+			return bulkService.GetPrices(ctx, itemCodes...)
+		})}, opts...)
+	}
+	return &PriceCache{Cache: NewCache(service.GetPrice, opts...)}
+}
+
+// priceFreshness is the FreshnessFunc of a PriceCache
+func priceFreshness(_ string, price Price) (time.Duration, time.Time) {
+	return price.TTL, price.FetchedAt
+}
+
+// GetPrice gets the price for the item, either from the cache or the service if it was not cached or too old,
+// so that a PriceCache is itself a PriceServiceV2
+func (c *PriceCache) GetPrice(ctx context.Context, itemCode string) (Price, error) {
+	return c.Get(ctx, itemCode)
+}
+
+// GetPrices gets the prices for several items at once, the returned error is a *BatchError holding the failure
+// of every item that could not be priced
+func (c *PriceCache) GetPrices(ctx context.Context, itemCodes ...string) ([]Price, error) {
+	return c.GetMany(ctx, itemCodes...)
+}
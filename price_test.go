@@ -0,0 +1,133 @@
+package sample1
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockPriceServiceV2 returns the prices it was given, counting the calls it gets
+type mockPriceServiceV2 struct {
+	mu       sync.Mutex
+	numCalls int
+	prices   map[string]Price
+}
+
+func (m *mockPriceServiceV2) GetPrice(_ context.Context, itemCode string) (Price, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.numCalls++
+	price, ok := m.prices[itemCode]
+	if !ok {
+		return Price{}, fmt.Errorf("unknown item [%v] : %w", itemCode, ErrNotFound)
+	}
+	return price, nil
+}
+
+func (m *mockPriceServiceV2) getNumCalls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.numCalls
+}
+
+func getPriceV2WithNoErr(t *testing.T, cache *PriceCache, itemCode string) Price {
+	t.Helper()
+	price, err := cache.GetPrice(context.Background(), itemCode)
+	if err != nil {
+		t.Fatalf("unexpected error getting the price for [%v] : %v", itemCode, err)
+	}
+	return price
+}
+
+// Check that every price is kept for its own TTL, and for the maxAge of the cache when it has none
+func TestPriceCache_HonorsTTL(t *testing.T) {
+	clock := newFakeClock()
+	service := &mockPriceServiceV2{prices: map[string]Price{
+		"p1": {Amount: 5, Currency: "USD", TTL: 10 * time.Second},
+		"p2": {Amount: 7, Currency: "EUR"},
+	}}
+	cache := NewPriceCache(service, WithMaxAge(time.Minute), WithClock(clock))
+	if price := getPriceV2WithNoErr(t, cache, "p1"); price.Amount != 5 || price.Currency != "USD" {
+		t.Errorf("wrong price returned: %+v", price)
+	}
+	getPriceV2WithNoErr(t, cache, "p2")
+	clock.Advance(11 * time.Second)
+	getPriceV2WithNoErr(t, cache, "p1")
+	getPriceV2WithNoErr(t, cache, "p2")
+	assertInt(t, 3, service.getNumCalls(), "only p1 should have expired")
+	if ttl, _ := cache.TTL("p1"); ttl != 10*time.Second {
+		t.Errorf("expected p1 to be fresh for the TTL of the service, got %v", ttl)
+	}
+}
+
+// Check that the age of a price counts from when the service priced it
+func TestPriceCache_AgesFromFetchedAt(t *testing.T) {
+	clock := newFakeClock()
+	service := &mockPriceServiceV2{prices: map[string]Price{
+		"p1": {Amount: 5, FetchedAt: clock.Now().Add(-50 * time.Second)},
+	}}
+	cache := NewPriceCache(service, WithMaxAge(time.Minute), WithClock(clock))
+	getPriceV2WithNoErr(t, cache, "p1")
+	if _, age, _ := cache.Peek("p1"); age != 50*time.Second {
+		t.Errorf("expected the price to be 50s old, got %v", age)
+	}
+	clock.Advance(11 * time.Second)
+	getPriceV2WithNoErr(t, cache, "p1")
+	assertInt(t, 2, service.getNumCalls(), "the price should have expired 1m after the service priced it")
+}
+
+// Check that a maxAge set for a key wins over the TTL of the service
+func TestPriceCache_OverrideWinsOverTTL(t *testing.T) {
+	clock := newFakeClock()
+	service := &mockPriceServiceV2{prices: map[string]Price{"p1": {Amount: 5, TTL: time.Hour}}}
+	cache := NewPriceCache(service, WithMaxAge(time.Minute), WithClock(clock))
+	getPriceV2WithNoErr(t, cache, "p1")
+	cache.SetMaxAgeFor("p1", time.Second)
+	clock.Advance(2 * time.Second)
+	getPriceV2WithNoErr(t, cache, "p1")
+	assertInt(t, 2, service.getNumCalls(), "the override should have applied")
+
+	cache.ResetMaxAgeFor("p1")
+	if ttl, _ := cache.TTL("p1"); ttl != time.Hour {
+		t.Errorf("expected the TTL of the service once the override is gone, got %v", ttl)
+	}
+}
+
+// Check that a PriceService adapted to a PriceServiceV2 keeps working, batches included
+func TestAsPriceServiceV2(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 5, err: nil},
+	}}
+	cache := NewPriceCache(AsPriceServiceV2(mockService), WithMaxAge(time.Minute))
+	assertFloat(t, 5, getPriceV2WithNoErr(t, cache, "p1").Amount, "wrong price returned")
+	assertFloat(t, 5, getPriceV2WithNoErr(t, cache, "p1").Amount, "wrong price returned")
+	assertInt(t, 1, mockService.getNumCalls(), "the price should have been cached")
+
+	if _, ok := AsPriceServiceV2(mockService).(BulkPriceServiceV2); ok {
+		t.Error("a service pricing one item at a time shouldn't become a bulk one")
+	}
+	bulkService := &bulkMockPriceService{mockPriceService: mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 5, err: nil},
+	}}}
+	bulk, ok := AsPriceServiceV2(bulkService).(BulkPriceServiceV2)
+	if !ok {
+		t.Fatal("a bulk service should stay one")
+	}
+	prices, err := bulk.GetPrices(context.Background(), "p1", "p1")
+	if err != nil || len(prices) != 2 || prices[1].Amount != 5 {
+		t.Errorf("wrong prices returned: %+v, %v", prices, err)
+	}
+	assertInt(t, 1, len(bulkService.bulkCalls), "the prices should have been asked with one call")
+}
+
+// Check that a freshness function can't be used for a cache of other types
+func TestWithFreshness_PanicsOnOtherTypes(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected NewCache to panic")
+		}
+	}()
+	NewCache(func(ctx context.Context, key string) (float64, error) { return 0, nil }, WithFreshness(priceFreshness))
+}
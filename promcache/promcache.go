@@ -0,0 +1,187 @@
+// Package promcache exposes the activity of a cache and of the service behind it as Prometheus metrics
+// It lives in its own package so that the cache itself doesn't depend on the Prometheus client
+package promcache
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// StatsSource is anything that reports cache counters, like a Cache or a TransparentCache
+type StatsSource interface {
+	Stats() sample1.Stats
+	Len() int
+}
+
+// Opts names the metrics, every metric is prefixed with Namespace and Subsystem and carries ConstLabels
+type Opts struct {
+	Namespace   string
+	Subsystem   string
+	ConstLabels prometheus.Labels
+	Buckets     []float64 // buckets of the service latency histogram, prometheus.DefBuckets if empty
+}
+
+// WindowStatsSource is a StatsSource that also reports its hit ratio over recent windows, like a Cache
+type WindowStatsSource interface {
+	StatsOver(window time.Duration) sample1.WindowStats
+}
+
+// hitRatioWindows are the windows of the cache_hit_ratio gauge, by the value of its window label
+var hitRatioWindows = []struct {
+	label  string
+	window time.Duration
+}{{"1m", time.Minute}, {"5m", 5 * time.Minute}, {"1h", time.Hour}}
+
+func (o Opts) desc(name, help string, variableLabels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(o.Namespace, o.Subsystem, name), help, variableLabels, o.ConstLabels)
+}
+
+// CacheCollector exports the cache counters and the latencies of the lookups by outcome, read from the cache every
+// time Prometheus scrapes
+// If the cache is a WindowStatsSource, it also exports its hit ratio over the last minute, 5 minutes and hour
+type CacheCollector struct {
+	cache      StatsSource
+	windows    WindowStatsSource // nil if the cache doesn't report recent windows
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	loads      *prometheus.Desc
+	loadErrors *prometheus.Desc
+	evictions  *prometheus.Desc
+	stale      *prometheus.Desc
+	entries    *prometheus.Desc
+	hitRatio   *prometheus.Desc
+	latency    *prometheus.Desc
+}
+
+// NewCacheCollector creates a collector for the counters of cache, it still has to be registered
+func NewCacheCollector(cache StatsSource, opts Opts) *CacheCollector {
+	windows, _ := cache.(WindowStatsSource)
+	return &CacheCollector{
+		cache:      cache,
+		windows:    windows,
+		hits:       opts.desc("cache_hits_total", "Lookups answered from the cache."),
+		misses:     opts.desc("cache_misses_total", "Lookups that were not cached or too old."),
+		loads:      opts.desc("cache_loads_total", "Calls made to the underlying service."),
+		loadErrors: opts.desc("cache_load_errors_total", "Calls to the underlying service that failed."),
+		evictions:  opts.desc("cache_evictions_total", "Entries dropped to stay within the max entries."),
+		stale:      opts.desc("cache_stale_served_total", "Expired values returned because loading a fresh one failed."),
+		entries:    opts.desc("cache_entries", "Entries currently in the cache."),
+		hitRatio:   opts.desc("cache_hit_ratio", "Fraction of the lookups of the window answered from the cache.", "window"),
+		latency:    opts.desc("cache_lookup_duration_seconds", "Latency of the lookups, by how they were answered.", "outcome"),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *CacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.loads
+	ch <- c.loadErrors
+	ch <- c.evictions
+	ch <- c.stale
+	ch <- c.entries
+	ch <- c.latency
+	if c.windows != nil {
+		ch <- c.hitRatio
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *CacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.cache.Stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.loads, prometheus.CounterValue, float64(stats.Loads))
+	ch <- prometheus.MustNewConstMetric(c.loadErrors, prometheus.CounterValue, float64(stats.LoadErrors))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(c.stale, prometheus.CounterValue, float64(stats.StaleServed))
+	ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(c.cache.Len()))
+	for _, outcome := range sample1.Outcomes {
+		h := stats.Latencies[outcome]
+		buckets := make(map[float64]uint64, len(sample1.LatencyBounds))
+		var cumulative uint64
+		for i, bound := range sample1.LatencyBounds {
+			cumulative += h.Counts[i]
+			buckets[bound.Seconds()] = cumulative
+		}
+		// the buckets are read one by one while lookups go on, the count can't be below them
+		count := max(h.Count, cumulative+h.Counts[len(sample1.LatencyBounds)])
+		ch <- prometheus.MustNewConstHistogram(c.latency, count, h.Sum.Seconds(), buckets, outcome.String())
+	}
+	if c.windows == nil {
+		return
+	}
+	for _, w := range hitRatioWindows {
+		ratio := c.windows.StatsOver(w.window).HitRatio()
+		ch <- prometheus.MustNewConstMetric(c.hitRatio, prometheus.GaugeValue, ratio, w.label)
+	}
+}
+
+// InstrumentedService is a PriceService that measures the latency and the number of in-flight calls
+// of the service it wraps, give it to the cache instead of the actual service and register it
+type InstrumentedService struct {
+	service  sample1.ContextPriceService
+	latency  *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+}
+
+// InstrumentService wraps service so that its calls are measured
+func InstrumentService(service sample1.PriceService, opts Opts) *InstrumentedService {
+	buckets := opts.Buckets
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+	return &InstrumentedService{
+		service: sample1.AsContextPriceService(service),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        "service_call_duration_seconds",
+			Help:        "Latency of the calls to the underlying service.",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     buckets,
+		}, []string{"result"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        "service_calls_in_flight",
+			Help:        "Calls to the underlying service that have not finished yet.",
+			ConstLabels: opts.ConstLabels,
+		}),
+	}
+}
+
+// GetPriceFor implements sample1.PriceService
+func (s *InstrumentedService) GetPriceFor(itemCode string) (float64, error) {
+	return s.GetPriceForCtx(context.Background(), itemCode)
+}
+
+// GetPriceForCtx implements sample1.ContextPriceService
+func (s *InstrumentedService) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	s.inFlight.Inc()
+	defer s.inFlight.Dec()
+	start := time.Now()
+	price, err := s.service.GetPriceForCtx(ctx, itemCode)
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	s.latency.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	return price, err
+}
+
+// Describe implements prometheus.Collector
+func (s *InstrumentedService) Describe(ch chan<- *prometheus.Desc) {
+	s.latency.Describe(ch)
+	s.inFlight.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (s *InstrumentedService) Collect(ch chan<- prometheus.Metric) {
+	s.latency.Collect(ch)
+	s.inFlight.Collect(ch)
+}
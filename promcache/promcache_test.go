@@ -0,0 +1,84 @@
+package promcache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+type fakePriceService map[string]float64
+
+func (f fakePriceService) GetPriceFor(itemCode string) (float64, error) {
+	price, ok := f[itemCode]
+	if !ok {
+		return 0, fmt.Errorf("unknown item [%v]", itemCode)
+	}
+	return price, nil
+}
+
+func TestCollectors_ExportCacheAndServiceMetrics(t *testing.T) {
+	opts := Opts{Namespace: "pricing"}
+	service := InstrumentService(fakePriceService{"p1": 5}, opts)
+	cache := sample1.NewTransparentCache(service, time.Minute)
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(NewCacheCollector(cache, opts), service)
+
+	cache.GetPriceFor("p1")
+	cache.GetPriceFor("p1")
+	cache.GetPriceForCtx(context.Background(), "p2")
+
+	expected := `
+# HELP pricing_cache_entries Entries currently in the cache.
+# TYPE pricing_cache_entries gauge
+pricing_cache_entries 1
+# HELP pricing_cache_hit_ratio Fraction of the lookups of the window answered from the cache.
+# TYPE pricing_cache_hit_ratio gauge
+pricing_cache_hit_ratio{window="1h"} 0.3333333333333333
+pricing_cache_hit_ratio{window="1m"} 0.3333333333333333
+pricing_cache_hit_ratio{window="5m"} 0.3333333333333333
+# HELP pricing_cache_hits_total Lookups answered from the cache.
+# TYPE pricing_cache_hits_total counter
+pricing_cache_hits_total 1
+# HELP pricing_cache_load_errors_total Calls to the underlying service that failed.
+# TYPE pricing_cache_load_errors_total counter
+pricing_cache_load_errors_total 1
+# HELP pricing_cache_misses_total Lookups that were not cached or too old.
+# TYPE pricing_cache_misses_total counter
+pricing_cache_misses_total 2
+# HELP pricing_service_calls_in_flight Calls to the underlying service that have not finished yet.
+# TYPE pricing_service_calls_in_flight gauge
+pricing_service_calls_in_flight 0
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"pricing_cache_entries", "pricing_cache_hit_ratio", "pricing_cache_hits_total", "pricing_cache_misses_total",
+		"pricing_cache_load_errors_total", "pricing_service_calls_in_flight")
+	if err != nil {
+		t.Error(err)
+	}
+	if count := testutil.CollectAndCount(service, "pricing_service_call_duration_seconds"); count != 2 {
+		t.Errorf("expected ok and error latency series, got %v", count)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]uint64{}
+	for _, family := range families {
+		if family.GetName() != "pricing_cache_lookup_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			counts[metric.GetLabel()[0].GetValue()] = metric.GetHistogram().GetSampleCount()
+		}
+	}
+	if len(counts) != 4 || counts["hit"] != 1 || counts["load"] != 1 || counts["error"] != 1 || counts["stale"] != 0 {
+		t.Errorf("expected a lookup latency series per outcome, got %v", counts)
+	}
+}
@@ -0,0 +1,107 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by the lookups that would need a load while WithRateLimit doesn't allow one
+// and their context asks not to wait, see FailFast
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// WithRateLimit limits the calls to the loader (the actual service) to perSecond on average, with bursts of up to
+// burst calls, so that the cache respects the quota of the service. A bulk load is a single call
+// Loads wait for their turn, or fail right away with ErrRateLimited if their context was made with FailFast
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(c *config) {
+		c.ratePerSecond = perSecond
+		c.rateBurst = burst
+	}
+}
+
+type failFastKey struct{}
+
+// FailFast returns a context for lookups that should fail with ErrRateLimited rather than wait when the
+// rate limit doesn't allow a load right away
+func FailFast(ctx context.Context) context.Context {
+	return context.WithValue(ctx, failFastKey{}, true)
+}
+
+func failsFast(ctx context.Context) bool {
+	failFast, _ := ctx.Value(failFastKey{}).(bool)
+	return failFast
+}
+
+// limiterFor returns the rate limiter of a cache, the shared one unless WithRateLimit was used
+func limiterFor(cfg config) *rateLimiter {
+	if cfg.ratePerSecond > 0 {
+		return newRateLimiter(cfg.ratePerSecond, cfg.rateBurst, cfg.clock)
+	}
+	return cfg.sharedLimiter
+}
+
+// rateLimiter is a token bucket, a nil one allows everything
+type rateLimiter struct {
+	mu        sync.Mutex
+	clock     Clock
+	perSecond float64
+	burst     float64
+	tokens    float64 // negative when calls are waiting for tokens that aren't there yet
+	last      time.Time
+}
+
+func newRateLimiter(perSecond float64, burst int, clock Clock) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{clock: clock, perSecond: perSecond, burst: float64(burst), tokens: float64(burst), last: clock.Now()}
+}
+
+// wait takes a token, waiting until there is one unless ctx fails fast
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	delay, ok := l.reserve(!failsFast(ctx))
+	if !ok {
+		return ErrRateLimited
+	}
+	if delay <= 0 {
+		return nil
+	}
+	if err := sleep(ctx, delay); err != nil {
+		l.cancel()
+		return err
+	}
+	return nil
+}
+
+// reserve takes a token and returns how long to wait until it is there, if it isn't there yet
+// it only takes it if canWait
+func (l *rateLimiter) reserve(canWait bool) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.perSecond)
+	l.last = now
+	if l.tokens < 1 && !canWait {
+		return 0, false
+	}
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0, true
+	}
+	return time.Duration(-l.tokens / l.perSecond * float64(time.Second)), true
+}
+
+// cancel gives back a token reserved by a call that stopped waiting for it
+func (l *rateLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = min(l.burst, l.tokens+1)
+}
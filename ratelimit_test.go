@@ -0,0 +1,97 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newRateLimitedService(n int) *mockPriceService {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{}}
+	for i := 0; i < n; i++ {
+		mockService.mockResults[fmt.Sprintf("p%d", i)] = mockResult{price: float64(i)}
+	}
+	return mockService
+}
+
+// Check that loads beyond the burst fail fast when asked to, until tokens come back
+func TestWithRateLimit_FailFast(t *testing.T) {
+	clock := newFakeClock()
+	mockService := newRateLimitedService(4)
+	cache := New(mockService, WithRateLimit(1, 2), WithClock(clock))
+	ctx := FailFast(context.Background())
+	for _, itemCode := range []string{"p0", "p1"} {
+		if _, err := cache.GetPriceForCtx(ctx, itemCode); err != nil {
+			t.Fatalf("the burst should have allowed %s: %v", itemCode, err)
+		}
+	}
+	if _, err := cache.GetPriceForCtx(ctx, "p2"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited but got %v", err)
+	}
+	assertFloat(t, 0, getPriceWithNoErr(t, cache, "p0"), "hits should not be rate limited")
+	clock.Advance(time.Second)
+	if _, err := cache.GetPriceForCtx(ctx, "p2"); err != nil {
+		t.Errorf("a token should have come back: %v", err)
+	}
+	assertInt(t, 3, mockService.getNumCalls(), "wrong number of service calls")
+}
+
+// Check that loads beyond the burst wait for their turn by default
+func TestWithRateLimit_Blocks(t *testing.T) {
+	mockService := newRateLimitedService(4)
+	cache := New(mockService, WithRateLimit(100, 1))
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		getPriceWithNoErr(t, cache, fmt.Sprintf("p%d", i))
+	}
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Errorf("4 calls at 100 per second with a burst of 1 should take 30ms, took %v", elapsed)
+	}
+}
+
+// Check that a lookup stops waiting when its context is done, and gives its token back
+func TestWithRateLimit_WaitHonorsTheContext(t *testing.T) {
+	clock := newFakeClock()
+	mockService := newRateLimitedService(3)
+	cache := New(mockService, WithRateLimit(0.001, 1), WithClock(clock))
+	getPriceWithNoErr(t, cache, "p0")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := cache.GetPriceForCtx(ctx, "p1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the context error but got %v", err)
+	}
+	clock.Advance(1000 * time.Second)
+	// the load gives its token back once it sees it was abandoned, which happens right after the lookup returned
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := cache.GetPriceForCtx(FailFast(context.Background()), "p2")
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the cancelled wait should have given its token back: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Check that a bulk load takes a single token
+func TestWithRateLimit_BulkLoads(t *testing.T) {
+	mockService := &bulkMockPriceService{mockPriceService: mockPriceService{
+		mockResults: map[string]mockResult{
+			"p0": {price: 0, err: nil},
+			"p1": {price: 1, err: nil},
+			"p2": {price: 2, err: nil},
+		},
+	}}
+	cache := New(mockService, WithRateLimit(1, 1))
+	ctx := FailFast(context.Background())
+	if _, err := cache.GetPricesForCtx(ctx, "p0", "p1", "p2"); err != nil {
+		t.Fatalf("the bulk load should have taken one token: %v", err)
+	}
+	if _, err := cache.GetPriceForCtx(ctx, "p3"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited but got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package sample1
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// MaxStatsWindow is the longest window StatsOver reports on
+const MaxStatsWindow = time.Hour
+
+// recentBucketWidth is how long each bucket of the recent counters counts, the windows of StatsOver are
+// rounded up to it
+const recentBucketWidth = 10 * time.Second
+
+// WindowStats are the hits and misses of the cache over the last Window, see StatsOver
+type WindowStats struct {
+	Window time.Duration
+	Hits   uint64
+	Misses uint64
+}
+
+// HitRatio returns the fraction of the lookups of the window that were answered from the cache, zero if there
+// were none
+func (s WindowStats) HitRatio() float64 {
+	return hitRatio(s.Hits, s.Misses)
+}
+
+// recentCounters count the hits and misses of the last MaxStatsWindow in buckets of recentBucketWidth, a bucket
+// being reset when its turn comes again
+type recentCounters struct {
+	buckets [MaxStatsWindow / recentBucketWidth]recentBucket
+}
+
+// recentBucket counts the hits and misses of the period number epoch
+type recentBucket struct {
+	epoch  atomic.Int64
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// epochOf returns the number of the period of recentBucketWidth now is in
+func epochOf(now time.Time) int64 {
+	return now.UnixNano() / int64(recentBucketWidth)
+}
+
+// bucket returns the bucket counting now, reset if it counted an older period
+// Lookups counted by another goroutine while the bucket is reset can be lost, the counts are approximate
+func (r *recentCounters) bucket(now time.Time) *recentBucket {
+	epoch := epochOf(now)
+	b := &r.buckets[epoch%int64(len(r.buckets))]
+	if previous := b.epoch.Load(); previous != epoch && b.epoch.CompareAndSwap(previous, epoch) {
+		b.hits.Store(0)
+		b.misses.Store(0)
+	}
+	return b
+}
+
+// over sums the buckets of the window ending at now
+func (r *recentCounters) over(window time.Duration, now time.Time) WindowStats {
+	window = min(max(window, recentBucketWidth), MaxStatsWindow)
+	n := int64((window + recentBucketWidth - 1) / recentBucketWidth)
+	stats := WindowStats{Window: window}
+	current := epochOf(now)
+	for i := range r.buckets {
+		b := &r.buckets[i]
+		if epoch := b.epoch.Load(); epoch <= current && epoch > current-n {
+			stats.Hits += b.hits.Load()
+			stats.Misses += b.misses.Load()
+		}
+	}
+	return stats
+}
+
+// StatsOver returns the hits and misses of the last window (at most MaxStatsWindow, rounded up to 10s), so that
+// dashboards and alerts see how effective the cache is now rather than since it was created
+func (c *Cache[K, V]) StatsOver(window time.Duration) WindowStats {
+	return c.counters.recent.over(window, c.clock.Now())
+}
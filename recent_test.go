@@ -0,0 +1,30 @@
+package sample1
+
+import (
+	"testing"
+	"time"
+)
+
+// Check that StatsOver only counts the lookups of the window
+func TestStatsOver(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewTransparentCache(pricesOfP123(), time.Minute, WithClock(clock))
+	getPriceWithNoErr(t, cache, "p1")
+	getPriceWithNoErr(t, cache, "p1")
+	clock.Advance(2 * time.Minute)
+	getPriceWithNoErr(t, cache, "p1") // expired
+	getPriceWithNoErr(t, cache, "p2")
+
+	lastMinute := cache.StatsOver(time.Minute)
+	assertInt(t, 0, int(lastMinute.Hits), "the hits of the last minute are wrong")
+	assertInt(t, 2, int(lastMinute.Misses), "the misses of the last minute are wrong")
+	assertFloat(t, 0, lastMinute.HitRatio(), "wrong hit ratio over the last minute")
+	assertFloat(t, 0.25, cache.StatsOver(5*time.Minute).HitRatio(), "wrong hit ratio over the last 5 minutes")
+
+	clock.Advance(MaxStatsWindow)
+	getPriceWithNoErr(t, cache, "p3")
+	lastHour := cache.StatsOver(2 * MaxStatsWindow)
+	assertInt(t, int(MaxStatsWindow), int(lastHour.Window), "the window should be bounded")
+	assertInt(t, 1, int(lastHour.Misses), "the lookups older than the window should be forgotten")
+	assertInt(t, 4, int(cache.Stats().Misses), "the lifetime counters should keep every lookup")
+}
@@ -0,0 +1,56 @@
+package sample1
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// UpdateConfig changes the settings of a running cache, to tune it under load without a restart. Only
+// WithMaxAge, WithMaxEntries and WithMaxConcurrency can be given, the settings they don't set are kept
+// The new maxAge applies to the values already cached (see SetMaxAge), a lower maxEntries evicts the entries
+// past it right away, and the new concurrency applies to the batches started from now on
+// A cache created unbounded evicts the least recently used entries once it is given a maxEntries
+func (c *Cache[K, V]) UpdateConfig(opts ...Option) error {
+	c.mu.Lock()
+	cfg := config{maxAge: c.maxAge, maxEntries: c.maxEntries, maxConcurrency: c.maxConcurrency}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := checkRuntimeConfig(cfg); err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	if cfg.maxAge != c.maxAge {
+		c.setMaxAge(cfg.maxAge)
+	}
+	c.maxConcurrency = cfg.maxConcurrency
+	c.maxEntries = cfg.maxEntries
+	if c.maxEntries > 0 && c.policy == nil {
+		// the cache was unbounded so far, the policy starts with what is cached, in no particular order
+		c.policy = newBufferedPolicy(evictionPolicyFor[K](nil), &c.mu)
+		c.store.Range(func(key K, _ Entry[V]) bool {
+			if !c.pinned(key) {
+				c.policy.OnInsert(key)
+			}
+			return true
+		})
+	}
+	evicted := c.evictOverflow()
+	c.mu.Unlock()
+	c.emit(evicted...)
+	return nil
+}
+
+// checkRuntimeConfig tells if cfg only sets what UpdateConfig can change, to values it accepts
+func checkRuntimeConfig(cfg config) error {
+	rest := cfg
+	rest.maxAge, rest.maxEntries, rest.maxConcurrency = 0, 0, 0
+	if !reflect.DeepEqual(rest, config{}) {
+		return errors.New("only WithMaxAge, WithMaxEntries and WithMaxConcurrency can be changed at runtime")
+	}
+	if cfg.maxAge < 0 {
+		return fmt.Errorf("invalid max age [%v]", cfg.maxAge)
+	}
+	return nil
+}
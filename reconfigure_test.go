@@ -0,0 +1,70 @@
+package sample1
+
+import (
+	"testing"
+	"time"
+)
+
+// Check that UpdateConfig changes the maxAge of the cached prices and keeps the settings it isn't given
+func TestUpdateConfig_MaxAge(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	clock := newFakeClock()
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock), WithMaxEntries(10))
+	getPriceWithNoErr(t, cache, "p1")
+	if err := cache.UpdateConfig(WithMaxAge(time.Hour)); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	clock.Advance(30 * time.Minute)
+	getPriceWithNoErr(t, cache, "p1")
+	assertInt(t, 1, mockService.getNumCalls(), "the cached price should be checked against the new maxAge")
+	if err := cache.UpdateConfig(WithMaxConcurrency(2)); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	if cache.MaxAge() != time.Hour {
+		t.Errorf("the maxAge should have been kept, got %v", cache.MaxAge())
+	}
+}
+
+// Check that lowering maxEntries evicts right away, and that an unbounded cache can be bounded
+func TestUpdateConfig_MaxEntries(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 6, err: nil},
+			"p3": {price: 7, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	getPricesWithNoErr(t, cache, "p1", "p2", "p3")
+	var evictions int
+	cache.OnEvict(func(Event[string, float64]) { evictions++ })
+	if err := cache.UpdateConfig(WithMaxEntries(1)); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	assertInt(t, 1, cache.Len(), "the cache should have been shrunk to the new maxEntries")
+	assertInt(t, 2, evictions, "the evictions should have been told about")
+
+	if err := cache.UpdateConfig(WithMaxEntries(0)); err != nil {
+		t.Fatalf("unexpected error : %v", err)
+	}
+	getPricesWithNoErr(t, cache, "p1", "p2", "p3")
+	assertInt(t, 3, cache.Len(), "an unbounded cache shouldn't evict")
+}
+
+// Check that the settings that can't change at runtime are refused
+func TestUpdateConfig_RefusesOtherOptions(t *testing.T) {
+	cache := NewTransparentCache(&mockPriceService{}, time.Minute)
+	if err := cache.UpdateConfig(WithStaleWhileRevalidate(time.Minute)); err == nil {
+		t.Error("expected an error for an option that can't change at runtime")
+	}
+	if err := cache.UpdateConfig(WithMaxAge(-time.Second)); err == nil {
+		t.Error("expected an error for a negative maxAge")
+	}
+	if cache.MaxAge() != time.Minute {
+		t.Errorf("a refused update shouldn't change anything, got a maxAge of %v", cache.MaxAge())
+	}
+}
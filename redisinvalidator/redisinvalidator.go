@@ -0,0 +1,99 @@
+// Package redisinvalidator carries the invalidations of a cache between processes over Redis pub/sub
+// It lives in its own package so that the cache itself doesn't depend on the Redis client
+package redisinvalidator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+	"github.com/MadHive/deviget_challenge/internal/invalidation"
+)
+
+// DefaultChannel is where invalidations are published when Options.Channel is empty
+const DefaultChannel = "pricecache:invalidations"
+
+// Options configures how an Invalidator talks to Redis
+type Options struct {
+	Channel string        // where invalidations are published, DefaultChannel if empty
+	Timeout time.Duration // of every Redis call, no timeout if zero
+	OnError func(error)   // called with every Redis failure and every message that can't be decoded
+}
+
+// Invalidator is a sample1.Invalidator publishing on a Redis channel, every cache sharing the channel gets the
+// invalidations of the others
+// Redis pub/sub delivers messages at most once: an instance that is disconnected while an invalidation is
+// published serves its copy until it expires
+type Invalidator struct {
+	client redis.UniversalClient
+	opts   Options
+	origin string
+}
+
+var _ sample1.Invalidator = (*Invalidator)(nil)
+
+// New returns an invalidator publishing through client
+func New(client redis.UniversalClient, opts Options) *Invalidator {
+	if opts.Channel == "" {
+		opts.Channel = DefaultChannel
+	}
+	return &Invalidator{client: client, opts: opts, origin: invalidation.NewOrigin()}
+}
+
+func (i *Invalidator) Invalidate(keys []string) {
+	i.publish(invalidation.Message{Origin: i.origin, Keys: keys})
+}
+
+func (i *Invalidator) Clear() {
+	i.publish(invalidation.Message{Origin: i.origin, Clear: true})
+}
+
+func (i *Invalidator) publish(msg invalidation.Message) {
+	ctx, cancel := i.context()
+	defer cancel()
+	if err := i.client.Publish(ctx, i.opts.Channel, invalidation.Encode(msg)).Err(); err != nil {
+		i.fail(fmt.Errorf("publishing on %s : %w", i.opts.Channel, err))
+	}
+}
+
+// Watch subscribes to the channel, it returns once subscribed
+func (i *Invalidator) Watch(onInvalidate func(keys []string), onClear func()) (stop func()) {
+	pubsub := i.client.Subscribe(context.Background(), i.opts.Channel)
+	ctx, cancel := i.context()
+	defer cancel()
+	if _, err := pubsub.Receive(ctx); err != nil { // the subscription confirmation
+		i.fail(fmt.Errorf("subscribing to %s : %w", i.opts.Channel, err))
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for redisMsg := range pubsub.Channel() {
+			msg, err := invalidation.Decode([]byte(redisMsg.Payload))
+			if err != nil {
+				i.fail(fmt.Errorf("on %s : %w", i.opts.Channel, err))
+				continue
+			}
+			invalidation.Deliver(msg, i.origin, onInvalidate, onClear)
+		}
+	}()
+	return func() {
+		pubsub.Close()
+		<-done
+	}
+}
+
+func (i *Invalidator) context() (context.Context, context.CancelFunc) {
+	if i.opts.Timeout <= 0 {
+		return context.Background(), func() {}
+	}
+	return context.WithTimeout(context.Background(), i.opts.Timeout)
+}
+
+func (i *Invalidator) fail(err error) {
+	if i.opts.OnError != nil {
+		i.opts.OnError(err)
+	}
+}
@@ -0,0 +1,61 @@
+package redisinvalidator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+type fakePriceService map[string]float64
+
+func (f fakePriceService) GetPriceFor(itemCode string) (float64, error) {
+	price, ok := f[itemCode]
+	if !ok {
+		return 0, fmt.Errorf("unknown item [%v] : %w", itemCode, sample1.ErrNotFound)
+	}
+	return price, nil
+}
+
+func newTestCache(t *testing.T, server *miniredis.Miniredis) *sample1.TransparentCache {
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	cache := sample1.New(fakePriceService{"p1": 5, "p2": 7}, sample1.WithInvalidator(New(client, Options{
+		OnError: func(err error) { t.Error(err) },
+	})))
+	t.Cleanup(func() {
+		cache.Close()
+		client.Close()
+	})
+	return cache
+}
+
+// Check that caches sharing a channel drop the items the others invalidate
+func TestInvalidator_InvalidatesOtherCaches(t *testing.T) {
+	server := miniredis.RunT(t)
+	first, second := newTestCache(t, server), newTestCache(t, server)
+	first.GetPricesFor("p1", "p2")
+	second.GetPricesFor("p1", "p2")
+
+	first.Invalidate("p1")
+	waitFor(t, func() bool { return !second.Contains("p1") }, "the other cache should have dropped p1")
+	if !second.Contains("p2") || !first.Contains("p2") {
+		t.Error("p2 should still be cached")
+	}
+
+	second.Clear()
+	waitFor(t, func() bool { return first.Len() == 0 }, "the clear should have emptied the other cache")
+}
+
+func waitFor(t *testing.T, condition func() bool, msg string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Error(msg)
+}
@@ -0,0 +1,44 @@
+package redisstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// lockPrefix is prepended to the keys of the leases, which live outside Prefix so that Clear, Len and Range
+// don't see them (give the store a Prefix when the database is shared)
+const lockPrefix = "lock:"
+
+// unlockScript deletes a lease only if it is still the one taken, it may have expired and been taken by another
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// TryLock takes the lease of key with SET NX, so that a Store is also a sample1.RefreshLocker for the caches
+// sharing it, see sample1.WithRefreshLock
+// The lease is granted when Redis fails, a refresh too many is better than no refresh at all
+func (s *Store[V]) TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool) {
+	lock, token := lockPrefix+s.opts.Prefix+key, newOrigin()
+	ctx, cancel := s.contextFrom(ctx)
+	defer cancel()
+	taken, err := s.client.SetNX(ctx, lock, token, ttl).Result()
+	if err != nil {
+		s.fail(fmt.Errorf("locking [%s] : %w", key, err))
+		return func() {}, true
+	}
+	if !taken {
+		return nil, false
+	}
+	return func() {
+		ctx, cancel := s.context()
+		defer cancel()
+		if err := unlockScript.Run(ctx, s.client, []string{lock}, token).Err(); err != nil {
+			s.fail(fmt.Errorf("unlocking [%s] : %w", key, err))
+		}
+	}, true
+}
@@ -0,0 +1,65 @@
+package redisstore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Check that a lease is only granted once until it is given back or expires
+func TestStore_TryLock(t *testing.T) {
+	store, server := newTestStore(t, Options{Prefix: "prices:"})
+	ctx := context.Background()
+	unlock, ok := store.TryLock(ctx, "p1", time.Second)
+	if !ok {
+		t.Fatal("the first lease should have been granted")
+	}
+	if !server.Exists("lock:prices:p1") {
+		t.Error("the lease should live outside the prefix of the entries")
+	}
+	if _, ok := store.TryLock(ctx, "p1", time.Second); ok {
+		t.Error("the lease is held, it shouldn't have been granted again")
+	}
+	if _, ok := store.TryLock(ctx, "p2", time.Second); !ok {
+		t.Error("the leases of other keys should be granted")
+	}
+	unlock()
+	if _, ok := store.TryLock(ctx, "p1", time.Second); !ok {
+		t.Error("the lease was given back, it should have been granted")
+	}
+
+	server.FastForward(time.Second)
+	if _, ok := store.TryLock(ctx, "p1", time.Second); !ok {
+		t.Error("the lease expired, it should have been granted")
+	}
+}
+
+// Check that giving back an expired lease doesn't release the one another instance took since
+func TestStore_UnlockKeepsLeasesOfOthers(t *testing.T) {
+	store, server := newTestStore(t, Options{})
+	ctx := context.Background()
+	unlock, _ := store.TryLock(ctx, "p1", time.Second)
+	server.FastForward(time.Second)
+	if _, ok := store.TryLock(ctx, "p1", time.Second); !ok {
+		t.Fatal("the lease expired, it should have been granted")
+	}
+	unlock()
+	if _, ok := store.TryLock(ctx, "p1", time.Second); ok {
+		t.Error("the lease of the other instance should still be held")
+	}
+}
+
+// Check that leases are granted when Redis is down, so that values are still refreshed
+func TestStore_TryLockFailsOpen(t *testing.T) {
+	var errs int
+	store, server := newTestStore(t, Options{OnError: func(error) { errs++ }})
+	server.Close()
+	unlock, ok := store.TryLock(context.Background(), "p1", time.Second)
+	if !ok {
+		t.Error("the lease should have been granted")
+	}
+	unlock()
+	if errs != 1 {
+		t.Errorf("expected the failure to be reported once, got %d", errs)
+	}
+}
@@ -0,0 +1,238 @@
+// Package redisstore keeps the entries of a cache in Redis, so that several processes can share them
+// It lives in its own package so that the cache itself doesn't depend on the Redis client
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+	"github.com/MadHive/deviget_challenge/internal/entrycodec"
+)
+
+// scanCount is how many keys are asked for on every SCAN, and deleted on every DEL by Clear
+const scanCount = 100
+
+// Options configures how a Store talks to Redis
+type Options struct {
+	Prefix   string        // prepended to every key, so that several caches can share a Redis database
+	ExtraTTL time.Duration // kept on top of the entry maxAge, so that stale values can still be served
+	Timeout  time.Duration // of every Redis call, no timeout if zero
+	OnError  func(error)   // called with every Redis failure, which the store treats as a miss
+	// Channel is where changes are published for the stores watching them (see Watch), nothing is published if empty
+	Channel string
+}
+
+// Store is a sample1.Store keeping its entries in Redis, encoded as JSON
+// Entries expire in Redis once they are older than their maxAge plus ExtraTTL
+type Store[V any] struct {
+	client redis.UniversalClient
+	opts   Options
+	origin string // tells the changes published by this store apart from the ones of other processes
+}
+
+// New creates a store on top of client
+func New[V any](client redis.UniversalClient, opts Options) *Store[V] {
+	return &Store[V]{client: client, opts: opts, origin: newOrigin()}
+}
+
+func (s *Store[V]) Get(key string) (sample1.Entry[V], bool) {
+	ctx, cancel := s.context()
+	defer cancel()
+	data, err := s.client.Get(ctx, s.opts.Prefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			s.fail(fmt.Errorf("getting [%s] : %w", key, err))
+		}
+		return sample1.Entry[V]{}, false
+	}
+	return s.decode(key, data)
+}
+
+// GetMany reads all the keys with a single MGET
+func (s *Store[V]) GetMany(keys []string) ([]sample1.Entry[V], []bool) {
+	entries := make([]sample1.Entry[V], len(keys))
+	found := make([]bool, len(keys))
+	if len(keys) == 0 {
+		return entries, found
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+	values, err := s.client.MGet(ctx, s.keys(keys)...).Result()
+	if err != nil {
+		s.fail(fmt.Errorf("getting %d keys : %w", len(keys), err))
+		return entries, found
+	}
+	for i, value := range values {
+		if data, ok := value.(string); ok {
+			entries[i], found[i] = s.decode(keys[i], []byte(data))
+		}
+	}
+	return entries, found
+}
+
+func (s *Store[V]) Set(key string, entry sample1.Entry[V]) {
+	data, err := entrycodec.Marshal(entry)
+	if err != nil {
+		s.fail(fmt.Errorf("encoding [%s] : %w", key, err))
+		return
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+	pipe := s.client.Pipeline()
+	pipe.Set(ctx, s.opts.Prefix+key, data, s.ttl(entry))
+	s.publish(ctx, pipe, changedKey, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.fail(fmt.Errorf("setting [%s] : %w", key, err))
+	}
+}
+
+// SetMany writes several entries in a single pipeline
+func (s *Store[V]) SetMany(entries map[string]sample1.Entry[V]) {
+	ctx, cancel := s.context()
+	defer cancel()
+	pipe := s.client.Pipeline()
+	for key, entry := range entries {
+		data, err := entrycodec.Marshal(entry)
+		if err != nil {
+			s.fail(fmt.Errorf("encoding [%s] : %w", key, err))
+			continue
+		}
+		pipe.Set(ctx, s.opts.Prefix+key, data, s.ttl(entry))
+		s.publish(ctx, pipe, changedKey, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.fail(fmt.Errorf("setting %d keys : %w", len(entries), err))
+	}
+}
+
+func (s *Store[V]) Delete(key string) {
+	ctx, cancel := s.context()
+	defer cancel()
+	pipe := s.client.Pipeline()
+	pipe.Del(ctx, s.opts.Prefix+key)
+	s.publish(ctx, pipe, changedKey, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.fail(fmt.Errorf("deleting [%s] : %w", key, err))
+	}
+}
+
+// Clear deletes every key under the prefix, they are found with SCAN so Redis isn't blocked
+// Keys are deleted once the scan is over, deleting while scanning could make it skip some
+func (s *Store[V]) Clear() {
+	var found []string
+	s.scan(func(_ context.Context, keys []string) bool {
+		found = append(found, keys...)
+		return true
+	})
+	ctx, cancel := s.context()
+	defer cancel()
+	for start := 0; start < len(found); start += scanCount {
+		keys := found[start:min(start+scanCount, len(found))]
+		if err := s.client.Del(ctx, keys...).Err(); err != nil {
+			s.fail(fmt.Errorf("deleting %d keys : %w", len(keys), err))
+		}
+	}
+	if err := s.publish(ctx, s.client, cleared, ""); err != nil {
+		s.fail(fmt.Errorf("publishing the clear : %w", err))
+	}
+}
+
+// Len counts the keys under the prefix with SCAN, it is meant for monitoring rather than hot paths
+func (s *Store[V]) Len() int {
+	n := 0
+	s.scan(func(_ context.Context, keys []string) bool {
+		n += len(keys)
+		return true
+	})
+	return n
+}
+
+// Range reads the keys under the prefix with SCAN and MGET, keys changed meanwhile may or may not be seen
+func (s *Store[V]) Range(fn func(key string, entry sample1.Entry[V]) bool) {
+	s.scan(func(ctx context.Context, keys []string) bool {
+		values, err := s.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			s.fail(fmt.Errorf("getting %d keys : %w", len(keys), err))
+			return false
+		}
+		for i, value := range values {
+			data, ok := value.(string)
+			if !ok {
+				continue // deleted or expired since the scan
+			}
+			key := keys[i][len(s.opts.Prefix):]
+			if entry, ok := s.decode(key, []byte(data)); ok && !fn(key, entry) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// scan calls fn with every batch of keys under the prefix until it returns false
+func (s *Store[V]) scan(fn func(ctx context.Context, keys []string) bool) {
+	ctx, cancel := s.context()
+	defer cancel()
+	var cursor uint64
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, s.opts.Prefix+"*", scanCount).Result()
+		if err != nil {
+			s.fail(fmt.Errorf("scanning : %w", err))
+			return
+		}
+		if len(keys) > 0 && !fn(ctx, keys) {
+			return
+		}
+		if next == 0 {
+			return
+		}
+		cursor = next
+	}
+}
+
+func (s *Store[V]) decode(key string, data []byte) (sample1.Entry[V], bool) {
+	entry, err := entrycodec.Unmarshal[V](data)
+	if err != nil {
+		s.fail(fmt.Errorf("decoding [%s] : %w", key, err))
+		return sample1.Entry[V]{}, false
+	}
+	return entry, true
+}
+
+// ttl is how long Redis keeps an entry, entries without a maxAge are kept until deleted
+func (s *Store[V]) ttl(entry sample1.Entry[V]) time.Duration {
+	if entry.MaxAge <= 0 {
+		return 0
+	}
+	return entry.MaxAge + s.opts.ExtraTTL
+}
+
+func (s *Store[V]) keys(keys []string) []string {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = s.opts.Prefix + key
+	}
+	return prefixed
+}
+
+func (s *Store[V]) context() (context.Context, context.CancelFunc) {
+	return s.contextFrom(context.Background())
+}
+
+func (s *Store[V]) contextFrom(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.opts.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.opts.Timeout)
+}
+
+func (s *Store[V]) fail(err error) {
+	if s.opts.OnError != nil {
+		s.opts.OnError(err)
+	}
+}
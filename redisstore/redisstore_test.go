@@ -0,0 +1,163 @@
+package redisstore
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+type fakePriceService struct {
+	prices   map[string]float64
+	numCalls int
+}
+
+func (f *fakePriceService) GetPriceFor(itemCode string) (float64, error) {
+	f.numCalls++
+	price, ok := f.prices[itemCode]
+	if !ok {
+		return 0, fmt.Errorf("unknown item [%v] : %w", itemCode, sample1.ErrNotFound)
+	}
+	return price, nil
+}
+
+func newTestStore(t *testing.T, opts Options) (*Store[float64], *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return New[float64](client, opts), server
+}
+
+// Check that entries round trip through Redis under the prefix
+func TestStore_SetAndGet(t *testing.T) {
+	store, server := newTestStore(t, Options{Prefix: "prices:"})
+	fetchedAt := time.Now().Round(0)
+	store.Set("p1", sample1.Entry[float64]{Value: 5, FetchedAt: fetchedAt, MaxAge: time.Minute})
+
+	entry, ok := store.Get("p1")
+	if !ok || entry.Value != 5 || !entry.FetchedAt.Equal(fetchedAt) || entry.MaxAge != time.Minute {
+		t.Errorf("wrong entry read back: %+v, %v", entry, ok)
+	}
+	if !server.Exists("prices:p1") {
+		t.Error("the key should have been prefixed")
+	}
+	if _, ok := store.Get("p2"); ok {
+		t.Error("p2 should not be found")
+	}
+}
+
+// Check that Redis expires entries after their maxAge plus ExtraTTL
+func TestStore_MapsMaxAgeToTTL(t *testing.T) {
+	store, server := newTestStore(t, Options{ExtraTTL: 30 * time.Second})
+	store.Set("p1", sample1.Entry[float64]{Value: 5, FetchedAt: time.Now(), MaxAge: time.Minute})
+	if ttl := server.TTL("p1"); ttl != 90*time.Second {
+		t.Errorf("wrong ttl, expected 1m30s but got %v", ttl)
+	}
+	server.FastForward(91 * time.Second)
+	if _, ok := store.Get("p1"); ok {
+		t.Error("p1 should have expired")
+	}
+}
+
+// Check that negative entries keep telling not found errors apart
+func TestStore_NegativeEntries(t *testing.T) {
+	store, _ := newTestStore(t, Options{})
+	err := fmt.Errorf("unknown item [p1] : %w", sample1.ErrNotFound)
+	store.Set("p1", sample1.Entry[float64]{Err: err, FetchedAt: time.Now(), MaxAge: time.Minute})
+
+	entry, ok := store.Get("p1")
+	if !ok || entry.Err == nil || entry.Err.Error() != err.Error() {
+		t.Fatalf("wrong entry read back: %+v, %v", entry, ok)
+	}
+	if !errors.Is(entry.Err, sample1.ErrNotFound) {
+		t.Error("the error should still be a not found error")
+	}
+}
+
+// Check that GetMany and SetMany work on several keys at once
+func TestStore_GetManyAndSetMany(t *testing.T) {
+	store, _ := newTestStore(t, Options{Prefix: "prices:"})
+	store.SetMany(map[string]sample1.Entry[float64]{
+		"p1": {Value: 5, FetchedAt: time.Now(), MaxAge: time.Minute},
+		"p3": {Value: 7, FetchedAt: time.Now(), MaxAge: time.Minute},
+	})
+	entries, found := store.GetMany([]string{"p1", "p2", "p3"})
+	if !found[0] || found[1] || !found[2] {
+		t.Fatalf("wrong keys found: %v", found)
+	}
+	if entries[0].Value != 5 || entries[2].Value != 7 {
+		t.Errorf("wrong values read back: %v, %v", entries[0].Value, entries[2].Value)
+	}
+}
+
+// Check that Len, Range and Clear only see the keys under the prefix
+func TestStore_LenRangeAndClear(t *testing.T) {
+	store, server := newTestStore(t, Options{Prefix: "prices:"})
+	server.Set("other", "value")
+	for i := 0; i < 250; i++ {
+		store.Set(fmt.Sprintf("p%d", i), sample1.Entry[float64]{Value: float64(i), FetchedAt: time.Now(), MaxAge: time.Minute})
+	}
+	if n := store.Len(); n != 250 {
+		t.Errorf("wrong length, expected 250 but got %d", n)
+	}
+	seen := map[string]float64{}
+	store.Range(func(key string, entry sample1.Entry[float64]) bool {
+		seen[key] = entry.Value
+		return true
+	})
+	if len(seen) != 250 || seen["p42"] != 42 {
+		t.Errorf("wrong entries ranged over: %d entries, p42 = %v", len(seen), seen["p42"])
+	}
+	store.Clear()
+	if n := store.Len(); n != 0 {
+		t.Errorf("every entry should have been deleted but %d are left", n)
+	}
+	if !server.Exists("other") {
+		t.Error("keys outside the prefix should have been kept")
+	}
+}
+
+// Check that Redis failures are reported and treated as misses
+func TestStore_FailuresAreMisses(t *testing.T) {
+	var failures []error
+	store, server := newTestStore(t, Options{OnError: func(err error) { failures = append(failures, err) }})
+	server.Set("p1", "not json")
+	if _, ok := store.Get("p1"); ok {
+		t.Error("an undecodable entry should be a miss")
+	}
+	server.Close()
+	if _, ok := store.Get("p2"); ok {
+		t.Error("p2 should be a miss while Redis is down")
+	}
+	store.Set("p2", sample1.Entry[float64]{Value: 5, FetchedAt: time.Now(), MaxAge: time.Minute})
+	if len(failures) != 3 {
+		t.Errorf("wrong number of failures reported, expected 3 but got %d: %v", len(failures), failures)
+	}
+}
+
+// Check that two caches share their entries through Redis
+func TestStore_SharedBetweenCaches(t *testing.T) {
+	store, _ := newTestStore(t, Options{Prefix: "prices:"})
+	service := &fakePriceService{prices: map[string]float64{"p1": 5, "p2": 7}}
+	first := sample1.New(service, sample1.WithStore[string, float64](store))
+	second := sample1.New(service, sample1.WithStore[string, float64](store))
+
+	if _, err := first.GetPricesFor("p1", "p2"); err != nil {
+		t.Fatal(err)
+	}
+	prices, err := second.GetPricesFor("p1", "p2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prices[0] != 5 || prices[1] != 7 {
+		t.Errorf("wrong prices returned: %v", prices)
+	}
+	if service.numCalls != 2 {
+		t.Errorf("the second cache should have used the shared entries, but the service was called %d times", service.numCalls)
+	}
+}
@@ -0,0 +1,74 @@
+package redisstore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Kinds of change published on the channel, messages are "<origin> <kind> <key>"
+const (
+	changedKey = "k"
+	cleared    = "c"
+)
+
+// Watch subscribes to the changes published on Channel by the other stores, so that a sample1.TieredStore
+// can drop its local copies of the changed entries
+// Changes made by this store aren't reported. It returns once subscribed, or right away if Channel is empty
+func (s *Store[V]) Watch(onChange func(key string), onClear func()) (stop func()) {
+	if s.opts.Channel == "" {
+		return func() {}
+	}
+	pubsub := s.client.Subscribe(context.Background(), s.opts.Channel)
+	ctx, cancel := s.context()
+	defer cancel()
+	if _, err := pubsub.Receive(ctx); err != nil { // the subscription confirmation
+		s.fail(fmt.Errorf("subscribing to %s : %w", s.opts.Channel, err))
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range pubsub.Channel() {
+			origin, kind, key, ok := parseChange(msg.Payload)
+			switch {
+			case !ok:
+				s.fail(fmt.Errorf("bad change published on %s : %q", s.opts.Channel, msg.Payload))
+			case origin == s.origin:
+			case kind == cleared:
+				onClear()
+			default:
+				onChange(key)
+			}
+		}
+	}()
+	return func() {
+		pubsub.Close()
+		<-done
+	}
+}
+
+// publish queues the change on c if there is a channel to publish it on
+func (s *Store[V]) publish(ctx context.Context, c redis.Cmdable, kind, key string) error {
+	if s.opts.Channel == "" {
+		return nil
+	}
+	return c.Publish(ctx, s.opts.Channel, s.origin+" "+kind+" "+key).Err()
+}
+
+func parseChange(payload string) (origin, kind, key string, ok bool) {
+	parts := strings.SplitN(payload, " ", 3)
+	if len(parts) != 3 {
+		return "", "", "", false
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+func newOrigin() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,52 @@
+package redisstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+var _ sample1.WatchableStore[string, float64] = (*Store[float64])(nil)
+
+// Check that tiered stores sharing Redis drop their local copies when another one changes them
+func TestWatch_InvalidatesTieredStores(t *testing.T) {
+	first, server := newTestStore(t, Options{Prefix: "prices:", Channel: "prices"})
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	second := New[float64](client, Options{Prefix: "prices:", Channel: "prices"})
+
+	firstTiered := sample1.NewTieredStore[string, float64](nil, first)
+	defer firstTiered.Close()
+	local := sample1.NewMapStore[string, float64]()
+	secondTiered := sample1.NewTieredStore[string, float64](local, second)
+	defer secondTiered.Close()
+
+	firstTiered.Set("p1", sample1.Entry[float64]{Value: 5, FetchedAt: time.Now(), MaxAge: time.Minute})
+	if entry, ok := secondTiered.Get("p1"); !ok || entry.Value != 5 {
+		t.Fatalf("wrong entry read: %+v, %v", entry, ok)
+	}
+	firstTiered.Set("p1", sample1.Entry[float64]{Value: 6, FetchedAt: time.Now(), MaxAge: time.Minute})
+	waitFor(t, func() bool { return local.Len() == 0 }, "the local copy of p1 should have been dropped")
+	if entry, ok := secondTiered.Get("p1"); !ok || entry.Value != 6 {
+		t.Errorf("wrong entry read: %+v, %v", entry, ok)
+	}
+	if firstTiered.Len() != 1 {
+		t.Error("the change should not have dropped the local copy of its author")
+	}
+
+	firstTiered.Clear()
+	waitFor(t, func() bool { return local.Len() == 0 }, "the clear should have emptied every local tier")
+}
+
+func waitFor(t *testing.T, condition func() bool, msg string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Error(msg)
+}
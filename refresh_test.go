@@ -0,0 +1,59 @@
+package sample1
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Check that prices accessed close to their max age are refreshed before they expire
+func TestGetPriceFor_RefreshAhead(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay: 20 * time.Millisecond,
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	maxAge := 400 * time.Millisecond
+	cache := NewTransparentCache(mockService, maxAge, WithRefreshAhead(0.5))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	// still in the first half of its max age, no refresh
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 1, mockService.getNumCalls(), "wrong number of service calls")
+
+	time.Sleep(240 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	}
+	time.Sleep(50 * time.Millisecond)
+	assertInt(t, 2, mockService.getNumCalls(), "expected a single refresh")
+
+	// past the original max age, the refreshed price is still fresh
+	time.Sleep(150 * time.Millisecond)
+	start := time.Now()
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	if time.Since(start) > 10*time.Millisecond {
+		t.Error("expected the refreshed price to be served from the cache")
+	}
+	assertInt(t, 2, mockService.getNumCalls(), "wrong number of service calls")
+}
+
+// Check that WaitForLoads waits for the refreshes started in the background
+func TestWaitForLoads(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay:   20 * time.Millisecond,
+		mockResults: map[string]mockResult{"p1": {price: 5, err: nil}},
+	}
+	clock := newFakeClock()
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock), WithRefreshAhead(0.5))
+	getPriceWithNoErr(t, cache, "p1")
+	clock.Advance(40 * time.Second)
+	getPriceWithNoErr(t, cache, "p1")
+	if err := cache.WaitForLoads(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 2, mockService.getNumCalls(), "the price should have been refreshed")
+	if _, age, _ := cache.Peek("p1"); age != 0 {
+		t.Errorf("expected the refreshed price to be stored, got an age of %v", age)
+	}
+}
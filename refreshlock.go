@@ -0,0 +1,45 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RefreshLocker hands out per key leases, so that when several instances share a store (see redisstore) only one
+// of them refreshes an expired value while the others keep serving it
+type RefreshLocker interface {
+	// TryLock takes the lease of key for ttl unless another instance holds it, unlock gives it back early
+	// Implementations that can't tell (their backend is down) should grant the lease, so that values are still refreshed
+	TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool)
+}
+
+// WithRefreshLock makes the background refreshes (see WithStaleWhileRevalidate, WithRefreshAhead and
+// WithEarlyExpiration) take the lease of their key from locker first: the instance that gets it refreshes the
+// value, the others skip the refresh and keep serving the value they have until the store has the new one
+// ttl bounds how long a lease is held, in case its holder dies before giving it back
+// Lookups that can't be served from the cache still load the value right away
+// Keys are sent as they are, the cache must be keyed by strings (a TransparentCache is), NewCache panics otherwise
+func WithRefreshLock(locker RefreshLocker, ttl time.Duration) Option {
+	return func(c *config) {
+		c.refreshLocker = locker
+		c.refreshLockTTL = ttl
+	}
+}
+
+// errRefreshLocked is what a refresh returns when another instance holds the lease of its key
+var errRefreshLocked = errors.New("another instance is refreshing the value")
+
+// refreshLocked runs a background refresh of key under its lease
+func (c *Cache[K, V]) refreshLocked(key K, refresh func() (V, error)) (V, error) {
+	if c.refreshLocker == nil {
+		return refresh()
+	}
+	unlock, ok := c.refreshLocker.TryLock(context.Background(), any(key).(string), c.refreshLockTTL)
+	if !ok {
+		var zero V
+		return zero, errRefreshLocked
+	}
+	defer unlock()
+	return refresh()
+}
@@ -0,0 +1,137 @@
+package sample1
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLocker is a RefreshLocker with the leases of every instance in memory
+type fakeLocker struct {
+	mu    sync.Mutex
+	held  map[string]bool
+	taken int
+}
+
+func (l *fakeLocker) TryLock(_ context.Context, key string, _ time.Duration) (func(), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[key] {
+		return nil, false
+	}
+	if l.held == nil {
+		l.held = map[string]bool{}
+	}
+	l.held[key] = true
+	l.taken++
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, key)
+	}, true
+}
+
+func (l *fakeLocker) isHeld(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.held[key]
+}
+
+// Check that an instance doesn't refresh a value while another one holds its lease, and keeps serving it meanwhile
+func TestWithRefreshLock_SkipsRefreshesLeasedElsewhere(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	clock := newFakeClock()
+	locker := &fakeLocker{}
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock),
+		WithStaleWhileRevalidate(time.Minute), WithRefreshLock(locker, time.Second))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	clock.Advance(90 * time.Second)
+
+	locker.TryLock(context.Background(), "p1", time.Second) // another instance is refreshing p1
+	mockService.setPrice("p1", 6)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "expected the stale price")
+	time.Sleep(20 * time.Millisecond)
+	assertInt(t, 1, mockService.getNumCalls(), "the refresh should have been skipped")
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "expected the stale price")
+}
+
+// Check that the instance getting the lease refreshes the value and gives the lease back once done
+func TestWithRefreshLock_RefreshesUnderTheLease(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	clock := newFakeClock()
+	locker := &fakeLocker{}
+	cache := NewTransparentCache(mockService, time.Minute, WithClock(clock),
+		WithStaleWhileRevalidate(time.Minute), WithRefreshLock(locker, time.Second))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 0, locker.taken, "loads of missing values shouldn't take the lease")
+	clock.Advance(90 * time.Second)
+
+	mockService.setPrice("p1", 6)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "expected the stale price")
+	for deadline := time.Now().Add(time.Second); mockService.getNumCalls() < 2 || locker.isHeld("p1"); {
+		if time.Now().After(deadline) {
+			t.Fatal("the value wasn't refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assertInt(t, 1, locker.taken, "the refresh should have taken the lease")
+	assertFloat(t, 6, getPriceWithNoErr(t, cache, "p1"), "expected the refreshed price")
+}
+
+// Check that two caches sharing a store and a locker refresh an expired value only once
+func TestWithRefreshLock_SharedAcrossInstances(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay: 50 * time.Millisecond,
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	clock := newFakeClock()
+	locker := &fakeLocker{}
+	store := NewMapStore[string, float64]()
+	opts := []Option{WithClock(clock), WithStore[string, float64](store),
+		WithStaleWhileRevalidate(time.Minute), WithRefreshLock(locker, time.Second)}
+	cache1 := NewTransparentCache(mockService, time.Minute, opts...)
+	cache2 := NewTransparentCache(mockService, time.Minute, opts...)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache1, "p1"), "wrong price returned")
+	clock.Advance(90 * time.Second)
+
+	mockService.setPrice("p1", 6)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache1, "p1"), "expected the stale price")
+	assertFloat(t, 5, getPriceWithNoErr(t, cache2, "p1"), "expected the stale price")
+	time.Sleep(100 * time.Millisecond)
+	assertInt(t, 2, mockService.getNumCalls(), "only one of the instances should have refreshed p1")
+	assertFloat(t, 6, getPriceWithNoErr(t, cache2, "p1"), "expected the price refreshed by the other instance")
+}
+
+// Check that a lookup that can't be served stale loads the value even when the lease is held elsewhere
+func TestWithRefreshLock_MissesStillLoad(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	locker := &fakeLocker{}
+	locker.TryLock(context.Background(), "p1", time.Second)
+	cache := NewTransparentCache(mockService, time.Minute, WithRefreshLock(locker, time.Second))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+}
+
+// Check that a refresh locker can't be used for a cache not keyed by strings
+func TestWithRefreshLock_PanicsOnOtherKeys(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected NewCache to panic")
+		}
+	}()
+	NewCache(func(ctx context.Context, key int) (float64, error) { return 0, nil }, WithRefreshLock(&fakeLocker{}, time.Second))
+}
@@ -0,0 +1,106 @@
+package sample1
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// NewRecordingService returns a PriceService recording every call to service in w, a line of JSON each (see
+// NewJSONAuditSink), for NewReplayService to serve them again without service
+// If service is a BulkPriceService (or a ContextBulkPriceService), so is the returned service
+func NewRecordingService(service PriceService, w io.Writer) PriceService {
+	return NewAuditedService(service, NewJSONAuditSink(w))
+}
+
+// ReplayService is a PriceService serving the calls recorded by NewRecordingService (or NewJSONAuditSink), so
+// that tests and local development don't need the actual service
+// Every item answers its recorded calls in turn, the last one being answered again once they were all replayed.
+// It is safe for concurrent use by multiple goroutines
+type ReplayService struct {
+	latency bool // whether the calls take as long as they were recorded to
+
+	mu    sync.Mutex
+	calls map[string][]jsonAuditRecord // the recorded calls of every item, in order
+	next  map[string]int               // the index of the next call to replay for every item
+}
+
+// NewReplayService reads the recording in r, the replayed calls take as long as the recorded ones if latency is
+// true and return right away otherwise
+func NewReplayService(r io.Reader, latency bool) (*ReplayService, error) {
+	s := &ReplayService{latency: latency, calls: map[string][]jsonAuditRecord{}, next: map[string]int{}}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var call jsonAuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &call); err != nil {
+			return nil, fmt.Errorf("decoding the call at line %d : %w", line, err)
+		}
+		s.calls[call.ItemCode] = append(s.calls[call.ItemCode], call)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading the recording : %w", err)
+	}
+	return s, nil
+}
+
+func (s *ReplayService) GetPriceFor(itemCode string) (float64, error) {
+	return s.GetPriceForCtx(context.Background(), itemCode)
+}
+
+// GetPriceForCtx replays the next recorded call for the item, it fails with ErrNotFound for the items that
+// weren't recorded
+func (s *ReplayService) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	call, ok := s.nextCall(itemCode)
+	if !ok {
+		return 0, fmt.Errorf("no call recorded for [%v] : %w", itemCode, ErrNotFound)
+	}
+	if s.latency && call.LatencyMs > 0 {
+		timer := time.NewTimer(time.Duration(call.LatencyMs * float64(time.Millisecond)))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	if call.Err != "" {
+		return 0, replayedError{message: call.Err, notFound: call.NotFound}
+	}
+	return call.Price, nil
+}
+
+// replayedError is a recorded error, it is ErrNotFound if the recorded one was
+type replayedError struct {
+	message  string
+	notFound bool
+}
+
+func (e replayedError) Error() string {
+	return e.message
+}
+
+func (e replayedError) Is(target error) bool {
+	return e.notFound && target == ErrNotFound
+}
+
+// nextCall returns the call to replay for itemCode, ok is false if none was recorded
+func (s *ReplayService) nextCall(itemCode string) (call jsonAuditRecord, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := s.calls[itemCode]
+	if len(calls) == 0 {
+		return call, false
+	}
+	i := s.next[itemCode]
+	if i < len(calls)-1 {
+		s.next[itemCode] = i + 1
+	}
+	return calls[i], true
+}
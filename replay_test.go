@@ -0,0 +1,69 @@
+package sample1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Check that the recorded calls are replayed in turn, errors included, without the actual service
+func TestReplayService(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{
+		"p1": {price: 5, err: nil},
+		"p2": {price: 0, err: fmt.Errorf("no price for p2 : %w", ErrNotFound)},
+		"p3": {price: 0, err: errors.New("service down")},
+	}}
+	var recording bytes.Buffer
+	recorder := NewRecordingService(mockService, &recording)
+	for _, itemCode := range []string{"p1", "p2", "p3"} {
+		recorder.GetPriceFor(itemCode)
+	}
+	mockService.setPrice("p1", 6)
+	recorder.GetPriceFor("p1")
+
+	replay, err := NewReplayService(&recording, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []float64{5, 6, 6} {
+		price, err := replay.GetPriceFor("p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertFloat(t, expected, price, "the calls should be replayed in turn, the last one again")
+	}
+	if _, err := replay.GetPriceFor("p2"); !errors.Is(err, ErrNotFound) || err.Error() != "no price for p2 : not found" {
+		t.Errorf("expected the recorded not found error but got %v", err)
+	}
+	if _, err := replay.GetPriceFor("p3"); err == nil || errors.Is(err, ErrNotFound) || err.Error() != "service down" {
+		t.Errorf("expected the recorded error but got %v", err)
+	}
+	if _, err := replay.GetPriceFor("p4"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an item that wasn't recorded but got %v", err)
+	}
+	assertInt(t, 4, mockService.getNumCalls(), "the replay should not call the service")
+}
+
+// Check that the replayed calls take as long as the recorded ones if asked to, and give up when ctx is done
+func TestReplayService_Latency(t *testing.T) {
+	recording := bytes.NewBufferString(`{"itemCode":"p1","price":5,"latencyMs":50}` + "\n")
+	replay, err := NewReplayService(recording, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := replay.GetPriceFor("p1"); err != nil || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("the call should have taken the recorded latency, took %v (err %v)", time.Since(start), err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := replay.GetPriceForCtx(ctx, "p1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline of the context but got %v", err)
+	}
+	if _, err := NewReplayService(bytes.NewBufferString("not json\n"), false); err == nil {
+		t.Error("expected an error for a broken recording")
+	}
+}
@@ -0,0 +1,49 @@
+package sample1
+
+import "context"
+
+// Result is the outcome of one key of a batch, either its value or the reason it could not be loaded
+type Result[K comparable, V any] struct {
+	Key   K
+	Value V
+	Err   error
+}
+
+// GetEach is GetMany for callers that can handle partial failures: it returns the result of every key,
+// in the same order as the keys, and the keys that fail don't hide the values of the others
+func (c *Cache[K, V]) GetEach(ctx context.Context, keys ...K) []Result[K, V] {
+	ctx, end := c.observer.StartBatch(ctx, len(keys))
+	values, errs := c.getEach(ctx, keys)
+	results := make([]Result[K, V], len(keys))
+	for i, key := range keys {
+		results[i] = Result[K, V]{Key: key, Value: values[i], Err: errs[i]}
+	}
+	if batchErr := newBatchError(keys, errs); batchErr != nil {
+		end(batchErr)
+	} else {
+		end(nil)
+	}
+	return results
+}
+
+// Stream is GetEach for big batches: it sends the result of every key on the returned channel as soon as
+// it is known, in the order the keys complete, and closes the channel after the last one
+// The channel is buffered for the whole batch, so the lookups never wait for the caller to read it
+func (c *Cache[K, V]) Stream(ctx context.Context, keys ...K) <-chan Result[K, V] {
+	results := make(chan Result[K, V], len(keys))
+	go func() {
+		defer close(results)
+		ctx, end := c.observer.StartBatch(ctx, len(keys))
+		errs := make([]error, len(keys))
+		c.forEach(ctx, keys, func(i int, value V, err error) {
+			errs[i] = err
+			results <- Result[K, V]{Key: keys[i], Value: value, Err: err}
+		})
+		if batchErr := newBatchError(keys, errs); batchErr != nil {
+			end(batchErr)
+		} else {
+			end(nil)
+		}
+	}()
+	return results
+}
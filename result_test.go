@@ -0,0 +1,111 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Check that every item gets its own result, so that failures don't hide the prices that were found
+func TestGetPriceResultsFor_PartialFailures(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 0, err: ErrNotFound},
+			"p3": {price: 9, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	results := cache.GetPriceResultsFor("p1", "p2", "p3")
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results but got %d", len(results))
+	}
+	for i, itemCode := range []string{"p1", "p2", "p3"} {
+		if results[i].Key != itemCode {
+			t.Errorf("result %d should be for %s but is for %s", i, itemCode, results[i].Key)
+		}
+	}
+	if results[0].Err != nil || results[2].Err != nil {
+		t.Errorf("p1 and p3 should not have failed: %v, %v", results[0].Err, results[2].Err)
+	}
+	assertFloats(t, []float64{5, 9}, []float64{results[0].Value, results[2].Value}, "wrong price returned")
+	if !errors.Is(results[1].Err, ErrNotFound) {
+		t.Errorf("expected a not found error for p2 but got %v", results[1].Err)
+	}
+}
+
+// Check that the observer sees the batch fail when some keys fail
+func TestGetEach_NotifiesTheObserver(t *testing.T) {
+	observer := &recordingObserver{}
+	cache := NewCache(func(_ context.Context, key int) (float64, error) {
+		if key == 2 {
+			return 0, ErrNotFound
+		}
+		return float64(key), nil
+	}, WithObserver(observer))
+	cache.GetEach(context.Background(), 1, 2)
+	cache.GetEach(context.Background(), 1)
+	var batches []string
+	for _, event := range observer.events {
+		if strings.HasPrefix(event, "batch") {
+			batches = append(batches, event)
+		}
+	}
+	if len(batches) != 2 || batches[0] != "batch 2 err=true" || batches[1] != "batch 1 err=false" {
+		t.Errorf("wrong batches observed: %v", batches)
+	}
+}
+
+// slowMockPriceService is a mockPriceService that takes longer for some items
+type slowMockPriceService struct {
+	mockPriceService
+	slow    string
+	release chan struct{}
+}
+
+func (m *slowMockPriceService) GetPriceFor(itemCode string) (float64, error) {
+	if itemCode == m.slow {
+		<-m.release
+	}
+	return m.mockPriceService.GetPriceFor(itemCode)
+}
+
+// Check that results are streamed as soon as each item is priced, without waiting for the slowest one
+func TestStreamPricesFor_SendsResultsAsTheyComplete(t *testing.T) {
+	mockService := &slowMockPriceService{
+		mockPriceService: mockPriceService{
+			mockResults: map[string]mockResult{
+				"p1": {price: 5, err: nil},
+				"p2": {price: 0, err: ErrNotFound},
+				"p3": {price: 9, err: nil},
+			},
+		},
+		slow:    "p1",
+		release: make(chan struct{}),
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	results := cache.StreamPricesFor("p1", "p2", "p3", "p3")
+
+	received := map[string]PriceResult{}
+	for i := 0; i < 3; i++ {
+		result := <-results
+		if result.Key == "p1" {
+			t.Fatal("p1 should not have been priced yet")
+		}
+		received[result.Key] = result
+	}
+	close(mockService.release)
+	for result := range results {
+		received[result.Key] = result
+	}
+	if len(received) != 3 {
+		t.Fatalf("expected results for 3 items but got %v", received)
+	}
+	assertFloats(t, []float64{5, 9}, []float64{received["p1"].Value, received["p3"].Value}, "wrong price returned")
+	if !errors.Is(received["p2"].Err, ErrNotFound) {
+		t.Errorf("expected a not found error for p2 but got %v", received["p2"].Err)
+	}
+	assertInt(t, 3, mockService.getNumCalls(), "the repeated item should have been priced once")
+}
@@ -0,0 +1,94 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy tells how a RetryingService retries the calls that fail, zero fields take their defaults
+type RetryPolicy struct {
+	MaxAttempts    int              // calls made at most for one price, the first one included, 3 if zero
+	InitialBackoff time.Duration    // wait before the first retry, 100ms if zero
+	MaxBackoff     time.Duration    // longest wait between two attempts, unbounded if zero
+	Multiplier     float64          // how much the wait grows after every retry, 2 if zero
+	Jitter         float64          // max fraction of every wait that is randomly taken off, so that clients don't retry in sync
+	Retryable      func(error) bool // tells which errors are worth a retry, the Transient ones for DefaultErrorClassifier if nil
+}
+
+// RetryingService is a PriceService that retries the failed calls to another one, waiting longer and longer
+// between attempts
+// It can be given to the cache like any service, the cache then only sees the errors of the last attempt
+type RetryingService struct {
+	service ContextPriceService
+	policy  RetryPolicy
+	random  func() float64                                   // returns numbers in [0, 1), only swapped by tests
+	sleep   func(ctx context.Context, d time.Duration) error // only swapped by tests
+}
+
+// NewRetryingService wraps service so that its failed calls are retried following policy
+func NewRetryingService(service PriceService, policy RetryPolicy) *RetryingService {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 3
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = 100 * time.Millisecond
+	}
+	if policy.Multiplier <= 0 {
+		policy.Multiplier = 2
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = isTransient
+	}
+	return &RetryingService{
+		service: AsContextPriceService(service),
+		policy:  policy,
+		random:  rand.Float64,
+		sleep:   sleep,
+	}
+}
+
+func (s *RetryingService) GetPriceFor(itemCode string) (float64, error) {
+	return s.GetPriceForCtx(context.Background(), itemCode)
+}
+
+// GetPriceForCtx calls the service until it succeeds, fails with an error that isn't retryable, runs out
+// of attempts or ctx is done
+func (s *RetryingService) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	backoff := s.policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		price, err := s.service.GetPriceForCtx(ctx, itemCode)
+		if err == nil || attempt >= s.policy.MaxAttempts || !s.retryable(ctx, err) {
+			return price, err
+		}
+		wait := backoff - time.Duration(s.policy.Jitter*s.random()*float64(backoff))
+		if err := s.sleep(ctx, wait); err != nil {
+			return 0, err
+		}
+		backoff = time.Duration(float64(backoff) * s.policy.Multiplier)
+		if s.policy.MaxBackoff > 0 && backoff > s.policy.MaxBackoff {
+			backoff = s.policy.MaxBackoff
+		}
+	}
+}
+
+// retryable tells if err is worth another attempt, errors of a done ctx never are
+func (s *RetryingService) retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return s.policy.Retryable(err)
+}
+
+// sleep waits for d, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,118 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyPriceService fails its first calls
+type flakyPriceService struct {
+	mockPriceService
+	failures int // calls that fail before the service recovers
+	err      error
+}
+
+func (f *flakyPriceService) GetPriceFor(itemCode string) (float64, error) {
+	price, err := f.mockPriceService.GetPriceFor(itemCode)
+	if f.getNumCalls() <= f.failures {
+		return 0, f.err
+	}
+	return price, err
+}
+
+// newTestRetryingService returns a RetryingService that records its waits instead of sleeping
+func newTestRetryingService(service PriceService, policy RetryPolicy) (*RetryingService, *[]time.Duration) {
+	s := NewRetryingService(service, policy)
+	var waits []time.Duration
+	s.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	return s, &waits
+}
+
+// Check that failed calls are retried with an exponential backoff until they succeed
+func TestRetryingService_RetriesWithBackoff(t *testing.T) {
+	service := &flakyPriceService{
+		mockPriceService: mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}}},
+		failures:         3,
+		err:              errors.New("503 service unavailable"),
+	}
+	retrying, waits := newTestRetryingService(service, RetryPolicy{
+		MaxAttempts: 5, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 30 * time.Millisecond,
+	})
+	cache := NewTransparentCache(retrying, time.Minute)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 4, service.getNumCalls(), "wrong number of service calls")
+	expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond}
+	if len(*waits) != len(expected) {
+		t.Fatalf("expected waits of %v but got %v", expected, *waits)
+	}
+	for i := range expected {
+		if (*waits)[i] != expected[i] {
+			t.Errorf("expected waits of %v but got %v", expected, *waits)
+		}
+	}
+}
+
+// Check that retries stop after MaxAttempts and return the last error
+func TestRetryingService_GivesUpAfterMaxAttempts(t *testing.T) {
+	failing := errors.New("503 service unavailable")
+	service := &flakyPriceService{
+		mockPriceService: mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}}},
+		failures:         10,
+		err:              failing,
+	}
+	retrying, _ := newTestRetryingService(service, RetryPolicy{MaxAttempts: 3})
+	if _, err := retrying.GetPriceFor("p1"); !errors.Is(err, failing) {
+		t.Errorf("expected the last error but got %v", err)
+	}
+	assertInt(t, 3, service.getNumCalls(), "wrong number of service calls")
+}
+
+// Check that errors that aren't retryable are returned right away
+func TestRetryingService_DoesNotRetryPermanentErrors(t *testing.T) {
+	service := &mockPriceService{mockResults: map[string]mockResult{"p1": {err: ErrNotFound}, "p2": {err: errors.New("400 bad request")}}}
+	retrying, _ := newTestRetryingService(service, RetryPolicy{})
+	if _, err := retrying.GetPriceFor("p1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a not found error but got %v", err)
+	}
+	assertInt(t, 1, service.getNumCalls(), "not found errors should not be retried by default")
+
+	retrying, _ = newTestRetryingService(service, RetryPolicy{Retryable: func(err error) bool { return false }})
+	retrying.GetPriceFor("p2")
+	assertInt(t, 2, service.getNumCalls(), "the predicate should have stopped the retries")
+}
+
+// Check that retries stop as soon as the context is done
+func TestRetryingService_StopsWhenTheContextIsDone(t *testing.T) {
+	service := &flakyPriceService{
+		mockPriceService: mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}}},
+		failures:         10,
+		err:              errors.New("503 service unavailable"),
+	}
+	retrying := NewRetryingService(service, RetryPolicy{MaxAttempts: 10, InitialBackoff: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := retrying.GetPriceForCtx(ctx, "p1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context error but got %v", err)
+	}
+	assertInt(t, 1, service.getNumCalls(), "wrong number of service calls")
+}
+
+// Check that jitter takes a random part off every wait
+func TestRetryingService_Jitter(t *testing.T) {
+	service := &flakyPriceService{
+		mockPriceService: mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5}}},
+		failures:         1,
+		err:              errors.New("503 service unavailable"),
+	}
+	retrying, waits := newTestRetryingService(service, RetryPolicy{InitialBackoff: 100 * time.Millisecond, Jitter: 0.5})
+	retrying.random = func() float64 { return 0.5 }
+	retrying.GetPriceFor("p1")
+	if len(*waits) != 1 || (*waits)[0] != 75*time.Millisecond {
+		t.Errorf("expected a wait of 75ms but got %v", *waits)
+	}
+}
@@ -0,0 +1,264 @@
+package sample1
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Node is one of the backends of a RingService, Name places it on the ring so it must be the same on every client
+type Node struct {
+	Name    string
+	Service PriceService
+}
+
+// RingPolicy tells how a RingService spreads the item codes over its nodes, zero fields take their defaults
+type RingPolicy struct {
+	VirtualNodes int // points of every node on the ring, the more of them the more even the spread, 160 if zero
+	Replicas     int // distinct nodes owning every item code, asked in turn when one fails, 1 if zero
+	// Classifier tells which errors are asked to the next owner, the Transient ones, DefaultErrorClassifier if nil
+	Classifier ErrorClassifier
+}
+
+// RingService is a PriceService (and a BulkPriceService) sharding the item codes over several nodes, typically
+// remote caches (see grpccache), with a consistent hash ring: every item code is owned by the same nodes on every
+// client, and adding or removing a node only moves the item codes it gains or loses
+// An item code is asked to the first of its owners, then to the next ones if it fails with a Transient error (the
+// Permanent ones are the same on every node, they all front the same catalog), and reports which node priced it
+// (see SourceOf)
+type RingService struct {
+	names    []string
+	services []ContextPriceService
+	bulk     []BulkLoaderFunc[string, float64] // nil for the nodes that price one item at a time
+	points   []ringPoint                       // sorted by hash
+	replicas int
+	classify ErrorClassifier
+}
+
+// ringPoint is one of the virtual nodes of a node on the ring
+type ringPoint struct {
+	hash uint64
+	node int
+}
+
+// NewRingService returns a service sharding the item codes over nodes following policy
+// It panics if there are no nodes or if two of them have the same name, Replicas is capped to the number of nodes
+func NewRingService(policy RingPolicy, nodes ...Node) *RingService {
+	if len(nodes) == 0 {
+		panic("sample1: a ring service needs at least one node")
+	}
+	if policy.VirtualNodes <= 0 {
+		policy.VirtualNodes = 160
+	}
+	if policy.Replicas <= 0 {
+		policy.Replicas = 1
+	}
+	if policy.Classifier == nil {
+		policy.Classifier = DefaultErrorClassifier
+	}
+	s := &RingService{replicas: min(policy.Replicas, len(nodes)), classify: policy.Classifier}
+	for i, node := range nodes {
+		if slices.Contains(s.names, node.Name) {
+			panic(fmt.Sprintf("sample1: two nodes of the ring are named %q", node.Name))
+		}
+		s.names = append(s.names, node.Name)
+		s.services = append(s.services, AsContextPriceService(node.Service))
+		s.bulk = append(s.bulk, bulkLoaderForService(node.Service))
+		for v := 0; v < policy.VirtualNodes; v++ {
+			s.points = append(s.points, ringPoint{hash: ringHash(node.Name + "#" + strconv.Itoa(v)), node: i})
+		}
+	}
+	slices.SortFunc(s.points, func(a, b ringPoint) int {
+		if a.hash != b.hash {
+			return cmp.Compare(a.hash, b.hash)
+		}
+		return cmp.Compare(a.node, b.node) // the (unlikely) collisions are settled the same way on every client
+	})
+	return s
+}
+
+// ringHash places a string on the ring, FNV-1a is mixed further so that similar names and item codes spread out
+func ringHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Owners returns the names of the nodes owning itemCode, in the order they are asked
+func (s *RingService) Owners(itemCode string) []string {
+	owners := s.owners(itemCode)
+	names := make([]string, len(owners))
+	for i, node := range owners {
+		names[i] = s.names[node]
+	}
+	return names
+}
+
+// owners returns the nodes owning itemCode: the ones of the first points found clockwise from its hash
+func (s *RingService) owners(itemCode string) []int {
+	hash := ringHash(itemCode)
+	start, _ := slices.BinarySearchFunc(s.points, hash, func(p ringPoint, hash uint64) int {
+		return cmp.Compare(p.hash, hash)
+	})
+	owners := make([]int, 0, s.replicas)
+	for i := 0; i < len(s.points) && len(owners) < s.replicas; i++ {
+		node := s.points[(start+i)%len(s.points)].node
+		if !slices.Contains(owners, node) {
+			owners = append(owners, node)
+		}
+	}
+	return owners
+}
+
+func (s *RingService) GetPriceFor(itemCode string) (float64, error) {
+	return s.GetPriceForCtx(context.Background(), itemCode)
+}
+
+// GetPriceForCtx returns the price of itemCode from the first of its owners that has it
+// If they all fail, the error joins all of their errors
+func (s *RingService) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	var errs []error
+	for _, node := range s.owners(itemCode) {
+		price, err := s.services[node].GetPriceForCtx(ctx, itemCode)
+		if err == nil {
+			ReportSource(ctx, s.names[node])
+			return price, nil
+		}
+		errs = append(errs, fmt.Errorf("%s : %w", s.names[node], err))
+		if !s.failsOver(ctx, err) {
+			break
+		}
+	}
+	return 0, errors.Join(errs...)
+}
+
+func (s *RingService) GetPricesFor(itemCodes ...string) ([]float64, error) {
+	return s.GetPricesForCtx(context.Background(), itemCodes...)
+}
+
+// GetPricesForCtx asks every node for the item codes it owns, with a single call for the nodes that are
+// BulkPriceServices, all the nodes in parallel. The item codes that failed are then asked to their next owner
+// If some items fail on all their owners, it returns a *BatchError[string] with them, along with the prices of the others
+func (s *RingService) GetPricesForCtx(ctx context.Context, itemCodes ...string) ([]float64, error) {
+	prices := make([]float64, len(itemCodes))
+	errs := make([]error, len(itemCodes))
+	owners := make([][]int, len(itemCodes))
+	pending := make([]int, len(itemCodes)) // the indexes of the item codes without a price yet
+	for i, itemCode := range itemCodes {
+		owners[i] = s.owners(itemCode)
+		pending[i] = i
+	}
+	for attempt := 0; attempt < s.replicas && len(pending) > 0; attempt++ {
+		byNode := map[int][]int{}
+		for _, i := range pending {
+			node := owners[i][attempt]
+			byNode[node] = append(byNode[node], i)
+		}
+		var wg sync.WaitGroup
+		for node, indexes := range byNode {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				codes := make([]string, len(indexes))
+				for k, i := range indexes {
+					codes[k] = itemCodes[i]
+				}
+				values, nodeErrs := s.priceOn(ctx, node, codes)
+				for k, i := range indexes {
+					if nodeErrs[k] != nil {
+						errs[i] = errors.Join(errs[i], fmt.Errorf("%s : %w", s.names[node], nodeErrs[k]))
+						continue
+					}
+					prices[i], errs[i] = values[k], nil
+				}
+			}()
+		}
+		wg.Wait()
+		pending = pending[:0]
+		for i, err := range errs {
+			if err != nil && s.failsOver(ctx, err) {
+				pending = append(pending, i)
+			}
+		}
+	}
+	if batchErr := newBatchError(itemCodes, errs); batchErr != nil {
+		return prices, batchErr
+	}
+	return prices, nil
+}
+
+// priceOn prices itemCodes on a single node, with one call if it can price several items at once
+func (s *RingService) priceOn(ctx context.Context, node int, itemCodes []string) ([]float64, []error) {
+	errs := make([]error, len(itemCodes))
+	if s.bulk[node] == nil {
+		prices := make([]float64, len(itemCodes))
+		s.priceEach(ctx, node, itemCodes, prices, errs, nil)
+		return prices, errs
+	}
+	prices, err := s.bulk[node](ctx, itemCodes)
+	if err == nil && len(prices) != len(itemCodes) {
+		err = fmt.Errorf("asked for %d prices but got %d", len(itemCodes), len(prices))
+	}
+	var retry []int // items without an error nor a price, which are priced one by one
+	var batchErr *BatchError[string]
+	switch {
+	case errors.As(err, &batchErr):
+		failed := map[string]error{}
+		for _, keyErr := range batchErr.Errors {
+			failed[keyErr.Key] = keyErr.Err
+		}
+		for i, itemCode := range itemCodes {
+			errs[i] = failed[itemCode]
+			if errs[i] == nil && len(prices) != len(itemCodes) {
+				retry = append(retry, i)
+			}
+		}
+	case err != nil:
+		for i := range itemCodes {
+			errs[i] = err
+		}
+	}
+	if len(prices) != len(itemCodes) {
+		prices = make([]float64, len(itemCodes))
+	}
+	if len(retry) > 0 {
+		s.priceEach(ctx, node, itemCodes, prices, errs, retry)
+	}
+	return prices, errs
+}
+
+// priceEach prices the items of itemCodes at indices (all of them if nil) on a single node, one call per item in
+// parallel, into prices and errs
+func (s *RingService) priceEach(ctx context.Context, node int, itemCodes []string, prices []float64, errs []error, indices []int) {
+	if indices == nil {
+		indices = make([]int, len(itemCodes))
+		for i := range indices {
+			indices[i] = i
+		}
+	}
+	var wg sync.WaitGroup
+	for _, i := range indices {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prices[i], errs[i] = s.services[node].GetPriceForCtx(ctx, itemCodes[i])
+		}()
+	}
+	wg.Wait()
+}
+
+// failsOver tells if an item that failed with err is worth asking to its next owner
+func (s *RingService) failsOver(ctx context.Context, err error) bool {
+	return s.classify(err) == Transient && ctx.Err() == nil
+}
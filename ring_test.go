@@ -0,0 +1,247 @@
+package sample1
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ringNode is a node of a ring pricing every item at 1, counting the items and the calls it gets
+type ringNode struct {
+	mu        sync.Mutex
+	items     map[string]int
+	bulkCalls int
+	down      bool
+}
+
+func (n *ringNode) GetPriceFor(itemCode string) (float64, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.items == nil {
+		n.items = map[string]int{}
+	}
+	n.items[itemCode]++
+	if n.down {
+		return 0, errors.New("503 service unavailable")
+	}
+	return 1, nil
+}
+
+func (n *ringNode) numItems() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.items)
+}
+
+// bulkRingNode is a ringNode that prices several items at once
+type bulkRingNode struct {
+	ringNode
+}
+
+func (n *bulkRingNode) GetPricesFor(itemCodes ...string) ([]float64, error) {
+	n.mu.Lock()
+	n.bulkCalls++
+	n.mu.Unlock()
+	prices := make([]float64, len(itemCodes))
+	for i, itemCode := range itemCodes {
+		var err error
+		if prices[i], err = n.GetPriceFor(itemCode); err != nil {
+			return nil, err
+		}
+	}
+	return prices, nil
+}
+
+func ringItemCodes(n int) []string {
+	itemCodes := make([]string, n)
+	for i := range itemCodes {
+		itemCodes[i] = fmt.Sprintf("p%d", i)
+	}
+	return itemCodes
+}
+
+// Check that item codes are spread evenly over the nodes, and always go to the same node whatever the order of the nodes
+func TestRingService_SpreadsItemCodes(t *testing.T) {
+	nodes := []*ringNode{{}, {}, {}}
+	ring := NewRingService(RingPolicy{}, Node{Name: "a", Service: nodes[0]}, Node{Name: "b", Service: nodes[1]},
+		Node{Name: "c", Service: nodes[2]})
+	reordered := NewRingService(RingPolicy{}, Node{Name: "c", Service: nodes[2]}, Node{Name: "a", Service: nodes[0]},
+		Node{Name: "b", Service: nodes[1]})
+	itemCodes := ringItemCodes(3000)
+	for _, itemCode := range itemCodes {
+		if _, err := ring.GetPriceFor(itemCode); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if a, b := ring.Owners(itemCode), reordered.Owners(itemCode); !slices.Equal(a, b) {
+			t.Fatalf("[%v] is owned by %v or %v depending on the order of the nodes", itemCode, a, b)
+		}
+	}
+	for i, node := range nodes {
+		if n := node.numItems(); n < 700 || n > 1300 {
+			t.Errorf("node %d got %d of the 3000 item codes", i, n)
+		}
+	}
+}
+
+// Check that adding a node only moves to it a share of the item codes, the others keep their owner
+func TestRingService_AddingANodeMovesFewItemCodes(t *testing.T) {
+	nodes := []Node{{Name: "a", Service: &ringNode{}}, {Name: "b", Service: &ringNode{}}, {Name: "c", Service: &ringNode{}}}
+	before := NewRingService(RingPolicy{}, nodes...)
+	after := NewRingService(RingPolicy{}, append(nodes, Node{Name: "d", Service: &ringNode{}})...)
+	moved := 0
+	for _, itemCode := range ringItemCodes(4000) {
+		was, is := before.Owners(itemCode)[0], after.Owners(itemCode)[0]
+		if was == is {
+			continue
+		}
+		if is != "d" {
+			t.Fatalf("[%v] moved from %v to %v, only the new node should gain item codes", itemCode, was, is)
+		}
+		moved++
+	}
+	if moved < 700 || moved > 1300 {
+		t.Errorf("expected about a quarter of the item codes to move but %d of 4000 did", moved)
+	}
+}
+
+// Check that every item code has distinct owners, and that the next one is asked when the first fails
+func TestRingService_Replicas(t *testing.T) {
+	a, b := &ringNode{}, &ringNode{}
+	ring := NewRingService(RingPolicy{Replicas: 3}, Node{Name: "a", Service: a}, Node{Name: "b", Service: b})
+	owners := ring.Owners("p1")
+	if len(owners) != 2 || owners[0] == owners[1] {
+		t.Fatalf("expected both nodes to own p1 but got %v", owners)
+	}
+	first := map[string]*ringNode{"a": a, "b": b}[owners[0]]
+	first.down = true
+	cache := NewTransparentCache(ring, time.Minute)
+	assertFloat(t, 1, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	if source, _ := cache.SourceOf("p1"); source != owners[1] {
+		t.Errorf("expected p1 to come from %v but got %q", owners[1], source)
+	}
+
+	a.down, b.down = true, true
+	if _, err := ring.GetPriceFor("p1"); err == nil {
+		t.Error("expected an error when every owner fails")
+	}
+}
+
+// Check that ErrNotFound isn't asked to the other owners, they front the same catalog
+func TestRingService_NotFoundDoesNotFailOver(t *testing.T) {
+	missing := &mockPriceService{mockResults: map[string]mockResult{"p1": {err: ErrNotFound}}}
+	ring := NewRingService(RingPolicy{Replicas: 2}, Node{Name: "a", Service: missing}, Node{Name: "b", Service: missing})
+	if _, err := ring.GetPriceFor("p1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound but got %v", err)
+	}
+	assertInt(t, 1, missing.getNumCalls(), "only the first owner should have been asked")
+}
+
+// Check that a batch makes one call per bulk node, and asks the next owners for the items of a node that failed
+func TestRingService_GetPricesFor(t *testing.T) {
+	a, b, c := &bulkRingNode{}, &bulkRingNode{}, &bulkRingNode{}
+	ring := NewRingService(RingPolicy{Replicas: 2}, Node{Name: "a", Service: a}, Node{Name: "b", Service: b},
+		Node{Name: "c", Service: c})
+	itemCodes := ringItemCodes(100)
+	prices, err := ring.GetPricesFor(itemCodes...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertFloats(t, slices.Repeat([]float64{1}, 100), prices, "wrong prices returned")
+	for _, node := range []*bulkRingNode{a, b, c} {
+		assertInt(t, 1, node.bulkCalls, "every node should have been called once")
+	}
+
+	a.down = true
+	prices, err = ring.GetPricesFor(itemCodes...)
+	if err != nil {
+		t.Fatalf("the items of the node down should have been priced by their other owner: %v", err)
+	}
+	assertFloats(t, slices.Repeat([]float64{1}, 100), prices, "wrong prices returned")
+
+	b.down = true
+	prices, err = ring.GetPricesFor(itemCodes...)
+	var batchErr *BatchError[string]
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected a *BatchError but got %v", err)
+	}
+	for _, keyErr := range batchErr.Errors {
+		if owners := ring.Owners(keyErr.Key); slices.Contains(owners, "c") {
+			t.Errorf("[%v] is owned by %v, it should have been priced by c", keyErr.Key, owners)
+		}
+	}
+	for i, itemCode := range itemCodes {
+		if slices.Contains(ring.Owners(itemCode), "c") && prices[i] != 1 {
+			t.Errorf("[%v] should have been priced along with the failures", itemCode)
+		}
+	}
+}
+
+// partialRingNode is a ringNode whose batches fail as a whole when they have the item missing, returning no
+// prices and a *BatchError naming only that item, as grpccache.Client does
+type partialRingNode struct {
+	ringNode
+}
+
+func (n *partialRingNode) GetPriceFor(itemCode string) (float64, error) {
+	if itemCode == "missing" {
+		return 0, ErrNotFound
+	}
+	return n.ringNode.GetPriceFor(itemCode)
+}
+
+func (n *partialRingNode) GetPricesFor(itemCodes ...string) ([]float64, error) {
+	n.mu.Lock()
+	n.bulkCalls++
+	n.mu.Unlock()
+	if slices.Contains(itemCodes, "missing") {
+		return nil, &BatchError[string]{Errors: []*KeyError[string]{{Key: "missing", Err: ErrNotFound}}}
+	}
+	return slices.Repeat([]float64{1}, len(itemCodes)), nil
+}
+
+// Check that the items a bulk node returned no price nor error for are asked to it one by one, not failed
+func TestRingService_GetPricesFor_PartialBatchError(t *testing.T) {
+	node := &partialRingNode{}
+	ring := NewRingService(RingPolicy{}, Node{Name: "a", Service: node})
+	results := NewTransparentCache(ring, time.Minute).GetPriceResultsFor("a", "missing", "b")
+	for _, result := range results {
+		if result.Key == "missing" {
+			if !errors.Is(result.Err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound for [missing] but got %v", result.Err)
+			}
+		} else if result.Err != nil || result.Value != 1 {
+			t.Errorf("[%v] should have been priced one by one, got %v, %v", result.Key, result.Value, result.Err)
+		}
+	}
+	assertInt(t, 1, node.bulkCalls, "the node should have been called once for the batch")
+	assertInt(t, 2, node.numItems(), "the items left out should have been asked one by one")
+}
+
+// Check that two nodes can't have the same name
+func TestNewRingService_PanicsOnDuplicateNames(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected NewRingService to panic")
+		}
+	}()
+	NewRingService(RingPolicy{}, Node{Name: "a", Service: &ringNode{}}, Node{Name: "a", Service: &ringNode{}})
+}
+
+// Check that the classifier of the policy decides which errors are asked to the other owners
+func TestRingService_Classifier(t *testing.T) {
+	errDelisted := errors.New("delisted")
+	missing := &mockPriceService{mockResults: map[string]mockResult{"p1": {err: errDelisted}}}
+	ring := NewRingService(RingPolicy{Replicas: 2, Classifier: func(err error) ErrorClass {
+		if errors.Is(err, errDelisted) {
+			return Permanent
+		}
+		return DefaultErrorClassifier(err)
+	}}, Node{Name: "a", Service: missing}, Node{Name: "b", Service: missing})
+	if _, err := ring.GetPriceFor("p1"); !errors.Is(err, errDelisted) {
+		t.Errorf("expected the error of the owner but got %v", err)
+	}
+	assertInt(t, 1, missing.getNumCalls(), "only the first owner should have been asked")
+}
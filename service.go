@@ -0,0 +1,88 @@
+package sample1
+
+import "context"
+
+// ContextPriceService is a PriceService whose calls can be cancelled through a context
+// If the service given to the cache implements it, the cache will pass its context down to it
+type ContextPriceService interface {
+	GetPriceForCtx(ctx context.Context, itemCode string) (float64, error)
+}
+
+// AsContextPriceService returns a ContextPriceService for the given service
+// Services that don't know about contexts are wrapped so that the caller stops waiting as soon as
+// the context is done, even though the call to the service itself keeps running in the background
+func AsContextPriceService(service PriceService) ContextPriceService {
+	if ctxService, ok := service.(ContextPriceService); ok {
+		return ctxService
+	}
+	return contextAdapter{service: service}
+}
+
+// contextAdapter makes a plain PriceService honor context cancellation
+type contextAdapter struct {
+	service PriceService
+}
+
+func (a contextAdapter) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	return callWithContext(ctx, func() (float64, error) {
+		return a.service.GetPriceFor(itemCode)
+	})
+}
+
+// BulkPriceService is a PriceService that can price several items in one call, returning the prices
+// in the same order as the item codes
+// If the service given to the cache implements it, GetPricesFor loads all its misses with a single call
+// (or one per WithMaxBulkSize items), it can return a *BatchError[string] if only some items failed
+type BulkPriceService interface {
+	GetPricesFor(itemCodes ...string) ([]float64, error)
+}
+
+// ContextBulkPriceService is a BulkPriceService whose calls can be cancelled through a context
+type ContextBulkPriceService interface {
+	GetPricesForCtx(ctx context.Context, itemCodes ...string) ([]float64, error)
+}
+
+// bulkLoaderForService returns a bulk loader calling service, nil if it can't price several items at once
+func bulkLoaderForService(service PriceService) BulkLoaderFunc[string, float64] {
+	switch s := service.(type) {
+	case ContextBulkPriceService:
+		return func(ctx context.Context, itemCodes []string) ([]float64, error) {
+			return s.GetPricesForCtx(ctx, itemCodes...)
+		}
+	case BulkPriceService:
+		return func(ctx context.Context, itemCodes []string) ([]float64, error) {
+			return callWithContext(ctx, func() ([]float64, error) {
+				return s.GetPricesFor(itemCodes...)
+			})
+		}
+	default:
+		return nil
+	}
+}
+
+// callWithContext calls fn, but stops waiting for it as soon as ctx is done
+func callWithContext[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	if ctx.Done() == nil {
+		// the context can never be cancelled, don't pay for a goroutine
+		return fn()
+	}
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1) // buffered, so that the goroutine can finish if nobody is listening
+	go func() {
+		value, err := fn()
+		done <- result{value: value, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
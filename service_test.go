@@ -0,0 +1,83 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// ctxMockPriceService is a service that honors context cancellation
+type ctxMockPriceService struct {
+	mockPriceService
+	cancelled chan string // receives the item codes whose call was cancelled
+}
+
+func (m *ctxMockPriceService) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	select {
+	case <-time.After(m.callDelay):
+		m.mu.Lock()
+		m.numCalls++ // only the calls that answered count
+		result, ok := m.mockResults[itemCode]
+		m.mu.Unlock()
+		if !ok {
+			panic(fmt.Errorf("bug in the tests, we didn't have a mock result for [%v]", itemCode))
+		}
+		return result.price, result.err
+	case <-ctx.Done():
+		m.cancelled <- itemCode
+		return 0, ctx.Err()
+	}
+}
+
+// Check that a plain service is no longer waited on after the context is done
+func TestGetPriceForCtx_StopsWaitingOnCancel(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay: time.Second,
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := cache.GetPriceForCtx(ctx, "p1")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("call took too long, expected it to return when the context was done")
+	}
+}
+
+// Check that the context is passed down to services that support it, cancelling every batch fetch
+func TestGetPricesForCtx_CancelsOutstandingFetches(t *testing.T) {
+	mockService := &ctxMockPriceService{
+		mockPriceService: mockPriceService{
+			callDelay: time.Second,
+			mockResults: map[string]mockResult{
+				"p1": {price: 5, err: nil},
+				"p2": {price: 7, err: nil},
+			},
+		},
+		cancelled: make(chan string, 2),
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err := cache.GetPricesForCtx(ctx, "p1", "p2")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled, got %v", err)
+	}
+	// the calls are cancelled once the batch gave up on them, which happens right after it returned
+	for i := 0; i < 2; i++ {
+		select {
+		case <-mockService.cancelled:
+		case <-time.After(500 * time.Millisecond):
+			t.Fatalf("expected 2 cancelled calls, got %d", i)
+		}
+	}
+	assertInt(t, 0, mockService.getNumCalls(), "wrong number of service calls")
+}
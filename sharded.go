@@ -0,0 +1,117 @@
+package sample1
+
+import (
+	"hash/maphash"
+	"runtime"
+	"sync"
+)
+
+// ShardedStore is an in-memory Store split into hash-sharded segments with one lock each, so that
+// goroutines working on different keys rarely wait on each other. It is the default store of the cache
+type ShardedStore[K comparable, V any] struct {
+	seed   maphash.Seed
+	shards []storeShard[K, V]
+	mask   uint64 // len(shards)-1, the number of shards is a power of two
+}
+
+// storeShard is one segment of a ShardedStore, padded so that two shards don't share a cache line
+type storeShard[K comparable, V any] struct {
+	mu      sync.RWMutex
+	entries map[K]Entry[V]
+	_       [64]byte
+}
+
+// WithShards sets how many segments the default store is split into, rounded up to a power of two
+// A value of zero or less picks a number based on GOMAXPROCS, the option is ignored if WithStore is used
+func WithShards(n int) Option {
+	return func(c *config) {
+		c.shards = n
+	}
+}
+
+// NewShardedStore creates an empty store with n segments, rounded up to a power of two
+// A value of zero or less picks a number based on GOMAXPROCS
+func NewShardedStore[K comparable, V any](n int) *ShardedStore[K, V] {
+	if n <= 0 {
+		n = 4 * runtime.GOMAXPROCS(0)
+	}
+	size := 1
+	for size < n {
+		size <<= 1
+	}
+	s := &ShardedStore[K, V]{seed: maphash.MakeSeed(), shards: make([]storeShard[K, V], size), mask: uint64(size - 1)}
+	for i := range s.shards {
+		s.shards[i].entries = map[K]Entry[V]{}
+	}
+	return s
+}
+
+// shard returns the segment holding key
+func (s *ShardedStore[K, V]) shard(key K) *storeShard[K, V] {
+	return &s.shards[maphash.Comparable(s.seed, key)&s.mask]
+}
+
+func (s *ShardedStore[K, V]) Get(key K) (Entry[V], bool) {
+	shard := s.shard(key)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	entry, ok := shard.entries[key]
+	return entry, ok
+}
+
+func (s *ShardedStore[K, V]) Set(key K, entry Entry[V]) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	shard.entries[key] = entry
+}
+
+func (s *ShardedStore[K, V]) Delete(key K) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	delete(shard.entries, key)
+}
+
+// Clear empties the segments one after the other
+func (s *ShardedStore[K, V]) Clear() {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		shard.entries = map[K]Entry[V]{}
+		shard.mu.Unlock()
+	}
+}
+
+func (s *ShardedStore[K, V]) Len() int {
+	n := 0
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.RLock()
+		n += len(shard.entries)
+		shard.mu.RUnlock()
+	}
+	return n
+}
+
+// Range goes through the segments one after the other, only the segment being read is locked, so the
+// entries seen aren't a consistent snapshot of the store if it is written concurrently
+func (s *ShardedStore[K, V]) Range(fn func(key K, entry Entry[V]) bool) {
+	for i := range s.shards {
+		if !s.shards[i].each(fn) {
+			return
+		}
+	}
+}
+
+// each calls fn for every entry of the shard until it returns false, and tells whether it didn't
+func (shard *storeShard[K, V]) each(fn func(key K, entry Entry[V]) bool) bool {
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	for key, entry := range shard.entries {
+		if !fn(key, entry) {
+			return false
+		}
+	}
+	return true
+}
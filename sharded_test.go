@@ -0,0 +1,91 @@
+package sample1
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Check that the sharded store keeps, counts and drops entries across its segments
+func TestShardedStore(t *testing.T) {
+	store := NewShardedStore[string, float64](5)
+	assertInt(t, 8, len(store.shards), "the number of shards should be rounded up to a power of two")
+	for i := range 100 {
+		store.Set("p"+strconv.Itoa(i), Entry[float64]{Value: float64(i)})
+	}
+	assertInt(t, 100, store.Len(), "wrong number of entries")
+	entry, ok := store.Get("p42")
+	if !ok || entry.Value != 42 {
+		t.Errorf("expected p42 to be in the store, got %+v", entry)
+	}
+	seen := map[string]bool{}
+	store.Range(func(key string, entry Entry[float64]) bool {
+		seen[key] = true
+		return true
+	})
+	assertInt(t, 100, len(seen), "range should see every entry")
+	stops := 0
+	store.Range(func(key string, entry Entry[float64]) bool {
+		stops++
+		return false
+	})
+	assertInt(t, 1, stops, "range should stop when fn returns false")
+	store.Delete("p42")
+	if _, ok := store.Get("p42"); ok {
+		t.Error("p42 should have been deleted")
+	}
+	store.Clear()
+	assertInt(t, 0, store.Len(), "wrong number of entries")
+}
+
+// Check that the cache uses a sharded store unless told otherwise
+func TestWithShards(t *testing.T) {
+	cache := New(&mockPriceService{}, WithShards(64))
+	store, ok := cache.store.(*ShardedStore[string, float64])
+	if !ok {
+		t.Fatalf("expected a sharded store but got %T", cache.store)
+	}
+	assertInt(t, 64, len(store.shards), "wrong number of shards")
+}
+
+// benchmarkParallelHits serves hits for 1024 items from every goroutine of the benchmark, with one
+// write (Set) for every 16 reads
+func benchmarkParallelHits(b *testing.B, opts ...Option) {
+	cache := New(&mockPriceService{}, append([]Option{WithMaxAge(time.Hour)}, opts...)...)
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "p" + strconv.Itoa(i)
+		cache.Set(keys[i], float64(i))
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if i%16 == 0 {
+				cache.Set(key, 1)
+			} else if _, err := cache.GetPriceFor(key); err != nil {
+				b.Fatal(err)
+			}
+			i++
+		}
+	})
+}
+
+// Compare with go test -bench ParallelHits -cpu 1,8,16
+func BenchmarkParallelHits_MapStore(b *testing.B) {
+	benchmarkParallelHits(b, WithStore[string, float64](NewMapStore[string, float64]()))
+}
+
+func BenchmarkParallelHits_ShardedStore(b *testing.B) {
+	benchmarkParallelHits(b)
+}
+
+// The same hits, on a cache bounded by WithMaxEntries: every hit goes through the eviction policy
+func BenchmarkParallelHits_LRU(b *testing.B) {
+	benchmarkParallelHits(b, WithMaxEntries(2048))
+}
+
+func BenchmarkParallelHits_TinyLFU(b *testing.B) {
+	benchmarkParallelHits(b, WithMaxEntries(2048), WithEvictionPolicy(NewTinyLFUPolicy[string](2048)))
+}
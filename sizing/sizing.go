@@ -0,0 +1,128 @@
+// Package sizing tells how a cache would have done with other settings, replaying a trace of the lookups it
+// served (see TraceRecorder) against caches with other maxEntries and maxAge, so that they are chosen from the
+// hit ratios they get rather than guessed
+package sizing
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// Setting is a configuration of the cache to simulate
+type Setting struct {
+	MaxEntries int              // unbounded if zero
+	MaxAge     time.Duration    // sample1.DefaultMaxAge if zero
+	Options    []sample1.Option // the rest of the configuration (an eviction policy...), the fields above win over it
+}
+
+// maxAge returns the maxAge of the simulated cache
+func (s Setting) maxAge() time.Duration {
+	if s.MaxAge <= 0 {
+		return sample1.DefaultMaxAge
+	}
+	return s.MaxAge
+}
+
+func (s Setting) String() string {
+	maxEntries := "unbounded"
+	if s.MaxEntries > 0 {
+		maxEntries = fmt.Sprint(s.MaxEntries)
+	}
+	return fmt.Sprintf("maxEntries=%v maxAge=%v", maxEntries, s.maxAge())
+}
+
+// Grid returns a Setting for every pair of maxEntries and maxAges
+func Grid(maxEntries []int, maxAges []time.Duration) []Setting {
+	settings := make([]Setting, 0, len(maxEntries)*len(maxAges))
+	for _, n := range maxEntries {
+		for _, maxAge := range maxAges {
+			settings = append(settings, Setting{MaxEntries: n, MaxAge: maxAge})
+		}
+	}
+	return settings
+}
+
+// Result is how a Setting did on a trace
+type Result struct {
+	Setting   Setting
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// HitRatio returns the fraction of the lookups that were hits, zero if there was none
+func (r Result) HitRatio() float64 {
+	if r.Hits+r.Misses == 0 {
+		return 0
+	}
+	return float64(r.Hits) / float64(r.Hits+r.Misses)
+}
+
+// Simulate replays trace, in order of time, against a cache for every setting and tells how each of them did, in
+// the order of settings. The caches are in memory, on a clock following the times of the trace, in front of a
+// service pricing every item instantly
+// The settings refreshing in the background (WithStaleWhileRevalidate, WithRefreshAhead) aren't deterministic
+func Simulate(trace []Access, settings ...Setting) []Result {
+	trace = slices.Clone(trace)
+	slices.SortStableFunc(trace, func(a, b Access) int { return a.Time.Compare(b.Time) })
+	results := make([]Result, len(settings))
+	var wg sync.WaitGroup
+	for i, setting := range settings {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = simulate(trace, setting)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// simulate replays trace, sorted by time, against a cache configured as setting
+func simulate(trace []Access, setting Setting) Result {
+	clock := &traceClock{}
+	opts := append(slices.Clone(setting.Options), sample1.WithMaxEntries(setting.MaxEntries),
+		sample1.WithMaxAge(setting.maxAge()), sample1.WithClock(clock))
+	cache := sample1.New(freeService{}, opts...)
+	defer cache.Close()
+	for _, access := range trace {
+		clock.set(access.Time)
+		cache.GetPriceFor(access.ItemCode)
+	}
+	stats := cache.Stats()
+	return Result{Setting: setting, Hits: stats.Hits, Misses: stats.Misses, Evictions: stats.Evictions}
+}
+
+// traceClock is at the time of the access being replayed
+type traceClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *traceClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *traceClock) set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// freeService prices every item at zero, instantly
+type freeService struct{}
+
+func (freeService) GetPriceFor(itemCode string) (float64, error) {
+	return 0, nil
+}
+
+func (freeService) GetPriceForCtx(ctx context.Context, itemCode string) (float64, error) {
+	return 0, nil
+}
@@ -0,0 +1,82 @@
+package sizing
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	sample1 "github.com/MadHive/deviget_challenge"
+)
+
+// Check that every lookup of the cache is recorded, and read back
+func TestTraceRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	recorder := NewTraceRecorder(&buf)
+	cache := sample1.New(freeService{}, sample1.WithObserver(recorder))
+	cache.GetPriceFor("p1")
+	cache.GetPricesFor("p2", "p3")
+	cache.GetPriceFor("p1")
+	if err := recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+	trace, err := ReadTrace(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var itemCodes []string
+	for _, access := range trace {
+		if access.Time.IsZero() {
+			t.Errorf("expected the time of the lookup of [%v]", access.ItemCode)
+		}
+		itemCodes = append(itemCodes, access.ItemCode)
+	}
+	slices.Sort(itemCodes)
+	if !slices.Equal(itemCodes, []string{"p1", "p1", "p2", "p3"}) {
+		t.Errorf("expected every lookup in the trace but got %v", itemCodes)
+	}
+	if _, err := ReadTrace(strings.NewReader("{\"itemCode\": \"p1\"}\n\nnot json\n")); err == nil || !strings.HasPrefix(err.Error(), "line 3") {
+		t.Errorf("expected an error naming the line, got %v", err)
+	}
+}
+
+// Check that every setting replays the trace, in order of time, with its own maxEntries and maxAge
+func TestSimulate(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(itemCode string, seconds int) Access {
+		return Access{ItemCode: itemCode, Time: start.Add(time.Duration(seconds) * time.Second)}
+	}
+	// out of order, as the lookups of a batch can be recorded
+	trace := []Access{at("a", 0), at("a", 2), at("b", 1), at("c", 3), at("a", 4), at("a", 70)}
+	results := Simulate(trace,
+		Setting{MaxAge: time.Minute},
+		Setting{MaxEntries: 1, MaxAge: time.Hour},
+		Setting{MaxEntries: 2, MaxAge: time.Hour},
+	)
+	for i, want := range []struct{ hits, misses, evictions uint64 }{{2, 4, 0}, {1, 5, 4}, {3, 3, 1}} {
+		got := results[i]
+		if got.Hits != want.hits || got.Misses != want.misses || got.Evictions != want.evictions {
+			t.Errorf("%v : expected %d hits, %d misses and %d evictions but got %+v", got.Setting, want.hits, want.misses, want.evictions, got)
+		}
+	}
+	if ratio := results[2].HitRatio(); ratio != 0.5 {
+		t.Errorf("expected a hit ratio of 0.5 but got %v", ratio)
+	}
+}
+
+// Check that Grid crosses the maxEntries with the maxAges
+func TestGrid(t *testing.T) {
+	settings := Grid([]int{0, 100}, []time.Duration{0, time.Minute})
+	var names []string
+	for _, setting := range settings {
+		names = append(names, setting.String())
+	}
+	want := []string{
+		"maxEntries=unbounded maxAge=" + sample1.DefaultMaxAge.String(), "maxEntries=unbounded maxAge=1m0s",
+		"maxEntries=100 maxAge=" + sample1.DefaultMaxAge.String(), "maxEntries=100 maxAge=1m0s",
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("expected the settings %v but got %v", want, names)
+	}
+}
@@ -0,0 +1,76 @@
+package sizing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Access is a lookup of a trace, for an item at a time
+type Access struct {
+	ItemCode string    `json:"itemCode"`
+	Time     time.Time `json:"time"`
+}
+
+// TraceRecorder is a sample1.Observer writing every lookup of the cache (the keys of the batches included) as a
+// line of JSON, the trace ReadTrace reads back. Use it with sample1.WithObserver
+// It is safe for concurrent use by multiple goroutines
+type TraceRecorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error // the first write that failed, nothing is written after it
+}
+
+// NewTraceRecorder creates a TraceRecorder writing to w
+func NewTraceRecorder(w io.Writer) *TraceRecorder {
+	return &TraceRecorder{enc: json.NewEncoder(w)}
+}
+
+// Err returns the error of the first write that failed, the trace stops there
+func (r *TraceRecorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *TraceRecorder) StartLookup(ctx context.Context, key any) (context.Context, func(bool, error)) {
+	access := Access{ItemCode: fmt.Sprint(key), Time: time.Now()}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = r.enc.Encode(access)
+	}
+	return ctx, func(bool, error) {}
+}
+
+func (r *TraceRecorder) StartBatch(ctx context.Context, keys int) (context.Context, func(error)) {
+	return ctx, func(error) {}
+}
+
+func (r *TraceRecorder) StartLoad(ctx context.Context, key any) (context.Context, func(error)) {
+	return ctx, func(error) {}
+}
+
+// ReadTrace reads a trace written by a TraceRecorder, the blank lines are skipped
+func ReadTrace(r io.Reader) ([]Access, error) {
+	var trace []Access
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var access Access
+		if err := json.Unmarshal(scanner.Bytes(), &access); err != nil {
+			return nil, fmt.Errorf("line %d : %w", line, err)
+		}
+		trace = append(trace, access)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading the trace : %w", err)
+	}
+	return trace, nil
+}
@@ -0,0 +1,162 @@
+package sample1
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotVersion is written at the start of every snapshot, Load rejects the ones it doesn't know
+const snapshotVersion = 1
+
+type snapshotHeader struct {
+	Version int
+	Entries int
+}
+
+// snapshotEntry is how an entry is kept in a snapshot
+type snapshotEntry[K comparable, V any] struct {
+	Key       K
+	Value     V
+	FetchedAt time.Time
+	LoadTime  time.Duration
+	MaxAge    time.Duration
+	Source    string
+}
+
+// Save writes the values in the cache to w with encoding/gob, so that Load can bring them back after a restart
+// Keys and values must be encodable by gob (interface types need gob.Register), negative entries aren't saved
+func (c *Cache[K, V]) Save(w io.Writer) error {
+	var entries []snapshotEntry[K, V]
+	c.store.Range(func(key K, entry Entry[V]) bool {
+		if entry.Err == nil {
+			entries = append(entries, snapshotEntry[K, V]{
+				Key: key, Value: entry.Value, FetchedAt: entry.FetchedAt, LoadTime: entry.LoadTime, MaxAge: entry.MaxAge,
+				Source: entry.Source,
+			})
+		}
+		return true
+	})
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(snapshotHeader{Version: snapshotVersion, Entries: len(entries)}); err != nil {
+		return fmt.Errorf("saving the snapshot : %w", err)
+	}
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("saving [%v] : %w", entry.Key, err)
+		}
+	}
+	return nil
+}
+
+// Load adds the values saved by Save, keeping how old they are: values too old to be served, even stale,
+// are skipped, and so are the ones older than what the cache already has
+func (c *Cache[K, V]) Load(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("loading the snapshot : %w", err)
+	}
+	if header.Version != snapshotVersion {
+		return fmt.Errorf("loading the snapshot : unknown version %d", header.Version)
+	}
+	now := c.clock.Now()
+	for i := 0; i < header.Entries; i++ {
+		var e snapshotEntry[K, V]
+		if err := dec.Decode(&e); err != nil {
+			return fmt.Errorf("loading the snapshot : %w", err)
+		}
+		entry := Entry[V]{Value: e.Value, FetchedAt: e.FetchedAt, LoadTime: e.LoadTime, MaxAge: e.MaxAge, Source: e.Source}
+		if c.expired(entry, entry.MaxAge+max(c.maxStale, c.staleIfError), now) {
+			continue
+		}
+		var evicted []Event[K, V]
+		c.mu.Lock()
+		if current, ok := c.store.Get(e.Key); !ok || current.FetchedAt.Before(entry.FetchedAt) {
+			evicted = c.save(e.Key, entry)
+		}
+		c.mu.Unlock()
+		c.emit(evicted...)
+	}
+	return nil
+}
+
+// WithSnapshots makes the cache load the snapshot at path when it is created, and save a new one every interval
+// and when it is closed, so that a restarted process doesn't start with a cold cache
+// Snapshots are saved and loaded like Save and Load do, their errors are given to onError if it isn't nil
+// (a missing snapshot at startup isn't an error)
+func WithSnapshots(path string, interval time.Duration, onError func(error)) Option {
+	return func(c *config) {
+		c.snapshotPath = path
+		c.snapshotInterval = interval
+		c.onSnapshotError = onError
+	}
+}
+
+// snapshotter saves the snapshots of a cache in the background
+type snapshotter struct {
+	path     string
+	interval time.Duration
+	onError  func(error)
+}
+
+// startSnapshots loads the configured snapshot and starts saving new ones
+func (c *Cache[K, V]) startSnapshots(cfg config) {
+	if cfg.snapshotPath == "" {
+		return
+	}
+	s := &snapshotter{
+		path:     cfg.snapshotPath,
+		interval: cfg.snapshotInterval,
+		onError:  cfg.onSnapshotError,
+	}
+	c.snapshots = s
+	if err := c.loadFile(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		s.fail(err)
+	}
+	c.every(s.interval, func() {
+		if err := c.saveFile(s.path); err != nil {
+			s.fail(err)
+		}
+	})
+}
+
+// saveFile saves a snapshot to path, through a temporary file so that a crash never leaves half a snapshot
+func (c *Cache[K, V]) saveFile(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("saving the snapshot : %w", err)
+	}
+	defer os.Remove(f.Name()) // fails once renamed
+	if err := c.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("saving the snapshot : %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("saving the snapshot : %w", err)
+	}
+	return nil
+}
+
+func (c *Cache[K, V]) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.Load(f)
+}
+
+func (s *snapshotter) fail(err error) {
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
@@ -0,0 +1,118 @@
+package sample1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Check that a cache loaded from a snapshot answers without calling the service
+func TestSave_LoadBringsBackTheValues(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+			"p3": {price: 0, err: ErrNotFound},
+		},
+	}
+	cache := New(mockService, WithNegativeCaching(time.Minute, nil))
+	getPricesWithNoErr(t, cache, "p1", "p2")
+	cache.GetPriceFor("p3")
+	var snapshot bytes.Buffer
+	if err := cache.Save(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := New(mockService)
+	if err := restarted.Load(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 2, restarted.Len(), "negative entries should not have been saved")
+	assertFloats(t, []float64{5, 7}, getPricesWithNoErr(t, restarted, "p1", "p2"), "wrong price returned")
+	assertInt(t, 3, mockService.getNumCalls(), "the restarted cache should not have called the service")
+}
+
+// Check that values keep their age through a snapshot and are skipped once too old
+func TestSave_LoadKeepsTheAge(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(func(_ context.Context, key string) (float64, error) { return 5, nil },
+		WithMaxAge(time.Minute), WithClock(clock))
+	cache.Set("old", 1)
+	clock.Advance(50 * time.Second)
+	cache.Set("new", 2)
+	var snapshot bytes.Buffer
+	if err := cache.Save(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(20 * time.Second)
+	restarted := NewCache(func(_ context.Context, key string) (float64, error) { return 5, nil },
+		WithMaxAge(time.Minute), WithClock(clock))
+	if err := restarted.Load(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := restarted.Peek("old"); ok {
+		t.Error("the expired value should have been skipped")
+	}
+	if _, age, ok := restarted.Peek("new"); !ok || age != 20*time.Second {
+		t.Errorf("the value should have been loaded with its age, got %v, %v", age, ok)
+	}
+}
+
+// Check that garbage is rejected
+func TestLoad_RejectsBadSnapshots(t *testing.T) {
+	cache := New(&mockPriceService{})
+	if err := cache.Load(bytes.NewReader([]byte("not a snapshot"))); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+// Check that snapshots are saved periodically and on Close, and loaded when the cache is created
+func TestWithSnapshots_WarmRestart(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "prices.snapshot")
+	onError := func(err error) { t.Error(err) }
+	cache := New(mockService, WithSnapshots(path, 10*time.Millisecond, onError))
+	getPriceWithNoErr(t, cache, "p1")
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("no snapshot was saved: %v", err)
+	}
+	getPriceWithNoErr(t, cache, "p2")
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := New(mockService, WithSnapshots(path, 0, onError))
+	defer restarted.Close()
+	assertFloats(t, []float64{5, 7}, getPricesWithNoErr(t, restarted, "p1", "p2"), "wrong price returned")
+	assertInt(t, 2, mockService.getNumCalls(), "the restarted cache should not have called the service")
+}
+
+// Check that a missing snapshot isn't an error but a broken one is reported
+func TestWithSnapshots_StartupErrors(t *testing.T) {
+	var errs []error
+	onError := func(err error) { errs = append(errs, err) }
+	path := filepath.Join(t.TempDir(), "prices.snapshot")
+	New(&mockPriceService{}, WithSnapshots(path, 0, onError))
+	assertInt(t, 0, len(errs), "a missing snapshot should not be an error")
+
+	os.WriteFile(path, []byte("not a snapshot"), 0o600)
+	New(&mockPriceService{}, WithSnapshots(path, 0, onError))
+	if len(errs) != 1 || errors.Is(errs[0], os.ErrNotExist) {
+		t.Errorf("expected the broken snapshot to be reported, got %v", errs)
+	}
+}
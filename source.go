@@ -0,0 +1,55 @@
+package sample1
+
+import (
+	"context"
+	"sync"
+)
+
+// sourceKey is the context key of the sourceSink of a load
+type sourceKey struct{}
+
+// sourceSink keeps the source reported for a load, the first report wins so that a hedged call
+// finishing late can't overwrite the source of the value that was kept
+type sourceSink struct {
+	mu     sync.Mutex
+	source string
+	set    bool
+}
+
+// ReportSource records where the value being loaded came from, so that the cache keeps it with the entry
+// (see SourceOf)
+// Loaders and services call it with the context they were given, it does nothing outside of a cache load
+func ReportSource(ctx context.Context, source string) {
+	sink, ok := ctx.Value(sourceKey{}).(*sourceSink)
+	if !ok {
+		return
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if !sink.set {
+		sink.source, sink.set = source, true
+	}
+}
+
+// withSourceSink returns a context where the source of a load can be reported, and the sink that keeps it
+func withSourceSink(ctx context.Context) (context.Context, *sourceSink) {
+	sink := &sourceSink{}
+	return context.WithValue(ctx, sourceKey{}, sink), sink
+}
+
+// get returns the reported source, empty if there was none
+func (s *sourceSink) get() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.source
+}
+
+// SourceOf returns where the value cached for key came from, as reported by the loader with ReportSource
+// ok is false if there is no value cached for key, the source is empty if the loader didn't report it
+func (c *Cache[K, V]) SourceOf(key K) (source string, ok bool) {
+	entry, ok := c.store.Get(key)
+	if !ok || entry.Err != nil {
+		return "", false
+	}
+	return entry.Source, true
+}
@@ -0,0 +1,130 @@
+package sample1
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Check that expired prices are served right away while they are refreshed in the background
+func TestGetPriceFor_StaleWhileRevalidate(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay: 100 * time.Millisecond,
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	maxAge := 50 * time.Millisecond
+	cache := NewTransparentCache(mockService, maxAge, WithStaleWhileRevalidate(time.Second))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	time.Sleep(maxAge * 2)
+
+	mockService.mu.Lock()
+	mockService.mockResults = map[string]mockResult{"p1": {price: 6, err: nil}}
+	mockService.mu.Unlock()
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "expected the stale price")
+	}
+	if time.Since(start) > 50*time.Millisecond {
+		t.Error("stale prices should be returned without waiting for the service")
+	}
+	time.Sleep(125 * time.Millisecond) // the refresh is done after 100ms, and its price stays fresh until 150ms
+	assertFloat(t, 6, getPriceWithNoErr(t, cache, "p1"), "expected the refreshed price")
+	assertInt(t, 2, mockService.getNumCalls(), "only one refresh should have been made")
+}
+
+// Check that values older than the staleness window are not served
+func TestGetPriceFor_StaleWhileRevalidateHonorsMaxStale(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	maxAge := 20 * time.Millisecond
+	cache := NewTransparentCache(mockService, maxAge, WithStaleWhileRevalidate(maxAge))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	time.Sleep(maxAge * 3)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 2, mockService.getNumCalls(), "a too old price should be fetched synchronously")
+}
+
+// Check that the last known price is served when the service fails, as long as it is not too old
+func TestGetPriceFor_StaleIfError(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	maxAge := 20 * time.Millisecond
+	cache := NewTransparentCache(mockService, maxAge, WithStaleIfError(100*time.Millisecond))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	mockService.mu.Lock()
+	mockService.mockResults = map[string]mockResult{"p1": {price: 0, err: fmt.Errorf("some error")}}
+	mockService.mu.Unlock()
+
+	time.Sleep(maxAge * 2)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "expected the last known price")
+	assertInt(t, 1, int(cache.Stats().StaleServed), "wrong number of stale prices served")
+
+	time.Sleep(150 * time.Millisecond)
+	if _, err := cache.GetPriceFor("p1"); err == nil {
+		t.Error("expected an error once the last known price is too old")
+	}
+}
+
+// Check that past the soft TTL the price is served while refreshed, and that past the hard TTL it is never served,
+// not even when the service fails
+func TestWithSoftTTL(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	clock := newFakeClock()
+	cache := New(mockService, WithClock(clock), WithSoftTTL(time.Minute, 3*time.Minute), WithStaleIfError(time.Hour))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+
+	clock.Advance(2 * time.Minute)
+	mockService.setPrice("p1", 6)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "expected the price past its soft TTL")
+	for deadline := time.Now().Add(time.Second); ; {
+		if price, _, _ := cache.Peek("p1"); price == 6 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the price wasn't refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	mockService.mu.Lock()
+	mockService.mockResults = map[string]mockResult{"p1": {price: 0, err: fmt.Errorf("some error")}}
+	mockService.mu.Unlock()
+	clock.Advance(3*time.Minute + time.Second)
+	if _, ok := cache.TTL("p1"); ok {
+		t.Error("a price past its hard TTL shouldn't have a TTL")
+	}
+	if _, err := cache.GetPriceFor("p1"); err == nil {
+		t.Error("expected an error rather than a price past its hard TTL")
+	}
+}
+
+// Check that the hard TTL also bounds the maxAge set for a key
+func TestWithSoftTTL_BoundsMaxAgeFor(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	clock := newFakeClock()
+	cache := New(mockService, WithClock(clock), WithSoftTTL(time.Minute, 2*time.Minute))
+	cache.SetMaxAgeFor("p1", time.Hour)
+	getPriceWithNoErr(t, cache, "p1")
+	if ttl, _ := cache.TTL("p1"); ttl != 2*time.Minute {
+		t.Errorf("expected the TTL to be bounded by the hard TTL, got %v", ttl)
+	}
+	clock.Advance(2*time.Minute + time.Second)
+	getPriceWithNoErr(t, cache, "p1")
+	assertInt(t, 2, mockService.getNumCalls(), "a price past its hard TTL should be fetched synchronously")
+}
@@ -0,0 +1,93 @@
+package sample1
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the cache counters since it was created
+type Stats struct {
+	Hits        uint64        // values returned from the cache
+	Misses      uint64        // values that were not cached or too old
+	Loads       uint64        // values asked to the loader (the actual service), a bulk load counts once per key
+	LoadErrors  uint64        // calls to the loader that failed
+	Evictions   uint64        // entries dropped to stay within max entries
+	StaleServed uint64        // expired values returned because loading a fresh one failed
+	LoadCalls   uint64        // calls to the loader, a bulk load counts once
+	LoadTime    time.Duration // time spent waiting for the loader, over all the calls
+	// Latencies are how long the lookups took, by Outcome (Latencies[OutcomeHit] for the hits), a batch counts
+	// once per key
+	Latencies [numOutcomes]LatencyHistogram
+}
+
+// HitRatio returns the fraction of lookups that were answered from the cache, zero if there were none
+func (s Stats) HitRatio() float64 {
+	return hitRatio(s.Hits, s.Misses)
+}
+
+// AverageLoadTime returns how long a call to the loader takes on average, zero if there were none
+func (s Stats) AverageLoadTime() time.Duration {
+	if s.LoadCalls == 0 {
+		return 0
+	}
+	return s.LoadTime / time.Duration(s.LoadCalls)
+}
+
+// hitRatio returns the fraction of hits among the lookups, zero if there were none
+func hitRatio(hits, misses uint64) float64 {
+	total := hits + misses
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total)
+}
+
+// counters are updated atomically, so that recording them doesn't need the cache lock
+type counters struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	loads       atomic.Uint64
+	loadErrors  atomic.Uint64
+	evictions   atomic.Uint64
+	staleServed atomic.Uint64
+	loadCalls   atomic.Uint64
+	loadTime    atomic.Int64   // in nanoseconds
+	recent      recentCounters // the hits and misses of the last MaxStatsWindow, see StatsOver
+	latencies   [numOutcomes]latencyHistogram
+}
+
+// Stats returns the current value of the cache counters
+func (c *Cache[K, V]) Stats() Stats {
+	stats := Stats{
+		Hits:        c.counters.hits.Load(),
+		Misses:      c.counters.misses.Load(),
+		Loads:       c.counters.loads.Load(),
+		LoadErrors:  c.counters.loadErrors.Load(),
+		Evictions:   c.counters.evictions.Load(),
+		StaleServed: c.counters.staleServed.Load(),
+		LoadCalls:   c.counters.loadCalls.Load(),
+		LoadTime:    time.Duration(c.counters.loadTime.Load()),
+	}
+	for i := range c.counters.latencies {
+		stats.Latencies[i] = c.counters.latencies[i].snapshot()
+	}
+	return stats
+}
+
+// loaded counts a call to the loader that took loadTime
+func (c *counters) loaded(loadTime time.Duration) {
+	c.loadCalls.Add(1)
+	c.loadTime.Add(int64(loadTime))
+}
+
+// missed counts a lookup of key that wasn't answered from the cache
+func (c *Cache[K, V]) missed(key K) {
+	now := c.clock.Now()
+	c.counters.misses.Add(1)
+	c.counters.recent.bucket(now).misses.Add(1)
+	c.checkHitRatio(now)
+	c.logMiss(key)
+	if c.hotKeys.enabled() {
+		c.hotKeys.miss(key, now)
+	}
+}
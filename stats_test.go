@@ -0,0 +1,55 @@
+package sample1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Check that every lookup, load and eviction is counted
+func TestStats_CountsCacheActivity(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+			"p3": {price: 0, err: fmt.Errorf("some error")},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxEntries(1))
+	getPriceWithNoErr(t, cache, "p1")
+	getPriceWithNoErr(t, cache, "p1")
+	getPriceWithNoErr(t, cache, "p2") // evicts "p1"
+	cache.GetPriceFor("p3")
+	stats := cache.Stats()
+	assertInt(t, 1, int(stats.Hits), "wrong number of hits")
+	assertInt(t, 3, int(stats.Misses), "wrong number of misses")
+	assertInt(t, 3, int(stats.Loads), "wrong number of loads")
+	assertInt(t, 1, int(stats.LoadErrors), "wrong number of load errors")
+	assertInt(t, 1, int(stats.Evictions), "wrong number of evictions")
+	assertFloat(t, 0.25, stats.HitRatio(), "wrong hit ratio")
+}
+
+// Check that the calls to the loader are timed, a bulk load counting once
+func TestStats_LoadTime(t *testing.T) {
+	clock := newFakeClock()
+	cache := NewCache(func(_ context.Context, key int) (int, error) {
+		clock.Advance(time.Second)
+		return key, nil
+	}, WithClock(clock), WithBulkLoader(func(_ context.Context, keys []int) ([]int, error) {
+		clock.Advance(time.Second)
+		return keys, nil
+	}))
+	cache.Get(context.Background(), 1)
+	cache.GetMany(context.Background(), 2, 3)
+	stats := cache.Stats()
+	assertInt(t, 2, int(stats.LoadCalls), "wrong number of calls to the loader")
+	assertInt(t, 3, int(stats.Loads), "wrong number of loads")
+	assertInt(t, int(2*time.Second), int(stats.LoadTime), "wrong load time")
+	assertInt(t, int(time.Second), int(stats.AverageLoadTime()), "wrong average load time")
+	assertInt(t, 0, int(Stats{}.AverageLoadTime()), "the average load time should be zero without loads")
+}
+
+func TestStats_HitRatioWithoutLookups(t *testing.T) {
+	assertFloat(t, 0, Stats{}.HitRatio(), "wrong hit ratio")
+}
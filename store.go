@@ -0,0 +1,119 @@
+package sample1
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Entry is a value as the cache stores it, together with the moment it was loaded
+type Entry[V any] struct {
+	Value     V
+	FetchedAt time.Time
+	LoadTime  time.Duration // how long the loader took to return the value, zero if it was set manually
+	MaxAge    time.Duration // how long the value stays fresh, the cache maxAge unless jitter or an override applied
+	Err       error         // the load error of a negative entry, Value is meaningless if it is set
+	Source    string        // where the value came from, as reported by the loader with ReportSource
+}
+
+// expired tells if the entry is older than maxAge at the given moment
+func (e Entry[V]) expired(maxAge time.Duration, now time.Time) bool {
+	return now.Sub(e.FetchedAt) > maxAge
+}
+
+// Store is where a cache keeps its entries, an in-memory sharded map (ShardedStore) unless another one is given with WithStore
+// The cache decides what is fresh and what to evict, the store only keeps the entries
+// Implementations must be safe for concurrent use, stores backed by remote systems should treat their
+// failures as misses (the cache will load the value again) and report them by their own means
+type Store[K comparable, V any] interface {
+	Get(key K) (Entry[V], bool)
+	Set(key K, entry Entry[V])
+	Delete(key K)
+	// Clear deletes every entry
+	Clear()
+	// Len returns the number of entries, expired ones included
+	Len() int
+	// Range calls fn for every entry until it returns false, fn must not call back into the store
+	Range(fn func(key K, entry Entry[V]) bool)
+}
+
+// BatchStore is a Store that can read several entries at once, GetMany (and GetPricesFor) use it
+// to read the whole batch from the store in one call instead of one call per key
+type BatchStore[K comparable, V any] interface {
+	Store[K, V]
+	// GetMany returns the entries for keys, found[i] tells whether there was an entry for keys[i]
+	GetMany(keys []K) (entries []Entry[V], found []bool)
+}
+
+// WithStore sets where the cache keeps its entries
+// The store must be keyed and hold values of the same types as the cache, NewCache panics otherwise
+func WithStore[K comparable, V any](store Store[K, V]) Option {
+	return func(c *config) {
+		c.store = store
+	}
+}
+
+// storeFor returns the configured store for a cache of K and V, a new ShardedStore with shards segments
+// if none was configured
+func storeFor[K comparable, V any](store any, shards int) Store[K, V] {
+	if store == nil {
+		return NewShardedStore[K, V](shards)
+	}
+	s, ok := store.(Store[K, V])
+	if !ok {
+		panic(fmt.Sprintf("sample1: store %T can't be used for a cache of %T", store, (*Cache[K, V])(nil)))
+	}
+	return s
+}
+
+// MapStore is an in-memory Store, a single map behind a single lock
+type MapStore[K comparable, V any] struct {
+	mu      sync.RWMutex
+	entries map[K]Entry[V]
+}
+
+// NewMapStore creates an empty in-memory store
+func NewMapStore[K comparable, V any]() *MapStore[K, V] {
+	return &MapStore[K, V]{entries: map[K]Entry[V]{}}
+}
+
+func (s *MapStore[K, V]) Get(key K) (Entry[V], bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.entries[key]
+	return entry, ok
+}
+
+func (s *MapStore[K, V]) Set(key K, entry Entry[V]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = entry
+}
+
+func (s *MapStore[K, V]) Delete(key K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+func (s *MapStore[K, V]) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = map[K]Entry[V]{}
+}
+
+func (s *MapStore[K, V]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+func (s *MapStore[K, V]) Range(fn func(key K, entry Entry[V]) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for key, entry := range s.entries {
+		if !fn(key, entry) {
+			return
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package sample1
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingStore is a Store that counts how many times each operation was called
+type countingStore struct {
+	*MapStore[string, float64]
+	mu   sync.Mutex
+	gets int
+	sets int
+}
+
+func (s *countingStore) Get(key string) (Entry[float64], bool) {
+	s.mu.Lock()
+	s.gets++
+	s.mu.Unlock()
+	return s.MapStore.Get(key)
+}
+
+func (s *countingStore) Set(key string, entry Entry[float64]) {
+	s.mu.Lock()
+	s.sets++
+	s.mu.Unlock()
+	s.MapStore.Set(key, entry)
+}
+
+// Check that the cache keeps its entries in the configured store
+func TestWithStore_UsesTheGivenStore(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	store := &countingStore{MapStore: NewMapStore[string, float64]()}
+	store.MapStore.Set("p2", Entry[float64]{Value: 7, FetchedAt: time.Now(), MaxAge: time.Minute})
+	cache := NewTransparentCache(mockService, time.Minute, WithStore[string, float64](store))
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertFloat(t, 7, getPriceWithNoErr(t, cache, "p2"), "price already in the store should be used")
+	assertInt(t, 1, mockService.getNumCalls(), "wrong number of service calls")
+	assertInt(t, 1, store.sets, "wrong number of store sets")
+	if store.gets < 2 {
+		t.Errorf("expected the store to be read, got %v gets", store.gets)
+	}
+	entry, ok := store.MapStore.Get("p1")
+	if !ok || entry.Value != 5 || entry.MaxAge != time.Minute {
+		t.Errorf("expected p1 to be in the store, got %+v", entry)
+	}
+}
+
+func TestMapStore_RangeAndClear(t *testing.T) {
+	store := NewMapStore[string, float64]()
+	store.Set("p1", Entry[float64]{Value: 5})
+	store.Set("p2", Entry[float64]{Value: 7})
+	seen := 0
+	store.Range(func(key string, entry Entry[float64]) bool {
+		seen++
+		return false
+	})
+	assertInt(t, 1, seen, "range should stop when fn returns false")
+	store.Delete("p1")
+	assertInt(t, 1, store.Len(), "wrong number of entries")
+	store.Clear()
+	assertInt(t, 0, store.Len(), "wrong number of entries")
+}
+
+// Check that a store of the wrong types is reported when the cache is created
+func TestWithStore_PanicsOnTypeMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	New(&mockPriceService{}, WithStore[string, int](NewMapStore[string, int]()))
+}
+
+// batchCountingStore is a countingStore that can read several entries at once
+type batchCountingStore struct {
+	countingStore
+	batches int
+}
+
+func (s *batchCountingStore) GetMany(keys []string) ([]Entry[float64], []bool) {
+	s.mu.Lock()
+	s.batches++
+	s.mu.Unlock()
+	entries := make([]Entry[float64], len(keys))
+	found := make([]bool, len(keys))
+	for i, key := range keys {
+		entries[i], found[i] = s.MapStore.Get(key)
+	}
+	return entries, found
+}
+
+// Check that a batch reads the store in one call when it supports it
+func TestWithStore_BatchReadsUseGetMany(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+		},
+	}
+	store := &batchCountingStore{countingStore: countingStore{MapStore: NewMapStore[string, float64]()}}
+	cache := NewTransparentCache(mockService, time.Minute, WithStore[string, float64](store))
+	assertFloats(t, []float64{5, 7}, getPricesWithNoErr(t, cache, "p1", "p2"), "wrong price returned")
+	assertFloats(t, []float64{5, 7}, getPricesWithNoErr(t, cache, "p1", "p2"), "wrong price returned")
+	assertInt(t, 2, store.batches, "wrong number of batch reads")
+	assertInt(t, 0, store.gets, "no single reads should have been made")
+	assertInt(t, 2, mockService.getNumCalls(), "wrong number of service calls")
+}
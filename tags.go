@@ -0,0 +1,101 @@
+package sample1
+
+import "fmt"
+
+// TaggerFunc returns the tags of a value (a supplier, a category...), so that every value under a tag
+// can be invalidated at once with InvalidateTag
+type TaggerFunc[K comparable, V any] func(key K, value V) []string
+
+// WithTagger makes the cache tag every value it stores (loaded, set or restored from a snapshot) with fn
+// fn must take keys and values of the same types as the cache, NewCache panics otherwise
+func WithTagger[K comparable, V any](fn TaggerFunc[K, V]) Option {
+	return func(c *config) {
+		c.tagger = fn
+	}
+}
+
+// taggerFor returns the configured tagger for a cache of K and V, nil if none was configured
+func taggerFor[K comparable, V any](fn any) TaggerFunc[K, V] {
+	if fn == nil {
+		return nil
+	}
+	tagger, ok := fn.(TaggerFunc[K, V])
+	if !ok {
+		panic(fmt.Sprintf("sample1: tagger %T can't be used for a cache of %T", fn, (*Cache[K, V])(nil)))
+	}
+	return tagger
+}
+
+// tagIndex keeps the keys under every tag, it is guarded by the mutex of the cache
+type tagIndex[K comparable] struct {
+	keys map[string]map[K]struct{} // the keys under each tag
+	tags map[K][]string            // the tags of each key
+}
+
+// set replaces the tags of key
+func (t *tagIndex[K]) set(key K, tags []string) {
+	t.remove(key)
+	if len(tags) == 0 {
+		return
+	}
+	if t.keys == nil {
+		t.keys = map[string]map[K]struct{}{}
+		t.tags = map[K][]string{}
+	}
+	t.tags[key] = tags
+	for _, tag := range tags {
+		if t.keys[tag] == nil {
+			t.keys[tag] = map[K]struct{}{}
+		}
+		t.keys[tag][key] = struct{}{}
+	}
+}
+
+// remove forgets the tags of key
+func (t *tagIndex[K]) remove(key K) {
+	for _, tag := range t.tags[key] {
+		delete(t.keys[tag], key)
+		if len(t.keys[tag]) == 0 {
+			delete(t.keys, tag)
+		}
+	}
+	delete(t.tags, key)
+}
+
+// clear forgets every tag
+func (t *tagIndex[K]) clear() {
+	t.keys, t.tags = nil, nil
+}
+
+// InvalidateTag drops the cached values tagged with tag (see WithTagger), so that the next Get of those
+// keys loads them again
+func (c *Cache[K, V]) InvalidateTag(tag string) {
+	listening := c.listeners.has(Invalidated)
+	c.mu.Lock()
+	c.generation++
+	keys := make([]K, 0, len(c.tags.keys[tag]))
+	for key := range c.tags.keys[tag] {
+		keys = append(keys, key)
+	}
+	var events []Event[K, V]
+	for _, key := range keys {
+		if entry, ok := c.remove(key); ok && listening {
+			events = append(events, invalidated(key, entry))
+		}
+	}
+	c.mu.Unlock()
+	c.publishInvalidation(keys)
+	c.emit(events...)
+}
+
+// tag indexes the tags of the entry just stored for key, c.mu must be held for writing
+func (c *Cache[K, V]) tag(key K, entry Entry[V]) {
+	if c.tagger == nil {
+		return
+	}
+	if entry.Err != nil {
+		c.tags.remove(key)
+		return
+	}
+	c.tags.set(key, c.tagger(key, entry.Value))
+}
@@ -0,0 +1,68 @@
+package sample1
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// supplierOf tags an item code like "acme-p1" with its supplier, "acme"
+func supplierOf(itemCode string, _ float64) []string {
+	supplier, _, _ := strings.Cut(itemCode, "-")
+	return []string{"supplier:" + supplier}
+}
+
+// Check that invalidating a tag drops every price under it, and only those
+func TestInvalidateTag(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{
+		"acme-p1":  {price: 5, err: nil},
+		"acme-p2":  {price: 7, err: nil},
+		"other-p1": {price: 9, err: nil},
+	}}
+	cache := NewTransparentCache(mockService, time.Minute, WithTagger(supplierOf))
+	getPricesWithNoErr(t, cache, "acme-p1", "acme-p2", "other-p1")
+	cache.InvalidateTag("supplier:acme")
+	if cache.Contains("acme-p1") || cache.Contains("acme-p2") {
+		t.Error("the prices of the tag should have been dropped")
+	}
+	if !cache.Contains("other-p1") {
+		t.Error("the prices of other tags should stay")
+	}
+	getPricesWithNoErr(t, cache, "acme-p1", "acme-p2", "other-p1")
+	assertInt(t, 5, mockService.getNumCalls(), "the dropped prices should be loaded again")
+	cache.InvalidateTag("unknown")
+	assertInt(t, 3, cache.Len(), "an unknown tag should drop nothing")
+}
+
+// Check that the tags follow the entries when they are set again, evicted or cleared
+func TestInvalidateTag_FollowsEntries(t *testing.T) {
+	tagger := func(itemCode string, price float64) []string {
+		if price > 6 {
+			return []string{"expensive"}
+		}
+		return nil
+	}
+	cache := NewTransparentCache(&mockPriceService{}, time.Minute, WithTagger(tagger), WithMaxEntries(2))
+	cache.Set("p1", 7)
+	cache.Set("p1", 5)
+	cache.Set("p2", 8)
+	cache.Set("p3", 9) // evicts p1
+	assertInt(t, 2, len(cache.tags.keys["expensive"]), "wrong number of tagged keys")
+	cache.InvalidateTag("expensive")
+	assertInt(t, 0, cache.Len(), "every expensive price should have been dropped")
+	cache.Set("p4", 9)
+	cache.Clear()
+	if len(cache.tags.keys) != 0 || len(cache.tags.tags) != 0 {
+		t.Error("clear should forget every tag")
+	}
+}
+
+// Check that a tagger of the wrong types is reported when the cache is created
+func TestWithTagger_PanicsOnTypeMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	New(&mockPriceService{}, WithTagger(func(int, float64) []string { return nil }))
+}
@@ -0,0 +1,81 @@
+package sample1
+
+import (
+	"errors"
+	"sync"
+)
+
+// Tenants hands out one TransparentCache per tenant in front of a shared price service, so that tenants have
+// their own entries, stats, max entries and maxAge while sharing the service and its rate limit
+// It is safe for concurrent use by multiple goroutines
+type Tenants struct {
+	service   PriceService
+	opts      []Option
+	perTenant func(tenant string) []Option
+	limiter   *rateLimiter // the rate limit of opts, shared by the tenants without one of their own
+
+	mu     sync.Mutex
+	caches map[string]*TransparentCache
+}
+
+// NewTenants creates the caches of the tenants on demand, configured with opts and then with the options
+// perTenant returns for each of them (perTenant can be nil)
+// A WithRateLimit among opts is a limit for all the tenants together, one returned by perTenant gives that
+// tenant a limit of its own instead. A WithStore among opts would be shared as is, give every tenant its own
+// store or a distinct prefix
+func NewTenants(service PriceService, perTenant func(tenant string) []Option, opts ...Option) *Tenants {
+	cfg := newConfig(opts)
+	return &Tenants{
+		service:   service,
+		opts:      opts,
+		perTenant: perTenant,
+		limiter:   newRateLimiter(cfg.ratePerSecond, cfg.rateBurst, cfg.clock),
+		caches:    map[string]*TransparentCache{},
+	}
+}
+
+// withSharedLimiter makes the cache use limiter unless a later WithRateLimit gives it its own
+func withSharedLimiter(limiter *rateLimiter) Option {
+	return func(c *config) {
+		c.ratePerSecond = 0
+		c.sharedLimiter = limiter
+	}
+}
+
+// Tenant returns the cache of tenant, creating it on first use
+func (t *Tenants) Tenant(tenant string) *TransparentCache {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cache, ok := t.caches[tenant]; ok {
+		return cache
+	}
+	opts := append(append([]Option{}, t.opts...), withSharedLimiter(t.limiter))
+	if t.perTenant != nil {
+		opts = append(opts, t.perTenant(tenant)...)
+	}
+	cache := New(t.service, opts...)
+	t.caches[tenant] = cache
+	return cache
+}
+
+// Stats returns the stats of every tenant that has a cache
+func (t *Tenants) Stats() map[string]Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := make(map[string]Stats, len(t.caches))
+	for tenant, cache := range t.caches {
+		stats[tenant] = cache.Stats()
+	}
+	return stats
+}
+
+// Close closes the cache of every tenant, see Cache.Close
+func (t *Tenants) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var errs []error
+	for _, cache := range t.caches {
+		errs = append(errs, cache.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,59 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Check that tenants get their own entries, stats and options over the shared service
+func TestTenants_AreIsolated(t *testing.T) {
+	clock := newFakeClock()
+	mockService := newRateLimitedService(3)
+	tenants := NewTenants(mockService, func(tenant string) []Option {
+		if tenant == "small" {
+			return []Option{WithMaxEntries(1), WithMaxAge(time.Second)}
+		}
+		return nil
+	}, WithMaxAge(time.Minute), WithClock(clock))
+	defer tenants.Close()
+	big, small := tenants.Tenant("big"), tenants.Tenant("small")
+	if tenants.Tenant("big") != big {
+		t.Error("a tenant should keep its cache")
+	}
+	getPricesWithNoErr(t, big, "p0", "p1", "p2")
+	getPricesWithNoErr(t, small, "p0", "p1")
+	assertInt(t, 3, big.Len(), "wrong number of prices for big")
+	assertInt(t, 1, small.Len(), "small should be bounded to one price")
+
+	clock.Advance(2 * time.Second)
+	getPriceWithNoErr(t, big, "p2")
+	getPriceWithNoErr(t, small, "p1")
+	stats := tenants.Stats()
+	if stats["big"].Hits != 1 || stats["small"].Hits != 0 || stats["small"].Misses != 3 {
+		t.Errorf("wrong stats per tenant: %+v", stats)
+	}
+}
+
+// Check that the rate limit is shared by the tenants, unless one has its own
+func TestTenants_SharedRateLimit(t *testing.T) {
+	clock := newFakeClock()
+	mockService := newRateLimitedService(3)
+	tenants := NewTenants(mockService, func(tenant string) []Option {
+		if tenant == "vip" {
+			return []Option{WithRateLimit(1, 5)}
+		}
+		return nil
+	}, WithRateLimit(0.001, 1), WithClock(clock))
+	ctx := FailFast(context.Background())
+	if _, err := tenants.Tenant("a").GetPriceForCtx(ctx, "p0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tenants.Tenant("b").GetPriceForCtx(ctx, "p1"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("the tenants should share the rate limit, got %v", err)
+	}
+	if _, err := tenants.Tenant("vip").GetPricesForCtx(ctx, "p1", "p2"); err != nil {
+		t.Errorf("a tenant with its own rate limit should not be limited by the others: %v", err)
+	}
+}
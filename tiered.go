@@ -0,0 +1,117 @@
+package sample1
+
+import "sync"
+
+// WatchableStore is a Store that tells when its entries are changed by other processes sharing it
+type WatchableStore[K comparable, V any] interface {
+	Store[K, V]
+	// Watch calls onChange with every key set or deleted by another process and onClear when one clears the store,
+	// until stop is called
+	Watch(onChange func(key K), onClear func()) (stop func())
+}
+
+// TieredStore keeps the entries in two tiers: a fast local one in front of a shared remote one
+// Reads try the local tier first, then the remote one (copying what they find into the local tier),
+// writes go to both tiers
+// If the remote tier is a WatchableStore, local copies are dropped as soon as another process changes them,
+// otherwise they are only replaced once they expire and the cache loads them again
+// Len and Range only see the local tier, deletes (evictions included) apply to both tiers
+type TieredStore[K comparable, V any] struct {
+	local  Store[K, V]
+	remote Store[K, V]
+	mu     sync.Mutex
+	stop   func()
+}
+
+// NewTieredStore creates a store with local in front of remote, a new MapStore if local is nil
+func NewTieredStore[K comparable, V any](local, remote Store[K, V]) *TieredStore[K, V] {
+	if local == nil {
+		local = NewMapStore[K, V]()
+	}
+	s := &TieredStore[K, V]{local: local, remote: remote, stop: func() {}}
+	if watchable, ok := remote.(WatchableStore[K, V]); ok {
+		s.stop = watchable.Watch(local.Delete, local.Clear)
+	}
+	return s
+}
+
+func (s *TieredStore[K, V]) Get(key K) (Entry[V], bool) {
+	if entry, ok := s.local.Get(key); ok {
+		return entry, true
+	}
+	entry, ok := s.remote.Get(key)
+	if ok {
+		s.local.Set(key, entry)
+	}
+	return entry, ok
+}
+
+// GetMany reads the keys missing from the local tier from the remote one, in one call if it is a BatchStore
+func (s *TieredStore[K, V]) GetMany(keys []K) ([]Entry[V], []bool) {
+	entries := make([]Entry[V], len(keys))
+	found := make([]bool, len(keys))
+	var missing []int
+	for i, key := range keys {
+		if entries[i], found[i] = s.local.Get(key); !found[i] {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return entries, found
+	}
+	missingKeys := make([]K, len(missing))
+	for j, i := range missing {
+		missingKeys[j] = keys[i]
+	}
+	var remoteEntries []Entry[V]
+	var remoteFound []bool
+	if batchStore, ok := s.remote.(BatchStore[K, V]); ok {
+		remoteEntries, remoteFound = batchStore.GetMany(missingKeys)
+	} else {
+		remoteEntries, remoteFound = make([]Entry[V], len(missing)), make([]bool, len(missing))
+		for j, key := range missingKeys {
+			remoteEntries[j], remoteFound[j] = s.remote.Get(key)
+		}
+	}
+	for j, i := range missing {
+		if remoteFound[j] {
+			entries[i], found[i] = remoteEntries[j], true
+			s.local.Set(keys[i], remoteEntries[j])
+		}
+	}
+	return entries, found
+}
+
+func (s *TieredStore[K, V]) Set(key K, entry Entry[V]) {
+	s.remote.Set(key, entry)
+	s.local.Set(key, entry)
+}
+
+func (s *TieredStore[K, V]) Delete(key K) {
+	s.remote.Delete(key)
+	s.local.Delete(key)
+}
+
+// Clear clears both tiers
+func (s *TieredStore[K, V]) Clear() {
+	s.remote.Clear()
+	s.local.Clear()
+}
+
+// Len returns the number of entries in the local tier
+func (s *TieredStore[K, V]) Len() int {
+	return s.local.Len()
+}
+
+// Range calls fn for the entries in the local tier
+func (s *TieredStore[K, V]) Range(fn func(key K, entry Entry[V]) bool) {
+	s.local.Range(fn)
+}
+
+// Close stops watching the remote tier for changes, the store keeps working without invalidations
+func (s *TieredStore[K, V]) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+	s.stop = func() {}
+}
@@ -0,0 +1,123 @@
+package sample1
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// watchableStore is a MapStore shared by several tiered stores, it tells each watcher about the changes of the others
+type watchableStore struct {
+	*MapStore[string, float64]
+	mu       sync.Mutex
+	watchers []func(key string)
+}
+
+// view is how one process sees the shared store, its own changes aren't reported to it
+type view struct {
+	*watchableStore
+	id int
+}
+
+func (s *watchableStore) view() *view {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watchers = append(s.watchers, nil)
+	return &view{watchableStore: s, id: len(s.watchers) - 1}
+}
+
+func (v *view) Set(key string, entry Entry[float64]) {
+	v.MapStore.Set(key, entry)
+	v.notify(key)
+}
+
+func (v *view) Delete(key string) {
+	v.MapStore.Delete(key)
+	v.notify(key)
+}
+
+func (v *view) notify(key string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for id, watcher := range v.watchers {
+		if id != v.id && watcher != nil {
+			watcher(key)
+		}
+	}
+}
+
+func (v *view) Watch(onChange func(key string), onClear func()) func() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.watchers[v.id] = onChange
+	return func() {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		v.watchers[v.id] = nil
+	}
+}
+
+// Check that lookups go through the local tier, then the remote one, then the service
+func TestTieredStore_LooksUpEachTier(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+		},
+	}
+	remote := &countingStore{MapStore: NewMapStore[string, float64]()}
+	local := NewMapStore[string, float64]()
+	cache := New(mockService, WithStore[string, float64](NewTieredStore[string, float64](local, remote)))
+
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 1, remote.sets, "the loaded value should have been written to the remote tier")
+	assertInt(t, 1, local.Len(), "the loaded value should have been written to the local tier")
+
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 1, remote.gets, "the second lookup should have been answered by the local tier")
+
+	local.Clear()
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 2, remote.gets, "the remote tier should have been read")
+	assertInt(t, 1, local.Len(), "the remote entry should have been copied to the local tier")
+	assertInt(t, 1, mockService.getNumCalls(), "wrong number of service calls")
+}
+
+// Check that batches read the keys missing from the local tier from the remote one
+func TestTieredStore_GetMany(t *testing.T) {
+	remote := NewMapStore[string, float64]()
+	local := NewMapStore[string, float64]()
+	store := NewTieredStore[string, float64](local, remote)
+	local.Set("p1", Entry[float64]{Value: 5})
+	remote.Set("p2", Entry[float64]{Value: 7})
+
+	entries, found := store.GetMany([]string{"p1", "p2", "p3"})
+	if !found[0] || !found[1] || found[2] {
+		t.Fatalf("wrong keys found: %v", found)
+	}
+	assertFloats(t, []float64{5, 7}, []float64{entries[0].Value, entries[1].Value}, "wrong values read")
+	assertInt(t, 2, local.Len(), "the remote entry should have been copied to the local tier")
+}
+
+// Check that a change made by one process drops the local copies of the others
+func TestTieredStore_InvalidatesLocalCopies(t *testing.T) {
+	shared := &watchableStore{MapStore: NewMapStore[string, float64]()}
+	first := NewTieredStore[string, float64](nil, shared.view())
+	defer first.Close()
+	second := NewTieredStore[string, float64](nil, shared.view())
+	defer second.Close()
+
+	first.Set("p1", Entry[float64]{Value: 5, FetchedAt: time.Now()})
+	if entry, ok := second.Get("p1"); !ok || entry.Value != 5 {
+		t.Fatalf("wrong entry read: %+v, %v", entry, ok)
+	}
+	first.Set("p1", Entry[float64]{Value: 6, FetchedAt: time.Now()})
+	if entry, ok := second.Get("p1"); !ok || entry.Value != 6 {
+		t.Errorf("the local copy should have been dropped, read %+v, %v", entry, ok)
+	}
+	assertInt(t, 1, first.Len(), "the change should not have dropped the local copy of its author")
+
+	first.Delete("p1")
+	if _, ok := second.Get("p1"); ok {
+		t.Error("p1 should have been deleted from every tier")
+	}
+}
@@ -0,0 +1,48 @@
+package sample1
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WithLoadTimeout bounds every call to the loader (the actual service) to timeout, after which the lookup fails
+// with a *TimeoutError, or gets the stale value if WithStaleIfError allows it
+// The calls that don't stop when their context is done keep running in the background, the lookup doesn't wait
+func WithLoadTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.loadTimeout = timeout
+	}
+}
+
+// TimeoutError is the error of the loads cut short by WithLoadTimeout
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %v", e.Timeout)
+}
+
+// Is makes timeouts match context.DeadlineExceeded too
+func (e *TimeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// callWithTimeout calls fn with a context that is done after timeout, and stops waiting for it then
+// A zero or negative timeout leaves fn unbounded
+func callWithTimeout[T any](ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	value, err := callWithContext(timeoutCtx, func() (T, error) {
+		return fn(timeoutCtx)
+	})
+	if err != nil && ctx.Err() == nil && timeoutCtx.Err() == context.DeadlineExceeded {
+		var zero T
+		return zero, &TimeoutError{Timeout: timeout}
+	}
+	return value, err
+}
@@ -0,0 +1,72 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Check that slow calls are cut short with a *TimeoutError
+func TestWithLoadTimeout_BoundsSlowCalls(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay:   time.Second,
+		mockResults: map[string]mockResult{"p1": {price: 5, err: nil}},
+	}
+	cache := New(mockService, WithLoadTimeout(10*time.Millisecond))
+	start := time.Now()
+	_, err := cache.GetPriceFor("p1")
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("the lookup should have been cut short, it took %v", elapsed)
+	}
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Timeout != 10*time.Millisecond {
+		t.Fatalf("expected a timeout error but got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("the timeout should match context.DeadlineExceeded")
+	}
+}
+
+// Check that loaders ignoring their context are bounded too
+func TestWithLoadTimeout_BoundsLoadersIgnoringTheContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	cache := NewCache(func(_ context.Context, key string) (float64, error) {
+		<-release
+		return 5, nil
+	}, WithLoadTimeout(10*time.Millisecond))
+	var timeoutErr *TimeoutError
+	if _, err := cache.Get(context.Background(), "p1"); !errors.As(err, &timeoutErr) {
+		t.Errorf("expected a timeout error but got %v", err)
+	}
+}
+
+// Check that a timeout serves the stale value if stale-if-error allows it
+func TestWithLoadTimeout_ServesStaleValues(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{mockResults: map[string]mockResult{"p1": {price: 5, err: nil}}}
+	cache := New(mockService, WithClock(clock), WithMaxAge(time.Minute), WithStaleIfError(time.Minute),
+		WithLoadTimeout(10*time.Millisecond))
+	getPriceWithNoErr(t, cache, "p1")
+	mockService.callDelay = time.Second
+	clock.Advance(90 * time.Second)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "the stale price should have been served")
+	assertInt(t, 1, int(cache.Stats().StaleServed), "wrong number of stale values served")
+}
+
+// Check that the caller's own deadline is reported as such
+func TestWithLoadTimeout_CallerDeadline(t *testing.T) {
+	mockService := &mockPriceService{
+		callDelay:   time.Second,
+		mockResults: map[string]mockResult{"p1": {price: 5, err: nil}},
+	}
+	cache := New(mockService, WithLoadTimeout(time.Minute))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := cache.GetPriceForCtx(ctx, "p1")
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the caller's deadline error but got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package sample1
+
+import "hash/maphash"
+
+// TinyLFUPolicy is a W-TinyLFU eviction policy
+// New keys go to a small LRU window, keys leaving the window are only admitted into the main area
+// if they were used more often than the key they would replace there, so one-off lookups (a scan
+// over the catalog, for example) can't flush the keys that are used all the time
+// Frequencies are estimated with a count-min sketch that is halved periodically, so old popularity fades away
+type TinyLFUPolicy[K comparable] struct {
+	sketch       *countMinSketch[K]
+	window       *lruList[K]
+	probation    *lruList[K] // main area, keys that were admitted but not used again yet
+	protected    *lruList[K] // main area, keys that were used again after being admitted
+	windowCap    int
+	protectedCap int
+}
+
+// NewTinyLFUPolicy creates a W-TinyLFU eviction policy for a cache of the given capacity,
+// which should match the value given to WithMaxEntries
+func NewTinyLFUPolicy[K comparable](capacity int) *TinyLFUPolicy[K] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	windowCap := capacity / 100
+	if windowCap < 1 {
+		windowCap = 1
+	}
+	protectedCap := (capacity - windowCap) * 8 / 10
+	if protectedCap < 1 {
+		protectedCap = 1
+	}
+	return &TinyLFUPolicy[K]{
+		sketch:       newCountMinSketch[K](capacity),
+		window:       newLRUList[K](),
+		probation:    newLRUList[K](),
+		protected:    newLRUList[K](),
+		windowCap:    windowCap,
+		protectedCap: protectedCap,
+	}
+}
+
+func (p *TinyLFUPolicy[K]) OnInsert(key K) {
+	if p.window.contains(key) || p.probation.contains(key) || p.protected.contains(key) {
+		p.OnAccess(key)
+		return
+	}
+	p.sketch.increment(key)
+	p.window.touch(key)
+	for p.window.len() > p.windowCap {
+		// the oldest key in the window becomes a candidate to enter the main area
+		candidate, _ := p.window.oldest()
+		p.window.remove(candidate)
+		p.probation.touch(candidate)
+	}
+}
+
+func (p *TinyLFUPolicy[K]) OnAccess(key K) {
+	p.sketch.increment(key)
+	switch {
+	case p.window.contains(key):
+		p.window.touch(key)
+	case p.probation.contains(key):
+		p.probation.remove(key)
+		p.protected.touch(key)
+		for p.protected.len() > p.protectedCap {
+			demoted, _ := p.protected.oldest()
+			p.protected.remove(demoted)
+			p.probation.touch(demoted)
+		}
+	case p.protected.contains(key):
+		p.protected.touch(key)
+	}
+}
+
+func (p *TinyLFUPolicy[K]) OnRemove(key K) {
+	p.window.remove(key)
+	p.probation.remove(key)
+	p.protected.remove(key)
+}
+
+func (p *TinyLFUPolicy[K]) Victim() (K, bool) {
+	candidate, ok := p.probation.newest()
+	if !ok {
+		if victim, ok := p.protected.oldest(); ok {
+			return victim, true
+		}
+		return p.window.oldest()
+	}
+	victim, _ := p.probation.oldest()
+	if candidate != victim && p.sketch.estimate(candidate) > p.sketch.estimate(victim) {
+		return victim, true
+	}
+	return candidate, true
+}
+
+// countMinSketch estimates how many times each key was seen, using 4 rows of small saturating counters
+type countMinSketch[K comparable] struct {
+	seed       maphash.Seed
+	rows       [4][]uint8
+	mask       uint64
+	additions  int
+	resetAfter int // after this many additions every counter is halved
+}
+
+const sketchMaxCount = 15
+
+func newCountMinSketch[K comparable](capacity int) *countMinSketch[K] {
+	width := 16
+	for width < 4*capacity {
+		width *= 2
+	}
+	s := &countMinSketch[K]{
+		seed:       maphash.MakeSeed(),
+		mask:       uint64(width - 1),
+		resetAfter: 10 * capacity,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// indexes returns the counter position of key in each row
+func (s *countMinSketch[K]) indexes(key K) [4]uint64 {
+	sum := maphash.Comparable(s.seed, key)
+	var idx [4]uint64
+	for i := range idx {
+		// remix the hash for every row, so that keys colliding in one row don't collide in the others
+		h := sum + uint64(i+1)*0x9e3779b97f4a7c15
+		h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+		h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+		idx[i] = (h ^ (h >> 31)) & s.mask
+	}
+	return idx
+}
+
+func (s *countMinSketch[K]) increment(key K) {
+	for i, idx := range s.indexes(key) {
+		if s.rows[i][idx] < sketchMaxCount {
+			s.rows[i][idx]++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAfter {
+		s.reset()
+	}
+}
+
+func (s *countMinSketch[K]) estimate(key K) uint8 {
+	min := uint8(sketchMaxCount)
+	for i, idx := range s.indexes(key) {
+		if s.rows[i][idx] < min {
+			min = s.rows[i][idx]
+		}
+	}
+	return min
+}
+
+// reset halves every counter, so that keys that were popular long ago are slowly forgotten
+func (s *countMinSketch[K]) reset() {
+	for _, row := range s.rows {
+		for i := range row {
+			row[i] /= 2
+		}
+	}
+	s.additions /= 2
+}
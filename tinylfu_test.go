@@ -0,0 +1,50 @@
+package sample1
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Check that a scan over many one-off items doesn't flush the items that are used all the time
+func TestGetPriceFor_TinyLFUPolicyResistsScans(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{}}
+	for i := 0; i < 1000; i++ {
+		mockService.mockResults[fmt.Sprintf("p%v", i)] = mockResult{price: float64(i)}
+	}
+	capacity := 100
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxEntries(capacity), WithEvictionPolicy(NewTinyLFUPolicy[string](capacity)))
+	for round := 0; round < 10; round++ {
+		for i := 0; i < 10; i++ {
+			getPriceWithNoErr(t, cache, fmt.Sprintf("p%v", i))
+		}
+	}
+	assertInt(t, 10, mockService.getNumCalls(), "wrong number of service calls")
+	for i := 10; i < 1000; i++ {
+		getPriceWithNoErr(t, cache, fmt.Sprintf("p%v", i))
+	}
+	assertInt(t, capacity, cache.Len(), "wrong number of cached prices")
+	calls := mockService.getNumCalls()
+	for i := 0; i < 10; i++ {
+		assertFloat(t, float64(i), getPriceWithNoErr(t, cache, fmt.Sprintf("p%v", i)), "wrong price returned")
+	}
+	assertInt(t, calls, mockService.getNumCalls(), "hot items should have survived the scan")
+}
+
+func TestCountMinSketch_EstimatesAndResets(t *testing.T) {
+	s := newCountMinSketch[string](16)
+	for i := 0; i < 5; i++ {
+		s.increment("p1")
+	}
+	s.increment("p2")
+	if s.estimate("p1") < 5 {
+		t.Errorf("expected p1 to be seen at least 5 times, got %v", s.estimate("p1"))
+	}
+	if s.estimate("p1") <= s.estimate("p2") {
+		t.Error("expected p1 to be estimated as more frequent than p2")
+	}
+	s.reset()
+	if s.estimate("p1") > 3 {
+		t.Errorf("expected p1 estimate to be halved, got %v", s.estimate("p1"))
+	}
+}
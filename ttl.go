@@ -0,0 +1,81 @@
+package sample1
+
+import "time"
+
+// MaxAge returns the maxAge of the keys without an override
+func (c *Cache[K, V]) MaxAge() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.maxAge
+}
+
+// SetMaxAge changes the maxAge of the keys without an override, the values currently cached for them are
+// checked against the new maxAge from now on
+func (c *Cache[K, V]) SetMaxAge(maxAge time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setMaxAge(maxAge)
+}
+
+// setMaxAge is SetMaxAge, c.mu must be held for writing
+func (c *Cache[K, V]) setMaxAge(maxAge time.Duration) {
+	c.maxAge = maxAge
+	var keys []K
+	c.store.Range(func(key K, entry Entry[V]) bool {
+		if _, overridden := c.maxAges[key]; !overridden && entry.Err == nil {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	for _, key := range keys {
+		c.resetMaxAge(key)
+	}
+}
+
+// SetMaxAgeFor overrides the cache maxAge for key, the value currently cached for it (if any) is
+// checked against the new maxAge from now on
+func (c *Cache[K, V]) SetMaxAgeFor(key K, maxAge time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxAges[key] = maxAge
+	c.resetMaxAge(key)
+}
+
+// ResetMaxAgeFor removes the maxAge override for key, so that it uses the cache maxAge again
+func (c *Cache[K, V]) ResetMaxAgeFor(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.maxAges, key)
+	c.resetMaxAge(key)
+}
+
+// resetMaxAge recomputes the maxAge of the entry for key, c.mu must be held for writing
+func (c *Cache[K, V]) resetMaxAge(key K) {
+	if entry, ok := c.store.Get(key); ok && entry.Err == nil {
+		entry.MaxAge = c.valueMaxAge(key, entry.Value)
+		c.store.Set(key, entry)
+	}
+}
+
+// expired tells if entry is older than maxAge at now, or older than the hard TTL
+func (c *Cache[K, V]) expired(entry Entry[V], maxAge time.Duration, now time.Time) bool {
+	if c.hardTTL > 0 {
+		maxAge = min(maxAge, c.hardTTL)
+	}
+	return entry.expired(maxAge, now)
+}
+
+// entryMaxAge returns how long a value stored now for key stays fresh, the maxAge for the key minus the jitter
+// c.mu must be held
+func (c *Cache[K, V]) entryMaxAge(key K) time.Duration {
+	maxAge, ok := c.maxAges[key]
+	if !ok {
+		if maxAge, ok = c.adaptive.get(key); !ok {
+			maxAge = c.maxAge
+		}
+	}
+	if c.jitter > 0 && c.jitter <= 1 {
+		maxAge -= time.Duration(float64(maxAge) * c.jitter * c.random())
+	}
+	return maxAge
+}
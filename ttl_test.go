@@ -0,0 +1,80 @@
+package sample1
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Check that jitter spreads the max age of the entries without ever going over the cache max age
+func TestGetPriceFor_TTLJitterSpreadsExpiration(t *testing.T) {
+	mockService := &mockPriceService{mockResults: map[string]mockResult{}}
+	itemCodes := make([]string, 100)
+	for i := range itemCodes {
+		itemCodes[i] = fmt.Sprintf("p%v", i)
+		mockService.mockResults[itemCodes[i]] = mockResult{price: float64(i)}
+	}
+	maxAge := time.Minute
+	cache := NewTransparentCache(mockService, maxAge, WithTTLJitter(0.2))
+	getPricesWithNoErr(t, cache, itemCodes...)
+	distinct := map[time.Duration]bool{}
+	cache.store.Range(func(_ string, entry Entry[float64]) bool {
+		if entry.MaxAge > maxAge || entry.MaxAge < maxAge-maxAge/5 {
+			t.Errorf("max age %v out of the jitter range", entry.MaxAge)
+		}
+		distinct[entry.MaxAge] = true
+		return true
+	})
+	if len(distinct) < 50 {
+		t.Errorf("expected the max ages to be spread, got %v distinct values", len(distinct))
+	}
+}
+
+func TestEntryMaxAge_WithoutJitter(t *testing.T) {
+	cache := NewTransparentCache(&mockPriceService{}, time.Minute)
+	if cache.entryMaxAge("p1") != time.Minute {
+		t.Errorf("expected the cache max age, got %v", cache.entryMaxAge("p1"))
+	}
+}
+
+// Check that an item with its own max age expires before the others
+func TestSetMaxAgeFor_OverridesCacheMaxAge(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	assertFloats(t, []float64{5, 7}, getPricesWithNoErr(t, cache, "p1", "p2"), "wrong price returned")
+	flashSaleMaxAge := 30 * time.Millisecond
+	cache.SetMaxAgeFor("p1", flashSaleMaxAge)
+	time.Sleep(flashSaleMaxAge * 2)
+	assertFloats(t, []float64{5, 7}, getPricesWithNoErr(t, cache, "p1", "p2"), "wrong price returned")
+	assertInt(t, 3, mockService.getNumCalls(), "only p1 should have expired")
+
+	cache.ResetMaxAgeFor("p1")
+	time.Sleep(flashSaleMaxAge * 2)
+	assertFloat(t, 5, getPriceWithNoErr(t, cache, "p1"), "wrong price returned")
+	assertInt(t, 3, mockService.getNumCalls(), "p1 should be using the cache max age again")
+}
+
+// Check that changing the cache max age applies to the values already cached, but not to the overridden ones
+func TestSetMaxAge_AppliesToCachedValues(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	assertFloats(t, []float64{5, 7}, getPricesWithNoErr(t, cache, "p1", "p2"), "wrong price returned")
+	cache.SetMaxAgeFor("p2", time.Minute)
+	cache.SetMaxAge(30 * time.Millisecond)
+	if cache.MaxAge() != 30*time.Millisecond {
+		t.Errorf("expected the new max age, got %v", cache.MaxAge())
+	}
+	time.Sleep(60 * time.Millisecond)
+	assertFloats(t, []float64{5, 7}, getPricesWithNoErr(t, cache, "p1", "p2"), "wrong price returned")
+	assertInt(t, 3, mockService.getNumCalls(), "only p1 should have expired")
+}
@@ -0,0 +1,25 @@
+package sample1
+
+import "context"
+
+// Warm loads ahead of time the keys that aren't cached or are expired, in parallel as WithMaxConcurrency allows,
+// so that a cache can be primed before taking traffic
+// Warm-up loads don't count as lookups in Stats. If some keys fail it returns a *BatchError with their
+// failures, the other keys are cached anyway
+func (c *Cache[K, V]) Warm(ctx context.Context, keys ...K) error {
+	errs := make([]error, len(keys))
+	entries, found := c.getEntries(keys)
+	now := c.clock.Now()
+	c.parallel(len(keys), func(i int) {
+		if found[i] && entries[i].Err == nil && !c.expired(entries[i], entries[i].MaxAge, now) {
+			return
+		}
+		_, errs[i] = c.flights.do(ctx, keys[i], func(ctx context.Context) (V, error) {
+			return c.load(ctx, keys[i])
+		})
+	})
+	if batchErr := newBatchError(keys, errs); batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package sample1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Check that warmed up keys are answered from the cache, without counting as lookups
+func TestWarm_PrimesTheCache(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	if err := cache.Warm(context.Background(), "p1", "p2"); err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 2, mockService.getNumCalls(), "wrong number of service calls")
+	assertInt(t, 0, int(cache.Stats().Misses), "warm-up loads should not count as misses")
+
+	assertFloats(t, []float64{5, 7}, getPricesWithNoErr(t, cache, "p1", "p2"), "wrong price returned")
+	assertInt(t, 2, mockService.getNumCalls(), "the warmed up keys should have been cached")
+}
+
+// Check that fresh keys aren't loaded again and expired ones are
+func TestWarm_SkipsFreshKeys(t *testing.T) {
+	clock := newFakeClock()
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 7, err: nil},
+		},
+	}
+	cache := New(mockService, WithMaxAge(time.Minute), WithClock(clock))
+	getPriceWithNoErr(t, cache, "p1")
+	clock.Advance(2 * time.Minute)
+	getPriceWithNoErr(t, cache, "p2")
+	if err := cache.Warm(context.Background(), "p1", "p2"); err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 3, mockService.getNumCalls(), "only the expired key should have been loaded again")
+}
+
+// Check that the keys that fail are reported while the others are cached
+func TestWarm_ReportsFailures(t *testing.T) {
+	mockService := &mockPriceService{
+		mockResults: map[string]mockResult{
+			"p1": {price: 5, err: nil},
+			"p2": {price: 0, err: ErrNotFound},
+		},
+	}
+	cache := NewTransparentCache(mockService, time.Minute)
+	err := cache.Warm(context.Background(), "p1", "p2")
+	var batchErr *BatchError[string]
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[0].Key != "p2" {
+		t.Fatalf("expected a batch error for p2 but got %v", err)
+	}
+	if !cache.Contains("p1") {
+		t.Error("p1 should have been cached anyway")
+	}
+}
+
+// Check that a warm-up never runs more service calls in parallel than the configured limit
+func TestWarm_RespectsMaxConcurrency(t *testing.T) {
+	mockService := &concurrencyMockPriceService{
+		mockPriceService: mockPriceService{
+			callDelay:   10 * time.Millisecond,
+			mockResults: map[string]mockResult{},
+		},
+	}
+	var itemCodes []string
+	for i := 0; i < 10; i++ {
+		itemCode := fmt.Sprintf("p%d", i)
+		itemCodes = append(itemCodes, itemCode)
+		mockService.mockResults[itemCode] = mockResult{price: float64(i)}
+	}
+	cache := NewTransparentCache(mockService, time.Minute, WithMaxConcurrency(3))
+	if err := cache.Warm(context.Background(), itemCodes...); err != nil {
+		t.Fatal(err)
+	}
+	assertInt(t, 10, mockService.getNumCalls(), "wrong number of service calls")
+	if mockService.maxRunning > 3 {
+		t.Errorf("at most 3 calls should have run in parallel but %d did", mockService.maxRunning)
+	}
+}
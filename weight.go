@@ -0,0 +1,88 @@
+package sample1
+
+import "fmt"
+
+// WeigherFunc estimates what keeping value for key costs, in the unit of WithMaxWeight (bytes most often)
+type WeigherFunc[K comparable, V any] func(key K, value V) int64
+
+// WithWeigher makes the cache weigh every value it stores with fn, see WithMaxWeight
+// fn must take keys and values of the same types as the cache, NewCache panics otherwise
+func WithWeigher[K comparable, V any](fn WeigherFunc[K, V]) Option {
+	return func(c *config) {
+		c.weigher = fn
+	}
+}
+
+// WithMaxWeight bounds the total weight of the cached entries, evicting the entries chosen by the eviction
+// policy once it is exceeded, so that values of very different sizes are bounded by what they cost rather than
+// by their number. Values are weighed by the WithWeigher function, the load errors, and every value without
+// one, weigh 1. It can be combined with WithMaxEntries, the entries are evicted until both limits hold
+func WithMaxWeight(maxWeight int64) Option {
+	return func(c *config) {
+		c.maxWeight = maxWeight
+	}
+}
+
+// weigherFor returns the configured weigher for a cache of K and V, nil if none was configured
+func weigherFor[K comparable, V any](fn any) WeigherFunc[K, V] {
+	if fn == nil {
+		return nil
+	}
+	weigher, ok := fn.(WeigherFunc[K, V])
+	if !ok {
+		panic(fmt.Sprintf("sample1: weigher %T can't be used for a cache of %T", fn, (*Cache[K, V])(nil)))
+	}
+	return weigher
+}
+
+// weights keeps the weight of every cached entry and their total, it is guarded by the mutex of the cache
+type weights[K comparable] struct {
+	byKey map[K]int64
+	total int64
+}
+
+// set replaces the weight of key
+func (w *weights[K]) set(key K, weight int64) {
+	if w.byKey == nil {
+		w.byKey = map[K]int64{}
+	}
+	w.total += weight - w.byKey[key]
+	w.byKey[key] = weight
+}
+
+// remove forgets the weight of key
+func (w *weights[K]) remove(key K) {
+	w.total -= w.byKey[key]
+	delete(w.byKey, key)
+}
+
+// clear forgets every weight
+func (w *weights[K]) clear() {
+	w.byKey, w.total = nil, 0
+}
+
+// weigh records the weight of the entry stored for key, if the cache is bounded by weight
+// c.mu must be held for writing
+func (c *Cache[K, V]) weigh(key K, entry Entry[V]) {
+	if c.maxWeight <= 0 {
+		return
+	}
+	weight := int64(1)
+	if entry.Err == nil && c.weigher != nil {
+		weight = max(c.weigher(key, entry.Value), 0)
+	}
+	c.weights.set(key, weight)
+}
+
+// overflows tells if the cache holds more than maxEntries entries or weighs more than maxWeight
+// c.mu must be held
+func (c *Cache[K, V]) overflows() bool {
+	return (c.maxEntries > 0 && c.store.Len() > c.maxEntries) || (c.maxWeight > 0 && c.weights.total > c.maxWeight)
+}
+
+// Weight returns the total weight of the cached entries, zero unless WithMaxWeight is used
+func (c *Cache[K, V]) Weight() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.weights.total
+}
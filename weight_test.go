@@ -0,0 +1,82 @@
+package sample1
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// lenOf weighs a string by its length
+func lenOf(_ int, value string) int64 {
+	return int64(len(value))
+}
+
+// Check that the entries are evicted once their total weight goes past maxWeight, however few they are
+func TestWithMaxWeight_EvictsByWeight(t *testing.T) {
+	values := map[int]string{1: "aaaa", 2: "bbbb", 3: "cccccccc", 4: "d"}
+	cache := NewCache(func(_ context.Context, key int) (string, error) { return values[key], nil },
+		WithMaxWeight(10), WithWeigher(lenOf))
+	for _, key := range []int{1, 2} {
+		if _, err := cache.Get(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertInt(t, 8, int(cache.Weight()), "wrong weight")
+	cache.Get(context.Background(), 3) // evicts 1 and 2
+	assertInt(t, 1, cache.Len(), "the lightest entries should have been evicted to make room")
+	assertInt(t, 8, int(cache.Weight()), "the weight of the evicted entries should be forgotten")
+	cache.Get(context.Background(), 4)
+	assertInt(t, 2, cache.Len(), "a light entry should fit")
+	assertInt(t, 2, int(cache.Stats().Evictions), "wrong number of evictions")
+}
+
+// Check that the eviction policy picks the entries evicted by weight too
+func TestWithMaxWeight_UsesTheEvictionPolicy(t *testing.T) {
+	values := map[int]string{1: "aaaa", 2: "bbbb", 3: "cccc"}
+	cache := NewCache(func(_ context.Context, key int) (string, error) { return values[key], nil },
+		WithMaxWeight(8), WithWeigher(lenOf), WithEvictionPolicy(NewLFUPolicy[int]()))
+	for _, key := range []int{1, 1, 1, 2} {
+		cache.Get(context.Background(), key)
+	}
+	cache.Get(context.Background(), 3) // the LFU policy evicts 2, used once, where LRU would evict 1
+	if _, _, ok := cache.Peek(1); !ok {
+		t.Error("the most frequently used entry should have been kept")
+	}
+	if _, _, ok := cache.Peek(2); ok {
+		t.Error("the least frequently used entry should have been evicted")
+	}
+}
+
+// Check that the weight follows the entries when they are set again, invalidated or cleared
+func TestWithMaxWeight_FollowsEntries(t *testing.T) {
+	cache := NewCache(func(context.Context, int) (string, error) { return "", nil },
+		WithMaxWeight(100), WithWeigher(lenOf))
+	cache.Set(1, "aaaa")
+	cache.Set(1, "aa")
+	cache.Set(2, "bbb")
+	assertInt(t, 5, int(cache.Weight()), "setting a key again should replace its weight")
+	cache.Invalidate(2)
+	assertInt(t, 2, int(cache.Weight()), "an invalidated entry should weigh nothing")
+	cache.Clear()
+	assertInt(t, 0, int(cache.Weight()), "a cleared cache should weigh nothing")
+}
+
+// Check that without a weigher every entry weighs 1, and that maxEntries still holds alongside maxWeight
+func TestWithMaxWeight_WithoutWeigher(t *testing.T) {
+	cache := NewTransparentCache(&mockPriceService{}, time.Minute, WithMaxWeight(3), WithMaxEntries(2))
+	cache.Set("p1", 5)
+	cache.Set("p2", 6)
+	cache.Set("p3", 7)
+	assertInt(t, 2, cache.Len(), "maxEntries should still hold")
+	assertInt(t, 2, int(cache.Weight()), "every entry should weigh 1")
+}
+
+// Check that a weigher of the wrong types is reported when the cache is created
+func TestWithWeigher_PanicsOnTypeMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	New(&mockPriceService{}, WithWeigher(lenOf))
+}